@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gopay/internal/config"
+)
+
+func configCommand(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load("gopay config print", args[1:], os.Getenv, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	return config.Print(os.Stdout, cfg)
+}
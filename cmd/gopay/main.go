@@ -1,72 +1,46 @@
 package main
 
 import (
-	"net/http"
-	"time"
-
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
-
-	"github.com/gopay/internal"
-	"github.com/gopay/internal/models"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
 )
 
-func initDB() {
-	// temp fake db for accounts and transactions
-	models.Accounts["0001"] = &models.Account{
-		AccountId: "0001",
-		Name:      "Shankar",
-		LastName:  "Nakai",
-	}
+const usage = `Usage:
+  gopay [serve] [flags]                     run the API server
+  gopay config print [flags]                print the effective configuration
+  gopay seed -file FILE [-dry-run] [flags]  load a fixtures file
 
-	models.Accounts["0002"] = &models.Account{
-		AccountId: "0002",
-		Name:      "Jessica",
-		LastName:  "Lourenco",
-	}
+Run a command with -h to list its flags.
+`
 
-	models.Accounts["0003"] = &models.Account{
-		AccountId: "0003",
-		Name:      "Caio",
-		LastName:  "Henrique",
+func main() {
+	args := os.Args[1:]
+	cmd := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
 	}
 
-	models.Accounts["0004"] = &models.Account{
-		AccountId: "0004",
-		Name:      "Karina",
-		LastName:  "Domingues",
+	var err error
+	switch cmd {
+	case "serve":
+		err = serve(args)
+	case "config":
+		err = configCommand(args)
+	case "seed":
+		err = seedCommand(args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
 	}
 
-	models.Transactions["1000000"] = &models.Transaction{
-		TransactionId: "1000000",
-		Owner:         "0001",
-		Sender:        "0001",
-		Receiver:      "0001",
-		CreatedAt:     time.Now(),
-		Amount:        7000.00,
-		IsConsumed:    false,
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
 	}
-
-	models.Transactions["2000000"] = &models.Transaction{
-		TransactionId: "2000000",
-		Owner:         "0002",
-		Sender:        "0002",
-		Receiver:      "0002",
-		CreatedAt:     time.Now(),
-		Amount:        3000.00,
-		IsConsumed:    false,
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 }
-
-func main() {
-	router := internal.Router(internal.Routes())
-	initDB()
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-
-	log.Info().Msg("Server started at port :8080")
-
-	log.
-		Fatal().
-		Err(http.ListenAndServe(":8080", router)).
-		Msg("Server closed")
-}
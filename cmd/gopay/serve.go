@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+
+	"github.com/gopay/internal"
+	"github.com/gopay/internal/audit"
+	"github.com/gopay/internal/auth"
+	"github.com/gopay/internal/categories"
+	"github.com/gopay/internal/config"
+	"github.com/gopay/internal/events"
+	"github.com/gopay/internal/export"
+	"github.com/gopay/internal/gql"
+	"github.com/gopay/internal/groups"
+	"github.com/gopay/internal/health"
+	"github.com/gopay/internal/imports"
+	"github.com/gopay/internal/metrics"
+	"github.com/gopay/internal/openapi"
+	"github.com/gopay/internal/outbox"
+	"github.com/gopay/internal/payouts"
+	"github.com/gopay/internal/reconcile"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/rpc"
+	"github.com/gopay/internal/seed"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/splits"
+	"github.com/gopay/internal/statement"
+	"github.com/gopay/internal/stream"
+	"github.com/gopay/internal/tracing"
+	"github.com/gopay/internal/utils"
+	"github.com/gopay/internal/webhook"
+)
+
+func setupLogging(cfg config.Log) {
+	level, _ := zerolog.ParseLevel(cfg.Level)
+	zerolog.SetGlobalLevel(level)
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+
+	if cfg.Format == config.LogFormatConsole {
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339})
+	}
+
+	log.Logger = log.Logger.Hook(tracing.LogHook{})
+	zerolog.DefaultContextLogger = &log.Logger
+}
+
+func serve(args []string) error {
+	cfg, err := config.Load("gopay serve", args, os.Getenv, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	setupLogging(cfg.Log)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	m := metrics.New(registry)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Trace.Exporter)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = shutdownTracing(context.Background()) }()
+	tracer := tracing.Tracer()
+
+	subscriptions := webhook.NewSubscriptionRepo()
+	deliveries := webhook.NewDeliveryRepo()
+	dispatcher := webhook.NewDispatcher(subscriptions, deliveries)
+	if cfg.Webhooks.AllowPrivateTargets {
+		dispatcher.AllowPrivateTargets()
+	}
+	bus := events.NewBus()
+
+	outboxRepo := repository.NewOutboxRepo()
+	unitOfWork := repository.NewUnitOfWork()
+	relay := outbox.NewRelay(outboxRepo, time.Duration(cfg.Outbox.Interval))
+	relay.AddSink("webhooks", dispatcher)
+	relay.AddSink("bus", bus)
+	if cfg.Outbox.File != "" {
+		relay.AddSink("file", outbox.NewFileSink(cfg.Outbox.File))
+	}
+
+	baseAccounts, baseTransactions := openStorage(cfg.Storage)
+
+	var accountRepo repository.AccountRepo = baseAccounts
+	accountRepo = metrics.NewAccountRepo(accountRepo, m)
+	accountRepo = tracing.NewAccountRepo(accountRepo, tracer)
+	accountRepo = events.NewAccountRepo(accountRepo, outboxRepo, unitOfWork)
+
+	auditLog := audit.NewLog()
+	categoryRules := categories.NewRepo()
+	var transactionRepo repository.TransactionRepo = baseTransactions
+	transactionRepo = categories.NewTransactionRepo(transactionRepo, categoryRules)
+	transactionRepo = audit.NewTransactionRepo(transactionRepo, auditLog)
+	transactionRepo = metrics.NewTransactionRepo(transactionRepo, m)
+	transactionRepo = tracing.NewTransactionRepo(transactionRepo, tracer)
+
+	var transactionService service.TransactionService = service.NewTransactionService(transactionRepo, accountRepo, outboxRepo, unitOfWork)
+	transactionService = metrics.NewTransactionService(transactionService, m)
+	transactionService = tracing.NewTransactionService(transactionService, tracer)
+
+	hub := stream.NewHub(stream.DefaultBufferSize)
+	bus.Subscribe(hub.Handle)
+
+	// fixtures are parsed up front so a broken file fails before listening
+	fixtures := []*seed.Fixtures{}
+	if cfg.Features.SeedDemoData {
+		fixtures = append(fixtures, seed.Demo())
+	}
+	if cfg.Seed.File != "" {
+		f, err := seed.ParseFile(cfg.Seed.File)
+		if err != nil {
+			return err
+		}
+		fixtures = append(fixtures, f)
+	}
+
+	spec, err := openapi.Load()
+	if err != nil {
+		return err
+	}
+
+	routes := internal.Routes(internal.NewHandler(transactionService, transactionRepo, accountRepo))
+	routes = append(routes, internal.WebhookRoutes(internal.NewWebhookHandler(dispatcher, subscriptions, deliveries, accountRepo))...)
+	routes = append(routes, internal.StreamRoutes(internal.NewStreamHandler(hub, accountRepo))...)
+	imported := imports.NewRepo()
+	routes = append(routes, internal.ImportRoutes(internal.NewImportHandler(imports.NewImporter(accountRepo, transactionRepo, transactionService, imported), imported))...)
+	paid := payouts.NewRepo()
+	routes = append(routes, internal.PayoutRoutes(internal.NewPayoutHandler(payouts.NewProcessor(accountRepo, transactionRepo, transactionService, unitOfWork, paid), paid))...)
+	split := splits.NewRepo()
+	routes = append(routes, internal.SplitRoutes(internal.NewSplitHandler(splits.NewSplitter(accountRepo, transactionService, split), split))...)
+	routes = append(routes, internal.GroupRoutes(internal.NewGroupHandler(groups.NewLedger(accountRepo, transactionService, unitOfWork, groups.NewRepo())))...)
+	generator := statement.NewGenerator(transactionRepo)
+	routes = append(routes, internal.StatementRoutes(internal.NewStatementHandler(generator, accountRepo))...)
+	routes = append(routes, internal.ExportRoutes(internal.NewExportHandler(export.NewExporter(generator, accountRepo), accountRepo))...)
+	reconciler := reconcile.NewReconciler(accountRepo, transactionRepo, unitOfWork, m)
+	routes = append(routes, internal.ReconcileRoutes(internal.NewReconcileHandler(reconciler))...)
+	routes = append(routes, internal.AuditRoutes(internal.NewAuditHandler(auditLog))...)
+	routes = append(routes, internal.CategoryRoutes(internal.NewCategoryHandler(categories.NewCategoriser(accountRepo, transactionRepo, categoryRules)))...)
+	routes = append(routes, internal.OpenAPIRoutes(internal.NewOpenAPIHandler(spec))...)
+	if cfg.Features.GraphQL {
+		routes = append(routes, internal.GraphQLRoutes(internal.NewGraphQLHandler(gql.NewResolver(transactionService, transactionRepo, accountRepo)))...)
+	}
+	if cfg.Features.Metrics {
+		routes = append(routes, internal.MetricsRoutes(internal.NewMetricsHandler(m))...)
+	}
+
+	checker := health.NewChecker()
+	checker.Add("storage", func(ctx context.Context) error {
+		// the memory backend has nothing to dial, a read shows it isn't wedged
+		_, err := accountRepo.FindMany(ctx, nil)
+		return err
+	})
+	checker.Add("outbox-relay", relay.Check)
+	routes = append(routes, internal.HealthRoutes(internal.NewHealthHandler(checker))...)
+
+	// validated by config.Load
+	keys, err := auth.ParseKeys(cfg.Auth.APIKeys)
+	if err != nil {
+		return err
+	}
+
+	router := internal.Router(routes,
+		internal.Trace(tracer),
+		internal.RequestID(),
+		internal.AccessLog(),
+		internal.Instrument(m),
+		internal.Recover(),
+		internal.Authenticate(keys, "/", "/openapi.json", "/metrics", "/healthz", "/readyz"),
+		internal.Authorize(keys, auth.ScopeAdmin, internal.AdminPaths...),
+		internal.ValidateRequests(spec),
+	)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.Reconcile.Interval > 0 {
+		utils.Go(func() { reconciler.Loop(ctx, time.Duration(cfg.Reconcile.Interval)) })
+	}
+	if cfg.Audit.AnchorInterval > 0 {
+		utils.Go(func() { auditLog.Loop(ctx, time.Duration(cfg.Audit.AnchorInterval), cfg.Audit.AnchorFile) })
+	}
+
+	relayCtx, cancelRelay := context.WithCancel(context.Background())
+	defer cancelRelay()
+	relayDone := make(chan struct{})
+	go func() {
+		relay.Run(relayCtx)
+		close(relayDone)
+	}()
+	stopRelay := func(ctx context.Context) error {
+		cancelRelay()
+		select {
+		case <-relayDone:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return relay.Drain(ctx)
+	}
+
+	serveErr := make(chan error, 2)
+
+	var grpcServer *grpc.Server
+	if cfg.Features.GRPC {
+		grpcServer = rpc.NewServer(transactionService, transactionRepo, accountRepo, hub).Register(rpc.Authenticate(keys)...)
+		lis, err := net.Listen("tcp", cfg.Server.GRPCAddr)
+		if err != nil {
+			return err
+		}
+		go func() {
+			serveErr <- grpcServer.Serve(lis)
+		}()
+		log.Info().Msgf("gRPC server started at %s", cfg.Server.GRPCAddr)
+	}
+
+	server := &http.Server{
+		Addr:         cfg.Server.Addr,
+		Handler:      router,
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout),
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout),
+		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout),
+	}
+	// open event streams never go idle, end them so Shutdown can finish
+	server.RegisterOnShutdown(hub.Close)
+
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	log.Info().Msgf("Server started at %s", cfg.Server.Addr)
+
+	// /readyz reports starting until the data is in place
+	runErr := seedAll(ctx, seed.NewLoader(accountRepo, transactionRepo, unitOfWork), fixtures)
+	if runErr != nil {
+		log.Error().Err(runErr).Msg("Seeding failed, shutting down")
+	} else {
+		checker.SetState(health.StateServing)
+
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Shutting down")
+		case runErr = <-serveErr:
+			log.Error().Err(runErr).Msg("Server stopped, shutting down")
+		}
+	}
+	stop()
+
+	// keep serving while /readyz fails so load balancers stop routing here
+	// before the listeners close
+	checker.SetState(health.StateDraining)
+	if runErr == nil {
+		time.Sleep(time.Duration(cfg.Server.DrainDelay))
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
+	defer cancel()
+
+	return errors.Join(runErr, shutdown(shutdownCtx, server, grpcServer, stopRelay, dispatcher))
+}
+
+// shutdown stops accepting connections, drains in-flight requests and
+// streams, stops the outbox relay after a last flush, abandons webhook
+// retries and waits for the goroutines started with utils.Go, all within
+// ctx's deadline.
+func shutdown(ctx context.Context, server *http.Server, grpcServer *grpc.Server, stopRelay func(context.Context) error, dispatcher *webhook.Dispatcher) error {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, 3)
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs[0] = server.Shutdown(ctx)
+	}()
+
+	if grpcServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stopped := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				grpcServer.Stop()
+				errs[1] = ctx.Err()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// requests are drained, nothing appends to the outbox anymore
+	errs[2] = stopRelay(ctx)
+	if errs[2] != nil {
+		log.Error().Err(errs[2]).Msg("Outbox not fully relayed at shutdown")
+	}
+	dispatcher.Close()
+
+	err := utils.Wait(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Background work still running at shutdown deadline")
+	}
+
+	err = errors.Join(append(errs, err)...)
+	if err == nil {
+		log.Info().Msg("Shutdown complete")
+	}
+	return err
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"net/url"
+
+	"github.com/gopay/internal/config"
+	"github.com/gopay/internal/repository"
+)
+
+// openStorage returns the repositories of the backend storage.dsn selects,
+// before any decorator. The DSN is validated to be memory://, the only
+// backend so far, which starts empty and lives as long as the process.
+func openStorage(_ config.Storage) (repository.AccountRepo, repository.TransactionRepo) {
+	return repository.NewAccountRepo(), repository.NewTransactionRepo()
+}
+
+// ephemeral tells whether what is written to the storage is gone when the
+// process exits.
+func ephemeral(cfg config.Storage) bool {
+	u, err := url.Parse(cfg.DSN)
+	return err == nil && u.Scheme == config.StorageMemory
+}
@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gopay/internal/models"
+)
+
+// GenesisHash is the PrevHash of the first record.
+var GenesisHash = hex.EncodeToString(make([]byte, sha256.Size))
+
+// Hash returns the hex sha256 of record without its Hash field. The record
+// is encoded with encoding/json, whose field order and number formatting are
+// stable, so anyone holding the records can recompute it.
+func Hash(record models.AuditRecord) string {
+	record.Hash = ""
+	// a record is plain data, it always encodes
+	data, _ := json.Marshal(record)
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify walks records, which must start at seq 1, and checks every record
+// is the next seq, holds the hash of the one before and hashes to its own
+// Hash. Then every anchor must match the record it checkpoints. The result
+// points at the lowest seq found broken.
+func Verify(records []models.AuditRecord, anchors []models.AuditAnchor) models.AuditVerification {
+	result := models.AuditVerification{Records: len(records), Anchors: len(anchors)}
+	broken := func(seq uint64, format string, args ...interface{}) {
+		if result.BrokenAt != nil && *result.BrokenAt <= seq {
+			return
+		}
+		result.BrokenAt = &seq
+		result.Reason = fmt.Sprintf(format, args...)
+	}
+
+	hashes := make(map[uint64]string, len(records))
+	prev := GenesisHash
+	for i, r := range records {
+		want := uint64(i) + 1
+		if r.Seq != want {
+			broken(want, "record %d is missing, found %d in its place", want, r.Seq)
+			break
+		}
+		if r.PrevHash != prev {
+			broken(r.Seq, "prevHash %s doesn't match the hash of record %d, %s", r.PrevHash, r.Seq-1, prev)
+			break
+		}
+		if h := Hash(r); h != r.Hash {
+			broken(r.Seq, "hash %s doesn't match the content of the record, %s", r.Hash, h)
+			break
+		}
+
+		hashes[r.Seq] = r.Hash
+		prev = r.Hash
+	}
+
+	for _, a := range anchors {
+		h, found := hashes[a.Seq]
+		switch {
+		case !found && a.Seq > uint64(len(records)):
+			broken(a.Seq, "record %d is anchored but the log ends at %d", a.Seq, len(records))
+		case found && h != a.Hash:
+			broken(a.Seq, "hash %s doesn't match the anchor taken at %s, %s", h, a.At.Format(time.RFC3339), a.Hash)
+		}
+	}
+
+	result.Ok = result.BrokenAt == nil
+	return result
+}
@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// newChain returns a log of four records with an anchor on the third.
+func newChain() ([]models.AuditRecord, []models.AuditAnchor) {
+	l := NewLog()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	t := models.Transaction{TransactionId: "t-1", Owner: "a", Sender: "a", Receiver: "a", CreatedAt: now, Amount: 100}
+	l.Append(models.AuditRecord{Operation: models.AuditCreate, TransactionIds: []string{"t-1"}, Transaction: &t})
+	l.Append(models.AuditRecord{Operation: models.AuditConsume, TransactionIds: []string{"t-1"}})
+	l.Append(models.AuditRecord{Operation: models.AuditStatus, TransactionIds: []string{"t-1"}, Status: models.TransactionReversed})
+	l.Checkpoint()
+	l.Append(models.AuditRecord{Operation: models.AuditConsume, TransactionIds: []string{"t-1"}})
+
+	return l.Records(1), l.Anchors()
+}
+
+func TestVerify(t *testing.T) {
+	scenarios := map[string]struct {
+		tamper       func(records []models.AuditRecord, anchors []models.AuditAnchor) ([]models.AuditRecord, []models.AuditAnchor)
+		wantBrokenAt uint64
+		wantReason   string
+	}{
+		"intact": {
+			tamper: func(records []models.AuditRecord, anchors []models.AuditAnchor) ([]models.AuditRecord, []models.AuditAnchor) {
+				return records, anchors
+			},
+		},
+		"changed-amount": {
+			tamper: func(records []models.AuditRecord, anchors []models.AuditAnchor) ([]models.AuditRecord, []models.AuditAnchor) {
+				changed := *records[0].Transaction
+				changed.Amount = 1000
+				records[0].Transaction = &changed
+				return records, anchors
+			},
+			wantBrokenAt: 1,
+			wantReason:   "content",
+		},
+		"rehashed-record": {
+			tamper: func(records []models.AuditRecord, anchors []models.AuditAnchor) ([]models.AuditRecord, []models.AuditAnchor) {
+				records[1].TransactionIds = []string{"t-2"}
+				records[1].Hash = Hash(records[1])
+				return records, anchors
+			},
+			wantBrokenAt: 3,
+			wantReason:   "prevHash",
+		},
+		"dropped-record": {
+			tamper: func(records []models.AuditRecord, anchors []models.AuditAnchor) ([]models.AuditRecord, []models.AuditAnchor) {
+				return append(records[:1], records[2:]...), anchors
+			},
+			wantBrokenAt: 2,
+			wantReason:   "missing",
+		},
+		"rewritten-from-scratch": {
+			tamper: func(records []models.AuditRecord, anchors []models.AuditAnchor) ([]models.AuditRecord, []models.AuditAnchor) {
+				l := NewLog()
+				for _, r := range records {
+					if r.Operation != models.AuditStatus {
+						l.Append(r)
+					}
+				}
+				return l.Records(1), anchors
+			},
+			wantBrokenAt: 3,
+			wantReason:   "anchor",
+		},
+		"truncated": {
+			tamper: func(records []models.AuditRecord, anchors []models.AuditAnchor) ([]models.AuditRecord, []models.AuditAnchor) {
+				return records[:2], anchors
+			},
+			wantBrokenAt: 3,
+			wantReason:   "log ends at 2",
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			records, anchors := tcase.tamper(newChain())
+
+			result := Verify(records, anchors)
+
+			assert.Equal(t, len(records), result.Records)
+			assert.Equal(t, 1, result.Anchors)
+			if tcase.wantBrokenAt == 0 {
+				assert.True(t, result.Ok)
+				assert.Nil(t, result.BrokenAt)
+				return
+			}
+			assert.False(t, result.Ok)
+			if assert.NotNil(t, result.BrokenAt) {
+				assert.Equal(t, tcase.wantBrokenAt, *result.BrokenAt)
+			}
+			assert.Contains(t, result.Reason, tcase.wantReason)
+		})
+	}
+}
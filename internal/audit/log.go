@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gopay/internal/models"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+)
+
+// Log is the hash chain of every write to the transaction ledger, kept in
+// memory like the ledger itself.
+type Log struct {
+	mu      sync.RWMutex
+	records []models.AuditRecord
+	anchors []models.AuditAnchor
+	now     func() time.Time
+}
+
+func NewLog() *Log {
+	return &Log{now: time.Now}
+}
+
+// Append chains record, filling in its seq, time and hashes.
+func (l *Log) Append(record models.AuditRecord) models.AuditRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	prev := GenesisHash
+	if n := len(l.records); n > 0 {
+		prev = l.records[n-1].Hash
+	}
+
+	record.Seq = uint64(len(l.records)) + 1
+	record.At = l.now().UTC()
+	record.TransactionIds = append([]string{}, record.TransactionIds...)
+	record.PrevHash = prev
+	record.Hash = Hash(record)
+	l.records = append(l.records, record)
+
+	return record
+}
+
+// Records returns the records from seq from on, oldest first.
+func (l *Log) Records(from uint64) []models.AuditRecord {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if from < 1 {
+		from = 1
+	}
+	if from > uint64(len(l.records)) {
+		return []models.AuditRecord{}
+	}
+	return append([]models.AuditRecord{}, l.records[from-1:]...)
+}
+
+// Anchors returns the checkpoints taken so far, oldest first.
+func (l *Log) Anchors() []models.AuditAnchor {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return append([]models.AuditAnchor{}, l.anchors...)
+}
+
+// Checkpoint anchors the latest record. It returns false when there is
+// nothing new since the last anchor.
+func (l *Log) Checkpoint() (models.AuditAnchor, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := len(l.records)
+	if n == 0 || (len(l.anchors) > 0 && l.anchors[len(l.anchors)-1].Seq == uint64(n)) {
+		return models.AuditAnchor{}, false
+	}
+
+	anchor := models.AuditAnchor{Seq: uint64(n), Hash: l.records[n-1].Hash, At: l.now().UTC()}
+	l.anchors = append(l.anchors, anchor)
+	return anchor, true
+}
+
+// Verify checks the whole chain against the anchors taken so far.
+func (l *Log) Verify() models.AuditVerification {
+	return Verify(l.Records(1), l.Anchors())
+}
+
+// Loop takes a checkpoint every interval until ctx is cancelled. With a
+// file, every new anchor is also appended to it as a JSON line, so the
+// anchors outlive the process and can be handed to gopayctl audit verify.
+func (l *Log) Loop(ctx context.Context, interval time.Duration, file string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		anchor, ok := l.Checkpoint()
+		if !ok || file == "" {
+			continue
+		}
+		if err := appendLine(file, anchor); err != nil {
+			log.Error().Err(err).Str("file", file).Msg("Audit::Loop")
+		}
+	}
+}
+
+func appendLine(path string, anchor models.AuditAnchor) error {
+	line, err := jsoniter.Marshal(anchor)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+)
+
+var _ repository.TransactionRepo = (*transactionRepo)(nil)
+
+// transactionRepo appends a record to the log for every write of the
+// decorated TransactionRepo. Writes inside a unit of work are recorded once
+// it commits, so the log never holds writes that were undone.
+type transactionRepo struct {
+	repository.TransactionRepo
+	log *Log
+}
+
+func NewTransactionRepo(next repository.TransactionRepo, log *Log) *transactionRepo {
+	return &transactionRepo{
+		TransactionRepo: next,
+		log:             log,
+	}
+}
+
+func (r *transactionRepo) Create(ctx context.Context, t models.Transaction) (string, error) {
+	id, err := r.TransactionRepo.Create(ctx, t)
+	if err != nil {
+		return id, err
+	}
+
+	// record what was stored, with its type and status filled in
+	t.TransactionId = id
+	if stored, err := r.TransactionRepo.FindOne(ctx, id); err == nil {
+		t = stored
+	}
+	repository.AfterCommit(ctx, func() {
+		r.log.Append(models.AuditRecord{Operation: models.AuditCreate, TransactionIds: []string{id}, Transaction: &t})
+	})
+	return id, nil
+}
+
+func (r *transactionRepo) MarkAsConsumed(ctx context.Context, id string) error {
+	err := r.TransactionRepo.MarkAsConsumed(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	repository.AfterCommit(ctx, func() {
+		r.log.Append(models.AuditRecord{Operation: models.AuditConsume, TransactionIds: []string{id}})
+	})
+	return nil
+}
+
+func (r *transactionRepo) SetConsumedBy(ctx context.Context, ids []string, consumedBy string) error {
+	err := r.TransactionRepo.SetConsumedBy(ctx, ids, consumedBy)
+	if err != nil {
+		return err
+	}
+
+	ids = append([]string{}, ids...)
+	repository.AfterCommit(ctx, func() {
+		r.log.Append(models.AuditRecord{Operation: models.AuditLink, TransactionIds: ids, ConsumedBy: consumedBy})
+	})
+	return nil
+}
+
+func (r *transactionRepo) UpdateStatus(ctx context.Context, id string, status models.TransactionStatus) error {
+	err := r.TransactionRepo.UpdateStatus(ctx, id, status)
+	if err != nil {
+		return err
+	}
+
+	repository.AfterCommit(ctx, func() {
+		r.log.Append(models.AuditRecord{Operation: models.AuditStatus, TransactionIds: []string{id}, Status: status})
+	})
+	return nil
+}
+
+func (r *transactionRepo) SetCategory(ctx context.Context, id string, category string) error {
+	err := r.TransactionRepo.SetCategory(ctx, id, category)
+	if err != nil {
+		return err
+	}
+
+	repository.AfterCommit(ctx, func() {
+		r.log.Append(models.AuditRecord{Operation: models.AuditCategory, TransactionIds: []string{id}, Category: category})
+	})
+	return nil
+}
@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionRepo(t *testing.T) {
+	utils.SetSyncGoroutine()
+	t.Cleanup(utils.ResetGoroutine)
+	ctx := context.Background()
+
+	accounts := repository.NewAccountRepo()
+	l := NewLog()
+	transactions := NewTransactionRepo(repository.NewTransactionRepo(), l)
+	unitOfWork := repository.NewUnitOfWork()
+	svc := service.NewTransactionService(transactions, accounts, repository.NewOutboxRepo(), unitOfWork)
+
+	shankar, err := accounts.Create(ctx, "Shankar", "Nakai")
+	require.NoError(t, err)
+	jessica, err := accounts.Create(ctx, "Jessica", "Lourenco")
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Deposit(ctx, shankar, 100, service.Note{}))
+	require.NoError(t, svc.Transfer(ctx, shankar, jessica, -40, service.Note{}))
+	require.NoError(t, svc.Deposit(ctx, jessica, 7, service.Note{}))
+	received, err := transactions.FindAll(ctx, jessica)
+	require.NoError(t, err)
+	_, err = svc.UpdateStatus(ctx, received[len(received)-1].TransactionId, models.TransactionReversed)
+	require.NoError(t, err)
+
+	errAbort := errors.New("abort")
+	err = unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := svc.Deposit(ctx, jessica, 5, service.Note{}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	require.ErrorIs(t, err, errAbort)
+
+	operations := []models.AuditOperation{}
+	statuses := []models.TransactionStatus{}
+	for _, r := range l.Records(1) {
+		operations = append(operations, r.Operation)
+		if r.Operation == models.AuditCreate {
+			found, err := transactions.FindOne(ctx, r.TransactionIds[0])
+			require.NoError(t, err)
+			assert.Equal(t, found.Amount, r.Transaction.Amount)
+			assert.Equal(t, found.Type, r.Transaction.Type)
+		}
+		if r.Operation == models.AuditStatus {
+			statuses = append(statuses, r.Status)
+		}
+	}
+	// the deposit written pending and posted, then the transfer writing its
+	// pending legs, consuming the deposit, writing the remainder, linking
+	// the consumed deposit to the debit and posting both legs, then the
+	// deposit that got reversed
+	assert.Equal(t, []models.AuditOperation{
+		models.AuditCreate, models.AuditStatus,
+		models.AuditCreate, models.AuditCreate, models.AuditConsume, models.AuditCreate, models.AuditLink,
+		models.AuditStatus, models.AuditStatus,
+		models.AuditCreate, models.AuditStatus, models.AuditStatus,
+	}, operations)
+	assert.Equal(t, []models.TransactionStatus{
+		models.TransactionPosted,
+		models.TransactionPosted, models.TransactionPosted,
+		models.TransactionPosted, models.TransactionReversed,
+	}, statuses)
+	assert.True(t, l.Verify().Ok)
+}
+
+func TestLog_Loop(t *testing.T) {
+	l := NewLog()
+	l.Append(models.AuditRecord{Operation: models.AuditCreate, TransactionIds: []string{"t-1"}})
+	file := filepath.Join(t.TempDir(), "anchors.jsonl")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		l.Loop(ctx, time.Millisecond, file)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return len(l.Anchors()) == 1 }, time.Second, time.Millisecond)
+	l.Append(models.AuditRecord{Operation: models.AuditConsume, TransactionIds: []string{"t-1"}})
+	assert.Eventually(t, func() bool { return len(l.Anchors()) == 2 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	anchor := models.AuditAnchor{}
+	require.NoError(t, jsoniter.UnmarshalFromString(lines[1], &anchor))
+	assert.Equal(t, l.Anchors()[1], anchor)
+	assert.True(t, l.Verify().Ok)
+}
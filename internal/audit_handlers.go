@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gopay/internal/audit"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type AuditHandler struct {
+	log *audit.Log
+}
+
+func NewAuditHandler(log *audit.Log) *AuditHandler {
+	return &AuditHandler{
+		log: log,
+	}
+}
+
+func (h *AuditHandler) GetAuditRecords(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	from := uint64(1)
+	if v := r.URL.Query().Get("from"); v != "" {
+		var err error
+		from, err = strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetAuditRecords")
+			utils.ErrorWithMessage(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	h.respond(w, r, "Handler::GetAuditRecords", h.log.Records(from))
+}
+
+func (h *AuditHandler) GetAuditAnchors(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	h.respond(w, r, "Handler::GetAuditAnchors", h.log.Anchors())
+}
+
+func (h *AuditHandler) GetAuditVerification(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	h.respond(w, r, "Handler::GetAuditVerification", h.log.Verify())
+}
+
+func (h *AuditHandler) respond(w http.ResponseWriter, r *http.Request, name string, v interface{}) {
+	res, err := jsoniter.Marshal(v)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(name)
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusOK, res)
+}
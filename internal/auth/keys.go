@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	AuthorizationHeader = "Authorization"
+	APIKeyHeader        = "X-API-Key"
+
+	bearerPrefix = "Bearer "
+)
+
+var (
+	ErrUnauthenticated = errors.New("missing or invalid API key")
+	ErrForbidden       = errors.New("API key lacks the scope for this route")
+	ErrInvalidKeyEntry = errors.New(`API key entries must look like "principal:key" or "principal@scope:key"`)
+	ErrUnknownScope    = errors.New("unknown API key scope")
+	ErrDuplicateKey    = errors.New("duplicate API key")
+)
+
+// Scope is what a key may do. Every key can use the API, ScopeAdmin keys can
+// also freeze accounts, use the /admin routes and subscribe webhooks to every
+// account.
+type Scope string
+
+const (
+	ScopeAPI   Scope = "api"
+	ScopeAdmin Scope = "admin"
+)
+
+// Allows tells whether a key with scope s may use routes that need scope.
+func (s Scope) Allows(scope Scope) bool {
+	return s == scope || s == ScopeAdmin
+}
+
+type apiKey struct {
+	principal string
+	scope     Scope
+	key       []byte
+}
+
+// Keys maps API keys to the principal they authenticate. An empty Keys
+// turns authentication off.
+type Keys struct {
+	keys []apiKey
+}
+
+// ParseKeys reads "principal:key" entries, e.g. "ci:3f9a...", which get
+// ScopeAPI, and "principal@scope:key" ones, e.g. "ops@admin:7c1e...".
+func ParseKeys(entries []string) (*Keys, error) {
+	k := &Keys{}
+	seen := map[string]bool{}
+
+	for _, entry := range entries {
+		principal, key, ok := strings.Cut(entry, ":")
+		if !ok || principal == "" || key == "" {
+			return nil, ErrInvalidKeyEntry
+		}
+
+		scope := ScopeAPI
+		if name, s, found := strings.Cut(principal, "@"); found {
+			principal, scope = name, Scope(s)
+			if principal == "" {
+				return nil, ErrInvalidKeyEntry
+			}
+			if scope != ScopeAPI && scope != ScopeAdmin {
+				return nil, fmt.Errorf("%w %q for %s", ErrUnknownScope, scope, principal)
+			}
+		}
+
+		if seen[key] {
+			return nil, fmt.Errorf("%w for %s", ErrDuplicateKey, principal)
+		}
+		seen[key] = true
+
+		k.keys = append(k.keys, apiKey{principal: principal, scope: scope, key: []byte(key)})
+	}
+
+	return k, nil
+}
+
+func (k *Keys) Enabled() bool {
+	return len(k.keys) > 0
+}
+
+// Principal returns who key belongs to and its scope. Every configured key
+// is compared in constant time so the response time doesn't reveal a partial
+// match.
+func (k *Keys) Principal(key string) (string, Scope, error) {
+	principal, scope := "", Scope("")
+	for _, candidate := range k.keys {
+		if subtle.ConstantTimeCompare(candidate.key, []byte(key)) == 1 {
+			principal, scope = candidate.principal, candidate.scope
+		}
+	}
+
+	if key == "" || principal == "" {
+		return "", "", ErrUnauthenticated
+	}
+	return principal, scope, nil
+}
+
+// FromHeaders returns the key sent as "Authorization: Bearer <key>" or, if
+// that's missing, as X-API-Key.
+func FromHeaders(authorization string, apiKey string) string {
+	if len(authorization) > len(bearerPrefix) && strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+		return strings.TrimSpace(authorization[len(bearerPrefix):])
+	}
+	return strings.TrimSpace(apiKey)
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeys(t *testing.T) {
+	scenarios := map[string]struct {
+		entries     []string
+		wantEnabled bool
+		wantErr     error
+	}{
+		"none": {
+			entries: nil,
+		},
+		"valid": {
+			entries:     []string{"ops:secret", "ci:other"},
+			wantEnabled: true,
+		},
+		"key-with-colon": {
+			entries:     []string{"ops:sec:ret"},
+			wantEnabled: true,
+		},
+		"scoped": {
+			entries:     []string{"ops@admin:secret", "ci@api:other"},
+			wantEnabled: true,
+		},
+		"unknown-scope": {
+			entries: []string{"ops@root:secret"},
+			wantErr: ErrUnknownScope,
+		},
+		"scope-without-principal": {
+			entries: []string{"@admin:secret"},
+			wantErr: ErrInvalidKeyEntry,
+		},
+		"missing-principal": {
+			entries: []string{":secret"},
+			wantErr: ErrInvalidKeyEntry,
+		},
+		"missing-key": {
+			entries: []string{"ops"},
+			wantErr: ErrInvalidKeyEntry,
+		},
+		"duplicate-key": {
+			entries: []string{"ops:secret", "ci:secret"},
+			wantErr: ErrDuplicateKey,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			keys, err := ParseKeys(tcase.entries)
+
+			if tcase.wantErr != nil {
+				assert.ErrorIs(t, err, tcase.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tcase.wantEnabled, keys.Enabled())
+		})
+	}
+}
+
+func TestKeys_Principal(t *testing.T) {
+	keys, err := ParseKeys([]string{"ops@admin:secret", "ci:sec:ret"})
+	require.NoError(t, err)
+
+	scenarios := map[string]struct {
+		key           string
+		wantPrincipal string
+		wantScope     Scope
+		wantErr       error
+	}{
+		"ops":         {key: "secret", wantPrincipal: "ops", wantScope: ScopeAdmin},
+		"ci":          {key: "sec:ret", wantPrincipal: "ci", wantScope: ScopeAPI},
+		"unknown":     {key: "nope", wantErr: ErrUnauthenticated},
+		"prefix-only": {key: "secre", wantErr: ErrUnauthenticated},
+		"empty":       {key: "", wantErr: ErrUnauthenticated},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			principal, scope, err := keys.Principal(tcase.key)
+
+			assert.ErrorIs(t, err, tcase.wantErr)
+			assert.Equal(t, tcase.wantPrincipal, principal)
+			assert.Equal(t, tcase.wantScope, scope)
+		})
+	}
+}
+
+func TestFromHeaders(t *testing.T) {
+	assert.Equal(t, "secret", FromHeaders("Bearer secret", ""))
+	assert.Equal(t, "secret", FromHeaders("bearer secret", "other"))
+	assert.Equal(t, "other", FromHeaders("Basic b3BzOnNlY3JldA==", "other"))
+	assert.Equal(t, "other", FromHeaders("", " other "))
+	assert.Equal(t, "", FromHeaders("", ""))
+}
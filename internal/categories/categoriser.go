@@ -0,0 +1,116 @@
+package categories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+)
+
+// MaxRules bounds the category rules of one account.
+const MaxRules = 100
+
+var (
+	ErrInvalidRule    = errors.New("invalid category rule")
+	ErrNoConditions   = errors.New("a rule needs a counterparty, a memo pattern or an amount range")
+	ErrInvalidRange   = errors.New("minAmount must not be greater than maxAmount")
+	ErrTooManyRules   = fmt.Errorf("an account has at most %d category rules", MaxRules)
+	ErrInvalidPattern = errors.New("memoPattern is not a valid regular expression")
+)
+
+// Categoriser manages the accounts' category rules and recategorises
+// transactions by hand. The rules themselves are applied by the
+// TransactionRepo of this package.
+type Categoriser struct {
+	accountRepo     repository.AccountRepo
+	transactionRepo repository.TransactionRepo
+	repo            Repo
+	now             func() time.Time
+}
+
+func NewCategoriser(accountRepo repository.AccountRepo, transactionRepo repository.TransactionRepo, repo Repo) *Categoriser {
+	return &Categoriser{
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		repo:            repo,
+		now:             time.Now,
+	}
+}
+
+// AddRule compiles rule and stores it after the account's other rules, so it
+// only applies to transactions none of those match. Invalid rules, and rules
+// past MaxRules, fail with ErrInvalidRule.
+func (c *Categoriser) AddRule(ctx context.Context, rule models.CategoryRule) (models.CategoryRule, error) {
+	_, err := c.accountRepo.FindOne(ctx, rule.AccountId)
+	if err != nil {
+		return models.CategoryRule{}, err
+	}
+
+	err = validate(&rule)
+	if err != nil {
+		return models.CategoryRule{}, fmt.Errorf("%w: %w", ErrInvalidRule, err)
+	}
+
+	rule.RuleId = ""
+	rule.CreatedAt = c.now()
+	// the repo counts the account's rules under the lock it stores them
+	// with, so concurrent calls can't go past MaxRules
+	rule.RuleId, err = c.repo.Create(ctx, rule, MaxRules)
+	if errors.Is(err, ErrTooManyRules) {
+		return models.CategoryRule{}, fmt.Errorf("%w: %w", ErrInvalidRule, err)
+	}
+	return rule, err
+}
+
+func validate(rule *models.CategoryRule) error {
+	if !models.ValidCategory(rule.Category) {
+		return fmt.Errorf("%w %q", repository.ErrInvalidCategory, rule.Category)
+	}
+
+	if rule.Counterparty == "" && rule.MemoPattern == "" && rule.MinAmount == nil && rule.MaxAmount == nil {
+		return ErrNoConditions
+	}
+
+	if rule.MinAmount != nil && rule.MaxAmount != nil && *rule.MinAmount > *rule.MaxAmount {
+		return ErrInvalidRange
+	}
+
+	if err := rule.Compile(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPattern, err)
+	}
+
+	return nil
+}
+
+// Rules returns the rules of an account in the order they're tried.
+func (c *Categoriser) Rules(ctx context.Context, accountId string) ([]models.CategoryRule, error) {
+	_, err := c.accountRepo.FindOne(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.repo.FindAll(ctx, accountId)
+}
+
+func (c *Categoriser) DeleteRule(ctx context.Context, accountId string, ruleId string) error {
+	_, err := c.accountRepo.FindOne(ctx, accountId)
+	if err != nil {
+		return err
+	}
+
+	return c.repo.Delete(ctx, accountId, ruleId)
+}
+
+// Recategorise sets the category of a transaction, or clears it when
+// category is empty, and returns the transaction.
+func (c *Categoriser) Recategorise(ctx context.Context, id string, category string) (models.Transaction, error) {
+	err := c.transactionRepo.SetCategory(ctx, id, category)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	return c.transactionRepo.FindOne(ctx, id)
+}
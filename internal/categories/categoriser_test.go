@@ -0,0 +1,211 @@
+package categories
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	categoriser  *Categoriser
+	service      service.TransactionService
+	transactions repository.TransactionRepo
+	shankar      string
+	jessica      string
+}
+
+func newFixture(t *testing.T) *fixture {
+	rules := NewRepo()
+	bank := testutil.NewBankWith(t, repository.NewAccountRepo(), NewTransactionRepo(repository.NewTransactionRepo(), rules))
+
+	return &fixture{
+		categoriser:  NewCategoriser(bank.Accounts, bank.Transactions, rules),
+		service:      bank.Service,
+		transactions: bank.Transactions,
+		shankar:      bank.Account(t, "Shankar", "Nakai"),
+		jessica:      bank.Account(t, "Jessica", "Lourenco"),
+	}
+}
+
+// last returns the latest transaction of account that isn't a remainder,
+// which a debit writes after its own transaction.
+func (f *fixture) last(t *testing.T, account string) models.Transaction {
+	transactions, err := f.transactions.FindAll(context.Background(), account)
+	require.NoError(t, err)
+	for n := len(transactions) - 1; n >= 0; n-- {
+		if transactions[n].Type != models.TransactionRemainder {
+			return transactions[n]
+		}
+	}
+	require.Fail(t, "no transactions", account)
+	return models.Transaction{}
+}
+
+func amount(f float64) *float64 {
+	return &f
+}
+
+func TestCategoriser_AddRule(t *testing.T) {
+	scenarios := map[string]struct {
+		rule    models.CategoryRule
+		wantErr error
+	}{
+		"counterparty": {
+			rule: models.CategoryRule{Category: "food.restaurants", Counterparty: "0002"},
+		},
+		"amount-range": {
+			rule: models.CategoryRule{Category: "income.salary", MinAmount: amount(1000), MaxAmount: amount(5000)},
+		},
+		"unknown-category": {
+			rule:    models.CategoryRule{Category: "food.snacks", Counterparty: "0002"},
+			wantErr: repository.ErrInvalidCategory,
+		},
+		"no-conditions": {
+			rule:    models.CategoryRule{Category: "food"},
+			wantErr: ErrNoConditions,
+		},
+		"inverted-range": {
+			rule:    models.CategoryRule{Category: "food", MinAmount: amount(10), MaxAmount: amount(1)},
+			wantErr: ErrInvalidRange,
+		},
+		"invalid-pattern": {
+			rule:    models.CategoryRule{Category: "food", MemoPattern: "(lunch"},
+			wantErr: ErrInvalidPattern,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			tcase.rule.AccountId = f.shankar
+
+			rule, err := f.categoriser.AddRule(context.Background(), tcase.rule)
+
+			if tcase.wantErr == nil {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, rule.RuleId)
+				rules, err := f.categoriser.Rules(context.Background(), f.shankar)
+				assert.NoError(t, err)
+				assert.Equal(t, []models.CategoryRule{rule}, rules)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidRule)
+				assert.ErrorIs(t, err, tcase.wantErr)
+			}
+		})
+	}
+
+	f := newFixture(t)
+	_, err := f.categoriser.AddRule(context.Background(), models.CategoryRule{AccountId: "unknown", Category: "food", Counterparty: "0002"})
+	assert.ErrorIs(t, err, repository.ErrAccountNotFound)
+}
+
+func TestCategoriser_AddRuleLimit(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	// concurrent calls don't get past the limit between counting the
+	// account's rules and storing theirs
+	wg := sync.WaitGroup{}
+	errs := make(chan error, MaxRules+10)
+	for n := 0; n < MaxRules+10; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := f.categoriser.AddRule(ctx, models.CategoryRule{AccountId: f.shankar, Category: "food", Counterparty: f.jessica})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	failed := 0
+	for err := range errs {
+		if err != nil {
+			assert.ErrorIs(t, err, ErrInvalidRule)
+			assert.ErrorIs(t, err, ErrTooManyRules)
+			failed++
+		}
+	}
+	assert.Equal(t, 10, failed)
+
+	rules, err := f.categoriser.Rules(ctx, f.shankar)
+	require.NoError(t, err)
+	assert.Len(t, rules, MaxRules)
+
+	// the limit is per account
+	_, err = f.categoriser.AddRule(ctx, models.CategoryRule{AccountId: f.jessica, Category: "food", Counterparty: f.shankar})
+	assert.NoError(t, err)
+}
+
+func TestCategoriser_Categorise(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	for _, rule := range []models.CategoryRule{
+		{AccountId: f.shankar, Category: "food.groceries", MemoPattern: "(?i)market"},
+		{AccountId: f.shankar, Category: "transfers", Counterparty: f.jessica},
+		{AccountId: f.shankar, Category: "income", MinAmount: amount(500)},
+		{AccountId: f.jessica, Category: "housing.rent", Counterparty: f.shankar, MaxAmount: amount(0)},
+		{AccountId: f.jessica, Category: "income.refunds", Counterparty: f.shankar},
+	} {
+		_, err := f.categoriser.AddRule(ctx, rule)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, f.service.Deposit(ctx, f.shankar, 1000, service.Note{}))
+	assert.Equal(t, "income", f.last(t, f.shankar).Category)
+
+	require.NoError(t, f.service.Deposit(ctx, f.shankar, 10, service.Note{}))
+	assert.Empty(t, f.last(t, f.shankar).Category)
+
+	// the memo rule comes first, and the remainder gets no category
+	require.NoError(t, f.service.Transfer(ctx, f.shankar, f.jessica, -15, service.Note{Memo: "Farmers Market"}))
+	sent, err := f.transactions.FindAll(ctx, f.shankar)
+	require.NoError(t, err)
+	for _, transaction := range sent {
+		if transaction.Type == models.TransactionRemainder {
+			assert.Empty(t, transaction.Category)
+		}
+	}
+	assert.Equal(t, "food.groceries", f.last(t, f.shankar).Category)
+	assert.Equal(t, "income.refunds", f.last(t, f.jessica).Category)
+
+	require.NoError(t, f.service.Transfer(ctx, f.shankar, f.jessica, -5, service.Note{}))
+	assert.Equal(t, "transfers", f.last(t, f.shankar).Category)
+
+	id := f.last(t, f.jessica).TransactionId
+	transaction, err := f.categoriser.Recategorise(ctx, id, "food.restaurants")
+	require.NoError(t, err)
+	assert.Equal(t, "food.restaurants", transaction.Category)
+	_, err = f.categoriser.Recategorise(ctx, id, "food.snacks")
+	assert.ErrorIs(t, err, repository.ErrInvalidCategory)
+	transaction, err = f.categoriser.Recategorise(ctx, id, "")
+	require.NoError(t, err)
+	assert.Empty(t, transaction.Category)
+	_, err = f.categoriser.Recategorise(ctx, "unknown", "food")
+	assert.ErrorIs(t, err, repository.ErrTransactionNotFound)
+}
+
+func TestCategoriser_DeleteRule(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	rule, err := f.categoriser.AddRule(ctx, models.CategoryRule{AccountId: f.shankar, Category: "income", MinAmount: amount(0)})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, f.categoriser.DeleteRule(ctx, f.jessica, rule.RuleId), ErrRuleNotFound)
+	assert.ErrorIs(t, f.categoriser.DeleteRule(ctx, "unknown", rule.RuleId), repository.ErrAccountNotFound)
+	require.NoError(t, f.categoriser.DeleteRule(ctx, f.shankar, rule.RuleId))
+	assert.ErrorIs(t, f.categoriser.DeleteRule(ctx, f.shankar, rule.RuleId), ErrRuleNotFound)
+
+	require.NoError(t, f.service.Deposit(ctx, f.shankar, 10, service.Note{}))
+	assert.Empty(t, f.last(t, f.shankar).Category)
+}
@@ -0,0 +1,92 @@
+package categories
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/gopay/internal/models"
+)
+
+var ErrRuleNotFound = errors.New("category rule not found")
+
+// Repo keeps the category rules of every account.
+type Repo interface {
+	// FindAll returns the rules of an account, oldest first.
+	FindAll(ctx context.Context, accountId string) ([]models.CategoryRule, error)
+	// Create stores rule unless its account has max rules already, which
+	// fails with ErrTooManyRules.
+	Create(ctx context.Context, rule models.CategoryRule, max int) (string, error)
+	Delete(ctx context.Context, accountId string, ruleId string) error
+}
+
+var _ Repo = (*repoImpl)(nil)
+
+type repoImpl struct {
+	mu          sync.RWMutex
+	rules       map[string]models.CategoryRule
+	idGenerator func() string
+}
+
+func NewRepo() *repoImpl {
+	return &repoImpl{
+		rules:       make(map[string]models.CategoryRule),
+		idGenerator: uuid.NewString,
+	}
+}
+
+func (r *repoImpl) FindAll(_ context.Context, accountId string) ([]models.CategoryRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rules := []models.CategoryRule{}
+	for _, rule := range r.rules {
+		if rule.AccountId == accountId {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].CreatedAt.Equal(rules[j].CreatedAt) {
+			return rules[i].RuleId < rules[j].RuleId
+		}
+		return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+	})
+
+	return rules, nil
+}
+
+func (r *repoImpl) Create(_ context.Context, rule models.CategoryRule, max int) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, other := range r.rules {
+		if other.AccountId == rule.AccountId {
+			n++
+		}
+	}
+	if n >= max {
+		return "", ErrTooManyRules
+	}
+
+	id := r.idGenerator()
+	rule.RuleId = id
+	r.rules[id] = rule
+
+	return id, nil
+}
+
+func (r *repoImpl) Delete(_ context.Context, accountId string, ruleId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rule, found := r.rules[ruleId]
+	if !found || rule.AccountId != accountId {
+		return ErrRuleNotFound
+	}
+	delete(r.rules, ruleId)
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package categories
+
+import (
+	"context"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+)
+
+// transactionRepo assigns new transactions the category of the first of
+// their owner's rules they match. Transactions created with a category
+// keep it and remainders, which nobody made, get none.
+type transactionRepo struct {
+	repository.TransactionRepo
+	rules Repo
+}
+
+func NewTransactionRepo(next repository.TransactionRepo, rules Repo) *transactionRepo {
+	return &transactionRepo{
+		TransactionRepo: next,
+		rules:           rules,
+	}
+}
+
+func (r *transactionRepo) Create(ctx context.Context, t models.Transaction) (string, error) {
+	if t.Category == "" && t.Type != models.TransactionRemainder && !t.Remainder {
+		rules, err := r.rules.FindAll(ctx, t.Owner)
+		if err != nil {
+			return "", err
+		}
+
+		for _, rule := range rules {
+			if rule.Matches(t) {
+				t.Category = rule.Category
+				break
+			}
+		}
+	}
+
+	return r.TransactionRepo.Create(ctx, t)
+}
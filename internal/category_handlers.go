@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gopay/internal/categories"
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const RuleIdParam = "rule-id"
+
+type newTransactionCategory struct {
+	Category string `json:"category"`
+}
+
+type CategoryHandler struct {
+	categoriser *categories.Categoriser
+}
+
+func NewCategoryHandler(categoriser *categories.Categoriser) *CategoryHandler {
+	return &CategoryHandler{
+		categoriser: categoriser,
+	}
+}
+
+func (h *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	h.respond(w, r, "Handler::GetCategories", http.StatusOK, models.Categories)
+}
+
+func (h *CategoryHandler) GetCategoryRules(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	rules, err := h.categoriser.Rules(r.Context(), params.ByName(AccountIdParam))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetCategoryRules")
+		utils.ErrorWithMessage(w, categoryErrorStatus(err), err.Error())
+		return
+	}
+
+	h.respond(w, r, "Handler::GetCategoryRules", http.StatusOK, rules)
+}
+
+func (h *CategoryHandler) PostCategoryRule(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	rule := models.CategoryRule{}
+	if !h.decode(w, r, "Handler::PostCategoryRule", &rule) {
+		return
+	}
+
+	rule.AccountId = params.ByName(AccountIdParam)
+	rule, err := h.categoriser.AddRule(r.Context(), rule)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostCategoryRule")
+		utils.ErrorWithMessage(w, categoryErrorStatus(err), err.Error())
+		return
+	}
+
+	h.respond(w, r, "Handler::PostCategoryRule", http.StatusCreated, rule)
+}
+
+func (h *CategoryHandler) DeleteCategoryRule(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	err := h.categoriser.DeleteRule(r.Context(), params.ByName(AccountIdParam), params.ByName(RuleIdParam))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::DeleteCategoryRule")
+		utils.ErrorWithMessage(w, categoryErrorStatus(err), err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusNoContent, nil)
+}
+
+func (h *CategoryHandler) PostTransactionCategory(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	req := newTransactionCategory{}
+	if !h.decode(w, r, "Handler::PostTransactionCategory", &req) {
+		return
+	}
+
+	transaction, err := h.categoriser.Recategorise(r.Context(), params.ByName(TransactionIdParam), req.Category)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostTransactionCategory")
+		utils.ErrorWithMessage(w, categoryErrorStatus(err), err.Error())
+		return
+	}
+
+	h.respond(w, r, "Handler::PostTransactionCategory", http.StatusOK, transaction)
+}
+
+// decode reads the JSON body into v, or responds with the error and returns
+// false.
+func (h *CategoryHandler) decode(w http.ResponseWriter, r *http.Request, name string, v interface{}) bool {
+	body, err := io.ReadAll(io.LimitReader(r.Body, OneMegabyte))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(name)
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	defer r.Body.Close()
+
+	err = jsoniter.Unmarshal(body, v)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(name)
+		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, err.Error())
+		return false
+	}
+	return true
+}
+
+func (h *CategoryHandler) respond(w http.ResponseWriter, r *http.Request, name string, status int, v interface{}) {
+	res, err := jsoniter.Marshal(v)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(name)
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, status, res)
+}
+
+func categoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrAccountNotFound), errors.Is(err, repository.ErrTransactionNotFound),
+		errors.Is(err, categories.ErrRuleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, categories.ErrInvalidRule), errors.Is(err, repository.ErrInvalidCategory):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,241 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopay/internal/auth"
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+)
+
+var ErrInvalidAmount = errors.New("amount must be greater than zero")
+
+// APIError is a non 2xx response of the REST API.
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status))
+	}
+	return fmt.Sprintf("%d %s: %s", e.Status, http.StatusText(e.Status), e.Message)
+}
+
+// Client talks to the GoPay REST API. Amounts are always positive, the
+// client turns withdrawals and transfers into the API's negative amounts.
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+func New(baseURL string, apiKey string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		http:    httpClient,
+	}
+}
+
+func (c *Client) ListAccounts(ctx context.Context) ([]models.Account, error) {
+	accs := []models.Account{}
+	return accs, c.do(ctx, "GET", "/accounts", nil, &accs)
+}
+
+func (c *Client) GetAccount(ctx context.Context, id string) (models.Account, error) {
+	acc := models.Account{}
+	return acc, c.do(ctx, "GET", "/accounts/"+url.PathEscape(id), nil, &acc)
+}
+
+func (c *Client) CreateAccount(ctx context.Context, name string, lastName string) (models.Account, error) {
+	acc := models.Account{}
+	body := map[string]string{"name": name, "lastName": lastName}
+	return acc, c.do(ctx, "POST", "/accounts", body, &acc)
+}
+
+func (c *Client) FreezeAccount(ctx context.Context, id string) (models.Account, error) {
+	acc := models.Account{}
+	return acc, c.do(ctx, "POST", "/accounts/"+url.PathEscape(id)+"/freeze", nil, &acc)
+}
+
+func (c *Client) UnfreezeAccount(ctx context.Context, id string) (models.Account, error) {
+	acc := models.Account{}
+	return acc, c.do(ctx, "POST", "/accounts/"+url.PathEscape(id)+"/unfreeze", nil, &acc)
+}
+
+func (c *Client) Deposit(ctx context.Context, account string, amount float32) error {
+	return c.postTransaction(ctx, account, account, amount, amount)
+}
+
+func (c *Client) Withdraw(ctx context.Context, account string, amount float32) error {
+	return c.postTransaction(ctx, account, account, amount, -amount)
+}
+
+func (c *Client) Transfer(ctx context.Context, sender string, receiver string, amount float32) error {
+	return c.postTransaction(ctx, sender, receiver, amount, -amount)
+}
+
+func (c *Client) postTransaction(ctx context.Context, sender string, receiver string, amount float32, signed float32) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+
+	body := map[string]interface{}{"sender": sender, "receiver": receiver, "amount": signed}
+	return c.do(ctx, "POST", "/transactions", body, nil)
+}
+
+func (c *Client) Balance(ctx context.Context, account string) (models.Balance, error) {
+	balance := models.Balance{}
+	return balance, c.do(ctx, "GET", "/accounts/"+url.PathEscape(account)+"/balance", nil, &balance)
+}
+
+// Transactions returns the transactions of account matching filter, oldest
+// first.
+func (c *Client) Transactions(ctx context.Context, account string, filter models.TransactionFilter) ([]models.Transaction, error) {
+	query := url.Values{}
+	if filter.Consumed != nil {
+		query.Set("consumed", strconv.FormatBool(*filter.Consumed))
+	}
+	if filter.MinAmount != nil {
+		query.Set("minAmount", strconv.FormatFloat(*filter.MinAmount, 'f', -1, 64))
+	}
+	if filter.MaxAmount != nil {
+		query.Set("maxAmount", strconv.FormatFloat(*filter.MaxAmount, 'f', -1, 64))
+	}
+	if filter.Since != nil {
+		query.Set("since", filter.Since.Format(time.RFC3339Nano))
+	}
+	if filter.Until != nil {
+		query.Set("until", filter.Until.Format(time.RFC3339Nano))
+	}
+	if filter.Counterparty != nil {
+		query.Set("counterparty", *filter.Counterparty)
+	}
+
+	path := "/accounts/" + url.PathEscape(account) + "/transactions"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	transactions := []models.Transaction{}
+	return transactions, c.do(ctx, "GET", path, nil, &transactions)
+}
+
+// Export downloads the transactions of account as an ofx, qif or csv file.
+// since and until may be nil.
+func (c *Client) Export(ctx context.Context, account string, format string, since *time.Time, until *time.Time) ([]byte, error) {
+	query := url.Values{}
+	query.Set("format", format)
+	if since != nil {
+		query.Set("since", since.Format(time.RFC3339))
+	}
+	if until != nil {
+		query.Set("until", until.Format(time.RFC3339))
+	}
+
+	return c.send(ctx, "GET", "/accounts/"+url.PathEscape(account)+"/export?"+query.Encode(), nil, "", "*/*")
+}
+
+// Import uploads a CSV file of operations. With dryRun the rows are only
+// validated.
+func (c *Client) Import(ctx context.Context, file []byte, dryRun bool) (models.Import, error) {
+	imp := models.Import{}
+
+	payload, err := c.send(ctx, "POST", "/imports?dryRun="+strconv.FormatBool(dryRun), bytes.NewReader(file), "text/csv", "application/json")
+	if err != nil {
+		return imp, err
+	}
+	return imp, jsoniter.Unmarshal(payload, &imp)
+}
+
+// ImportResult downloads the result file of an executed import.
+func (c *Client) ImportResult(ctx context.Context, id string) ([]byte, error) {
+	return c.send(ctx, "GET", "/imports/"+url.PathEscape(id)+"/result", nil, "", "text/csv")
+}
+
+// AuditRecords returns the whole audit log, oldest first.
+func (c *Client) AuditRecords(ctx context.Context) ([]models.AuditRecord, error) {
+	records := []models.AuditRecord{}
+	return records, c.do(ctx, "GET", "/admin/audit", nil, &records)
+}
+
+// AuditAnchors returns the checkpoints of the audit log the server took.
+func (c *Client) AuditAnchors(ctx context.Context) ([]models.AuditAnchor, error) {
+	anchors := []models.AuditAnchor{}
+	return anchors, c.do(ctx, "GET", "/admin/audit/anchors", nil, &anchors)
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := jsoniter.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	payload, err := c.send(ctx, method, path, reader, "application/json", "application/json")
+	if err != nil {
+		return err
+	}
+
+	if out == nil || len(payload) == 0 {
+		return nil
+	}
+	return jsoniter.Unmarshal(payload, out)
+}
+
+// send makes the request and returns the response body, or an *APIError
+// for error statuses.
+func (c *Client) send(ctx context.Context, method string, path string, body io.Reader, contentType string, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.apiKey != "" {
+		req.Header.Set(auth.AuthorizationHeader, "Bearer "+c.apiKey)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	payload, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode >= 300 {
+		apiErr := &APIError{Status: res.StatusCode}
+		resp := utils.ErrorResponse{}
+		if jsoniter.Unmarshal(payload, &resp) == nil {
+			apiErr.Message = resp.Message
+		}
+		return nil, apiErr
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/gopay/internal/auth"
+	"github.com/rs/zerolog"
+)
+
+const (
+	LogFormatJSON    = "json"
+	LogFormatConsole = "console"
+
+	StorageMemory = "memory"
+)
+
+var (
+	ErrInvalidConfig        = errors.New("invalid configuration")
+	ErrUnsupportedStorage   = errors.New("unsupported storage backend")
+	ErrUnknownTraceExporter = errors.New("unknown trace exporter")
+)
+
+// Config is the effective configuration of the gopay server. It's built
+// from the defaults, then a YAML file, then GOPAY_* environment variables,
+// then command line flags, each overriding the previous one.
+type Config struct {
+	Server    Server    `json:"server"`
+	Storage   Storage   `json:"storage"`
+	Log       Log       `json:"log"`
+	Outbox    Outbox    `json:"outbox"`
+	Webhooks  Webhooks  `json:"webhooks"`
+	Trace     Trace     `json:"trace"`
+	Features  Features  `json:"features"`
+	Auth      Auth      `json:"auth"`
+	Seed      Seed      `json:"seed"`
+	Reconcile Reconcile `json:"reconcile"`
+	Audit     Audit     `json:"audit"`
+}
+
+type Server struct {
+	Addr            string   `json:"addr"`
+	GRPCAddr        string   `json:"grpcAddr"`
+	ReadTimeout     Duration `json:"readTimeout"`
+	WriteTimeout    Duration `json:"writeTimeout"`
+	IdleTimeout     Duration `json:"idleTimeout"`
+	ShutdownTimeout Duration `json:"shutdownTimeout"`
+	// DrainDelay is how long /readyz fails before shutdown starts, so load
+	// balancers stop routing new requests first.
+	DrainDelay Duration `json:"drainDelay"`
+}
+
+type Storage struct {
+	// DSN selects the backend by scheme. Only memory:// is available.
+	DSN string `json:"dsn"`
+}
+
+type Log struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+type Outbox struct {
+	// File also appends every event to a JSON Lines file when set.
+	File     string   `json:"file"`
+	Interval Duration `json:"interval"`
+}
+
+type Webhooks struct {
+	// AllowPrivateTargets lets subscriptions point at loopback and private
+	// addresses, for development against a local receiver.
+	AllowPrivateTargets bool `json:"allowPrivateTargets"`
+}
+
+type Trace struct {
+	Exporter string `json:"exporter"`
+}
+
+type Auth struct {
+	// APIKeys are "principal:key" entries; comma separated in the
+	// environment and flags. None turns authentication off.
+	APIKeys []string `json:"apiKeys"`
+}
+
+type Seed struct {
+	// File is loaded with seed.Loader at startup when set; gopay seed loads
+	// one the same way.
+	File string `json:"file"`
+}
+
+type Reconcile struct {
+	// Interval between background reconciliations, 0 turns them off. They
+	// can still be run with POST /admin/reconciliation.
+	Interval Duration `json:"interval"`
+}
+
+type Audit struct {
+	// AnchorInterval between checkpoints of the audit log, 0 turns them off.
+	AnchorInterval Duration `json:"anchorInterval"`
+	// AnchorFile also appends every checkpoint to a JSON Lines file when set.
+	AnchorFile string `json:"anchorFile"`
+}
+
+type Features struct {
+	SeedDemoData bool `json:"seedDemoData"`
+	GraphQL      bool `json:"graphql"`
+	GRPC         bool `json:"grpc"`
+	Metrics      bool `json:"metrics"`
+}
+
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:            ":8080",
+			GRPCAddr:        ":9090",
+			ReadTimeout:     Duration(10 * time.Second),
+			WriteTimeout:    Duration(30 * time.Second),
+			IdleTimeout:     Duration(120 * time.Second),
+			ShutdownTimeout: Duration(30 * time.Second),
+		},
+		Storage: Storage{
+			DSN: "memory://",
+		},
+		Log: Log{
+			Level:  zerolog.LevelInfoValue,
+			Format: LogFormatJSON,
+		},
+		Outbox: Outbox{
+			Interval: Duration(time.Second),
+		},
+		Reconcile: Reconcile{
+			Interval: Duration(time.Hour),
+		},
+		Audit: Audit{
+			AnchorInterval: Duration(time.Hour),
+		},
+		Features: Features{
+			SeedDemoData: true,
+			GraphQL:      true,
+			GRPC:         true,
+			Metrics:      true,
+		},
+	}
+}
+
+// LoadFile overrides cfg with the keys present in a YAML file. Unknown keys
+// are an error so typos don't go unnoticed.
+func LoadFile(cfg *Config, path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	js, err := yaml.YAMLToJSON(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (c Config) Validate() error {
+	errs := []error{}
+	invalid := func(key string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Server.Addr == "" {
+		invalid("server.addr", "must not be empty")
+	}
+	if c.Features.GRPC && c.Server.GRPCAddr == "" {
+		invalid("server.grpcAddr", "must not be empty when features.grpc is on")
+	}
+	for key, d := range map[string]Duration{
+		"server.readTimeout":     c.Server.ReadTimeout,
+		"server.writeTimeout":    c.Server.WriteTimeout,
+		"server.idleTimeout":     c.Server.IdleTimeout,
+		"server.shutdownTimeout": c.Server.ShutdownTimeout,
+		"outbox.interval":        c.Outbox.Interval,
+	} {
+		if d <= 0 {
+			invalid(key, "must be positive, got %s", d)
+		}
+	}
+
+	u, err := url.Parse(c.Storage.DSN)
+	switch {
+	case err != nil:
+		invalid("storage.dsn", "%v", err)
+	case u.Scheme != StorageMemory:
+		invalid("storage.dsn", "%v %q", ErrUnsupportedStorage, u.Scheme)
+	}
+
+	if c.Server.DrainDelay < 0 {
+		invalid("server.drainDelay", "must not be negative, got %s", c.Server.DrainDelay)
+	}
+	if c.Reconcile.Interval < 0 {
+		invalid("reconcile.interval", "must not be negative, got %s", c.Reconcile.Interval)
+	}
+	if c.Audit.AnchorInterval < 0 {
+		invalid("audit.anchorInterval", "must not be negative, got %s", c.Audit.AnchorInterval)
+	}
+
+	if _, err := zerolog.ParseLevel(c.Log.Level); err != nil || c.Log.Level == "" {
+		invalid("log.level", "unknown level %q", c.Log.Level)
+	}
+	if c.Log.Format != LogFormatJSON && c.Log.Format != LogFormatConsole {
+		invalid("log.format", "must be %q or %q, got %q", LogFormatJSON, LogFormatConsole, c.Log.Format)
+	}
+
+	if _, err := auth.ParseKeys(c.Auth.APIKeys); err != nil {
+		invalid("auth.apiKeys", "%v", err)
+	}
+
+	switch c.Trace.Exporter {
+	case "", "stdout", "otlp":
+	default:
+		invalid("trace.exporter", "%v %q", ErrUnknownTraceExporter, c.Trace.Exporter)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+	}
+	return nil
+}
+
+// Redacted returns a copy that is safe to print.
+func (c Config) Redacted() Config {
+	if u, err := url.Parse(c.Storage.DSN); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+			c.Storage.DSN = u.String()
+		}
+	}
+
+	keys := make([]string, len(c.Auth.APIKeys))
+	for i, entry := range c.Auth.APIKeys {
+		principal, _, _ := strings.Cut(entry, ":")
+		keys[i] = principal + ":xxxxx"
+	}
+	c.Auth.APIKeys = keys
+
+	return c
+}
+
+// Duration is a time.Duration written as "2s" in files, variables and flags.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+	return d.Set(s)
+}
+
+func (d *Duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ghodss/yaml"
+)
+
+const (
+	EnvPrefix  = "GOPAY_"
+	ConfigFlag = "config"
+	ConfigEnv  = EnvPrefix + "CONFIG"
+)
+
+// field is one settable leaf of Config, e.g. server.readTimeout, which is
+// GOPAY_SERVER_READ_TIMEOUT in the environment and -server.read-timeout on
+// the command line.
+type field struct {
+	key   string
+	env   string
+	flag  string
+	value reflect.Value
+}
+
+func fields(cfg *Config) []field {
+	res := []field{}
+
+	var walk func(v reflect.Value, keys []string)
+	walk = func(v reflect.Value, keys []string) {
+		for i := 0; i < v.NumField(); i++ {
+			name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+			path := append(append([]string{}, keys...), name)
+
+			fv := v.Field(i)
+			if fv.Kind() == reflect.Struct {
+				walk(fv, path)
+				continue
+			}
+
+			env, flg := []string{}, []string{}
+			for _, k := range path {
+				words := splitCamel(k)
+				env = append(env, strings.ToUpper(strings.Join(words, "_")))
+				flg = append(flg, strings.Join(words, "-"))
+			}
+
+			res = append(res, field{
+				key:   strings.Join(path, "."),
+				env:   EnvPrefix + strings.Join(env, "_"),
+				flag:  strings.Join(flg, "."),
+				value: fv,
+			})
+		}
+	}
+
+	walk(reflect.ValueOf(cfg).Elem(), nil)
+	return res
+}
+
+func splitCamel(s string) []string {
+	words := []string{}
+	start := 0
+	for i, r := range s {
+		if i > 0 && unicode.IsUpper(r) && !unicode.IsUpper(rune(s[i-1])) {
+			words = append(words, strings.ToLower(s[start:i]))
+			start = i
+		}
+	}
+	return append(words, strings.ToLower(s[start:]))
+}
+
+func (f field) set(s string) error {
+	var err error
+
+	switch v := f.value.Addr().Interface().(type) {
+	case *Duration:
+		err = v.Set(s)
+	case *string:
+		*v = s
+	case *int:
+		*v, err = strconv.Atoi(s)
+	case *bool:
+		*v, err = strconv.ParseBool(s)
+	case *[]string:
+		*v = strings.Split(s, ",")
+	default:
+		err = fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.key, err)
+	}
+	return nil
+}
+
+// Load builds the configuration from the defaults, the file named by
+// -config or GOPAY_CONFIG, the environment and the flags in args, then
+// validates it.
+func Load(name string, args []string, getenv func(string) string, output io.Writer) (Config, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(output)
+	return LoadFlags(fs, args, getenv)
+}
+
+// LoadFlags is Load for commands with flags of their own, which they define
+// on fs beforehand.
+func LoadFlags(fs *flag.FlagSet, args []string, getenv func(string) string) (Config, error) {
+	cfg := Default()
+	defaults := fields(&cfg)
+
+	path := fs.String(ConfigFlag, getenv(ConfigEnv), "YAML configuration file (env "+ConfigEnv+")")
+
+	flags := map[string]string{}
+	for _, f := range defaults {
+		f := f
+		usage := fmt.Sprintf("%s (env %s, default %v)", f.key, f.env, f.value.Interface())
+		fs.Func(f.flag, usage, func(s string) error {
+			flags[f.key] = s
+			return nil
+		})
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return Config{}, err
+	}
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("%w: unexpected arguments %v", ErrInvalidConfig, fs.Args())
+	}
+
+	if *path != "" {
+		err = LoadFile(&cfg, *path)
+		if err != nil {
+			return Config{}, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+		}
+	}
+
+	for _, f := range fields(&cfg) {
+		if s := getenv(f.env); s != "" {
+			err = f.set(s)
+			if err != nil {
+				return Config{}, fmt.Errorf("%w: %s: %w", ErrInvalidConfig, f.env, err)
+			}
+		}
+		if s, ok := flags[f.key]; ok {
+			err = f.set(s)
+			if err != nil {
+				return Config{}, fmt.Errorf("%w: -%s: %w", ErrInvalidConfig, f.flag, err)
+			}
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Print writes the redacted configuration as YAML.
+func Print(w io.Writer, cfg Config) error {
+	out, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(out)
+	return err
+}
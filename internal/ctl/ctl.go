@@ -0,0 +1,590 @@
+package ctl
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gopay/internal/audit"
+	"github.com/gopay/internal/client"
+	"github.com/gopay/internal/models"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	EnvConfig  = "GOPAYCTL_CONFIG"
+	EnvProfile = "GOPAYCTL_PROFILE"
+	EnvURL     = "GOPAYCTL_URL"
+	EnvAPIKey  = "GOPAYCTL_API_KEY"
+)
+
+var (
+	ErrUsage       = errors.New("usage")
+	ErrAuditBroken = errors.New("audit log is broken")
+)
+
+// options are the flags every command accepts. Empty ones fall back to the
+// environment, then the profile, then the defaults.
+type options struct {
+	config  string
+	profile string
+	url     string
+	apiKey  string
+	output  string
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.config, "config", o.config, "profile file (env "+EnvConfig+", default "+DefaultProfilePath()+")")
+	fs.StringVar(&o.profile, "profile", o.profile, "profile to use instead of the file's current one (env "+EnvProfile+")")
+	fs.StringVar(&o.url, "url", o.url, "API base URL (env "+EnvURL+", default "+DefaultURL+")")
+	fs.StringVar(&o.apiKey, "api-key", o.apiKey, "API key (env "+EnvAPIKey+")")
+	fs.StringVar(&o.output, "o", o.output, "output format: table, json or csv")
+}
+
+// session is what a command runs with once the options are resolved.
+type session struct {
+	client *client.Client
+	out    io.Writer
+	format string
+}
+
+func (s *session) render(v view) error {
+	return render(s.out, s.format, v)
+}
+
+// command registers its own flags and returns what to run with the
+// positional arguments.
+type command struct {
+	args  string
+	nargs int
+	about string
+	setup func(fs *flag.FlagSet) func(ctx context.Context, s *session, args []string) error
+}
+
+var commands = map[string]command{
+	"accounts list": {
+		about: "list every account",
+		setup: noFlags(func(ctx context.Context, s *session, _ []string) error {
+			accs, err := s.client.ListAccounts(ctx)
+			if err != nil {
+				return err
+			}
+			sort.Slice(accs, func(i, j int) bool {
+				return accs[i].Name+accs[i].LastName < accs[j].Name+accs[j].LastName
+			})
+			return s.render(accountsView(accs))
+		}),
+	},
+	"accounts get": {
+		args:  "ACCOUNT",
+		nargs: 1,
+		about: "show one account",
+		setup: noFlags(func(ctx context.Context, s *session, args []string) error {
+			acc, err := s.client.GetAccount(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			return s.render(accountView(acc))
+		}),
+	},
+	"accounts create": {
+		args:  "NAME LAST_NAME",
+		nargs: 2,
+		about: "open an account",
+		setup: noFlags(func(ctx context.Context, s *session, args []string) error {
+			acc, err := s.client.CreateAccount(ctx, args[0], args[1])
+			if err != nil {
+				return err
+			}
+			return s.render(accountView(acc))
+		}),
+	},
+	"accounts freeze": {
+		args:  "ACCOUNT",
+		nargs: 1,
+		about: "block deposits, withdrawals and transfers of an account",
+		setup: noFlags(func(ctx context.Context, s *session, args []string) error {
+			acc, err := s.client.FreezeAccount(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			return s.render(accountView(acc))
+		}),
+	},
+	"accounts unfreeze": {
+		args:  "ACCOUNT",
+		nargs: 1,
+		about: "lift a freeze",
+		setup: noFlags(func(ctx context.Context, s *session, args []string) error {
+			acc, err := s.client.UnfreezeAccount(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			return s.render(accountView(acc))
+		}),
+	},
+	"deposit": {
+		args:  "ACCOUNT AMOUNT",
+		nargs: 2,
+		about: "deposit into an account and show its balance",
+		setup: noFlags(func(ctx context.Context, s *session, args []string) error {
+			amount, err := parseAmount(args[1])
+			if err != nil {
+				return err
+			}
+			err = s.client.Deposit(ctx, args[0], amount)
+			if err != nil {
+				return err
+			}
+			return s.balances(ctx, args[0])
+		}),
+	},
+	"withdraw": {
+		args:  "ACCOUNT AMOUNT",
+		nargs: 2,
+		about: "withdraw from an account and show its balance",
+		setup: noFlags(func(ctx context.Context, s *session, args []string) error {
+			amount, err := parseAmount(args[1])
+			if err != nil {
+				return err
+			}
+			err = s.client.Withdraw(ctx, args[0], amount)
+			if err != nil {
+				return err
+			}
+			return s.balances(ctx, args[0])
+		}),
+	},
+	"transfer": {
+		args:  "SENDER RECEIVER AMOUNT",
+		nargs: 3,
+		about: "move money between accounts and show both balances",
+		setup: noFlags(func(ctx context.Context, s *session, args []string) error {
+			amount, err := parseAmount(args[2])
+			if err != nil {
+				return err
+			}
+			err = s.client.Transfer(ctx, args[0], args[1], amount)
+			if err != nil {
+				return err
+			}
+			return s.balances(ctx, args[0], args[1])
+		}),
+	},
+	"balance": {
+		args:  "ACCOUNT...",
+		nargs: -1,
+		about: "show the balance of accounts",
+		setup: noFlags(func(ctx context.Context, s *session, args []string) error {
+			return s.balances(ctx, args...)
+		}),
+	},
+	"history": {
+		args:  "ACCOUNT",
+		nargs: 1,
+		about: "show the transactions of an account, oldest first",
+		setup: history,
+	},
+	"import": {
+		args:  "FILE",
+		nargs: 1,
+		about: "run the deposits, withdrawals and transfers of a CSV file",
+		setup: importCommand,
+	},
+	"export": {
+		args:  "ACCOUNT",
+		nargs: 1,
+		about: "download the transactions of an account for finance tools",
+		setup: exportCommand,
+	},
+	"audit verify": {
+		about: "check the hash chain of the audit log and show the first broken record",
+		setup: auditVerify,
+	},
+}
+
+func noFlags(run func(ctx context.Context, s *session, args []string) error) func(*flag.FlagSet) func(context.Context, *session, []string) error {
+	return func(*flag.FlagSet) func(context.Context, *session, []string) error {
+		return run
+	}
+}
+
+func history(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+	consumed := fs.String("consumed", "", "only consumed (true) or available (false) transactions")
+	minAmount := fs.String("min", "", "minimum amount")
+	maxAmount := fs.String("max", "", "maximum amount")
+	since := fs.String("since", "", "created at or after, RFC 3339 or YYYY-MM-DD")
+	until := fs.String("until", "", "created at or before, RFC 3339 or YYYY-MM-DD")
+	counterparty := fs.String("counterparty", "", "sent to or received from this account")
+	limit := fs.Int("limit", 0, "only the newest N transactions")
+
+	return func(ctx context.Context, s *session, args []string) error {
+		filter := models.TransactionFilter{}
+		var err error
+
+		if *consumed != "" {
+			v, err := strconv.ParseBool(*consumed)
+			if err != nil {
+				return fmt.Errorf("%w: -consumed: %w", ErrUsage, err)
+			}
+			filter.Consumed = &v
+		}
+		if filter.MinAmount, err = parseFloat("min", *minAmount); err != nil {
+			return err
+		}
+		if filter.MaxAmount, err = parseFloat("max", *maxAmount); err != nil {
+			return err
+		}
+		if filter.Since, err = parseTime("since", *since, false); err != nil {
+			return err
+		}
+		if filter.Until, err = parseTime("until", *until, true); err != nil {
+			return err
+		}
+		if *counterparty != "" {
+			filter.Counterparty = counterparty
+		}
+
+		transactions, err := s.client.Transactions(ctx, args[0], filter)
+		if err != nil {
+			return err
+		}
+		if *limit > 0 && len(transactions) > *limit {
+			transactions = transactions[len(transactions)-*limit:]
+		}
+
+		return s.render(transactionsView(transactions))
+	}
+}
+
+func importCommand(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+	dryRun := fs.Bool("dry-run", false, "only validate the rows")
+	result := fs.String("result", "", "also save the result file, with the created transaction ids, here")
+
+	return func(ctx context.Context, s *session, args []string) error {
+		file, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		imp, err := s.client.Import(ctx, file, *dryRun)
+		if err != nil {
+			return err
+		}
+
+		if *result != "" && !imp.DryRun {
+			data, err := s.client.ImportResult(ctx, imp.ImportId)
+			if err != nil {
+				return err
+			}
+			err = os.WriteFile(*result, data, 0o644)
+			if err != nil {
+				return err
+			}
+		}
+
+		return s.render(importView(imp))
+	}
+}
+
+func exportCommand(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+	format := fs.String("format", "ofx", "file format: ofx, qif or csv")
+	since := fs.String("since", "", "created at or after, RFC 3339 or YYYY-MM-DD")
+	until := fs.String("until", "", "created at or before, RFC 3339 or YYYY-MM-DD")
+	file := fs.String("file", "", "write to this file instead of stdout")
+
+	return func(ctx context.Context, s *session, args []string) error {
+		from, err := parseTime("since", *since, false)
+		if err != nil {
+			return err
+		}
+		to, err := parseTime("until", *until, true)
+		if err != nil {
+			return err
+		}
+
+		data, err := s.client.Export(ctx, args[0], *format, from, to)
+		if err != nil {
+			return err
+		}
+
+		if *file != "" {
+			return os.WriteFile(*file, data, 0o644)
+		}
+		_, err = s.out.Write(data)
+		return err
+	}
+}
+
+func auditVerify(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+	records := fs.String("file", "", "verify the records saved in this JSON file instead of the server's")
+	anchors := fs.String("anchors", "", "check against the anchors of this JSON Lines file instead of the server's")
+
+	return func(ctx context.Context, s *session, _ []string) error {
+		var log []models.AuditRecord
+		var err error
+		if *records != "" {
+			log, err = readRecords(*records)
+		} else {
+			log, err = s.client.AuditRecords(ctx)
+		}
+		if err != nil {
+			return err
+		}
+
+		var checkpoints []models.AuditAnchor
+		if *anchors != "" {
+			checkpoints, err = readAnchors(*anchors)
+		} else {
+			checkpoints, err = s.client.AuditAnchors(ctx)
+		}
+		if err != nil {
+			return err
+		}
+
+		// the records are checked here, a tampered server can't vouch for
+		// itself
+		result := audit.Verify(log, checkpoints)
+		err = s.render(auditView(result))
+		if err != nil {
+			return err
+		}
+		if !result.Ok {
+			return fmt.Errorf("%w at record %d", ErrAuditBroken, *result.BrokenAt)
+		}
+		return nil
+	}
+}
+
+func readRecords(path string) ([]models.AuditRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []models.AuditRecord{}
+	err = jsoniter.Unmarshal(data, &records)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return records, nil
+}
+
+func readAnchors(path string) ([]models.AuditAnchor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	anchors := []models.AuditAnchor{}
+	dec := jsoniter.NewDecoder(f)
+	for dec.More() {
+		a := models.AuditAnchor{}
+		err = dec.Decode(&a)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		anchors = append(anchors, a)
+	}
+	return anchors, nil
+}
+
+func (s *session) balances(ctx context.Context, accounts ...string) error {
+	balances := []models.Balance{}
+	for _, acc := range accounts {
+		b, err := s.client.Balance(ctx, acc)
+		if err != nil {
+			return err
+		}
+		balances = append(balances, b)
+	}
+	return s.render(balancesView(balances))
+}
+
+// Run executes one gopayctl command line, without the program name.
+func Run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer, getenv func(string) string) error {
+	opts := &options{}
+	global := flag.NewFlagSet("gopayctl", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	opts.register(global)
+	global.Usage = func() { usage(stderr, global) }
+
+	err := global.Parse(args)
+	if err != nil {
+		return usageError(err)
+	}
+
+	name, args := commandName(global.Args())
+	cmd, found := commands[name]
+	if !found {
+		usage(stderr, global)
+		if name == "" {
+			return fmt.Errorf("%w: missing command", ErrUsage)
+		}
+		return fmt.Errorf("%w: unknown command %q", ErrUsage, name)
+	}
+
+	fs := flag.NewFlagSet("gopayctl "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	opts.register(fs)
+	run := cmd.setup(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gopayctl %s [flags] %s\n\n%s\n\nFlags:\n", name, cmd.args, cmd.about)
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return usageError(err)
+	}
+	if (cmd.nargs >= 0 && len(positional) != cmd.nargs) || (cmd.nargs < 0 && len(positional) == 0) {
+		fs.Usage()
+		return fmt.Errorf("%w: gopayctl %s %s", ErrUsage, name, cmd.args)
+	}
+
+	s, err := opts.session(stdout, getenv)
+	if err != nil {
+		return err
+	}
+
+	return run(ctx, s, positional)
+}
+
+func usageError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrUsage, err)
+}
+
+// commandName takes "accounts <sub>", "audit <sub>" or a single word
+// command off args.
+func commandName(args []string) (string, []string) {
+	switch {
+	case len(args) == 0:
+		return "", nil
+	case (args[0] == "accounts" || args[0] == "audit") && len(args) > 1:
+		return args[0] + " " + args[1], args[2:]
+	default:
+		return args[0], args[1:]
+	}
+}
+
+// parseInterspersed lets flags follow positional arguments, e.g.
+// "history ACCOUNT -since 2024-01-01".
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func (o *options) session(out io.Writer, getenv func(string) string) (*session, error) {
+	path, required := first(o.config, getenv(EnvConfig)), true
+	if path == "" {
+		path, required = DefaultProfilePath(), false
+	}
+
+	profile, err := LoadProfile(path, first(o.profile, getenv(EnvProfile)), required)
+	if err != nil {
+		return nil, err
+	}
+
+	format := first(o.output, profile.Output, FormatTable)
+	switch format {
+	case FormatTable, FormatJSON, FormatCSV:
+	default:
+		return nil, fmt.Errorf("%w %q, use %s, %s or %s", ErrUnknownFormat, format, FormatTable, FormatJSON, FormatCSV)
+	}
+
+	return &session{
+		client: client.New(first(o.url, getenv(EnvURL), profile.URL, DefaultURL), first(o.apiKey, getenv(EnvAPIKey), profile.APIKey), nil),
+		out:    out,
+		format: format,
+	}, nil
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func usage(w io.Writer, global *flag.FlagSet) {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "Usage: gopayctl [flags] COMMAND [flags] ARGS\n\nCommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-34s %s\n", name+" "+commands[name].args, commands[name].about)
+	}
+	fmt.Fprintf(w, "\nFlags:\n")
+	global.PrintDefaults()
+}
+
+func parseAmount(s string) (float32, error) {
+	amount, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: amount %q: %w", ErrUsage, s, err)
+	}
+	if amount <= 0 {
+		return 0, client.ErrInvalidAmount
+	}
+	return float32(amount), nil
+}
+
+func parseFloat(name string, s string) (*float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: -%s: %w", ErrUsage, name, err)
+	}
+	return &v, nil
+}
+
+// parseTime reads RFC 3339 or a date. A date is the start of the day, or
+// its end when endOfDay is set, so "-until 2024-01-31" includes that day.
+func parseTime(name string, s string, endOfDay bool) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: -%s: want RFC 3339 or YYYY-MM-DD, got %q", ErrUsage, name, s)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
@@ -0,0 +1,360 @@
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gopay/internal"
+	"github.com/gopay/internal/audit"
+	"github.com/gopay/internal/auth"
+	"github.com/gopay/internal/client"
+	"github.com/gopay/internal/export"
+	"github.com/gopay/internal/imports"
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/statement"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const apiKey = "secret"
+
+type ctlFixture struct {
+	url     string
+	env     map[string]string
+	shankar string
+	jessica string
+	audit   *audit.Log
+}
+
+func newCtlFixture(t *testing.T) *ctlFixture {
+	ctx := context.Background()
+
+	// keep the developer's own profile file out of the tests
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	accountRepo := repository.NewAccountRepo()
+	auditLog := audit.NewLog()
+	transactionRepo := audit.NewTransactionRepo(repository.NewTransactionRepo(), auditLog)
+	transactionService := service.NewTransactionService(transactionRepo, accountRepo, repository.NewOutboxRepo(), repository.NewUnitOfWork())
+
+	keys, err := auth.ParseKeys([]string{"ops:" + apiKey})
+	require.NoError(t, err)
+
+	routes := internal.Routes(internal.NewHandler(transactionService, transactionRepo, accountRepo))
+	imported := imports.NewRepo()
+	routes = append(routes, internal.ImportRoutes(internal.NewImportHandler(imports.NewImporter(accountRepo, transactionRepo, transactionService, imported), imported))...)
+	routes = append(routes, internal.AuditRoutes(internal.NewAuditHandler(auditLog))...)
+	routes = append(routes, internal.ExportRoutes(internal.NewExportHandler(export.NewExporter(statement.NewGenerator(transactionRepo), accountRepo), accountRepo))...)
+
+	router := internal.Router(routes,
+		internal.RequestID(),
+		internal.Authenticate(keys),
+	)
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	shankar, err := accountRepo.Create(ctx, "Shankar", "Nakai")
+	require.NoError(t, err)
+	jessica, err := accountRepo.Create(ctx, "Jessica", "Lourenco")
+	require.NoError(t, err)
+	require.NoError(t, transactionService.Deposit(ctx, shankar, 100, service.Note{}))
+
+	return &ctlFixture{
+		url: srv.URL,
+		env: map[string]string{
+			EnvURL:    srv.URL,
+			EnvAPIKey: apiKey,
+		},
+		shankar: shankar,
+		jessica: jessica,
+		audit:   auditLog,
+	}
+}
+
+func (f *ctlFixture) run(t *testing.T, args ...string) (string, error) {
+	out := &bytes.Buffer{}
+	err := Run(context.Background(), args, out, &bytes.Buffer{}, func(key string) string {
+		return f.env[key]
+	})
+	return out.String(), err
+}
+
+func TestRun_Commands(t *testing.T) {
+	f := newCtlFixture(t)
+
+	out, err := f.run(t, "-o", "json", "accounts", "create", "Caio", "Henrique")
+	require.NoError(t, err)
+	created := models.Account{}
+	require.NoError(t, jsoniter.Unmarshal([]byte(out), &created))
+	assert.Equal(t, "Caio", created.Name)
+	assert.NotEmpty(t, created.AccountId)
+
+	out, err = f.run(t, "accounts", "list")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 4)
+	assert.Regexp(t, `^ID\s+NAME\s+LAST NAME\s+FROZEN$`, lines[0])
+	assert.Contains(t, lines[1], "Caio")
+
+	out, err = f.run(t, "deposit", f.jessica, "50")
+	require.NoError(t, err)
+	assert.Regexp(t, f.jessica+`\s+50.00`, out)
+
+	out, err = f.run(t, "transfer", f.shankar, f.jessica, "30", "-o", "csv")
+	require.NoError(t, err)
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"ACCOUNT", "BALANCE"}, {f.shankar, "70.00"}, {f.jessica, "80.00"}}, records)
+
+	out, err = f.run(t, "withdraw", f.shankar, "20")
+	require.NoError(t, err)
+	assert.Regexp(t, f.shankar+`\s+50.00`, out)
+
+	_, err = f.run(t, "withdraw", f.shankar, "500")
+	apiErr := &client.APIError{}
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusForbidden, apiErr.Status)
+	assert.Equal(t, "insufficient balance", apiErr.Message)
+
+	out, err = f.run(t, "accounts", "freeze", f.jessica, "-o", "json")
+	require.NoError(t, err)
+	assert.Contains(t, out, `"frozen": true`)
+
+	_, err = f.run(t, "deposit", f.jessica, "10")
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "account is frozen", apiErr.Message)
+
+	_, err = f.run(t, "accounts", "unfreeze", f.jessica)
+	require.NoError(t, err)
+
+	out, err = f.run(t, "balance", f.shankar, f.jessica, "-o", "json")
+	require.NoError(t, err)
+	balances := []models.Balance{}
+	require.NoError(t, jsoniter.Unmarshal([]byte(out), &balances))
+	assert.Equal(t, []models.Balance{{AccountId: f.shankar, Amount: 50}, {AccountId: f.jessica, Amount: 80}}, balances)
+}
+
+func TestRun_History(t *testing.T) {
+	f := newCtlFixture(t)
+
+	_, err := f.run(t, "transfer", f.shankar, f.jessica, "30")
+	require.NoError(t, err)
+	_, err = f.run(t, "deposit", f.shankar, "5")
+	require.NoError(t, err)
+
+	scenarios := map[string]struct {
+		args      []string
+		wantCount int
+	}{
+		"all":          {args: []string{"history", f.shankar}, wantCount: 4},
+		"available":    {args: []string{"history", f.shankar, "-consumed=false"}, wantCount: 2},
+		"counterparty": {args: []string{"history", "-counterparty", f.jessica, f.shankar}, wantCount: 1},
+		"amount-range": {args: []string{"history", f.shankar, "-min", "0", "-max", "10"}, wantCount: 1},
+		"date-range":   {args: []string{"history", f.shankar, "-since", "2000-01-01", "-until", "2000-12-31"}, wantCount: 0},
+		"limit":        {args: []string{"history", f.shankar, "-limit", "1"}, wantCount: 1},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			out, err := f.run(t, append(tcase.args, "-o", "json")...)
+			require.NoError(t, err)
+
+			transactions := []models.Transaction{}
+			require.NoError(t, jsoniter.Unmarshal([]byte(out), &transactions))
+			assert.Len(t, transactions, tcase.wantCount)
+		})
+	}
+}
+
+func TestRun_Export(t *testing.T) {
+	f := newCtlFixture(t)
+
+	_, err := f.run(t, "transfer", f.shankar, f.jessica, "30")
+	require.NoError(t, err)
+
+	out, err := f.run(t, "export", f.shankar)
+	require.NoError(t, err)
+	assert.Contains(t, out, "<OFX>")
+	assert.Equal(t, 2, strings.Count(out, "<STMTTRN>"))
+	assert.Contains(t, out, "<NAME>Jessica Lourenco</NAME>")
+
+	path := filepath.Join(t.TempDir(), "shankar.qif")
+	out, err = f.run(t, "export", f.shankar, "-format", "qif", "-since", "2000-01-01", "-file", path)
+	require.NoError(t, err)
+	assert.Empty(t, out)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "!Type:Bank\n"))
+
+	out, err = f.run(t, "export", f.shankar, "-format", "csv", "-until", "2000-01-01")
+	require.NoError(t, err)
+	assert.Equal(t, "date,fitid,kind,payee,counterparty,amount,balance\n", out)
+
+	_, err = f.run(t, "export", f.shankar, "-format", "xls")
+	apiErr := &client.APIError{}
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.Status)
+}
+
+func TestRun_Import(t *testing.T) {
+	f := newCtlFixture(t)
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "batch.csv")
+	require.NoError(t, os.WriteFile(file, []byte("operation,account,receiver,amount\n"+
+		"transfer,"+f.shankar+","+f.jessica+",40\n"+
+		"withdrawal,"+f.jessica+",,500\n"), 0o644))
+
+	out, err := f.run(t, "import", file, "-dry-run", "-o", "json")
+	require.NoError(t, err)
+	imp := models.Import{}
+	require.NoError(t, jsoniter.Unmarshal([]byte(out), &imp))
+	assert.Equal(t, map[models.ImportRowStatus]int{models.ImportRowValid: 1, models.ImportRowInvalid: 1}, imp.Counts)
+
+	result := filepath.Join(dir, "result.csv")
+	out, err = f.run(t, "import", file, "-result", result)
+	require.NoError(t, err)
+	assert.Regexp(t, `2\s+transfer.*succeeded`, out)
+	assert.Regexp(t, `3\s+withdrawal.*invalid\s+insufficient balance`, out)
+
+	data, err := os.ReadFile(result)
+	require.NoError(t, err)
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Len(t, strings.Fields(records[1][8]), 2)
+
+	_, err = f.run(t, "import", filepath.Join(dir, "missing.csv"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRun_AuditVerify(t *testing.T) {
+	f := newCtlFixture(t)
+	dir := t.TempDir()
+
+	_, err := f.run(t, "transfer", f.shankar, f.jessica, "30")
+	require.NoError(t, err)
+	anchor, ok := f.audit.Checkpoint()
+	require.True(t, ok)
+
+	out, err := f.run(t, "audit", "verify")
+	require.NoError(t, err)
+	assert.Regexp(t, `true\s+9\s+1`, out)
+
+	records := f.audit.Records(1)
+	records[2].TransactionIds = []string{"forged"}
+	data, err := jsoniter.Marshal(records)
+	require.NoError(t, err)
+	file := filepath.Join(dir, "records.json")
+	require.NoError(t, os.WriteFile(file, data, 0o644))
+
+	out, err = f.run(t, "audit", "verify", "-file", file, "-o", "json")
+	assert.ErrorIs(t, err, ErrAuditBroken)
+	result := models.AuditVerification{}
+	require.NoError(t, jsoniter.Unmarshal([]byte(out), &result))
+	require.NotNil(t, result.BrokenAt)
+	assert.Equal(t, uint64(3), *result.BrokenAt)
+
+	line, err := jsoniter.Marshal(models.AuditAnchor{Seq: anchor.Seq + 1, Hash: anchor.Hash, At: anchor.At})
+	require.NoError(t, err)
+	anchors := filepath.Join(dir, "anchors.jsonl")
+	require.NoError(t, os.WriteFile(anchors, append(line, '\n'), 0o644))
+
+	out, err = f.run(t, "audit", "verify", "-anchors", anchors)
+	assert.ErrorIs(t, err, ErrAuditBroken)
+	assert.Contains(t, out, "log ends at 9")
+}
+
+func TestRun_Errors(t *testing.T) {
+	f := newCtlFixture(t)
+
+	scenarios := map[string]struct {
+		args       []string
+		env        map[string]string
+		wantErr    error
+		wantStatus int
+	}{
+		"missing-command":  {args: nil, wantErr: ErrUsage},
+		"unknown-command":  {args: []string{"refund"}, wantErr: ErrUsage},
+		"missing-argument": {args: []string{"deposit", "0001"}, wantErr: ErrUsage},
+		"unknown-flag":     {args: []string{"accounts", "list", "-verbose"}, wantErr: ErrUsage},
+		"malformed-amount": {args: []string{"deposit", "0001", "ten"}, wantErr: ErrUsage},
+		"negative-amount":  {args: []string{"deposit", "0001", "-5"}, wantErr: ErrUsage},
+		"zero-amount":      {args: []string{"deposit", "0001", "0"}, wantErr: client.ErrInvalidAmount},
+		"bad-date":         {args: []string{"history", "0001", "-since", "yesterday"}, wantErr: ErrUsage},
+		"unknown-format":   {args: []string{"accounts", "list", "-o", "xml"}, wantErr: ErrUnknownFormat},
+		"unknown-account":  {args: []string{"balance", "0009"}, wantStatus: http.StatusNotFound},
+		"wrong-api-key":    {args: []string{"accounts", "list", "-api-key", "nope"}, wantStatus: http.StatusUnauthorized},
+		"no-api-key":       {args: []string{"accounts", "list"}, env: map[string]string{EnvAPIKey: ""}, wantStatus: http.StatusUnauthorized},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			for k, v := range tcase.env {
+				old := f.env[k]
+				f.env[k] = v
+				defer func() { f.env[k] = old }()
+			}
+
+			_, err := f.run(t, tcase.args...)
+
+			if tcase.wantErr != nil {
+				assert.ErrorIs(t, err, tcase.wantErr)
+			}
+			if tcase.wantStatus != 0 {
+				apiErr := &client.APIError{}
+				require.ErrorAs(t, err, &apiErr)
+				assert.Equal(t, tcase.wantStatus, apiErr.Status)
+			}
+		})
+	}
+}
+
+func TestRun_Profiles(t *testing.T) {
+	f := newCtlFixture(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+current: local
+profiles:
+  local:
+    url: `+f.url+`
+    apiKey: `+apiKey+`
+    output: csv
+  broken:
+    url: `+f.url+`
+    apiKey: nope
+`), 0o600))
+	f.env = map[string]string{EnvConfig: path}
+
+	out, err := f.run(t, "balance", f.shankar)
+	require.NoError(t, err)
+	assert.Equal(t, "ACCOUNT,BALANCE\n"+f.shankar+",100.00\n", out)
+
+	_, err = f.run(t, "-profile", "broken", "balance", f.shankar)
+	apiErr := &client.APIError{}
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.Status)
+
+	f.env[EnvAPIKey] = apiKey
+	_, err = f.run(t, "-profile", "broken", "balance", f.shankar)
+	assert.NoError(t, err)
+
+	_, err = f.run(t, "-profile", "staging", "balance", f.shankar)
+	assert.ErrorIs(t, err, ErrUnknownProfile)
+
+	_, err = f.run(t, "-config", filepath.Join(t.TempDir(), "nope.yaml"), "balance", f.shankar)
+	assert.Error(t, err)
+}
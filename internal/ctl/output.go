@@ -0,0 +1,131 @@
+package ctl
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gopay/internal/models"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatCSV   = "csv"
+)
+
+var ErrUnknownFormat = errors.New("unknown output format")
+
+// view is a command result: value for JSON, header and rows for tables and
+// CSV.
+type view struct {
+	value  interface{}
+	header []string
+	rows   [][]string
+}
+
+func render(w io.Writer, format string, v view) error {
+	switch format {
+	case FormatJSON:
+		out, err := jsoniter.MarshalIndent(v.value, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", out)
+		return err
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write(v.header)
+		_ = cw.WriteAll(v.rows)
+		return cw.Error()
+	case FormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(v.header, "\t"))
+		for _, row := range v.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("%w %q, use %s, %s or %s", ErrUnknownFormat, format, FormatTable, FormatJSON, FormatCSV)
+	}
+}
+
+func accountsView(accs []models.Account) view {
+	v := view{value: accs, header: []string{"ID", "NAME", "LAST NAME", "FROZEN"}}
+	for _, acc := range accs {
+		v.rows = append(v.rows, []string{acc.AccountId, acc.Name, acc.LastName, strconv.FormatBool(acc.Frozen)})
+	}
+	return v
+}
+
+func accountView(acc models.Account) view {
+	v := accountsView([]models.Account{acc})
+	v.value = acc
+	return v
+}
+
+func balancesView(balances []models.Balance) view {
+	v := view{value: balances, header: []string{"ACCOUNT", "BALANCE"}}
+	for _, b := range balances {
+		v.rows = append(v.rows, []string{b.AccountId, strconv.FormatFloat(b.Amount, 'f', 2, 64)})
+	}
+	return v
+}
+
+func transactionsView(transactions []models.Transaction) view {
+	v := view{value: transactions, header: []string{"ID", "CREATED AT", "OWNER", "SENDER", "RECEIVER", "AMOUNT", "CONSUMED"}}
+	for _, t := range transactions {
+		v.rows = append(v.rows, []string{
+			t.TransactionId,
+			t.CreatedAt.Format(time.RFC3339),
+			t.Owner,
+			t.Sender,
+			t.Receiver,
+			strconv.FormatFloat(float64(t.Amount), 'f', 2, 32),
+			strconv.FormatBool(t.IsConsumed),
+		})
+	}
+	return v
+}
+
+func importView(imp models.Import) view {
+	v := view{value: imp, header: []string{"LINE", "OPERATION", "ACCOUNT", "RECEIVER", "AMOUNT", "STATUS", "ERROR", "TRANSACTIONS"}}
+	for _, row := range imp.Rows {
+		v.rows = append(v.rows, []string{
+			strconv.Itoa(row.Line),
+			string(row.Operation),
+			row.Account,
+			row.Receiver,
+			strconv.FormatFloat(float64(row.Amount), 'f', 2, 32),
+			string(row.Status),
+			row.Error,
+			strings.Join(row.TransactionIds, " "),
+		})
+	}
+	return v
+}
+
+func auditView(result models.AuditVerification) view {
+	brokenAt := ""
+	if result.BrokenAt != nil {
+		brokenAt = strconv.FormatUint(*result.BrokenAt, 10)
+	}
+
+	return view{
+		value:  result,
+		header: []string{"OK", "RECORDS", "ANCHORS", "BROKEN AT", "REASON"},
+		rows: [][]string{{
+			strconv.FormatBool(result.Ok),
+			strconv.Itoa(result.Records),
+			strconv.Itoa(result.Anchors),
+			brokenAt,
+			result.Reason,
+		}},
+	}
+}
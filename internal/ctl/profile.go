@@ -0,0 +1,87 @@
+package ctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ghodss/yaml"
+)
+
+const DefaultURL = "http://localhost:8080"
+
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// Profile is one API endpoint and how to talk to it.
+type Profile struct {
+	URL    string `json:"url"`
+	APIKey string `json:"apiKey"`
+	Output string `json:"output"`
+}
+
+// ProfileFile is the YAML profile file, e.g.
+//
+//	current: staging
+//	profiles:
+//	  staging:
+//	    url: https://gopay.staging.example.com
+//	    apiKey: ...
+//	    output: table
+type ProfileFile struct {
+	Current  string             `json:"current"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// DefaultProfilePath is gopayctl/config.yaml in the user's config directory.
+func DefaultProfilePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gopayctl", "config.yaml")
+}
+
+// LoadProfile reads the named profile, or the file's current one when name
+// is empty. A missing file is only an error when required.
+func LoadProfile(path string, name string, required bool) (Profile, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		if name != "" {
+			return Profile{}, fmt.Errorf("%w %q: %s doesn't exist", ErrUnknownProfile, name, path)
+		}
+		return Profile{}, nil
+	}
+	if err != nil {
+		return Profile{}, err
+	}
+
+	js, err := yaml.YAMLToJSON(raw)
+	if err != nil {
+		return Profile{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	file := ProfileFile{}
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&file)
+	if err != nil {
+		return Profile{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if name == "" {
+		name = file.Current
+	}
+	if name == "" {
+		return Profile{}, nil
+	}
+
+	profile, found := file.Profiles[name]
+	if !found {
+		return Profile{}, fmt.Errorf("%w %q in %s", ErrUnknownProfile, name, path)
+	}
+	return profile, nil
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"context"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+)
+
+var _ repository.AccountRepo = (*accountRepo)(nil)
+
+// accountRepo decorates an AccountRepo, recording account.created,
+// account.frozen and account.unfrozen in the outbox in the same unit of work
+// as every Create and SetFrozen.
+type accountRepo struct {
+	repository.AccountRepo
+	outboxRepo repository.OutboxRepo
+	unitOfWork repository.UnitOfWork
+}
+
+func NewAccountRepo(next repository.AccountRepo, outboxRepo repository.OutboxRepo, unitOfWork repository.UnitOfWork) *accountRepo {
+	return &accountRepo{
+		AccountRepo: next,
+		outboxRepo:  outboxRepo,
+		unitOfWork:  unitOfWork,
+	}
+}
+
+func (r *accountRepo) Create(ctx context.Context, name string, lastname string) (string, error) {
+	var id string
+
+	err := r.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		var err error
+
+		id, err = r.AccountRepo.Create(ctx, name, lastname)
+		if err != nil {
+			return err
+		}
+
+		account, err := r.AccountRepo.FindOne(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return r.outboxRepo.Append(ctx, New(models.EventAccountCreated, id, account))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (r *accountRepo) SetFrozen(ctx context.Context, id string, frozen bool) (models.Account, error) {
+	var account models.Account
+
+	err := r.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		var err error
+
+		account, err = r.AccountRepo.SetFrozen(ctx, id, frozen)
+		if err != nil {
+			return err
+		}
+
+		eventType := models.EventAccountUnfrozen
+		if frozen {
+			eventType = models.EventAccountFrozen
+		}
+		return r.outboxRepo.Append(ctx, New(eventType, id, account))
+	})
+	if err != nil {
+		return models.Account{}, err
+	}
+
+	return account, nil
+}
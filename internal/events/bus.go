@@ -0,0 +1,52 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gopay/internal/models"
+)
+
+var _ Publisher = (*Bus)(nil)
+
+// Bus fans events out to in-process subscribers. Handlers run synchronously
+// on the publishing goroutine and must not block.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[int]func(models.Event)
+	nextId   int
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		handlers: make(map[int]func(models.Event)),
+	}
+}
+
+// Subscribe registers fn for every published event and returns a function
+// that removes it.
+func (b *Bus) Subscribe(fn func(models.Event)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextId
+	b.nextId++
+	b.handlers[id] = fn
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+func (b *Bus) Publish(_ context.Context, event models.Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, fn := range b.handlers {
+		fn(event)
+	}
+
+	return nil
+}
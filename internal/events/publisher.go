@@ -0,0 +1,27 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gopay/internal/models"
+)
+
+// Publisher delivers events to a sink such as webhooks or an in-process bus.
+// Delivery is at least once: implementations should de-duplicate by EventId.
+type Publisher interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+var idGenerator = uuid.NewString
+
+func New(eventType models.EventType, accountId string, data interface{}) models.Event {
+	return models.Event{
+		EventId:   idGenerator(),
+		Type:      eventType,
+		AccountId: accountId,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+}
@@ -0,0 +1,277 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/statement"
+	"github.com/gopay/internal/utils"
+)
+
+const (
+	FormatOFX Format = "ofx"
+	FormatQIF Format = "qif"
+	FormatCSV Format = "csv"
+
+	// Currency is what every GoPay amount is in.
+	Currency = "USD"
+	// BankId identifies GoPay in BANKACCTFROM, which OFX requires.
+	BankId = "GOPAY"
+)
+
+var ErrUnknownFormat = errors.New("format must be ofx, qif or csv")
+
+type Format string
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatOFX, FormatQIF, FormatCSV:
+		return f, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatOFX:
+		return "application/x-ofx"
+	case FormatQIF:
+		return "application/qif"
+	}
+	return "text/csv"
+}
+
+// FITID is the id finance tools de-duplicate imports by. It's the
+// transaction id itself, so every export of a transaction carries the same
+// one however the date range is cut.
+func FITID(transactionId string) string {
+	return transactionId
+}
+
+// Exporter writes the credits and debits of an account, as they appear in
+// its statements, in formats personal finance tools import.
+type Exporter struct {
+	generator   *statement.Generator
+	accountRepo repository.AccountRepo
+	now         func() time.Time
+}
+
+func NewExporter(generator *statement.Generator, accountRepo repository.AccountRepo) *Exporter {
+	return &Exporter{
+		generator:   generator,
+		accountRepo: accountRepo,
+		now:         time.Now,
+	}
+}
+
+// entry is a statement line with what the formats need on top of it.
+type entry struct {
+	statement.Line
+	FITID string
+	Payee string
+}
+
+// Export writes the transactions of accountId created in [from, to) to w.
+func (e *Exporter) Export(ctx context.Context, w io.Writer, accountId string, format Format, from time.Time, to time.Time) error {
+	s, err := e.generator.Generate(ctx, accountId, statement.Period{Start: from, End: to})
+	if err != nil {
+		return err
+	}
+
+	names := map[string]string{}
+	entries := []entry{}
+	for _, line := range s.Lines {
+		entries = append(entries, entry{
+			Line:  line,
+			FITID: FITID(line.TransactionId),
+			Payee: e.payee(ctx, line, names),
+		})
+	}
+
+	switch format {
+	case FormatOFX:
+		return writeOFX(w, s, entries, e.now())
+	case FormatQIF:
+		return writeQIF(w, entries)
+	case FormatCSV:
+		return writeCSV(w, entries)
+	}
+	return ErrUnknownFormat
+}
+
+// payee names the counterparty of a transfer, or describes the line.
+func (e *Exporter) payee(ctx context.Context, line statement.Line, names map[string]string) string {
+	switch line.Kind {
+	case statement.KindDeposit:
+		return "Deposit"
+	case statement.KindWithdrawal:
+		return "Withdrawal"
+	}
+
+	name, found := names[line.Counterparty]
+	if !found {
+		name = line.Counterparty
+		acc, err := e.accountRepo.FindOne(ctx, line.Counterparty)
+		if err == nil {
+			name = acc.Name + " " + acc.LastName
+		}
+		names[line.Counterparty] = name
+	}
+	return name
+}
+
+func amount(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
+
+const ofxTime = "20060102150405"
+
+type ofxDoc struct {
+	XMLName xml.Name `xml:"OFX"`
+	SignOn  struct {
+		Status   ofxStatus `xml:"SONRS>STATUS"`
+		Server   string    `xml:"SONRS>DTSERVER"`
+		Language string    `xml:"SONRS>LANGUAGE"`
+	} `xml:"SIGNONMSGSRSV1"`
+	Bank struct {
+		TrnUID    string    `xml:"STMTTRNRS>TRNUID"`
+		Status    ofxStatus `xml:"STMTTRNRS>STATUS"`
+		Statement struct {
+			Currency string `xml:"CURDEF"`
+			Account  struct {
+				BankId    string `xml:"BANKID"`
+				AccountId string `xml:"ACCTID"`
+				Type      string `xml:"ACCTTYPE"`
+			} `xml:"BANKACCTFROM"`
+			Transactions struct {
+				Start string   `xml:"DTSTART"`
+				End   string   `xml:"DTEND"`
+				Lines []ofxTrn `xml:"STMTTRN"`
+			} `xml:"BANKTRANLIST"`
+			Balance struct {
+				Amount string `xml:"BALAMT"`
+				AsOf   string `xml:"DTASOF"`
+			} `xml:"LEDGERBAL"`
+		} `xml:"STMTTRNRS>STMTRS"`
+	} `xml:"BANKMSGSRSV1"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxTrn struct {
+	Type   string `xml:"TRNTYPE"`
+	Posted string `xml:"DTPOSTED"`
+	Amount string `xml:"TRNAMT"`
+	FITID  string `xml:"FITID"`
+	Name   string `xml:"NAME"`
+	Memo   string `xml:"MEMO"`
+}
+
+func writeOFX(w io.Writer, s statement.Statement, entries []entry, now time.Time) error {
+	doc := ofxDoc{}
+	ok := ofxStatus{Code: 0, Severity: "INFO"}
+	doc.SignOn.Status = ok
+	doc.SignOn.Server = now.UTC().Format(ofxTime)
+	doc.SignOn.Language = "ENG"
+	doc.Bank.TrnUID = "0"
+	doc.Bank.Status = ok
+
+	stmt := &doc.Bank.Statement
+	stmt.Currency = Currency
+	stmt.Account.BankId = BankId
+	stmt.Account.AccountId = s.AccountId
+	stmt.Account.Type = "CHECKING"
+	stmt.Transactions.Start = s.From.UTC().Format(ofxTime)
+	stmt.Transactions.End = s.To.UTC().Format(ofxTime)
+	stmt.Balance.Amount = amount(s.ClosingBalance)
+	stmt.Balance.AsOf = stmt.Transactions.End
+
+	for _, e := range entries {
+		trnType := "XFER"
+		switch e.Kind {
+		case statement.KindDeposit:
+			trnType = "DEP"
+		case statement.KindWithdrawal:
+			trnType = "DEBIT"
+		}
+
+		// NAME is limited to 32 characters
+		name := e.Payee
+		if runes := []rune(name); len(runes) > 32 {
+			name = string(runes[:32])
+		}
+
+		stmt.Transactions.Lines = append(stmt.Transactions.Lines, ofxTrn{
+			Type:   trnType,
+			Posted: e.CreatedAt.UTC().Format(ofxTime),
+			Amount: amount(e.Amount),
+			FITID:  e.FITID,
+			Name:   name,
+			Memo:   string(e.Kind),
+		})
+	}
+
+	_, err := io.WriteString(w, xml.Header+
+		`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`+"\n")
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(&doc)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// writeQIF writes a bank register. QIF has no transaction id, the FITID goes
+// in the number field, which importers match on.
+func writeQIF(w io.Writer, entries []entry) error {
+	b := &strings.Builder{}
+	b.WriteString("!Type:Bank\n")
+	for _, e := range entries {
+		fmt.Fprintf(b, "D%s\nT%s\nN%s\nP%s\nM%s\n^\n",
+			e.CreatedAt.UTC().Format("01/02/2006"), amount(e.Amount), e.FITID, qifText(e.Payee), e.Kind)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// qifText keeps a value on its line, QIF fields end at the newline.
+func qifText(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
+
+// writeCSV escapes the text cells, payees are account names anyone can pick
+// and a spreadsheet would run one starting with = as a formula.
+func writeCSV(w io.Writer, entries []entry) error {
+	out := csv.NewWriter(w)
+
+	rows := [][]string{{"date", "fitid", "kind", "payee", "counterparty", "amount", "balance"}}
+	for _, e := range entries {
+		rows = append(rows, []string{
+			e.CreatedAt.UTC().Format(time.RFC3339), utils.CSVText(e.FITID), string(e.Kind), utils.CSVText(e.Payee), utils.CSVText(e.Counterparty), amount(e.Amount), amount(e.Balance),
+		})
+	}
+
+	err := out.WriteAll(rows)
+	if err != nil {
+		return err
+	}
+	return out.Error()
+}
@@ -0,0 +1,158 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/statement"
+	"github.com/gopay/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	exporter     *Exporter
+	transactions repository.TransactionRepo
+	alice        string
+	bob          string
+	ids          []string
+}
+
+func day(d int) time.Time {
+	return time.Date(2026, time.September, d, 12, 0, 0, 0, time.UTC)
+}
+
+func newFixture(t *testing.T) *fixture {
+	ctx := context.Background()
+	bank := testutil.NewBank(t)
+	alice := bank.Account(t, "Alice", "Liddell")
+	bob := bank.Account(t, "Bob", "Ray")
+
+	f := &fixture{transactions: bank.Transactions, alice: alice, bob: bob}
+	for _, tr := range []models.Transaction{
+		{CreatedAt: day(1), Owner: alice, Sender: alice, Receiver: alice, Amount: 1000, IsConsumed: true},
+		{CreatedAt: day(5), Owner: alice, Sender: alice, Receiver: alice, Amount: 800, Remainder: true},
+		{CreatedAt: day(5), Owner: alice, Sender: alice, Receiver: bob, Amount: -200, IsConsumed: true},
+		{CreatedAt: day(9), Owner: alice, Sender: bob, Receiver: alice, Amount: 50},
+	} {
+		id, err := bank.Transactions.Create(ctx, tr)
+		require.NoError(t, err)
+		if !tr.Remainder {
+			f.ids = append(f.ids, id)
+		}
+	}
+
+	f.exporter = NewExporter(statement.NewGenerator(bank.Transactions), bank.Accounts)
+	f.exporter.now = func() time.Time { return day(30) }
+	return f
+}
+
+func (f *fixture) export(t *testing.T, format Format, from time.Time, to time.Time) string {
+	buf := &bytes.Buffer{}
+	require.NoError(t, f.exporter.Export(context.Background(), buf, f.alice, format, from, to))
+	return buf.String()
+}
+
+func TestParseFormat(t *testing.T) {
+	scenarios := map[string]struct {
+		given   string
+		want    Format
+		wantErr error
+	}{
+		"ofx":       {given: "ofx", want: FormatOFX},
+		"qif-upper": {given: "QIF", want: FormatQIF},
+		"csv":       {given: "csv", want: FormatCSV},
+		"unknown":   {given: "xls", wantErr: ErrUnknownFormat},
+		"empty":     {given: "", wantErr: ErrUnknownFormat},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseFormat(tcase.given)
+
+			assert.ErrorIs(t, err, tcase.wantErr)
+			assert.Equal(t, tcase.want, got)
+		})
+	}
+}
+
+func TestExporter_OFX(t *testing.T) {
+	f := newFixture(t)
+
+	out := f.export(t, FormatOFX, day(1), day(10))
+	assert.Contains(t, out, `<?OFX OFXHEADER="200" VERSION="220"`)
+
+	doc := ofxDoc{}
+	require.NoError(t, xml.Unmarshal([]byte(out), &doc))
+
+	stmt := doc.Bank.Statement
+	assert.Equal(t, f.alice, stmt.Account.AccountId)
+	assert.Equal(t, "20260901120000", stmt.Transactions.Start)
+	assert.Equal(t, "850.00", stmt.Balance.Amount)
+	assert.Equal(t, "20260930120000", doc.SignOn.Server)
+	assert.Equal(t, []ofxTrn{
+		{Type: "DEP", Posted: "20260901120000", Amount: "1000.00", FITID: f.ids[0], Name: "Deposit", Memo: "deposit"},
+		{Type: "XFER", Posted: "20260905120000", Amount: "-200.00", FITID: f.ids[1], Name: "Bob Ray", Memo: "transfer_out"},
+		{Type: "XFER", Posted: "20260909120000", Amount: "50.00", FITID: f.ids[2], Name: "Bob Ray", Memo: "transfer_in"},
+	}, stmt.Transactions.Lines)
+}
+
+func TestExporter_StableFITIDs(t *testing.T) {
+	f := newFixture(t)
+
+	fitids := func(from time.Time, to time.Time) map[string]string {
+		doc := ofxDoc{}
+		require.NoError(t, xml.Unmarshal([]byte(f.export(t, FormatOFX, from, to)), &doc))
+
+		res := map[string]string{}
+		for _, line := range doc.Bank.Statement.Transactions.Lines {
+			res[line.Posted] = line.FITID
+		}
+		return res
+	}
+
+	// overlapping ranges give the shared transactions the same FITID
+	first, second := fitids(day(1), day(6)), fitids(day(5), day(10))
+	assert.Len(t, first, 2)
+	assert.Len(t, second, 2)
+	assert.Equal(t, first["20260905120000"], second["20260905120000"])
+}
+
+func TestExporter_QIF(t *testing.T) {
+	f := newFixture(t)
+
+	assert.Equal(t, "!Type:Bank\n"+
+		"D09/05/2026\nT-200.00\nN"+f.ids[1]+"\nPBob Ray\nMtransfer_out\n^\n"+
+		"D09/09/2026\nT50.00\nN"+f.ids[2]+"\nPBob Ray\nMtransfer_in\n^\n",
+		f.export(t, FormatQIF, day(2), day(10)))
+}
+
+func TestExporter_CSV(t *testing.T) {
+	f := newFixture(t)
+
+	assert.Equal(t, "date,fitid,kind,payee,counterparty,amount,balance\n"+
+		"2026-09-01T12:00:00Z,"+f.ids[0]+",deposit,Deposit,,1000.00,1000.00\n"+
+		"2026-09-05T12:00:00Z,"+f.ids[1]+",transfer_out,Bob Ray,"+f.bob+",-200.00,800.00\n",
+		f.export(t, FormatCSV, day(1), day(6)))
+}
+
+func TestExporter_CSVEscapesFormulas(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	mallory, err := f.exporter.accountRepo.Create(ctx, "=HYPERLINK(\"http://example.com\")", "x")
+	require.NoError(t, err)
+	id, err := f.transactions.Create(ctx, models.Transaction{CreatedAt: day(12), Owner: f.alice, Sender: mallory, Receiver: f.alice, Amount: 5})
+	require.NoError(t, err)
+
+	assert.Equal(t, "date,fitid,kind,payee,counterparty,amount,balance\n"+
+		"2026-09-12T12:00:00Z,"+id+",transfer_in,\"'=HYPERLINK(\"\"http://example.com\"\") x\","+mallory+",5.00,855.00\n",
+		f.export(t, FormatCSV, day(11), day(13)))
+}
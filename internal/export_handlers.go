@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gopay/internal/export"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/utils"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type ExportHandler struct {
+	exporter    *export.Exporter
+	accountRepo repository.AccountRepo
+}
+
+func NewExportHandler(exporter *export.Exporter, accountRepo repository.AccountRepo) *ExportHandler {
+	return &ExportHandler{
+		exporter:    exporter,
+		accountRepo: accountRepo,
+	}
+}
+
+// GetExport writes the transactions created between since and until, both
+// included, as a file. Without since it starts with the first transaction,
+// without until it ends now. since after until is a bad request rather than
+// an empty file.
+func (h *ExportHandler) GetExport(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	id := params.ByName(AccountIdParam)
+	query := r.URL.Query()
+
+	format, err := export.ParseFormat(query.Get("format"))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetExport")
+		utils.ErrorWithMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	since, until := time.Time{}, time.Now()
+	for name, dst := range map[string]*time.Time{"since": &since, "until": &until} {
+		if v := query.Get(name); v != "" {
+			*dst, err = time.Parse(time.RFC3339, v)
+			if err != nil {
+				err = fmt.Errorf("%w: %s: %w", ErrInvalidFilter, name, err)
+				log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetExport")
+				utils.ErrorWithMessage(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+	}
+	if since.After(until) {
+		err = fmt.Errorf("%w: since is after until", ErrInvalidFilter)
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetExport")
+		utils.ErrorWithMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	_, err = h.accountRepo.FindOne(r.Context(), id)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(ErrAccountNotFound).Msg("Handler::GetExport")
+		utils.ErrorWithMessage(w, http.StatusNotFound, ErrAccountNotFound.Error())
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	err = h.exporter.Export(r.Context(), buf, id, format, since, until.Add(time.Nanosecond))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetExport")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="gopay-%s.%s"`, id, format))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,51 @@
+package gql
+
+import (
+	"errors"
+
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+)
+
+// resolverError exposes a machine readable code in the error extensions.
+type resolverError struct {
+	err  error
+	code string
+}
+
+func (e resolverError) Error() string {
+	return e.err.Error()
+}
+
+func (e resolverError) Unwrap() error {
+	return e.err
+}
+
+func (e resolverError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+func toError(err error) error {
+	code := "INTERNAL"
+
+	switch {
+	case errors.Is(err, repository.ErrAccountNotFound),
+		errors.Is(err, repository.ErrTransactionNotFound):
+		code = "NOT_FOUND"
+	case errors.Is(err, service.ErrInsufficentBalance):
+		code = "INSUFFICIENT_BALANCE"
+	case errors.Is(err, service.ErrAccountFrozen):
+		code = "ACCOUNT_FROZEN"
+	case errors.Is(err, service.ErrInvalidAmount),
+		errors.Is(err, service.ErrSameAccount),
+		errors.Is(err, repository.ErrMissingFields),
+		errors.Is(err, repository.ErrZeroAmount),
+		errors.Is(err, ErrInvalidCursor),
+		errors.Is(err, ErrInvalidFirst):
+		code = "BAD_USER_INPUT"
+	case errors.Is(err, service.ErrFailedDebitOperation):
+		code = "ABORTED"
+	}
+
+	return resolverError{err: err, code: code}
+}
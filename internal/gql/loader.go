@@ -0,0 +1,82 @@
+package gql
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+)
+
+type loaderKey struct{}
+
+// accountLoader caches accounts for the duration of one request. Lists
+// prime it with every account they reference in a single FindMany, so
+// resolving sender and receiver of each transaction doesn't hit the
+// repository once per row.
+type accountLoader struct {
+	mu          sync.Mutex
+	accountRepo repository.AccountRepo
+	accounts    map[string]models.Account
+}
+
+func withLoader(ctx context.Context, accountRepo repository.AccountRepo) context.Context {
+	return context.WithValue(ctx, loaderKey{}, &accountLoader{
+		accountRepo: accountRepo,
+		accounts:    make(map[string]models.Account),
+	})
+}
+
+func loaderFrom(ctx context.Context, accountRepo repository.AccountRepo) *accountLoader {
+	if l, ok := ctx.Value(loaderKey{}).(*accountLoader); ok {
+		return l
+	}
+	// uncached fallback for callers that skipped withLoader
+	return &accountLoader{accountRepo: accountRepo, accounts: make(map[string]models.Account)}
+}
+
+func (l *accountLoader) prime(ctx context.Context, ids []string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	missing := []string{}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if _, found := l.accounts[id]; !found && !seen[id] {
+			missing = append(missing, id)
+			seen[id] = true
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	accs, err := l.accountRepo.FindMany(ctx, missing)
+	if err != nil {
+		return err
+	}
+
+	for _, acc := range accs {
+		l.accounts[acc.AccountId] = acc
+	}
+
+	return nil
+}
+
+func (l *accountLoader) load(ctx context.Context, id string) (models.Account, error) {
+	err := l.prime(ctx, []string{id})
+	if err != nil {
+		return models.Account{}, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	acc, found := l.accounts[id]
+	if !found {
+		return models.Account{}, repository.ErrAccountNotFound
+	}
+
+	return acc, nil
+}
@@ -0,0 +1,436 @@
+package gql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+//go:embed schema.graphql
+var schemaString string
+
+const cursorPrefix = "transaction:"
+
+// Limits on what a single request may ask for. Account.transactions nests
+// back into Account, so without a depth limit one query could walk the
+// whole ledger.
+const (
+	maxDepth       = 8
+	maxParallelism = 10
+	maxQueryLength = 8 * 1024
+	maxFirst       = 100
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidFirst  = errors.New("first must not be negative")
+	ErrQueryTooLong  = errors.New("query is too long")
+)
+
+// Resolver is the root of the GraphQL schema. It is built on the same
+// service and repositories as the REST handlers.
+type Resolver struct {
+	transactionService service.TransactionService
+	transactionRepo    repository.TransactionRepo
+	accountRepo        repository.AccountRepo
+	schema             *graphql.Schema
+}
+
+func NewResolver(transactionService service.TransactionService, transactionRepo repository.TransactionRepo, accountRepo repository.AccountRepo) *Resolver {
+	r := &Resolver{
+		transactionService: transactionService,
+		transactionRepo:    transactionRepo,
+		accountRepo:        accountRepo,
+	}
+	r.schema = graphql.MustParseSchema(schemaString, r, graphql.MaxDepth(maxDepth), graphql.MaxParallelism(maxParallelism))
+	return r
+}
+
+// Exec runs one GraphQL request with its own account loader.
+func (r *Resolver) Exec(ctx context.Context, query string, operationName string, variables map[string]interface{}) *graphql.Response {
+	if len(query) > maxQueryLength {
+		err := gqlerrors.Errorf("%v", ErrQueryTooLong)
+		err.Extensions = map[string]interface{}{"code": "BAD_USER_INPUT"}
+		return &graphql.Response{Errors: []*gqlerrors.QueryError{err}}
+	}
+
+	return r.schema.Exec(withLoader(ctx, r.accountRepo), query, operationName, variables)
+}
+
+func (r *Resolver) Account(ctx context.Context, args struct{ Id graphql.ID }) (*accountResolver, error) {
+	acc, err := loaderFrom(ctx, r.accountRepo).load(ctx, string(args.Id))
+	if errors.Is(err, repository.ErrAccountNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	return &accountResolver{r: r, acc: acc}, nil
+}
+
+func (r *Resolver) Accounts(ctx context.Context) ([]*accountResolver, error) {
+	accs, err := r.accountRepo.FindAll(ctx)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	res := []*accountResolver{}
+	for _, acc := range accs {
+		res = append(res, &accountResolver{r: r, acc: acc})
+	}
+
+	return res, nil
+}
+
+func (r *Resolver) Transaction(ctx context.Context, args struct{ Id graphql.ID }) (*transactionResolver, error) {
+	t, err := r.transactionRepo.FindOne(ctx, string(args.Id))
+	if errors.Is(err, repository.ErrTransactionNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	return &transactionResolver{r: r, t: t}, nil
+}
+
+type depositArgs struct {
+	AccountId graphql.ID
+	Amount    float64
+}
+
+func (r *Resolver) Deposit(ctx context.Context, args depositArgs) (*balanceResolver, error) {
+	err := r.transactionService.Deposit(ctx, string(args.AccountId), float32(args.Amount), service.Note{})
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	return r.balance(ctx, string(args.AccountId))
+}
+
+func (r *Resolver) Withdraw(ctx context.Context, args depositArgs) (*balanceResolver, error) {
+	if args.Amount <= 0 {
+		return nil, toError(service.ErrInvalidAmount)
+	}
+
+	err := r.transactionService.Withdraw(ctx, string(args.AccountId), float32((-1)*args.Amount), service.Note{})
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	return r.balance(ctx, string(args.AccountId))
+}
+
+type transferArgs struct {
+	Sender   graphql.ID
+	Receiver graphql.ID
+	Amount   float64
+}
+
+func (r *Resolver) Transfer(ctx context.Context, args transferArgs) (*balanceResolver, error) {
+	if args.Amount <= 0 {
+		return nil, toError(service.ErrInvalidAmount)
+	}
+
+	err := r.transactionService.Transfer(ctx, string(args.Sender), string(args.Receiver), float32((-1)*args.Amount), service.Note{})
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	return r.balance(ctx, string(args.Sender))
+}
+
+func (r *Resolver) balance(ctx context.Context, accountId string) (*balanceResolver, error) {
+	balance, err := r.transactionRepo.GetBalance(ctx, accountId)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	return &balanceResolver{b: balance}, nil
+}
+
+type accountResolver struct {
+	r   *Resolver
+	acc models.Account
+}
+
+func (a *accountResolver) Id() graphql.ID {
+	return graphql.ID(a.acc.AccountId)
+}
+
+func (a *accountResolver) Name() string {
+	return a.acc.Name
+}
+
+func (a *accountResolver) LastName() string {
+	return a.acc.LastName
+}
+
+func (a *accountResolver) Frozen() bool {
+	return a.acc.Frozen
+}
+
+func (a *accountResolver) Balance(ctx context.Context) (float64, error) {
+	balance, err := a.r.transactionRepo.GetBalance(ctx, a.acc.AccountId)
+	if err != nil {
+		return 0, toError(err)
+	}
+
+	return balance.Amount, nil
+}
+
+type transactionFilter struct {
+	Consumed     *bool
+	MinAmount    *float64
+	MaxAmount    *float64
+	Since        *graphql.Time
+	Until        *graphql.Time
+	Counterparty *graphql.ID
+}
+
+func (f *transactionFilter) matches(t models.Transaction) bool {
+	if f == nil {
+		return true
+	}
+
+	filter := models.TransactionFilter{
+		Consumed:  f.Consumed,
+		MinAmount: f.MinAmount,
+		MaxAmount: f.MaxAmount,
+	}
+	if f.Since != nil {
+		filter.Since = &f.Since.Time
+	}
+	if f.Until != nil {
+		filter.Until = &f.Until.Time
+	}
+	if f.Counterparty != nil {
+		counterparty := string(*f.Counterparty)
+		filter.Counterparty = &counterparty
+	}
+
+	return filter.Matches(t)
+}
+
+type transactionsArgs struct {
+	First  int32
+	After  *string
+	Filter *transactionFilter
+}
+
+func (a *accountResolver) Transactions(ctx context.Context, args transactionsArgs) (*connectionResolver, error) {
+	if args.First < 0 {
+		return nil, toError(ErrInvalidFirst)
+	}
+
+	all, err := a.r.transactionRepo.FindAll(ctx, a.acc.AccountId)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	// newest first
+	filtered := []models.Transaction{}
+	for i := len(all) - 1; i >= 0; i-- {
+		if args.Filter.matches(all[i]) {
+			filtered = append(filtered, all[i])
+		}
+	}
+
+	start := 0
+	if args.After != nil {
+		id, err := decodeCursor(*args.After)
+		if err != nil {
+			return nil, toError(err)
+		}
+
+		start = -1
+		for i, t := range filtered {
+			if t.TransactionId == id {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, toError(ErrInvalidCursor)
+		}
+	}
+
+	first := min(int(args.First), maxFirst)
+	end := len(filtered)
+	if start+first < end {
+		end = start + first
+	}
+
+	page := filtered[start:end]
+
+	ids := []string{}
+	for _, t := range page {
+		ids = append(ids, t.Owner, t.Sender, t.Receiver)
+	}
+	err = loaderFrom(ctx, a.r.accountRepo).prime(ctx, ids)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	return &connectionResolver{r: a.r, page: page, total: len(filtered), hasNext: end < len(filtered)}, nil
+}
+
+type connectionResolver struct {
+	r       *Resolver
+	page    []models.Transaction
+	total   int
+	hasNext bool
+}
+
+func (c *connectionResolver) TotalCount() int32 {
+	return int32(c.total)
+}
+
+func (c *connectionResolver) Edges() []*edgeResolver {
+	edges := []*edgeResolver{}
+	for _, t := range c.page {
+		edges = append(edges, &edgeResolver{node: &transactionResolver{r: c.r, t: t}})
+	}
+	return edges
+}
+
+func (c *connectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNext: c.hasNext}
+	if len(c.page) > 0 {
+		cursor := encodeCursor(c.page[len(c.page)-1].TransactionId)
+		info.endCursor = &cursor
+	}
+	return info
+}
+
+type edgeResolver struct {
+	node *transactionResolver
+}
+
+func (e *edgeResolver) Cursor() string {
+	return encodeCursor(e.node.t.TransactionId)
+}
+
+func (e *edgeResolver) Node() *transactionResolver {
+	return e.node
+}
+
+type pageInfoResolver struct {
+	hasNext   bool
+	endCursor *string
+}
+
+func (p *pageInfoResolver) HasNextPage() bool {
+	return p.hasNext
+}
+
+func (p *pageInfoResolver) EndCursor() *string {
+	return p.endCursor
+}
+
+type transactionResolver struct {
+	r *Resolver
+	t models.Transaction
+}
+
+func (t *transactionResolver) Id() graphql.ID {
+	return graphql.ID(t.t.TransactionId)
+}
+
+func (t *transactionResolver) Owner(ctx context.Context) (*accountResolver, error) {
+	return t.account(ctx, t.t.Owner)
+}
+
+func (t *transactionResolver) Sender(ctx context.Context) (*accountResolver, error) {
+	return t.account(ctx, t.t.Sender)
+}
+
+func (t *transactionResolver) Receiver(ctx context.Context) (*accountResolver, error) {
+	return t.account(ctx, t.t.Receiver)
+}
+
+func (t *transactionResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: t.t.CreatedAt}
+}
+
+func (t *transactionResolver) Amount() float64 {
+	return float64(t.t.Amount)
+}
+
+func (t *transactionResolver) IsConsumed() bool {
+	return t.t.IsConsumed
+}
+
+func (t *transactionResolver) Type() string {
+	return string(t.t.Type)
+}
+
+func (t *transactionResolver) Status() string {
+	return string(t.t.Status)
+}
+
+func (t *transactionResolver) Memo() *string {
+	return optional(t.t.Memo)
+}
+
+func (t *transactionResolver) Tags() []string {
+	if t.t.Tags == nil {
+		return []string{}
+	}
+	return t.t.Tags
+}
+
+func (t *transactionResolver) Category() *string {
+	return optional(t.t.Category)
+}
+
+func (t *transactionResolver) account(ctx context.Context, id string) (*accountResolver, error) {
+	acc, err := loaderFrom(ctx, t.r.accountRepo).load(ctx, id)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	return &accountResolver{r: t.r, acc: acc}, nil
+}
+
+type balanceResolver struct {
+	b models.Balance
+}
+
+func (b *balanceResolver) AccountId() graphql.ID {
+	return graphql.ID(b.b.AccountId)
+}
+
+func (b *balanceResolver) Balance() float64 {
+	return b.b.Amount
+}
+
+func encodeCursor(id string) string {
+	return base64.URLEncoding.EncodeToString([]byte(cursorPrefix + id))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	raw, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return "", ErrInvalidCursor
+	}
+
+	return strings.TrimPrefix(string(raw), cursorPrefix), nil
+}
+
+// optional maps the empty string to null.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,328 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingAccountRepo struct {
+	repository.AccountRepo
+	findMany int
+	findOne  int
+}
+
+func (r *countingAccountRepo) FindMany(ctx context.Context, ids []string) ([]models.Account, error) {
+	r.findMany++
+	return r.AccountRepo.FindMany(ctx, ids)
+}
+
+func (r *countingAccountRepo) FindOne(ctx context.Context, id string) (models.Account, error) {
+	r.findOne++
+	return r.AccountRepo.FindOne(ctx, id)
+}
+
+type fixture struct {
+	resolver *Resolver
+	accounts *countingAccountRepo
+	alice    string
+	bob      string
+	carol    string
+}
+
+func newFixture(t *testing.T) *fixture {
+	ctx := context.Background()
+	accounts := &countingAccountRepo{AccountRepo: repository.NewAccountRepo()}
+	bank := testutil.NewBankWith(t, accounts, repository.NewTransactionRepo())
+
+	alice := bank.Account(t, "Alice", "Smith")
+	bob := bank.Account(t, "Bob", "Jones")
+	carol := bank.Account(t, "Carol", "White")
+
+	bank.Deposit(t, 100, alice)
+	require.NoError(t, bank.Service.Transfer(ctx, alice, bob, -10, service.Note{}))
+	require.NoError(t, bank.Service.Transfer(ctx, alice, carol, -20, service.Note{}))
+	require.NoError(t, bank.Service.Transfer(ctx, alice, bob, -30, service.Note{Memo: "Rent", Tags: []string{"flat"}}))
+	accounts.findMany, accounts.findOne = 0, 0
+
+	return &fixture{
+		resolver: NewResolver(bank.Service, bank.Transactions, accounts),
+		accounts: accounts,
+		alice:    alice,
+		bob:      bob,
+		carol:    carol,
+	}
+}
+
+func (f *fixture) exec(t *testing.T, query string, variables map[string]interface{}) (map[string]interface{}, []map[string]interface{}) {
+	resp := f.resolver.Exec(context.Background(), query, "", variables)
+
+	data := map[string]interface{}{}
+	if resp.Data != nil {
+		require.NoError(t, json.Unmarshal(resp.Data, &data))
+	}
+
+	errs := []map[string]interface{}{}
+	for _, err := range resp.Errors {
+		errs = append(errs, map[string]interface{}{"message": err.Message, "extensions": err.Extensions})
+	}
+
+	return data, errs
+}
+
+func TestResolver_AccountTransactions(t *testing.T) {
+	f := newFixture(t)
+	query := `query($id: ID!) {
+		account(id: $id) {
+			name
+			balance
+			transactions {
+				totalCount
+				edges { node { amount owner { name } sender { name } receiver { name } memo tags category } }
+			}
+		}
+	}`
+
+	data, errs := f.exec(t, query, map[string]interface{}{"id": f.alice})
+	assert.Empty(t, errs)
+
+	account := data["account"].(map[string]interface{})
+	assert.Equal(t, "Alice", account["name"])
+	assert.Equal(t, 40.0, account["balance"])
+
+	conn := account["transactions"].(map[string]interface{})
+	edges := conn["edges"].([]interface{})
+	assert.Equal(t, 7.0, conn["totalCount"])
+	assert.Len(t, edges, 7)
+
+	// the remainder of the rent is written after it
+	remainder := edges[0].(map[string]interface{})["node"].(map[string]interface{})
+	assert.Equal(t, 40.0, remainder["amount"])
+
+	rent := edges[1].(map[string]interface{})["node"].(map[string]interface{})
+	assert.Equal(t, -30.0, rent["amount"])
+	assert.Equal(t, "Bob", rent["receiver"].(map[string]interface{})["name"])
+	assert.Equal(t, "Rent", rent["memo"])
+	assert.Equal(t, []interface{}{"flat"}, rent["tags"])
+	assert.Nil(t, rent["category"])
+
+	// one lookup for the account itself, one for every counterparty on the page
+	assert.Equal(t, 2, f.accounts.findMany)
+	assert.Equal(t, 0, f.accounts.findOne)
+}
+
+func TestResolver_Pagination(t *testing.T) {
+	f := newFixture(t)
+	query := `query($id: ID!, $after: String) {
+		account(id: $id) {
+			transactions(first: 3, after: $after) {
+				edges { cursor node { id } }
+				pageInfo { hasNextPage endCursor }
+			}
+		}
+	}`
+
+	seen := []string{}
+	var after interface{}
+	for page := 0; page < 5; page++ {
+		data, errs := f.exec(t, query, map[string]interface{}{"id": f.alice, "after": after})
+		require.Empty(t, errs)
+
+		conn := data["account"].(map[string]interface{})["transactions"].(map[string]interface{})
+		for _, edge := range conn["edges"].([]interface{}) {
+			seen = append(seen, edge.(map[string]interface{})["node"].(map[string]interface{})["id"].(string))
+		}
+
+		info := conn["pageInfo"].(map[string]interface{})
+		if !info["hasNextPage"].(bool) {
+			break
+		}
+		after = info["endCursor"]
+	}
+
+	assert.Len(t, seen, 7)
+	assert.ElementsMatch(t, seen, uniq(seen))
+}
+
+func TestResolver_Filter(t *testing.T) {
+	f := newFixture(t)
+
+	scenarios := map[string]struct {
+		given     string
+		wantCount float64
+	}{
+		"consumed": {
+			given:     `{ consumed: true }`,
+			wantCount: 6,
+		},
+		"counterparty": {
+			given:     `{ counterparty: "` + f.bob + `" }`,
+			wantCount: 2,
+		},
+		"amount-range": {
+			given:     `{ minAmount: 10, maxAmount: 80 }`,
+			wantCount: 2,
+		},
+		"since-future": {
+			given:     `{ since: "2100-01-01T00:00:00Z" }`,
+			wantCount: 0,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+
+		t.Run(name, func(t *testing.T) {
+			query := `query($id: ID!) { account(id: $id) { transactions(filter: ` + tcase.given + `) { totalCount } } }`
+
+			data, errs := f.exec(t, query, map[string]interface{}{"id": f.alice})
+			assert.Empty(t, errs)
+
+			conn := data["account"].(map[string]interface{})["transactions"].(map[string]interface{})
+			assert.Equal(t, tcase.wantCount, conn["totalCount"])
+		})
+	}
+}
+
+func TestResolver_Mutations(t *testing.T) {
+	f := newFixture(t)
+
+	scenarios := map[string]struct {
+		given    string
+		vars     map[string]interface{}
+		want     float64
+		wantCode string
+	}{
+		"deposit": {
+			given: `mutation { deposit(accountId: "` + f.carol + `", amount: 5) { balance } }`,
+			want:  25,
+		},
+		"withdraw": {
+			given: `mutation { withdraw(accountId: "` + f.bob + `", amount: 15) { balance } }`,
+			want:  25,
+		},
+		"transfer": {
+			given: `mutation { transfer(sender: "` + f.alice + `", receiver: "` + f.bob + `", amount: 10) { balance } }`,
+			want:  30,
+		},
+		"insufficient-balance": {
+			given:    `mutation { withdraw(accountId: "` + f.carol + `", amount: 1000) { balance } }`,
+			wantCode: "INSUFFICIENT_BALANCE",
+		},
+		"negative-amount": {
+			given:    `mutation($amount: Float!) { transfer(sender: "` + f.alice + `", receiver: "` + f.bob + `", amount: $amount) { balance } }`,
+			vars:     map[string]interface{}{"amount": -10.0},
+			wantCode: "BAD_USER_INPUT",
+		},
+		"unknown-account": {
+			given:    `mutation { deposit(accountId: "nope", amount: 5) { balance } }`,
+			wantCode: "NOT_FOUND",
+		},
+	}
+
+	for _, name := range []string{"deposit", "withdraw", "transfer", "insufficient-balance", "negative-amount", "unknown-account"} {
+		tcase := scenarios[name]
+
+		t.Run(name, func(t *testing.T) {
+			data, errs := f.exec(t, tcase.given, tcase.vars)
+
+			if tcase.wantCode != "" {
+				require.Len(t, errs, 1)
+				assert.Equal(t, tcase.wantCode, errs[0]["extensions"].(map[string]interface{})["code"])
+				return
+			}
+
+			assert.Empty(t, errs)
+			for _, v := range data {
+				assert.Equal(t, tcase.want, v.(map[string]interface{})["balance"])
+			}
+		})
+	}
+}
+
+func TestResolver_UnknownAccount(t *testing.T) {
+	f := newFixture(t)
+
+	data, errs := f.exec(t, `{ account(id: "nope") { name } }`, nil)
+	assert.Empty(t, errs)
+	assert.Nil(t, data["account"])
+
+	_, errs = f.exec(t, `query($id: ID!) { account(id: $id) { transactions(after: "bogus") { totalCount } } }`, map[string]interface{}{"id": f.alice})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "BAD_USER_INPUT", errs[0]["extensions"].(map[string]interface{})["code"])
+}
+
+func TestResolver_Limits(t *testing.T) {
+	f := newFixture(t)
+
+	deep := `{ account(id: "` + f.alice + `") { transactions { edges { node { owner { transactions { edges { node { owner { name } } } } } } } } } }`
+
+	scenarios := map[string]struct {
+		given     string
+		wantCode  string
+		wantError string
+		wantEdges int
+	}{
+		"negative-first": {
+			given:    `{ account(id: "` + f.carol + `") { transactions(first: -1) { totalCount } } }`,
+			wantCode: "BAD_USER_INPUT",
+		},
+		"first-capped": {
+			given:     `{ account(id: "` + f.carol + `") { transactions(first: 1000) { edges { cursor } } } }`,
+			wantEdges: maxFirst,
+		},
+		"too-deep": {
+			given:     deep,
+			wantError: "exceeds max depth",
+		},
+		"too-long": {
+			given:    `{ accounts { name } }` + strings.Repeat(" ", maxQueryLength),
+			wantCode: "BAD_USER_INPUT",
+		},
+	}
+
+	for i := 0; i <= maxFirst; i++ {
+		require.NoError(t, f.resolver.transactionService.Deposit(context.Background(), f.carol, 1, service.Note{}))
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+
+		t.Run(name, func(t *testing.T) {
+			data, errs := f.exec(t, tcase.given, nil)
+
+			switch {
+			case tcase.wantCode != "":
+				require.Len(t, errs, 1)
+				assert.Equal(t, tcase.wantCode, errs[0]["extensions"].(map[string]interface{})["code"])
+			case tcase.wantError != "":
+				require.NotEmpty(t, errs)
+				assert.Contains(t, errs[0]["message"], tcase.wantError)
+			default:
+				require.Empty(t, errs)
+				conn := data["account"].(map[string]interface{})["transactions"].(map[string]interface{})
+				assert.Len(t, conn["edges"], tcase.wantEdges)
+			}
+		})
+	}
+}
+
+func uniq(ids []string) []string {
+	seen := map[string]bool{}
+	res := []string{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			res = append(res, id)
+		}
+	}
+	return res
+}
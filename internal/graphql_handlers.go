@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gopay/internal/gql"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type GraphQLHandler struct {
+	resolver *gql.Resolver
+}
+
+func NewGraphQLHandler(resolver *gql.Resolver) *GraphQLHandler {
+	return &GraphQLHandler{
+		resolver: resolver,
+	}
+}
+
+func (h *GraphQLHandler) PostGraphQL(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	req := graphQLRequest{}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, OneMegabyte))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostGraphQL")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer r.Body.Close()
+	err = jsoniter.Unmarshal(body, &req)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostGraphQL")
+		utils.ErrorWithMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// resolver errors are reported in the response body, as GraphQL clients expect
+	resp := h.resolver.Exec(r.Context(), req.Query, req.OperationName, req.Variables)
+
+	res, err := jsoniter.Marshal(resp)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostGraphQL")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusOK, res)
+}
@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gopay/internal/groups"
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const GroupIdParam = "group-id"
+
+type GroupHandler struct {
+	ledger *groups.Ledger
+}
+
+func NewGroupHandler(ledger *groups.Ledger) *GroupHandler {
+	return &GroupHandler{
+		ledger: ledger,
+	}
+}
+
+type newGroup struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+type newMember struct {
+	Account string `json:"account"`
+}
+
+func (h *GroupHandler) PostGroup(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	req := newGroup{}
+	if !h.decode(w, r, "Handler::PostGroup", &req) {
+		return
+	}
+
+	group, err := h.ledger.Create(r.Context(), req.Name, req.Members)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostGroup")
+		utils.ErrorWithMessage(w, groupErrorStatus(err), err.Error())
+		return
+	}
+
+	h.respond(w, r, "Handler::PostGroup", http.StatusCreated, group)
+}
+
+func (h *GroupHandler) GetGroup(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	group, err := h.ledger.Get(r.Context(), params.ByName(GroupIdParam))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetGroup")
+		utils.ErrorWithMessage(w, groupErrorStatus(err), err.Error())
+		return
+	}
+
+	h.respond(w, r, "Handler::GetGroup", http.StatusOK, group)
+}
+
+func (h *GroupHandler) PostGroupMember(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	req := newMember{}
+	if !h.decode(w, r, "Handler::PostGroupMember", &req) {
+		return
+	}
+
+	group, err := h.ledger.AddMember(r.Context(), params.ByName(GroupIdParam), req.Account)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostGroupMember")
+		utils.ErrorWithMessage(w, groupErrorStatus(err), err.Error())
+		return
+	}
+
+	h.respond(w, r, "Handler::PostGroupMember", http.StatusOK, group)
+}
+
+func (h *GroupHandler) PostGroupExpense(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	expense := models.Expense{}
+	if !h.decode(w, r, "Handler::PostGroupExpense", &expense) {
+		return
+	}
+
+	group, err := h.ledger.AddExpense(r.Context(), params.ByName(GroupIdParam), expense)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostGroupExpense")
+		utils.ErrorWithMessage(w, groupErrorStatus(err), err.Error())
+		return
+	}
+
+	h.respond(w, r, "Handler::PostGroupExpense", http.StatusCreated, group)
+}
+
+func (h *GroupHandler) GetGroupSettlement(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	transfers, err := h.ledger.Plan(r.Context(), params.ByName(GroupIdParam))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetGroupSettlement")
+		utils.ErrorWithMessage(w, groupErrorStatus(err), err.Error())
+		return
+	}
+
+	res, err := jsoniter.Marshal(&transfers)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetGroupSettlement")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusOK, res)
+}
+
+func (h *GroupHandler) PostGroupSettle(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	group, err := h.ledger.SettleUp(r.Context(), params.ByName(GroupIdParam))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostGroupSettle")
+		utils.ErrorWithMessage(w, groupErrorStatus(err), err.Error())
+		return
+	}
+
+	h.respond(w, r, "Handler::PostGroupSettle", http.StatusOK, group)
+}
+
+// decode reads the JSON body into v, or responds with the error and returns
+// false.
+func (h *GroupHandler) decode(w http.ResponseWriter, r *http.Request, name string, v interface{}) bool {
+	body, err := io.ReadAll(io.LimitReader(r.Body, OneMegabyte))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(name)
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	defer r.Body.Close()
+
+	err = jsoniter.Unmarshal(body, v)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(name)
+		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, err.Error())
+		return false
+	}
+	return true
+}
+
+func (h *GroupHandler) respond(w http.ResponseWriter, r *http.Request, name string, status int, group models.Group) {
+	res, err := jsoniter.Marshal(&group)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(name)
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, status, res)
+}
+
+func groupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, groups.ErrGroupNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, groups.ErrInvalidGroup), errors.Is(err, groups.ErrInvalidExpense):
+		return http.StatusUnprocessableEntity
+	default:
+		return transactionErrorStatus(err)
+	}
+}
@@ -0,0 +1,342 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/splits"
+)
+
+// MaxMembers bounds the size of a group, the settlement plan looks at every
+// subset of its members.
+const MaxMembers = 20
+
+var (
+	ErrInvalidGroup         = errors.New("invalid group")
+	ErrMissingName          = errors.New("a group needs a name")
+	ErrNoMembers            = errors.New("a group needs at least one member")
+	ErrTooManyMembers       = fmt.Errorf("a group has at most %d members", MaxMembers)
+	ErrDuplicateMember      = errors.New("account is already a member")
+	ErrUnknownMember        = errors.New("member account not found")
+	ErrInvalidExpense       = errors.New("invalid expense")
+	ErrInvalidAmount        = errors.New("amount must be positive with at most two decimals")
+	ErrNotAMember           = errors.New("account is not a member of the group")
+	ErrSettlementFailed     = errors.New("settlement failed, no transfer was made")
+	ErrDuplicateParticipant = errors.New("participant is listed twice")
+	ErrCorruptGroup         = errors.New("group holds an amount that isn't whole cents")
+)
+
+// Ledger tracks who owes whom in groups of accounts and settles the debts
+// with transfers through the TransactionService.
+type Ledger struct {
+	accountRepo        repository.AccountRepo
+	transactionService service.TransactionService
+	unitOfWork         repository.UnitOfWork
+	repo               Repo
+	now                func() time.Time
+	idGenerator        func() string
+
+	// writing serializes changes to groups so a settlement never runs twice
+	// or misses an expense added meanwhile
+	writing sync.Mutex
+}
+
+func NewLedger(accountRepo repository.AccountRepo, transactionService service.TransactionService, unitOfWork repository.UnitOfWork, repo Repo) *Ledger {
+	return &Ledger{
+		accountRepo:        accountRepo,
+		transactionService: transactionService,
+		unitOfWork:         unitOfWork,
+		repo:               repo,
+		now:                time.Now,
+		idGenerator:        uuid.NewString,
+	}
+}
+
+// Create stores a group of existing accounts. Invalid groups fail with
+// ErrInvalidGroup.
+func (l *Ledger) Create(ctx context.Context, name string, members []string) (models.Group, error) {
+	group := models.Group{
+		Name:      strings.TrimSpace(name),
+		CreatedAt: l.now(),
+		Expenses:  []models.Expense{},
+		Transfers: []models.GroupTransfer{},
+	}
+
+	switch {
+	case group.Name == "":
+		return models.Group{}, fmt.Errorf("%w: %w", ErrInvalidGroup, ErrMissingName)
+	case len(members) == 0:
+		return models.Group{}, fmt.Errorf("%w: %w", ErrInvalidGroup, ErrNoMembers)
+	}
+
+	for _, member := range members {
+		err := l.addMember(ctx, &group, member)
+		if err != nil {
+			return models.Group{}, fmt.Errorf("%w: %w", ErrInvalidGroup, err)
+		}
+	}
+
+	var err error
+	group.GroupId, err = l.repo.Create(ctx, group)
+	if err != nil {
+		return models.Group{}, err
+	}
+	return withBalances(group)
+}
+
+// Get returns the group with its current balances.
+func (l *Ledger) Get(ctx context.Context, id string) (models.Group, error) {
+	group, err := l.repo.FindOne(ctx, id)
+	if err != nil {
+		return models.Group{}, err
+	}
+	return withBalances(group)
+}
+
+// AddMember adds an existing account to the group.
+func (l *Ledger) AddMember(ctx context.Context, id string, account string) (models.Group, error) {
+	l.writing.Lock()
+	defer l.writing.Unlock()
+
+	group, err := l.repo.FindOne(ctx, id)
+	if err != nil {
+		return models.Group{}, err
+	}
+
+	err = l.addMember(ctx, &group, account)
+	if err != nil {
+		return models.Group{}, fmt.Errorf("%w: %w", ErrInvalidGroup, err)
+	}
+
+	return l.update(ctx, group)
+}
+
+func (l *Ledger) addMember(ctx context.Context, group *models.Group, account string) error {
+	if isMember(*group, account) {
+		return ErrDuplicateMember
+	}
+	if len(group.Members) == MaxMembers {
+		return ErrTooManyMembers
+	}
+
+	_, err := l.accountRepo.FindOne(ctx, account)
+	if errors.Is(err, repository.ErrAccountNotFound) {
+		return ErrUnknownMember
+	}
+	if err != nil {
+		return err
+	}
+
+	group.Members = append(group.Members, account)
+	return nil
+}
+
+// AddExpense records a bill one member paid for others of the group.
+// Invalid expenses fail with ErrInvalidExpense.
+func (l *Ledger) AddExpense(ctx context.Context, id string, expense models.Expense) (models.Group, error) {
+	l.writing.Lock()
+	defer l.writing.Unlock()
+
+	group, err := l.repo.FindOne(ctx, id)
+	if err != nil {
+		return models.Group{}, err
+	}
+
+	err = checkExpense(group, expense)
+	if err != nil {
+		return models.Group{}, fmt.Errorf("%w: %w", ErrInvalidExpense, err)
+	}
+
+	// later members don't share earlier expenses
+	if len(expense.Participants) == 0 {
+		expense.Participants = append([]string{}, group.Members...)
+	}
+	expense.ExpenseId, expense.CreatedAt = l.idGenerator(), l.now()
+	group.Expenses = append(group.Expenses, expense)
+
+	return l.update(ctx, group)
+}
+
+func checkExpense(group models.Group, expense models.Expense) error {
+	cents, ok := splits.ToCents(expense.Amount)
+	if !ok || cents <= 0 {
+		return ErrInvalidAmount
+	}
+
+	if !isMember(group, expense.PaidBy) {
+		return fmt.Errorf("paidBy: %w", ErrNotAMember)
+	}
+
+	seen := map[string]bool{}
+	for n, p := range expense.Participants {
+		if seen[p] {
+			return fmt.Errorf("participants[%d]: %w", n, ErrDuplicateParticipant)
+		}
+		seen[p] = true
+		if !isMember(group, p) {
+			return fmt.Errorf("participants[%d]: %w", n, ErrNotAMember)
+		}
+	}
+
+	return nil
+}
+
+// Plan returns the fewest transfers that would balance the group.
+func (l *Ledger) Plan(ctx context.Context, id string) ([]models.GroupTransfer, error) {
+	group, err := l.repo.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return plan(group)
+}
+
+// SettleUp executes the transfers of the group's plan in one unit of work,
+// so either all of them are made and the group is balanced or none is and
+// the error wraps ErrSettlementFailed and the transfer's error.
+func (l *Ledger) SettleUp(ctx context.Context, id string) (models.Group, error) {
+	l.writing.Lock()
+	defer l.writing.Unlock()
+
+	group, err := l.repo.FindOne(ctx, id)
+	if err != nil {
+		return models.Group{}, err
+	}
+
+	transfers, err := plan(group)
+	if err != nil {
+		return models.Group{}, err
+	}
+	err = l.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		for n := range transfers {
+			t := &transfers[n]
+
+			ids, err := repository.CreatedIds(ctx, func(ctx context.Context) error {
+				return l.transactionService.Transfer(ctx, t.From, t.To, (-1)*t.Amount, service.Note{})
+			})
+			if err != nil {
+				return fmt.Errorf("%w: %s to %s: %w", ErrSettlementFailed, t.From, t.To, err)
+			}
+			t.TransactionIds = ids
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Group{}, err
+	}
+
+	now := l.now()
+	for n := range transfers {
+		transfers[n].SettledAt = &now
+	}
+	group.Transfers = append(group.Transfers, transfers...)
+
+	return l.update(ctx, group)
+}
+
+// update stores group and returns it with its balances.
+func (l *Ledger) update(ctx context.Context, group models.Group) (models.Group, error) {
+	res, err := withBalances(group)
+	if err != nil {
+		return models.Group{}, err
+	}
+	return res, l.repo.Update(ctx, group)
+}
+
+// balances returns every member's balance in cents, in member order.
+// Expenses are shared with splits.Allocate, so leftover cents fall on the
+// same participants as in an equal split. Stored amounts were checked when
+// they were added, one that isn't whole cents fails with ErrCorruptGroup
+// rather than being rounded into the balances.
+func balances(group models.Group) ([]int64, error) {
+	index := map[string]int{}
+	for i, m := range group.Members {
+		index[m] = i
+	}
+
+	cents := make([]int64, len(group.Members))
+	for _, e := range group.Expenses {
+		participants := e.Participants
+		total, ok := splits.ToCents(e.Amount)
+		if !ok {
+			return nil, fmt.Errorf("%w: expense %s", ErrCorruptGroup, e.ExpenseId)
+		}
+		weights := make([]int64, len(participants))
+		for n := range weights {
+			weights[n] = 1
+		}
+
+		cents[index[e.PaidBy]] += total
+		for n, share := range splits.Allocate(total, weights) {
+			cents[index[participants[n]]] -= share
+		}
+	}
+
+	for n, t := range group.Transfers {
+		amount, ok := splits.ToCents(t.Amount)
+		if !ok {
+			return nil, fmt.Errorf("%w: transfer %d", ErrCorruptGroup, n)
+		}
+		cents[index[t.From]] += amount
+		cents[index[t.To]] -= amount
+	}
+
+	return cents, nil
+}
+
+func plan(group models.Group) ([]models.GroupTransfer, error) {
+	cents, err := balances(group)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := []models.GroupTransfer{}
+	for _, d := range simplify(cents) {
+		transfers = append(transfers, models.GroupTransfer{
+			From:   group.Members[d.from],
+			To:     group.Members[d.to],
+			Amount: float32(d.cents) / 100,
+		})
+	}
+
+	// largest first reads best, the order doesn't change the outcome
+	sort.SliceStable(transfers, func(i, j int) bool {
+		return transfers[i].Amount > transfers[j].Amount
+	})
+	return transfers, nil
+}
+
+// withBalances fills the computed fields of group.
+func withBalances(group models.Group) (models.Group, error) {
+	all, err := balances(group)
+	if err != nil {
+		return models.Group{}, err
+	}
+
+	group.Status = models.GroupBalanced
+	group.Balances = make([]models.GroupBalance, len(group.Members))
+	for i, cents := range all {
+		group.Balances[i] = models.GroupBalance{Account: group.Members[i], Amount: float32(cents) / 100}
+		if cents != 0 {
+			group.Status = models.GroupUnbalanced
+		}
+	}
+	return group, nil
+}
+
+func isMember(group models.Group, account string) bool {
+	for _, m := range group.Members {
+		if m == account {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package groups
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimplify(t *testing.T) {
+	scenarios := map[string]struct {
+		balances  []int64
+		wantCount int
+	}{
+		"balanced": {
+			balances:  []int64{0, 0},
+			wantCount: 0,
+		},
+		"one-debt": {
+			balances:  []int64{-500, 500},
+			wantCount: 1,
+		},
+		"chain-collapses": {
+			balances:  []int64{-500, 0, 500},
+			wantCount: 1,
+		},
+		"zero-sum-subsets": {
+			// largest debtor to largest creditor would need 5 transfers
+			balances:  []int64{-3, -1, 6, 7, -5, -4},
+			wantCount: 4,
+		},
+		"pairs": {
+			balances:  []int64{100, -100, 250, -250, 1, -1},
+			wantCount: 3,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			debts := simplify(tcase.balances)
+			assert.Len(t, debts, tcase.wantCount)
+
+			left := append([]int64{}, tcase.balances...)
+			for _, d := range debts {
+				assert.Positive(t, d.cents)
+				assert.Negative(t, tcase.balances[d.from], "only debtors pay")
+				assert.Positive(t, tcase.balances[d.to], "only creditors are paid")
+				left[d.from] += d.cents
+				left[d.to] -= d.cents
+			}
+			for i, b := range left {
+				assert.Zero(t, b, "balances[%d]", i)
+			}
+		})
+	}
+}
+
+type fixture struct {
+	ledger       *Ledger
+	accounts     repository.AccountRepo
+	transactions repository.TransactionRepo
+	shankar      string
+	jessica      string
+	caio         string
+}
+
+func newFixture(t *testing.T) *fixture {
+	bank := testutil.NewBank(t)
+
+	f := &fixture{
+		accounts:     bank.Accounts,
+		transactions: bank.Transactions,
+		shankar:      bank.Account(t, "Shankar", "Nakai"),
+		jessica:      bank.Account(t, "Jessica", "Lourenco"),
+		caio:         bank.Account(t, "Caio", "Henrique"),
+	}
+	bank.Deposit(t, 100, f.shankar, f.jessica, f.caio)
+
+	f.ledger = NewLedger(bank.Accounts, bank.Service, bank.UnitOfWork, NewRepo())
+	return f
+}
+
+func (f *fixture) balance(t *testing.T, id string) float64 {
+	balance, err := f.transactions.GetBalance(context.Background(), id)
+	require.NoError(t, err)
+	return balance.Amount
+}
+
+func TestLedger_Create(t *testing.T) {
+	scenarios := map[string]struct {
+		name    string
+		members func(f *fixture) []string
+		wantErr error
+	}{
+		"valid": {
+			name:    "Trip",
+			members: func(f *fixture) []string { return []string{f.shankar, f.jessica} },
+		},
+		"missing-name": {
+			name:    " ",
+			members: func(f *fixture) []string { return []string{f.shankar} },
+			wantErr: ErrMissingName,
+		},
+		"no-members": {
+			name:    "Trip",
+			members: func(f *fixture) []string { return nil },
+			wantErr: ErrNoMembers,
+		},
+		"duplicate-member": {
+			name:    "Trip",
+			members: func(f *fixture) []string { return []string{f.shankar, f.shankar} },
+			wantErr: ErrDuplicateMember,
+		},
+		"unknown-member": {
+			name:    "Trip",
+			members: func(f *fixture) []string { return []string{f.shankar, "unknown"} },
+			wantErr: ErrUnknownMember,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+
+			group, err := f.ledger.Create(context.Background(), tcase.name, tcase.members(f))
+			if tcase.wantErr != nil {
+				assert.ErrorIs(t, err, ErrInvalidGroup)
+				assert.ErrorIs(t, err, tcase.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, group.GroupId)
+			assert.Equal(t, models.GroupBalanced, group.Status)
+		})
+	}
+}
+
+func TestLedger_SettleUp(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	group, err := f.ledger.Create(ctx, "Trip", []string{f.shankar, f.jessica})
+	require.NoError(t, err)
+
+	group, err = f.ledger.AddExpense(ctx, group.GroupId, models.Expense{PaidBy: f.shankar, Amount: 90, Description: "hotel"})
+	require.NoError(t, err)
+	group, err = f.ledger.AddMember(ctx, group.GroupId, f.caio)
+	require.NoError(t, err)
+	group, err = f.ledger.AddExpense(ctx, group.GroupId, models.Expense{PaidBy: f.jessica, Amount: 10, Participants: []string{f.jessica, f.caio}})
+	require.NoError(t, err)
+
+	// caio joined after the hotel and only shares the second expense
+	assert.Equal(t, models.GroupUnbalanced, group.Status)
+	assert.Equal(t, []models.GroupBalance{
+		{Account: f.shankar, Amount: 45},
+		{Account: f.jessica, Amount: -40},
+		{Account: f.caio, Amount: -5},
+	}, group.Balances)
+
+	_, err = f.ledger.AddExpense(ctx, group.GroupId, models.Expense{PaidBy: "unknown", Amount: 10})
+	assert.ErrorIs(t, err, ErrNotAMember)
+	_, err = f.ledger.AddExpense(ctx, group.GroupId, models.Expense{PaidBy: f.caio, Amount: 0.001})
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+
+	transfers, err := f.ledger.Plan(ctx, group.GroupId)
+	require.NoError(t, err)
+	assert.Equal(t, []models.GroupTransfer{
+		{From: f.jessica, To: f.shankar, Amount: 40},
+		{From: f.caio, To: f.shankar, Amount: 5},
+	}, transfers)
+
+	group, err = f.ledger.SettleUp(ctx, group.GroupId)
+	require.NoError(t, err)
+	assert.Equal(t, models.GroupBalanced, group.Status)
+	assert.Len(t, group.Transfers, 2)
+	for _, tr := range group.Transfers {
+		assert.Len(t, tr.TransactionIds, 2)
+		assert.NotNil(t, tr.SettledAt)
+	}
+	assert.InDelta(t, 145, f.balance(t, f.shankar), 0.001)
+	assert.InDelta(t, 60, f.balance(t, f.jessica), 0.001)
+	assert.InDelta(t, 95, f.balance(t, f.caio), 0.001)
+
+	transfers, err = f.ledger.Plan(ctx, group.GroupId)
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+}
+
+func TestLedger_SettleUpIsAtomic(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	group, err := f.ledger.Create(ctx, "Trip", []string{f.shankar, f.jessica, f.caio})
+	require.NoError(t, err)
+	_, err = f.ledger.AddExpense(ctx, group.GroupId, models.Expense{PaidBy: f.shankar, Amount: 90})
+	require.NoError(t, err)
+
+	// the second transfer of the plan fails
+	_, err = f.accounts.SetFrozen(ctx, f.caio, true)
+	require.NoError(t, err)
+
+	_, err = f.ledger.SettleUp(ctx, group.GroupId)
+	assert.ErrorIs(t, err, ErrSettlementFailed)
+	assert.ErrorIs(t, err, service.ErrAccountFrozen)
+
+	group, err = f.ledger.Get(ctx, group.GroupId)
+	require.NoError(t, err)
+	assert.Equal(t, models.GroupUnbalanced, group.Status)
+	assert.Empty(t, group.Transfers)
+	for _, id := range []string{f.shankar, f.jessica, f.caio} {
+		assert.InDelta(t, 100, f.balance(t, id), 0.001)
+	}
+}
+
+func TestLedger_CorruptAmounts(t *testing.T) {
+	scenarios := map[string]struct {
+		corrupt func(group *models.Group)
+	}{
+		"expense": {
+			corrupt: func(group *models.Group) {
+				group.Expenses = append(group.Expenses, models.Expense{ExpenseId: "e1", PaidBy: group.Members[0], Participants: group.Members, Amount: 0.125})
+			},
+		},
+		"transfer": {
+			corrupt: func(group *models.Group) {
+				group.Transfers = append(group.Transfers, models.GroupTransfer{From: group.Members[0], To: group.Members[1], Amount: 0.125})
+			},
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newFixture(t)
+
+			group, err := f.ledger.Create(ctx, "Trip", []string{f.shankar, f.jessica})
+			require.NoError(t, err)
+			tcase.corrupt(&group)
+			require.NoError(t, f.ledger.repo.Update(ctx, group))
+
+			_, err = f.ledger.Get(ctx, group.GroupId)
+			assert.ErrorIs(t, err, ErrCorruptGroup)
+			_, err = f.ledger.Plan(ctx, group.GroupId)
+			assert.ErrorIs(t, err, ErrCorruptGroup)
+			_, err = f.ledger.SettleUp(ctx, group.GroupId)
+			assert.ErrorIs(t, err, ErrCorruptGroup)
+			assert.Equal(t, 100.0, f.balance(t, f.shankar))
+		})
+	}
+}
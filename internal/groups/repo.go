@@ -0,0 +1,69 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/gopay/internal/models"
+)
+
+var ErrGroupNotFound = errors.New("group not found")
+
+// Repo keeps groups with their expenses and settled transfers.
+type Repo interface {
+	FindOne(ctx context.Context, id string) (models.Group, error)
+	Create(ctx context.Context, group models.Group) (string, error)
+	Update(ctx context.Context, group models.Group) error
+}
+
+var _ Repo = (*repoImpl)(nil)
+
+type repoImpl struct {
+	mu          sync.RWMutex
+	groups      map[string]models.Group
+	idGenerator func() string
+}
+
+func NewRepo() *repoImpl {
+	return &repoImpl{
+		groups:      make(map[string]models.Group),
+		idGenerator: uuid.NewString,
+	}
+}
+
+func (r *repoImpl) FindOne(_ context.Context, id string) (models.Group, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	group, found := r.groups[id]
+	if !found {
+		return models.Group{}, ErrGroupNotFound
+	}
+
+	return group, nil
+}
+
+func (r *repoImpl) Create(_ context.Context, group models.Group) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.idGenerator()
+	group.GroupId = id
+	r.groups[id] = group
+
+	return id, nil
+}
+
+func (r *repoImpl) Update(_ context.Context, group models.Group) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.groups[group.GroupId]; !found {
+		return ErrGroupNotFound
+	}
+	r.groups[group.GroupId] = group
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package groups
+
+import "math/bits"
+
+// debt is a transfer of cents between two indexes of the balances given to
+// simplify.
+type debt struct {
+	from, to int
+	cents    int64
+}
+
+// simplify returns the fewest transfers that bring every balance, in cents,
+// to zero. Positive balances are owed money. The balances must add up to
+// zero.
+//
+// A set of members whose balances add up to zero can always be settled with
+// one transfer less than its size, so the fewest transfers are the number of
+// members with a balance minus the most disjoint zero-sum sets they can be
+// partitioned into. That partition is found with a dynamic program over the
+// subsets of members, which is why groups are kept small, and every set is
+// then settled by having its largest debtor pay its largest creditor.
+func simplify(balances []int64) []debt {
+	idx := []int{}
+	for i, b := range balances {
+		if b != 0 {
+			idx = append(idx, i)
+		}
+	}
+	if len(idx) == 0 {
+		return nil
+	}
+
+	full := 1<<len(idx) - 1
+	sums := make([]int64, full+1)
+	best := make([]int8, full+1)
+	for mask := 1; mask <= full; mask++ {
+		low := bits.TrailingZeros(uint(mask))
+		sums[mask] = sums[mask&(mask-1)] + balances[idx[low]]
+
+		for rest := mask; rest != 0; rest &= rest - 1 {
+			i := bits.TrailingZeros(uint(rest))
+			if b := best[mask&^(1<<i)]; b > best[mask] {
+				best[mask] = b
+			}
+		}
+		if sums[mask] == 0 {
+			best[mask]++
+		}
+	}
+
+	// walk back the choices to order the members so that every zero-sum
+	// set is a run of the order
+	order := make([]int, 0, len(idx))
+	for mask := full; mask != 0; {
+		zero := int8(0)
+		if sums[mask] == 0 {
+			zero = 1
+		}
+		for rest := mask; rest != 0; rest &= rest - 1 {
+			i := bits.TrailingZeros(uint(rest))
+			if best[mask&^(1<<i)]+zero == best[mask] {
+				order = append(order, i)
+				mask &^= 1 << i
+				break
+			}
+		}
+	}
+
+	debts := []debt{}
+	set, prefix := []int{}, 0
+	for n := len(order) - 1; n >= 0; n-- {
+		set = append(set, idx[order[n]])
+		prefix |= 1 << order[n]
+		if sums[prefix] == 0 {
+			debts = append(debts, settleSet(balances, set)...)
+			set = []int{}
+		}
+	}
+
+	return debts
+}
+
+// settleSet settles a zero-sum set of balances by repeatedly having the
+// largest debtor pay the largest creditor, ties to the lower index.
+func settleSet(balances []int64, set []int) []debt {
+	left := map[int]int64{}
+	for _, i := range set {
+		left[i] = balances[i]
+	}
+
+	debts := []debt{}
+	for {
+		from, to := -1, -1
+		for _, i := range set {
+			switch {
+			case left[i] < 0 && (from < 0 || left[i] < left[from] || left[i] == left[from] && i < from):
+				from = i
+			case left[i] > 0 && (to < 0 || left[i] > left[to] || left[i] == left[to] && i < to):
+				to = i
+			}
+		}
+		if from < 0 || to < 0 {
+			return debts
+		}
+
+		cents := min(-left[from], left[to])
+		debts = append(debts, debt{from: from, to: to, cents: cents})
+		left[from] += cents
+		left[to] -= cents
+	}
+}
@@ -2,11 +2,17 @@ package internal
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
+	"github.com/gopay/internal/lineage"
 	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
 	"github.com/gopay/internal/utils"
 	jsoniter "github.com/json-iterator/go"
 
@@ -27,14 +33,33 @@ var (
 	ErrReceiverNotFound    = errors.New("receiver account not found")
 	ErrSenderNotFound      = errors.New("sender account not found")
 	ErrInsufficentBalance  = errors.New("insufficient balance")
+	ErrInvalidFilter       = errors.New("invalid filter")
 )
 
+type newTransactionStatus struct {
+	Status models.TransactionStatus `json:"status"`
+}
+
+type Handler struct {
+	transactionService service.TransactionService
+	transactionRepo    repository.TransactionRepo
+	accountRepo        repository.AccountRepo
+}
+
+func NewHandler(transactionService service.TransactionService, transactionRepo repository.TransactionRepo, accountRepo repository.AccountRepo) *Handler {
+	return &Handler{
+		transactionService: transactionService,
+		transactionRepo:    transactionRepo,
+		accountRepo:        accountRepo,
+	}
+}
+
 func Index(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF8")
 
 	res, err := jsoniter.Marshal("Welcome to GoPay!")
 	if err != nil {
-		log.Error().Err(err).Msg(err.Error())
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
 		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -42,17 +67,18 @@ func Index(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	utils.WithPayload(w, http.StatusOK, res)
 }
 
-func GetAllAccounts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	accs := []*models.Account{}
-
-	for _, account := range models.Accounts {
-		accs = append(accs, account)
+func (h *Handler) GetAllAccounts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	accs, err := h.accountRepo.FindAll(r.Context())
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 
 	res, err := jsoniter.Marshal(&accs)
 
 	if err != nil {
-		log.Error().Err(err).Msg(err.Error())
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
 		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -60,35 +86,32 @@ func GetAllAccounts(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	utils.WithPayload(w, http.StatusOK, res)
 }
 
-func GetAccount(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+func (h *Handler) GetAccount(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	id := params.ByName(AccountIdParam)
 
-	account, found := models.Accounts[id]
+	account, err := h.accountRepo.FindOne(r.Context(), id)
 
-	if !found {
-		log.Error().Err(ErrAccountNotFound).Msg("Handler::GetAccount")
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(ErrAccountNotFound).Msg("Handler::GetAccount")
 		utils.ErrorWithMessage(w, http.StatusNotFound, ErrAccountNotFound.Error())
 		return
 	}
 
 	res, err := jsoniter.Marshal(&account)
 	if err != nil {
-		log.Error().Err(err).Msg(err.Error())
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
 		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 	utils.WithPayload(w, http.StatusOK, res)
 }
 
-func PostAccount(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	id := utils.GetAccountUUID()
-
+func (h *Handler) PostAccount(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	account := &models.Account{}
-	account.AccountId = id
 
 	body, err := io.ReadAll(io.LimitReader(r.Body, OneMegabyte))
 	if err != nil {
-		log.Error().Err(err).Msg(err.Error())
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
 		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -97,37 +120,101 @@ func PostAccount(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	err = jsoniter.Unmarshal(body, &account)
 
 	if err != nil {
-		log.Error().Err(err).Msg(err.Error())
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
 		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, err.Error())
 		return
 	}
 
-	models.Accounts[account.AccountId] = account
-	utils.WithPayload(w, http.StatusCreated, nil)
+	id, err := h.accountRepo.Create(r.Context(), account.Name, account.LastName)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostAccount")
+		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	created, err := h.accountRepo.FindOne(r.Context(), id)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostAccount")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	res, err := jsoniter.Marshal(&created)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusCreated, res)
 }
 
-func GetAllTransactions(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	transactions := []*models.Transaction{}
+func (h *Handler) PostFreezeAccount(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.setFrozen(w, r, params.ByName(AccountIdParam), true, "Handler::PostFreezeAccount")
+}
 
+func (h *Handler) PostUnfreezeAccount(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.setFrozen(w, r, params.ByName(AccountIdParam), false, "Handler::PostUnfreezeAccount")
+}
+
+func (h *Handler) setFrozen(w http.ResponseWriter, r *http.Request, id string, frozen bool, op string) {
+	account, err := h.accountRepo.SetFrozen(r.Context(), id, frozen)
+	if errors.Is(err, repository.ErrAccountNotFound) {
+		log.Ctx(r.Context()).Error().Err(ErrAccountNotFound).Msg(op)
+		utils.ErrorWithMessage(w, http.StatusNotFound, ErrAccountNotFound.Error())
+		return
+	}
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(op)
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	res, err := jsoniter.Marshal(&account)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusOK, res)
+}
+
+func (h *Handler) GetAllTransactions(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	accountId := params.ByName(AccountIdParam)
 
-	_, found := models.Accounts[accountId]
+	_, err := h.accountRepo.FindOne(r.Context(), accountId)
 
-	if !found {
-		log.Error().Err(ErrAccountNotFound).Msg("Handler::GetAllTransactions")
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(ErrAccountNotFound).Msg("Handler::GetAllTransactions")
 		utils.ErrorWithMessage(w, http.StatusNotFound, ErrAccountNotFound.Error())
 		return
 	}
 
-	for _, transaction := range models.Transactions {
-		if transaction.Owner == accountId {
-			transactions = append(transactions, transaction)
+	filter, err := transactionFilter(r.URL.Query())
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetAllTransactions")
+		utils.ErrorWithMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	all, err := h.transactionRepo.FindAll(r.Context(), accountId)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	transactions := []models.Transaction{}
+	for _, t := range all {
+		if filter.Matches(t) {
+			transactions = append(transactions, t)
 		}
 	}
 
 	res, err := jsoniter.Marshal(&transactions)
 	if err != nil {
-		log.Error().Err(err).Msg(err.Error())
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
 		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -135,37 +222,68 @@ func GetAllTransactions(w http.ResponseWriter, r *http.Request, params httproute
 	utils.WithPayload(w, http.StatusOK, res)
 }
 
-func GetTransaction(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+func (h *Handler) GetTransaction(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	id := params.ByName(TransactionIdParam)
 
-	transaction, found := models.Transactions[id]
+	transaction, err := h.transactionRepo.FindOne(r.Context(), id)
 
-	if !found {
-		log.Error().Err(ErrTransactionNotFound).Msg("Handler::GetTransaction")
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(ErrTransactionNotFound).Msg("Handler::GetTransaction")
 		utils.ErrorWithMessage(w, http.StatusNotFound, ErrTransactionNotFound.Error())
 		return
 	}
 
 	res, err := jsoniter.Marshal(&transaction)
 	if err != nil {
-		log.Error().Err(err).Msg(err.Error())
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
 		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 	utils.WithPayload(w, http.StatusOK, res)
 }
 
-func PostTransaction(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	id := utils.GetTransactionUUID()
+func (h *Handler) GetTransactionLineage(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	depth := 0
+	if v := r.URL.Query().Get("depth"); v != "" {
+		var err error
+		depth, err = strconv.Atoi(v)
+		if err == nil && depth < 0 {
+			err = fmt.Errorf("depth must not be negative, got %d", depth)
+		}
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetTransactionLineage")
+			utils.ErrorWithMessage(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	graph, err := lineage.Build(r.Context(), h.transactionRepo, params.ByName(TransactionIdParam), depth)
+	if errors.Is(err, repository.ErrTransactionNotFound) {
+		log.Ctx(r.Context()).Error().Err(ErrTransactionNotFound).Msg("Handler::GetTransactionLineage")
+		utils.ErrorWithMessage(w, http.StatusNotFound, ErrTransactionNotFound.Error())
+		return
+	}
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetTransactionLineage")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
+	res, err := jsoniter.Marshal(&graph)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetTransactionLineage")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WithPayload(w, http.StatusOK, res)
+}
+
+func (h *Handler) PostTransaction(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	transaction := &models.Transaction{}
-	transaction.TransactionId = id
-	transaction.CreatedAt = time.Now()
-	transaction.IsConsumed = false
 
 	body, err := io.ReadAll(io.LimitReader(r.Body, OneMegabyte))
 	if err != nil {
-		log.Error().Err(err).Msg(err.Error())
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
 		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -174,82 +292,188 @@ func PostTransaction(w http.ResponseWriter, r *http.Request, _ httprouter.Params
 	err = jsoniter.Unmarshal(body, &transaction)
 
 	if err != nil {
-		log.Error().Err(err).Msg(err.Error())
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
 		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, err.Error())
 		return
 	}
 
-	_, found := models.Accounts[transaction.Receiver]
-	if !found {
-		log.Error().Err(ErrReceiverNotFound).Msg("Handler::PostTransaction")
+	_, err = h.accountRepo.FindOne(r.Context(), transaction.Receiver)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(ErrReceiverNotFound).Msg("Handler::PostTransaction")
 		utils.ErrorWithMessage(w, http.StatusNotFound, ErrReceiverNotFound.Error())
 		return
 	}
 
-	_, found = models.Accounts[transaction.Sender]
-	if !found {
-		log.Error().Err(ErrSenderNotFound).Msg("Handler::PostTransaction")
+	_, err = h.accountRepo.FindOne(r.Context(), transaction.Sender)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(ErrSenderNotFound).Msg("Handler::PostTransaction")
 		utils.ErrorWithMessage(w, http.StatusNotFound, ErrSenderNotFound.Error())
 		return
 	}
 
-	transaction.Owner = transaction.Sender
-	var success bool
+	note := service.Note{Memo: transaction.Memo, Tags: transaction.Tags}
+	switch {
+	case transaction.Sender != transaction.Receiver:
+		err = h.transactionService.Transfer(r.Context(), transaction.Sender, transaction.Receiver, transaction.Amount, note)
+	case transaction.Amount > 0:
+		err = h.transactionService.Deposit(r.Context(), transaction.Sender, transaction.Amount, note)
+	default:
+		err = h.transactionService.Withdraw(r.Context(), transaction.Sender, transaction.Amount, note)
+	}
+
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostTransaction")
+		utils.ErrorWithMessage(w, transactionErrorStatus(err), err.Error())
+		return
+	}
 
-	if transaction.Sender == transaction.Receiver {
-		if transaction.Amount > 0 {
-			success = utils.Deposit(transaction)
-		} else {
-			success = utils.Withdrawal(transaction)
-		}
+	utils.WithPayload(w, http.StatusCreated, nil)
+}
 
-		if !success {
-			log.Error().Err(ErrInsufficentBalance).Msg("Handler::PostTransaction")
-			utils.ErrorWithMessage(w, http.StatusForbidden, ErrInsufficentBalance.Error())
-			return
-		}
+func (h *Handler) PostTransactionStatus(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	req := newTransactionStatus{}
 
-		utils.WithPayload(w, http.StatusCreated, nil)
+	body, err := io.ReadAll(io.LimitReader(r.Body, OneMegabyte))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostTransactionStatus")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	success = utils.Pay(transaction)
-	if !success {
-		log.Error().Err(ErrInsufficentBalance).Msg("Handler::PostTransaction")
-		utils.ErrorWithMessage(w, http.StatusForbidden, ErrInsufficentBalance.Error())
+	defer r.Body.Close()
+	if err := jsoniter.Unmarshal(body, &req); err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostTransactionStatus")
+		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, err.Error())
 		return
 	}
 
-	utils.WithPayload(w, http.StatusCreated, nil)
+	transaction, err := h.transactionService.UpdateStatus(r.Context(), params.ByName(TransactionIdParam), req.Status)
+	if errors.Is(err, repository.ErrTransactionNotFound) {
+		log.Ctx(r.Context()).Error().Err(ErrTransactionNotFound).Msg("Handler::PostTransactionStatus")
+		utils.ErrorWithMessage(w, http.StatusNotFound, ErrTransactionNotFound.Error())
+		return
+	}
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostTransactionStatus")
+		utils.ErrorWithMessage(w, transactionErrorStatus(err), err.Error())
+		return
+	}
+
+	res, err := jsoniter.Marshal(&transaction)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostTransactionStatus")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WithPayload(w, http.StatusOK, res)
 }
 
-func GetBalance(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+func (h *Handler) GetBalance(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	id := params.ByName(AccountIdParam)
 
-	_, found := models.Accounts[id]
+	_, err := h.accountRepo.FindOne(r.Context(), id)
 
-	if !found {
-		log.Error().Err(ErrAccountNotFound).Msg("Handler::GetBalance")
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(ErrAccountNotFound).Msg("Handler::GetBalance")
 		utils.ErrorWithMessage(w, http.StatusNotFound, ErrAccountNotFound.Error())
 		return
 	}
 
-	balance := models.Balance{
-		AccountId: id,
-		Amount:    0.00,
-	}
-
-	for _, transaction := range models.Transactions {
-		if transaction.Owner == id && !transaction.IsConsumed {
-			balance.Amount += float64(transaction.Amount)
-		}
+	balance, err := h.transactionRepo.GetBalance(r.Context(), id)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetBalance")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 
 	res, err := jsoniter.Marshal(&balance)
 	if err != nil {
-		log.Error().Err(err).Msg(err.Error())
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
 		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 	utils.WithPayload(w, http.StatusOK, res)
 }
+
+// transactionFilter reads the consumed, minAmount, maxAmount, since, until,
+// counterparty, type, status, category and tag query parameters.
+func transactionFilter(query url.Values) (models.TransactionFilter, error) {
+	filter := models.TransactionFilter{}
+
+	if v := query.Get("consumed"); v != "" {
+		consumed, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, fmt.Errorf("%w: consumed: %w", ErrInvalidFilter, err)
+		}
+		filter.Consumed = &consumed
+	}
+
+	for name, dst := range map[string]**float64{"minAmount": &filter.MinAmount, "maxAmount": &filter.MaxAmount} {
+		if v := query.Get(name); v != "" {
+			amount, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return filter, fmt.Errorf("%w: %s: %w", ErrInvalidFilter, name, err)
+			}
+			*dst = &amount
+		}
+	}
+
+	for name, dst := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := query.Get(name); v != "" {
+			at, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("%w: %s: %w", ErrInvalidFilter, name, err)
+			}
+			*dst = &at
+		}
+	}
+
+	if v := query.Get("counterparty"); v != "" {
+		filter.Counterparty = &v
+	}
+
+	if v := models.TransactionType(query.Get("type")); v != "" {
+		if !v.Valid() {
+			return filter, fmt.Errorf("%w: type %q", ErrInvalidFilter, v)
+		}
+		filter.Type = &v
+	}
+
+	if v := models.TransactionStatus(query.Get("status")); v != "" {
+		if !v.Valid() {
+			return filter, fmt.Errorf("%w: status %q", ErrInvalidFilter, v)
+		}
+		filter.Status = &v
+	}
+
+	if v := query.Get("category"); v != "" {
+		if !models.ValidCategory(v) {
+			return filter, fmt.Errorf("%w: category %q", ErrInvalidFilter, v)
+		}
+		filter.Category = &v
+	}
+
+	if v := query.Get("tag"); v != "" {
+		filter.Tag = &v
+	}
+
+	return filter, nil
+}
+
+func transactionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInsufficentBalance), errors.Is(err, service.ErrAccountFrozen):
+		return http.StatusForbidden
+	case errors.Is(err, repository.ErrAccountNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidAmount), errors.Is(err, service.ErrSameAccount),
+		errors.Is(err, service.ErrInvalidMemo), errors.Is(err, service.ErrInvalidTags),
+		errors.Is(err, repository.ErrInvalidStatus), errors.Is(err, repository.ErrInvalidCategory):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, repository.ErrInvalidTransition), errors.Is(err, repository.ErrConsumed),
+		errors.Is(err, service.ErrNotReversible):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,178 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds every check so a hanging dependency fails readiness
+// instead of hanging the probe.
+const DefaultTimeout = 2 * time.Second
+
+// State is where the process is in its lifecycle. Only StateServing can be
+// ready.
+type State string
+
+const (
+	StateStarting State = "starting"
+	StateServing  State = "serving"
+	StateDraining State = "draining"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusFailing     = "failing"
+	StatusReady       = "ready"
+	StatusUnavailable = "unavailable"
+)
+
+var (
+	ErrStarting = errors.New("startup in progress")
+	ErrDraining = errors.New("shutting down")
+)
+
+// CheckFunc reports whether a dependency can serve requests.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Liveness only says the process is up and answering.
+type Liveness struct {
+	Status string `json:"status"`
+	State  State  `json:"state"`
+}
+
+// Report is the readiness of the process, with the result of every check.
+type Report struct {
+	Status string                 `json:"status"`
+	State  State                  `json:"state"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type CheckResult struct {
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency"`
+}
+
+// Checker aggregates the named checks of the process and its lifecycle
+// state. It starts in StateStarting.
+type Checker struct {
+	mu      sync.RWMutex
+	state   State
+	checks  []check
+	timeout time.Duration
+}
+
+func NewChecker() *Checker {
+	return &Checker{
+		state:   StateStarting,
+		timeout: DefaultTimeout,
+	}
+}
+
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+func (c *Checker) SetState(state State) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state = state
+}
+
+func (c *Checker) State() State {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state
+}
+
+func (c *Checker) Live() Liveness {
+	return Liveness{Status: StatusOK, State: c.State()}
+}
+
+// Ready runs every check concurrently. The process is ready when it's
+// serving and every check passes; the lifecycle state is reported as the
+// "lifecycle" check.
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.RLock()
+	state, checks := c.state, c.checks
+	c.mu.RUnlock()
+
+	report := Report{
+		Status: StatusReady,
+		State:  state,
+		Checks: make(map[string]CheckResult, len(checks)+1),
+	}
+
+	var lifecycle error
+	switch state {
+	case StateStarting:
+		lifecycle = ErrStarting
+	case StateDraining:
+		lifecycle = ErrDraining
+	}
+	report.Checks["lifecycle"] = result(lifecycle, 0)
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, chk := range checks {
+		chk := chk
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := run(ctx, chk.fn)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[chk.name] = result(err, time.Since(start))
+		}()
+	}
+	wg.Wait()
+
+	for _, res := range report.Checks {
+		if res.Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+
+	return report
+}
+
+// run returns ctx.Err() when fn doesn't return before ctx is done.
+func run(ctx context.Context, fn CheckFunc) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func result(err error, latency time.Duration) CheckResult {
+	res := CheckResult{Status: StatusOK, Latency: latency.String()}
+	if err != nil {
+		res.Status = StatusFailing
+		res.Error = err.Error()
+	}
+	return res
+}
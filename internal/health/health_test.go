@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker_Ready(t *testing.T) {
+	errDown := errors.New("connection refused")
+	ok := func(context.Context) error { return nil }
+
+	scenarios := map[string]struct {
+		state      State
+		checks     map[string]CheckFunc
+		wantStatus string
+		wantChecks map[string]string
+	}{
+		"ready": {
+			state:      StateServing,
+			checks:     map[string]CheckFunc{"storage": ok, "outbox": ok},
+			wantStatus: StatusReady,
+			wantChecks: map[string]string{"lifecycle": "", "storage": "", "outbox": ""},
+		},
+		"starting": {
+			state:      StateStarting,
+			checks:     map[string]CheckFunc{"storage": ok},
+			wantStatus: StatusUnavailable,
+			wantChecks: map[string]string{"lifecycle": ErrStarting.Error(), "storage": ""},
+		},
+		"draining": {
+			state:      StateDraining,
+			wantStatus: StatusUnavailable,
+			wantChecks: map[string]string{"lifecycle": ErrDraining.Error()},
+		},
+		"failing-check": {
+			state: StateServing,
+			checks: map[string]CheckFunc{
+				"storage": func(context.Context) error { return errDown },
+				"outbox":  ok,
+			},
+			wantStatus: StatusUnavailable,
+			wantChecks: map[string]string{"lifecycle": "", "storage": errDown.Error(), "outbox": ""},
+		},
+		"hanging-check": {
+			state: StateServing,
+			checks: map[string]CheckFunc{
+				"storage": func(context.Context) error { select {} },
+			},
+			wantStatus: StatusUnavailable,
+			wantChecks: map[string]string{"lifecycle": "", "storage": context.DeadlineExceeded.Error()},
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			checker := NewChecker()
+			checker.timeout = 10 * time.Millisecond
+			checker.SetState(tcase.state)
+			for name, fn := range tcase.checks {
+				checker.Add(name, fn)
+			}
+
+			report := checker.Ready(context.Background())
+
+			assert.Equal(t, tcase.wantStatus, report.Status)
+			assert.Equal(t, tcase.state, report.State)
+			assert.Len(t, report.Checks, len(tcase.wantChecks))
+			for name, wantErr := range tcase.wantChecks {
+				res := report.Checks[name]
+				assert.Equal(t, wantErr, res.Error, name)
+				if wantErr == "" {
+					assert.Equal(t, StatusOK, res.Status, name)
+				} else {
+					assert.Equal(t, StatusFailing, res.Status, name)
+				}
+			}
+		})
+	}
+}
+
+func TestChecker_Live(t *testing.T) {
+	checker := NewChecker()
+	checker.Add("storage", func(context.Context) error { return errors.New("down") })
+	checker.SetState(StateDraining)
+
+	assert.Equal(t, Liveness{Status: StatusOK, State: StateDraining}, checker.Live())
+}
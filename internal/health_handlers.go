@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"net/http"
+
+	"github.com/gopay/internal/health"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{
+		checker: checker,
+	}
+}
+
+func (h *HealthHandler) GetHealthz(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	res, err := jsoniter.Marshal(h.checker.Live())
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetHealthz")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusOK, res)
+}
+
+func (h *HealthHandler) GetReadyz(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	report := h.checker.Ready(r.Context())
+
+	res, err := jsoniter.Marshal(&report)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetReadyz")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if report.Status != health.StatusReady {
+		status = http.StatusServiceUnavailable
+	}
+	utils.WithPayload(w, status, res)
+}
@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gopay/internal/imports"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	ImportIdParam = "import-id"
+	// MaxImportSize bounds import files, about MaxRows rows.
+	MaxImportSize = 10 * OneMegabyte
+)
+
+var ErrImportTooLarge = fmt.Errorf("import file is larger than %d bytes", MaxImportSize)
+
+type ImportHandler struct {
+	importer *imports.Importer
+	repo     imports.Repo
+}
+
+func NewImportHandler(importer *imports.Importer, repo imports.Repo) *ImportHandler {
+	return &ImportHandler{
+		importer: importer,
+		repo:     repo,
+	}
+}
+
+func (h *ImportHandler) PostImport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	dryRun := false
+	if v := r.URL.Query().Get("dryRun"); v != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostImport")
+			utils.ErrorWithMessage(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxImportSize+1))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostImport")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(body) > MaxImportSize {
+		log.Ctx(r.Context()).Error().Err(ErrImportTooLarge).Msg("Handler::PostImport")
+		utils.ErrorWithMessage(w, http.StatusRequestEntityTooLarge, ErrImportTooLarge.Error())
+		return
+	}
+
+	imp, err := h.importer.Run(r.Context(), bytes.NewReader(body), dryRun)
+	if errors.Is(err, imports.ErrInvalidFile) {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostImport")
+		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostImport")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	res, err := jsoniter.Marshal(&imp)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostImport")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	utils.WithPayload(w, status, res)
+}
+
+func (h *ImportHandler) GetImport(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	imp, err := h.repo.FindOne(r.Context(), params.ByName(ImportIdParam))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetImport")
+		utils.ErrorWithMessage(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	res, err := jsoniter.Marshal(&imp)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetImport")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusOK, res)
+}
+
+func (h *ImportHandler) GetImportResult(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	imp, err := h.repo.FindOne(r.Context(), params.ByName(ImportIdParam))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetImportResult")
+		utils.ErrorWithMessage(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="import-%s.csv"`, imp.ImportId))
+	w.WriteHeader(http.StatusOK)
+
+	err = imports.WriteResult(w, imp)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetImportResult")
+	}
+}
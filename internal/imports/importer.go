@@ -0,0 +1,236 @@
+package imports
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+)
+
+var (
+	ErrMissingReceiver    = errors.New("transfers need a receiver")
+	ErrUnexpectedReceiver = errors.New("only transfers have a receiver")
+	ErrUnknownAccount     = errors.New("account not found")
+	ErrUnknownReceiver    = errors.New("receiver account not found")
+)
+
+// Importer validates bulk imports and executes their rows through the
+// TransactionService, so they follow the same rules and emit the same
+// events as single operations.
+type Importer struct {
+	accountRepo        repository.AccountRepo
+	transactionRepo    repository.TransactionRepo
+	transactionService service.TransactionService
+	repo               Repo
+	now                func() time.Time
+}
+
+func NewImporter(accountRepo repository.AccountRepo, transactionRepo repository.TransactionRepo, transactionService service.TransactionService, repo Repo) *Importer {
+	return &Importer{
+		accountRepo:        accountRepo,
+		transactionRepo:    transactionRepo,
+		transactionService: transactionService,
+		repo:               repo,
+		now:                time.Now,
+	}
+}
+
+// Run reads an import file and checks every row. A dry run stops there and
+// reports which rows would fail; otherwise the valid rows are executed in
+// file order and the report is stored. A failing row doesn't stop the rows
+// after it.
+func (i *Importer) Run(ctx context.Context, r io.Reader, dryRun bool) (models.Import, error) {
+	rows, err := Parse(r)
+	if err != nil {
+		return models.Import{}, err
+	}
+
+	imp := models.Import{
+		DryRun:    dryRun,
+		CreatedAt: i.now(),
+		Rows:      rows,
+	}
+
+	err = i.validate(ctx, imp.Rows)
+	if err != nil {
+		return models.Import{}, err
+	}
+
+	if !dryRun {
+		for n := range imp.Rows {
+			i.execute(ctx, &imp.Rows[n])
+		}
+	}
+
+	imp.Counts = map[models.ImportRowStatus]int{}
+	for _, row := range imp.Rows {
+		imp.Counts[row.Status]++
+	}
+
+	if dryRun {
+		return imp, nil
+	}
+
+	imp.ImportId, err = i.repo.Create(ctx, imp)
+	return imp, err
+}
+
+// validate marks the rows that can't succeed. Debits are checked against
+// the balance each account would have after the valid rows before them.
+func (i *Importer) validate(ctx context.Context, rows []models.ImportRow) error {
+	accounts := map[string]*models.Account{}
+	balances := map[string]float64{}
+
+	lookup := func(id string) (*models.Account, error) {
+		if acc, found := accounts[id]; found {
+			return acc, nil
+		}
+
+		acc, err := i.accountRepo.FindOne(ctx, id)
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			accounts[id] = nil
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		balance, err := i.transactionRepo.GetBalance(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		accounts[id], balances[id] = &acc, balance.Amount
+		return &acc, nil
+	}
+
+	for n := range rows {
+		row := &rows[n]
+		if row.Status == models.ImportRowInvalid {
+			continue
+		}
+
+		account, err := lookup(row.Account)
+		if err != nil {
+			return err
+		}
+
+		var receiver *models.Account
+		if row.Receiver != "" {
+			receiver, err = lookup(row.Receiver)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = check(row, account, receiver, balances)
+		if err != nil {
+			row.Status, row.Error = models.ImportRowInvalid, err.Error()
+			continue
+		}
+
+		switch row.Operation {
+		case models.ImportDeposit:
+			balances[row.Account] += float64(row.Amount)
+		case models.ImportWithdrawal:
+			balances[row.Account] -= float64(row.Amount)
+		case models.ImportTransfer:
+			balances[row.Account] -= float64(row.Amount)
+			balances[row.Receiver] += float64(row.Amount)
+		}
+	}
+
+	return nil
+}
+
+func check(row *models.ImportRow, account *models.Account, receiver *models.Account, balances map[string]float64) error {
+	switch row.Operation {
+	case models.ImportDeposit, models.ImportWithdrawal:
+		if row.Receiver != "" {
+			return ErrUnexpectedReceiver
+		}
+	case models.ImportTransfer:
+		if row.Receiver == "" {
+			return ErrMissingReceiver
+		}
+		if row.Receiver == row.Account {
+			return service.ErrSameAccount
+		}
+	default:
+		return ErrUnknownOperation
+	}
+
+	switch {
+	case account == nil:
+		return ErrUnknownAccount
+	case account.Frozen:
+		return service.ErrAccountFrozen
+	}
+
+	if row.Operation == models.ImportTransfer {
+		switch {
+		case receiver == nil:
+			return ErrUnknownReceiver
+		case receiver.Frozen:
+			return service.ErrAccountFrozen
+		}
+	}
+
+	if row.Operation != models.ImportDeposit && balances[row.Account] < float64(row.Amount) {
+		return service.ErrInsufficentBalance
+	}
+
+	return nil
+}
+
+// execute runs a valid row and records the transactions it created.
+func (i *Importer) execute(ctx context.Context, row *models.ImportRow) {
+	if row.Status != models.ImportRowValid {
+		return
+	}
+
+	ids, err := repository.CreatedIds(ctx, func(ctx context.Context) error {
+		switch row.Operation {
+		case models.ImportDeposit:
+			return i.transactionService.Deposit(ctx, row.Account, row.Amount, service.Note{})
+		case models.ImportWithdrawal:
+			return i.transactionService.Withdraw(ctx, row.Account, (-1)*row.Amount, service.Note{})
+		case models.ImportTransfer:
+			return i.transactionService.Transfer(ctx, row.Account, row.Receiver, (-1)*row.Amount, service.Note{})
+		}
+		return nil
+	})
+	if err != nil {
+		row.Status, row.Error = models.ImportRowFailed, err.Error()
+		return
+	}
+
+	row.Status, row.TransactionIds = models.ImportRowSucceeded, ids
+}
+
+// WriteResult writes the rows of imp with their status and the ids of the
+// transactions they created, space separated.
+func WriteResult(w io.Writer, imp models.Import) error {
+	out := csv.NewWriter(w)
+
+	rows := [][]string{{"line", "operation", "account", "receiver", "amount", "reference", "status", "error", "transactionIds"}}
+	for _, row := range imp.Rows {
+		rows = append(rows, []string{
+			strconv.Itoa(row.Line), string(row.Operation), row.Account, row.Receiver,
+			strconv.FormatFloat(float64(row.Amount), 'f', 2, 32), row.Reference,
+			string(row.Status), row.Error, strings.Join(row.TransactionIds, " "),
+		})
+	}
+
+	err := out.WriteAll(rows)
+	if err != nil {
+		return err
+	}
+	return out.Error()
+}
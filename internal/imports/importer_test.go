@@ -0,0 +1,204 @@
+package imports
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	importer     *Importer
+	repo         Repo
+	transactions repository.TransactionRepo
+	shankar      string
+	jessica      string
+	frozen       string
+}
+
+func newFixture(t *testing.T) *fixture {
+	bank := testutil.NewBank(t)
+
+	f := &fixture{
+		repo:         NewRepo(),
+		transactions: bank.Transactions,
+		shankar:      bank.Account(t, "Shankar", "Nakai"),
+		jessica:      bank.Account(t, "Jessica", "Lourenco"),
+		frozen:       bank.FrozenAccount(t, "Caio", "Henrique"),
+	}
+	bank.Deposit(t, 100, f.shankar)
+
+	f.importer = NewImporter(bank.Accounts, bank.Transactions, bank.Service, f.repo)
+	return f
+}
+
+func (f *fixture) file(lines ...string) string {
+	r := strings.NewReplacer("$shankar", f.shankar, "$jessica", f.jessica, "$frozen", f.frozen)
+	return r.Replace(strings.Join(lines, "\n") + "\n")
+}
+
+func TestParse(t *testing.T) {
+	scenarios := map[string]struct {
+		given      string
+		wantErr    error
+		wantRows   int
+		wantErrors map[int]string
+	}{
+		"columns-in-any-order": {
+			given:    "amount,account,operation\n10,0001,deposit\n",
+			wantRows: 1,
+		},
+		"invalid-amounts": {
+			given:    "operation,account,amount\ndeposit,0001,ten\ndeposit,0001,-1\ndeposit,0001,1.005\ndeposit,0001,NaN\ndeposit,0001,2.50\n",
+			wantRows: 5,
+			wantErrors: map[int]string{
+				2: ErrInvalidAmount.Error(),
+				3: ErrInvalidAmount.Error(),
+				4: ErrInvalidAmount.Error(),
+				5: ErrInvalidAmount.Error(),
+			},
+		},
+		"missing-column": {
+			given:   "operation,account\ndeposit,0001\n",
+			wantErr: ErrMissingColumn,
+		},
+		"empty": {
+			given:   "",
+			wantErr: ErrNoRows,
+		},
+		"header-only": {
+			given:   "operation,account,amount\n",
+			wantErr: ErrNoRows,
+		},
+		"too-many-rows": {
+			given:   "operation,account,amount\n" + strings.Repeat("deposit,0001,1\n", MaxRows+1),
+			wantErr: ErrTooManyRows,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+
+		t.Run(name, func(t *testing.T) {
+			rows, err := Parse(strings.NewReader(tcase.given))
+
+			if tcase.wantErr != nil {
+				assert.ErrorIs(t, err, ErrInvalidFile)
+				assert.ErrorIs(t, err, tcase.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, rows, tcase.wantRows)
+			for _, row := range rows {
+				assert.Equal(t, tcase.wantErrors[row.Line], row.Error, "line %d", row.Line)
+			}
+		})
+	}
+}
+
+func TestImporter_Run_DryRun(t *testing.T) {
+	f := newFixture(t)
+
+	imp, err := f.importer.Run(context.Background(), strings.NewReader(f.file(
+		"operation,account,receiver,amount,reference",
+		"deposit,$jessica,,50,salary",
+		"transfer,$shankar,$jessica,80,rent",
+		"withdrawal,$shankar,,30,",
+		"withdrawal,$jessica,,130,",
+		"transfer,$shankar,,10,",
+		"transfer,$shankar,$shankar,10,",
+		"deposit,$shankar,$jessica,10,",
+		"refund,$shankar,,10,",
+		"deposit,unknown,,10,",
+		"transfer,$shankar,unknown,10,",
+		"deposit,$frozen,,10,",
+		"transfer,$shankar,$frozen,10,",
+	)), true)
+	require.NoError(t, err)
+
+	assert.True(t, imp.DryRun)
+	assert.Empty(t, imp.ImportId)
+
+	errs := map[int]string{}
+	for _, row := range imp.Rows {
+		errs[row.Line] = row.Error
+	}
+	assert.Equal(t, map[int]string{
+		2:  "",
+		3:  "",
+		4:  service.ErrInsufficentBalance.Error(),
+		5:  "",
+		6:  ErrMissingReceiver.Error(),
+		7:  service.ErrSameAccount.Error(),
+		8:  ErrUnexpectedReceiver.Error(),
+		9:  ErrUnknownOperation.Error(),
+		10: ErrUnknownAccount.Error(),
+		11: ErrUnknownReceiver.Error(),
+		12: service.ErrAccountFrozen.Error(),
+		13: service.ErrAccountFrozen.Error(),
+	}, errs)
+	assert.Equal(t, map[models.ImportRowStatus]int{models.ImportRowValid: 3, models.ImportRowInvalid: 9}, imp.Counts)
+	assert.Equal(t, "rent", imp.Rows[1].Reference)
+
+	// nothing was executed
+	balance, err := f.transactions.GetBalance(context.Background(), f.jessica)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, balance.Amount)
+}
+
+func TestImporter_Run(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	imp, err := f.importer.Run(ctx, strings.NewReader(f.file(
+		"operation,account,receiver,amount",
+		"deposit,$jessica,,50",
+		"transfer,$shankar,$jessica,80",
+		"withdrawal,$shankar,,50",
+		"withdrawal,$jessica,,30",
+	)), false)
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, imp.ImportId)
+	assert.Equal(t, map[models.ImportRowStatus]int{models.ImportRowSucceeded: 3, models.ImportRowInvalid: 1}, imp.Counts)
+
+	// a deposit and a withdrawal create one transaction, a transfer two
+	assert.Len(t, imp.Rows[0].TransactionIds, 1)
+	assert.Len(t, imp.Rows[1].TransactionIds, 2)
+	assert.Len(t, imp.Rows[3].TransactionIds, 1)
+	assert.Equal(t, models.ImportRowInvalid, imp.Rows[2].Status)
+	for _, id := range imp.Rows[1].TransactionIds {
+		tr, err := f.transactions.FindOne(ctx, id)
+		require.NoError(t, err)
+		assert.False(t, tr.Remainder)
+		assert.NotEqual(t, tr.Sender, tr.Receiver)
+	}
+
+	for account, want := range map[string]float64{f.shankar: 20, f.jessica: 100} {
+		balance, err := f.transactions.GetBalance(ctx, account)
+		require.NoError(t, err)
+		assert.Equal(t, want, balance.Amount)
+	}
+
+	stored, err := f.repo.FindOne(ctx, imp.ImportId)
+	require.NoError(t, err)
+	assert.Equal(t, imp, stored)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteResult(buf, stored))
+	records, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, []string{"2", "deposit", f.jessica, "", "50.00", "", "succeeded", "", imp.Rows[0].TransactionIds[0]}, records[1])
+	assert.Equal(t, strings.Join(imp.Rows[1].TransactionIds, " "), records[2][8])
+	assert.Equal(t, "invalid", records[3][6])
+}
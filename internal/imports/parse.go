@@ -0,0 +1,117 @@
+package imports
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gopay/internal/models"
+)
+
+// MaxRows bounds the size of one import.
+const MaxRows = 10000
+
+var (
+	ErrInvalidFile      = errors.New("invalid import file")
+	ErrMissingColumn    = errors.New("missing column")
+	ErrTooManyRows      = fmt.Errorf("more than %d rows", MaxRows)
+	ErrNoRows           = errors.New("no rows")
+	ErrUnknownOperation = errors.New("operation must be deposit, withdrawal or transfer")
+	ErrInvalidAmount    = errors.New("amount must be a positive number with at most two decimals")
+)
+
+// Columns of an import file, in any order after a header row. receiver is
+// only used by transfers and reference is free text kept in the report.
+var (
+	requiredColumns = []string{"operation", "account", "amount"}
+	optionalColumns = []string{"receiver", "reference"}
+)
+
+// Parse reads an import file. Problems with the file as a whole are errors;
+// a row that can't be read is returned invalid, with its error, so the
+// report covers every line.
+func Parse(r io.Reader) ([]models.ImportRow, error) {
+	in := csv.NewReader(r)
+	in.FieldsPerRecord = -1
+	in.TrimLeadingSpace = true
+
+	header, err := in.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, ErrNoRows)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range requiredColumns {
+		if _, found := columns[name]; !found {
+			return nil, fmt.Errorf("%w: %w %q", ErrInvalidFile, ErrMissingColumn, name)
+		}
+	}
+
+	rows := []models.ImportRow{}
+	for {
+		record, err := in.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := in.FieldPos(0)
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+		}
+		if len(rows) == MaxRows {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFile, ErrTooManyRows)
+		}
+
+		rows = append(rows, parseRow(line, record, columns))
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, ErrNoRows)
+	}
+	return rows, nil
+}
+
+func parseRow(line int, record []string, columns map[string]int) models.ImportRow {
+	field := func(name string) string {
+		i, found := columns[name]
+		if !found || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := models.ImportRow{
+		Line:      line,
+		Operation: models.ImportOperation(strings.ToLower(field("operation"))),
+		Account:   field("account"),
+		Receiver:  field("receiver"),
+		Reference: field("reference"),
+		Status:    models.ImportRowValid,
+	}
+
+	amount, err := parseAmount(field("amount"))
+	row.Amount = amount
+	if err != nil {
+		row.Status, row.Error = models.ImportRowInvalid, err.Error()
+	}
+
+	return row
+}
+
+func parseAmount(s string) (float32, error) {
+	_, decimals, _ := strings.Cut(s, ".")
+	amount, err := strconv.ParseFloat(s, 32)
+	if err != nil || !(amount > 0) || math.IsInf(amount, 0) || len(decimals) > 2 {
+		return 0, ErrInvalidAmount
+	}
+	return float32(amount), nil
+}
@@ -0,0 +1,57 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/gopay/internal/models"
+)
+
+var ErrImportNotFound = errors.New("import not found")
+
+// Repo keeps the reports of executed imports so their result can be
+// downloaded later.
+type Repo interface {
+	FindOne(ctx context.Context, id string) (models.Import, error)
+	Create(ctx context.Context, imp models.Import) (string, error)
+}
+
+var _ Repo = (*repoImpl)(nil)
+
+type repoImpl struct {
+	mu          sync.RWMutex
+	imports     map[string]models.Import
+	idGenerator func() string
+}
+
+func NewRepo() *repoImpl {
+	return &repoImpl{
+		imports:     make(map[string]models.Import),
+		idGenerator: uuid.NewString,
+	}
+}
+
+func (r *repoImpl) FindOne(_ context.Context, id string) (models.Import, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	imp, found := r.imports[id]
+	if !found {
+		return models.Import{}, ErrImportNotFound
+	}
+
+	return imp, nil
+}
+
+func (r *repoImpl) Create(_ context.Context, imp models.Import) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.idGenerator()
+	imp.ImportId = id
+	r.imports[id] = imp
+
+	return id, nil
+}
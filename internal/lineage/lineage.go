@@ -0,0 +1,163 @@
+package lineage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+)
+
+// Build walks the links of transaction id, parent and children, what
+// consumed it and what it consumed, both ways and across accounts. depth
+// limits how many links away from id the walk goes, 0 doesn't limit it.
+func Build(ctx context.Context, transactionRepo repository.TransactionRepo, id string, depth int) (models.Lineage, error) {
+	root, err := transactionRepo.FindOne(ctx, id)
+	if err != nil {
+		return models.Lineage{}, err
+	}
+
+	w := &walker{
+		transactionRepo: transactionRepo,
+		found:           map[string]models.Transaction{id: root},
+		edges:           map[models.LineageEdge]bool{},
+		byOwner:         map[string][]models.Transaction{},
+	}
+
+	frontier := []models.Transaction{root}
+	for hops := 0; len(frontier) > 0 && (depth == 0 || hops < depth); hops++ {
+		next := []models.Transaction{}
+		for _, t := range frontier {
+			linked, err := w.links(ctx, t)
+			if err != nil {
+				return models.Lineage{}, err
+			}
+			next = append(next, linked...)
+		}
+		frontier = next
+	}
+
+	lineage := models.Lineage{TransactionId: id, Transactions: []models.Transaction{}, Edges: []models.LineageEdge{}}
+	for _, t := range w.found {
+		lineage.Transactions = append(lineage.Transactions, t)
+	}
+	sort.Slice(lineage.Transactions, func(i, j int) bool {
+		a, b := lineage.Transactions[i], lineage.Transactions[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.TransactionId < b.TransactionId
+	})
+
+	for e := range w.edges {
+		lineage.Edges = append(lineage.Edges, e)
+	}
+	sort.Slice(lineage.Edges, func(i, j int) bool {
+		a, b := lineage.Edges[i], lineage.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+
+	return lineage, nil
+}
+
+type walker struct {
+	transactionRepo repository.TransactionRepo
+	found           map[string]models.Transaction
+	edges           map[models.LineageEdge]bool
+	// byOwner caches FindAll, used to find what a debit consumed
+	byOwner map[string][]models.Transaction
+}
+
+// links records the edges of t and returns the transactions they lead to
+// that weren't found yet.
+func (w *walker) links(ctx context.Context, t models.Transaction) ([]models.Transaction, error) {
+	linked := []models.Transaction{}
+	visit := func(id string) (models.Transaction, error) {
+		if other, seen := w.found[id]; seen {
+			return other, nil
+		}
+
+		other, err := w.transactionRepo.FindOne(ctx, id)
+		if err != nil {
+			return models.Transaction{}, err
+		}
+		w.found[id] = other
+		linked = append(linked, other)
+		return other, nil
+	}
+
+	if t.ParentId != "" {
+		if _, err := visit(t.ParentId); err != nil {
+			return nil, err
+		}
+		w.edge(t.ParentId, t.TransactionId, kindOf(t))
+	}
+
+	for _, id := range t.ChildIds {
+		child, err := visit(id)
+		if err != nil {
+			return nil, err
+		}
+		w.edge(t.TransactionId, id, kindOf(child))
+	}
+
+	if t.ConsumedBy != "" {
+		if _, err := visit(t.ConsumedBy); err != nil {
+			return nil, err
+		}
+		w.edge(t.TransactionId, t.ConsumedBy, models.LineageConsumed)
+	}
+
+	// only debits consume, and they're never consumed by anything
+	if t.Amount < 0 {
+		inputs, err := w.consumedBy(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		for _, input := range inputs {
+			if _, seen := w.found[input.TransactionId]; !seen {
+				w.found[input.TransactionId] = input
+				linked = append(linked, input)
+			}
+			w.edge(input.TransactionId, t.TransactionId, models.LineageConsumed)
+		}
+	}
+
+	return linked, nil
+}
+
+func (w *walker) edge(from string, to string, kind models.LineageKind) {
+	w.edges[models.LineageEdge{From: from, To: to, Kind: kind}] = true
+}
+
+// consumedBy returns the transactions debit consumed. They belong to the
+// same account.
+func (w *walker) consumedBy(ctx context.Context, debit models.Transaction) ([]models.Transaction, error) {
+	all, cached := w.byOwner[debit.Owner]
+	if !cached {
+		var err error
+		all, err = w.transactionRepo.FindAll(ctx, debit.Owner)
+		if err != nil {
+			return nil, err
+		}
+		w.byOwner[debit.Owner] = all
+	}
+
+	inputs := []models.Transaction{}
+	for _, t := range all {
+		if t.ConsumedBy == debit.TransactionId {
+			inputs = append(inputs, t)
+		}
+	}
+	return inputs, nil
+}
+
+func kindOf(child models.Transaction) models.LineageKind {
+	if child.Type == models.TransactionRemainder {
+		return models.LineageRemainder
+	}
+	return models.LineageTransfer
+}
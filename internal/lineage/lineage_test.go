@@ -0,0 +1,122 @@
+package lineage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild(t *testing.T) {
+	utils.SetSyncGoroutine()
+	t.Cleanup(utils.ResetGoroutine)
+	ctx := context.Background()
+
+	accounts := repository.NewAccountRepo()
+	transactions := repository.NewTransactionRepo()
+	svc := service.NewTransactionService(transactions, accounts, repository.NewOutboxRepo(), repository.NewUnitOfWork())
+
+	shankar, err := accounts.Create(ctx, "Shankar", "Nakai")
+	require.NoError(t, err)
+	jessica, err := accounts.Create(ctx, "Jessica", "Lourenco")
+	require.NoError(t, err)
+	caio, err := accounts.Create(ctx, "Caio", "Henrique")
+	require.NoError(t, err)
+
+	// shankar sends 120 out of two deposits, leaving a remainder of 30;
+	// jessica withdraws 20 of it, leaving 100
+	require.NoError(t, svc.Deposit(ctx, shankar, 100, service.Note{}))
+	require.NoError(t, svc.Deposit(ctx, shankar, 50, service.Note{}))
+	require.NoError(t, svc.Transfer(ctx, shankar, jessica, -120, service.Note{}))
+	require.NoError(t, svc.Withdraw(ctx, jessica, -20, service.Note{}))
+	require.NoError(t, svc.Deposit(ctx, caio, 10, service.Note{}))
+
+	ids := map[string]string{}
+	label := func(account string, names ...string) {
+		all, err := transactions.FindAll(ctx, account)
+		require.NoError(t, err)
+		require.Len(t, all, len(names))
+		for i, tr := range all {
+			ids[names[i]] = tr.TransactionId
+		}
+	}
+	label(shankar, "deposit-1", "deposit-2", "debit", "remainder-1")
+	label(jessica, "credit", "withdrawal", "remainder-2")
+	label(caio, "unrelated")
+
+	all := []string{"deposit-1", "deposit-2", "debit", "credit", "remainder-1", "withdrawal", "remainder-2"}
+	allEdges := []string{
+		"deposit-1 consumed debit", "deposit-2 consumed debit", "deposit-2 remainder remainder-1",
+		"debit transfer credit", "credit consumed withdrawal", "credit remainder remainder-2",
+	}
+
+	scenarios := map[string]struct {
+		id        string
+		depth     int
+		want      []string
+		wantEdges []string
+	}{
+		"debit": {
+			id:        "debit",
+			want:      all,
+			wantEdges: allEdges,
+		},
+		"from-the-other-end": {
+			id:        "remainder-2",
+			want:      all,
+			wantEdges: allEdges,
+		},
+		"one-link": {
+			id:        "debit",
+			depth:     1,
+			want:      []string{"deposit-1", "deposit-2", "debit", "credit"},
+			wantEdges: []string{"deposit-1 consumed debit", "deposit-2 consumed debit", "debit transfer credit"},
+		},
+		"two-links": {
+			id:    "remainder-1",
+			depth: 2,
+			want:  []string{"deposit-2", "debit", "remainder-1"},
+			wantEdges: []string{
+				"deposit-2 consumed debit", "deposit-2 remainder remainder-1",
+			},
+		},
+		"no-links": {
+			id:        "unrelated",
+			want:      []string{"unrelated"},
+			wantEdges: []string{},
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			names := map[string]string{}
+			for n, id := range ids {
+				names[id] = n
+			}
+
+			lineage, err := Build(ctx, transactions, ids[tcase.id], tcase.depth)
+			require.NoError(t, err)
+
+			assert.Equal(t, ids[tcase.id], lineage.TransactionId)
+			got := []string{}
+			for _, tr := range lineage.Transactions {
+				got = append(got, names[tr.TransactionId])
+			}
+			assert.Equal(t, tcase.want, got)
+
+			gotEdges := []string{}
+			for _, e := range lineage.Edges {
+				gotEdges = append(gotEdges, names[e.From]+" "+string(e.Kind)+" "+names[e.To])
+			}
+			assert.ElementsMatch(t, tcase.wantEdges, gotEdges)
+		})
+	}
+
+	_, err = Build(ctx, transactions, "unknown", 0)
+	assert.ErrorIs(t, err, repository.ErrTransactionNotFound)
+}
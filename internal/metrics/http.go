@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gopay/internal/utils"
+	"github.com/julienschmidt/httprouter"
+)
+
+// InstrumentHandler counts and times requests to one route. The route is the
+// path template, so ids don't end up in label values.
+func (m *Metrics) InstrumentHandler(method string, route string, next httprouter.Handle) httprouter.Handle {
+	requests := m.httpRequests.MustCurryWith(map[string]string{"method": method, "route": route})
+	duration := m.httpDuration.WithLabelValues(method, route)
+
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		rec := utils.NewStatusRecorder(w)
+		start := time.Now()
+
+		next(rec, r, params)
+
+		duration.Observe(time.Since(start).Seconds())
+		requests.WithLabelValues(strconv.Itoa(rec.Status)).Inc()
+	}
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/reconcile"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gopay"
+
+const (
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+var _ reconcile.Observer = (*Metrics)(nil)
+
+// Metrics holds every GoPay collector. Collectors are registered on the
+// registerer given to New so tests can use a fresh registry each.
+type Metrics struct {
+	gatherer prometheus.Gatherer
+
+	httpRequests        *prometheus.CounterVec
+	httpDuration        *prometheus.HistogramVec
+	operations          *prometheus.CounterVec
+	insufficientBalance *prometheus.CounterVec
+	repoDuration        *prometheus.HistogramVec
+	reconciliations     *prometheus.CounterVec
+	violations          *prometheus.GaugeVec
+	lastReconciliation  prometheus.Gauge
+}
+
+func New(registry *prometheus.Registry) *Metrics {
+	factory := promauto.With(registry)
+
+	return &Metrics{
+		gatherer: registry,
+		httpRequests: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		operations: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transaction_operations_total",
+			Help:      "Deposits, withdrawals and transfers by result.",
+		}, []string{"operation", "result"}),
+		insufficientBalance: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "insufficient_balance_total",
+			Help:      "Withdrawals and transfers rejected for insufficient balance.",
+		}, []string{"operation"}),
+		repoDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_call_duration_seconds",
+			Help:      "Repository call latency by repository, method and result.",
+			Buckets:   []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1},
+		}, []string{"repository", "method", "result"}),
+		reconciliations: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reconciliations_total",
+			Help:      "Reconciliation runs by result, ok or violations.",
+		}, []string{"result"}),
+		violations: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "reconciliation_violations",
+			Help:      "Violations the last reconciliation found by invariant.",
+		}, []string{"invariant"}),
+		lastReconciliation: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "reconciliation_last_run_timestamp_seconds",
+			Help:      "When the last reconciliation finished.",
+		}),
+	}
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.gatherer, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) Reconciled(report models.Reconciliation) {
+	res := "ok"
+	if !report.Ok {
+		res = "violations"
+	}
+	m.reconciliations.WithLabelValues(res).Inc()
+
+	counts := map[models.Invariant]int{}
+	for _, v := range report.Violations {
+		counts[v.Invariant]++
+	}
+	for _, inv := range models.Invariants {
+		m.violations.WithLabelValues(string(inv)).Set(float64(counts[inv]))
+	}
+	m.lastReconciliation.Set(float64(report.FinishedAt.Unix()))
+}
+
+func result(err error) string {
+	if err != nil {
+		return resultError
+	}
+	return resultSuccess
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubService struct {
+	err error
+}
+
+func (s stubService) Deposit(context.Context, string, float32, service.Note) error { return s.err }
+
+func (s stubService) Withdraw(context.Context, string, float32, service.Note) error { return s.err }
+
+func (s stubService) Transfer(context.Context, string, string, float32, service.Note) error {
+	return s.err
+}
+
+func (s stubService) UpdateStatus(context.Context, string, models.TransactionStatus) (models.Transaction, error) {
+	return models.Transaction{}, s.err
+}
+
+func TestTransactionService(t *testing.T) {
+	ctx := context.Background()
+
+	scenarios := map[string]struct {
+		given                   error
+		wantResult              string
+		wantInsufficientBalance float64
+	}{
+		"success": {
+			given:      nil,
+			wantResult: resultSuccess,
+		},
+		"insufficient-balance": {
+			given:                   service.ErrInsufficentBalance,
+			wantResult:              resultError,
+			wantInsufficientBalance: 1,
+		},
+		"other-error": {
+			given:      service.ErrInvalidAmount,
+			wantResult: resultError,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+
+		t.Run(name, func(t *testing.T) {
+			m := New(prometheus.NewRegistry())
+			s := NewTransactionService(stubService{err: tcase.given}, m)
+
+			assert.Equal(t, tcase.given, s.Withdraw(ctx, "0001", -10, service.Note{}))
+			assert.Equal(t, tcase.given, s.Transfer(ctx, "0001", "0002", -10, service.Note{}))
+
+			assert.Equal(t, 1.0, testutil.ToFloat64(m.operations.WithLabelValues("withdraw", tcase.wantResult)))
+			assert.Equal(t, 1.0, testutil.ToFloat64(m.operations.WithLabelValues("transfer", tcase.wantResult)))
+			assert.Equal(t, tcase.wantInsufficientBalance, testutil.ToFloat64(m.insufficientBalance.WithLabelValues("withdraw")))
+			assert.Equal(t, tcase.wantInsufficientBalance, testutil.ToFloat64(m.insufficientBalance.WithLabelValues("transfer")))
+		})
+	}
+}
+
+func TestRepositories(t *testing.T) {
+	ctx := context.Background()
+	m := New(prometheus.NewRegistry())
+
+	accountRepo := NewAccountRepo(repository.NewAccountRepo(), m)
+	transactionRepo := NewTransactionRepo(repository.NewTransactionRepo(), m)
+
+	id, err := accountRepo.Create(ctx, "Shankar", "Nakai")
+	require.NoError(t, err)
+	_, err = accountRepo.FindOne(ctx, "unknown")
+	assert.ErrorIs(t, err, repository.ErrAccountNotFound)
+
+	tid, err := transactionRepo.Create(ctx, models.Transaction{Owner: id, Sender: id, Receiver: id, Amount: 10})
+	require.NoError(t, err)
+	require.NoError(t, transactionRepo.MarkAsConsumed(ctx, tid))
+	assert.Error(t, transactionRepo.MarkAsConsumed(ctx, "unknown"))
+
+	assert.Equal(t, uint64(1), sampleCount(t, m.repoDuration, "account", "Create", resultSuccess))
+	assert.Equal(t, uint64(1), sampleCount(t, m.repoDuration, "account", "FindOne", resultError))
+	assert.Equal(t, uint64(1), sampleCount(t, m.repoDuration, "transaction", "Create", resultSuccess))
+	assert.Equal(t, uint64(1), sampleCount(t, m.repoDuration, "transaction", "MarkAsConsumed", resultSuccess))
+	assert.Equal(t, uint64(1), sampleCount(t, m.repoDuration, "transaction", "MarkAsConsumed", resultError))
+	assert.Equal(t, 5, testutil.CollectAndCount(m.repoDuration))
+}
+
+func sampleCount(t *testing.T, h *prometheus.HistogramVec, labels ...string) uint64 {
+	metric := &dto.Metric{}
+	require.NoError(t, h.WithLabelValues(labels...).(prometheus.Metric).Write(metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestInstrumentHandler(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	handle := m.InstrumentHandler("GET", "/accounts/:account-id", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		if r.URL.Path == "/accounts/unknown" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	})
+
+	for _, path := range []string{"/accounts/0001", "/accounts/0002", "/accounts/unknown"} {
+		handle(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil), nil)
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/accounts/:account-id", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/accounts/:account-id", "404")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.httpDuration))
+}
+
+func TestReconciled(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	m.Reconciled(models.Reconciliation{
+		FinishedAt: time.Unix(1700000000, 0),
+		Violations: []models.Violation{
+			{Invariant: models.InvariantBalance},
+			{Invariant: models.InvariantBalance},
+			{Invariant: models.InvariantTotals},
+		},
+	})
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.reconciliations.WithLabelValues("violations")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.violations.WithLabelValues(string(models.InvariantBalance))))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.violations.WithLabelValues(string(models.InvariantNonNegative))))
+	assert.Equal(t, 1700000000.0, testutil.ToFloat64(m.lastReconciliation))
+
+	// a clean run resets the gauges
+	m.Reconciled(models.Reconciliation{Ok: true, Violations: []models.Violation{}})
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.reconciliations.WithLabelValues("ok")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.violations.WithLabelValues(string(models.InvariantBalance))))
+}
+
+func TestHandler(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	m.operations.WithLabelValues("deposit", resultSuccess).Inc()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), `gopay_transaction_operations_total{operation="deposit",result="success"} 1`))
+}
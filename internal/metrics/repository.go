@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	_ repository.TransactionRepo = (*transactionRepo)(nil)
+	_ repository.AccountRepo     = (*accountRepo)(nil)
+)
+
+// transactionRepo times every call to the decorated TransactionRepo.
+type transactionRepo struct {
+	next     repository.TransactionRepo
+	duration prometheus.ObserverVec
+	metrics  *Metrics
+}
+
+func NewTransactionRepo(next repository.TransactionRepo, m *Metrics) *transactionRepo {
+	return &transactionRepo{
+		next:     next,
+		duration: m.repoDuration.MustCurryWith(prometheus.Labels{"repository": "transaction"}),
+		metrics:  m,
+	}
+}
+
+func (r *transactionRepo) FindAll(ctx context.Context, accId string) (ts []models.Transaction, err error) {
+	defer observe(r.duration, "FindAll", time.Now(), &err)
+	return r.next.FindAll(ctx, accId)
+}
+
+func (r *transactionRepo) FindOne(ctx context.Context, id string) (t models.Transaction, err error) {
+	defer observe(r.duration, "FindOne", time.Now(), &err)
+	return r.next.FindOne(ctx, id)
+}
+
+func (r *transactionRepo) Create(ctx context.Context, t models.Transaction) (id string, err error) {
+	defer observe(r.duration, "Create", time.Now(), &err)
+	return r.next.Create(ctx, t)
+}
+
+func (r *transactionRepo) MarkAsConsumed(ctx context.Context, id string) (err error) {
+	defer observe(r.duration, "MarkAsConsumed", time.Now(), &err)
+	return r.next.MarkAsConsumed(ctx, id)
+}
+
+func (r *transactionRepo) SetConsumedBy(ctx context.Context, ids []string, consumedBy string) (err error) {
+	defer observe(r.duration, "SetConsumedBy", time.Now(), &err)
+	return r.next.SetConsumedBy(ctx, ids, consumedBy)
+}
+
+func (r *transactionRepo) UpdateStatus(ctx context.Context, id string, status models.TransactionStatus) (err error) {
+	defer observe(r.duration, "UpdateStatus", time.Now(), &err)
+	return r.next.UpdateStatus(ctx, id, status)
+}
+
+func (r *transactionRepo) SetCategory(ctx context.Context, id string, category string) (err error) {
+	defer observe(r.duration, "SetCategory", time.Now(), &err)
+	return r.next.SetCategory(ctx, id, category)
+}
+
+func (r *transactionRepo) GetBalance(ctx context.Context, id string) (b models.Balance, err error) {
+	defer observe(r.duration, "GetBalance", time.Now(), &err)
+	return r.next.GetBalance(ctx, id)
+}
+
+// accountRepo times every call to the decorated AccountRepo.
+type accountRepo struct {
+	next     repository.AccountRepo
+	duration prometheus.ObserverVec
+}
+
+func NewAccountRepo(next repository.AccountRepo, m *Metrics) *accountRepo {
+	return &accountRepo{
+		next:     next,
+		duration: m.repoDuration.MustCurryWith(prometheus.Labels{"repository": "account"}),
+	}
+}
+
+func (r *accountRepo) FindAll(ctx context.Context) (accs []models.Account, err error) {
+	defer observe(r.duration, "FindAll", time.Now(), &err)
+	return r.next.FindAll(ctx)
+}
+
+func (r *accountRepo) FindOne(ctx context.Context, id string) (acc models.Account, err error) {
+	defer observe(r.duration, "FindOne", time.Now(), &err)
+	return r.next.FindOne(ctx, id)
+}
+
+func (r *accountRepo) FindMany(ctx context.Context, ids []string) (accs []models.Account, err error) {
+	defer observe(r.duration, "FindMany", time.Now(), &err)
+	return r.next.FindMany(ctx, ids)
+}
+
+func (r *accountRepo) Create(ctx context.Context, name string, lastname string) (id string, err error) {
+	defer observe(r.duration, "Create", time.Now(), &err)
+	return r.next.Create(ctx, name, lastname)
+}
+
+func (r *accountRepo) SetFrozen(ctx context.Context, id string, frozen bool) (acc models.Account, err error) {
+	defer observe(r.duration, "SetFrozen", time.Now(), &err)
+	return r.next.SetFrozen(ctx, id, frozen)
+}
+
+// observe is deferred with a pointer to the named error result, which is
+// only read once the call has returned.
+func observe(duration prometheus.ObserverVec, method string, start time.Time, err *error) {
+	duration.WithLabelValues(method, result(*err)).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/service"
+)
+
+var _ service.TransactionService = (*transactionService)(nil)
+
+// transactionService counts the outcome of every operation of the decorated
+// TransactionService.
+type transactionService struct {
+	next    service.TransactionService
+	metrics *Metrics
+}
+
+func NewTransactionService(next service.TransactionService, m *Metrics) *transactionService {
+	return &transactionService{
+		next:    next,
+		metrics: m,
+	}
+}
+
+func (s *transactionService) Deposit(ctx context.Context, owner string, amount float32, note service.Note) error {
+	err := s.next.Deposit(ctx, owner, amount, note)
+	s.count("deposit", err)
+	return err
+}
+
+func (s *transactionService) Withdraw(ctx context.Context, owner string, amount float32, note service.Note) error {
+	err := s.next.Withdraw(ctx, owner, amount, note)
+	s.count("withdraw", err)
+	return err
+}
+
+func (s *transactionService) Transfer(ctx context.Context, sender string, receiver string, amount float32, note service.Note) error {
+	err := s.next.Transfer(ctx, sender, receiver, amount, note)
+	s.count("transfer", err)
+	return err
+}
+
+func (s *transactionService) UpdateStatus(ctx context.Context, id string, status models.TransactionStatus) (models.Transaction, error) {
+	t, err := s.next.UpdateStatus(ctx, id, status)
+	s.count("update_status", err)
+	return t, err
+}
+
+func (s *transactionService) count(operation string, err error) {
+	s.metrics.operations.WithLabelValues(operation, result(err)).Inc()
+
+	if errors.Is(err, service.ErrInsufficentBalance) {
+		s.metrics.insufficientBalance.WithLabelValues(operation).Inc()
+	}
+}
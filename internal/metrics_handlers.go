@@ -0,0 +1,23 @@
+package internal
+
+import (
+	"net/http"
+
+	"github.com/gopay/internal/metrics"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type MetricsHandler struct {
+	metrics *metrics.Metrics
+}
+
+func NewMetricsHandler(m *metrics.Metrics) *MetricsHandler {
+	return &MetricsHandler{
+		metrics: m,
+	}
+}
+
+func (h *MetricsHandler) GetMetrics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	h.metrics.Handler().ServeHTTP(w, r)
+}
@@ -0,0 +1,250 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gopay/internal/auth"
+	"github.com/gopay/internal/metrics"
+	"github.com/gopay/internal/openapi"
+	"github.com/gopay/internal/tracing"
+	"github.com/gopay/internal/utils"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	RequestIdHeader    = "X-Request-ID"
+	AnonymousPrincipal = "anonymous"
+
+	maxRequestIdLength = 128
+)
+
+type requestKey struct{}
+
+// request is shared by every middleware of one request. Inner middlewares,
+// e.g. authentication, fill in what the outer ones log.
+type request struct {
+	id        string
+	principal string
+	scope     auth.Scope
+}
+
+// Chain composes middlewares into one; the first is the outermost.
+func Chain(middlewares ...Middleware) Middleware {
+	return func(route Route, next httprouter.Handle) httprouter.Handle {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](route, next)
+		}
+		return next
+	}
+}
+
+// RequestID assigns every request an id, reusing a valid X-Request-ID sent
+// by the client, and echoes it in the response. The request context gets a
+// logger that tags every line with the id; handlers log through
+// log.Ctx(r.Context()).
+func RequestID() Middleware {
+	return func(route Route, next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			id := r.Header.Get(RequestIdHeader)
+			if !validRequestId(id) {
+				id = uuid.NewString()
+			}
+			w.Header().Set(RequestIdHeader, id)
+
+			// without keys the API is open; Authenticate narrows the scope
+			ctx := context.WithValue(r.Context(), requestKey{}, &request{id: id, principal: AnonymousPrincipal, scope: auth.ScopeAdmin})
+			trace.SpanFromContext(ctx).SetAttributes(attribute.String("gopay.request_id", id))
+
+			logger := log.Logger.With().Str("request_id", id).Ctx(ctx).Logger()
+			next(w, r.WithContext(logger.WithContext(ctx)), params)
+		}
+	}
+}
+
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLength {
+		return false
+	}
+
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDFrom returns the id RequestID assigned to the request.
+func RequestIDFrom(ctx context.Context) string {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		return req.id
+	}
+	return ""
+}
+
+// SetPrincipal records who made the request for the access log.
+func SetPrincipal(ctx context.Context, principal string) {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		req.principal = principal
+	}
+}
+
+// ScopeFrom returns the scope of the key the request was made with. Requests
+// that went through no authentication may do anything, like anonymous ones
+// without configured keys.
+func ScopeFrom(ctx context.Context) auth.Scope {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		return req.scope
+	}
+	return auth.ScopeAdmin
+}
+
+// PrincipalFrom returns who made the request, or AnonymousPrincipal.
+func PrincipalFrom(ctx context.Context) string {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		return req.principal
+	}
+	return AnonymousPrincipal
+}
+
+// AccessLog logs one line per request once the handler returned.
+func AccessLog() Middleware {
+	return func(route Route, next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			rec := utils.NewStatusRecorder(w)
+			start := time.Now()
+
+			next(rec, r, params)
+
+			log.Ctx(r.Context()).Info().
+				Str("method", route.Method).
+				Str("route", route.Path).
+				Str("path", r.URL.Path).
+				Int("status", rec.Status).
+				Dur("latency", time.Since(start)).
+				Str("principal", PrincipalFrom(r.Context())).
+				Msg("request")
+		}
+	}
+}
+
+// Recover turns a panicking handler into a JSON 500 instead of a dropped
+// connection.
+func Recover() Middleware {
+	return func(route Route, next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			rec := utils.NewStatusRecorder(w)
+
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+
+				log.Ctx(r.Context()).Error().Interface("panic", v).Bytes("stack", debug.Stack()).Msg("Handler::Recover")
+				if !rec.WroteHeader {
+					utils.ErrorWithMessage(rec, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+				}
+			}()
+
+			next(rec, r, params)
+		}
+	}
+}
+
+// Authenticate requires a valid API key on every route but the public
+// ones and records its principal and scope. Without configured keys every
+// request goes through as AnonymousPrincipal.
+func Authenticate(keys *auth.Keys, public ...string) Middleware {
+	open := map[string]bool{}
+	for _, path := range public {
+		open[path] = true
+	}
+
+	return func(route Route, next httprouter.Handle) httprouter.Handle {
+		if !keys.Enabled() || open[route.Path] {
+			return next
+		}
+
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			key := auth.FromHeaders(r.Header.Get(auth.AuthorizationHeader), r.Header.Get(auth.APIKeyHeader))
+			principal, scope, err := keys.Principal(key)
+			if err != nil {
+				log.Ctx(r.Context()).Error().Err(err).Msg("Handler::Authenticate")
+				w.Header().Set("WWW-Authenticate", `Bearer realm="gopay"`)
+				utils.ErrorWithMessage(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+
+			SetPrincipal(r.Context(), principal)
+			if req, ok := r.Context().Value(requestKey{}).(*request); ok {
+				req.scope = scope
+			}
+			next(w, r, params)
+		}
+	}
+}
+
+// Authorize answers 403 when the key Authenticate accepted lacks scope on
+// the given routes; a path ending in "/" covers every route under it.
+// Without configured keys every request goes through.
+func Authorize(keys *auth.Keys, scope auth.Scope, paths ...string) Middleware {
+	return func(route Route, next httprouter.Handle) httprouter.Handle {
+		if !keys.Enabled() || !covers(paths, route.Path) {
+			return next
+		}
+
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			req, ok := r.Context().Value(requestKey{}).(*request)
+			if !ok || !req.scope.Allows(scope) {
+				log.Ctx(r.Context()).Error().Err(auth.ErrForbidden).Msg("Handler::Authorize")
+				utils.ErrorWithMessage(w, http.StatusForbidden, auth.ErrForbidden.Error())
+				return
+			}
+
+			next(w, r, params)
+		}
+	}
+}
+
+func covers(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path || strings.HasSuffix(p, "/") && strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Trace starts a server span for every request, continuing the caller's
+// trace when the request carries a traceparent header.
+func Trace(tracer trace.Tracer) Middleware {
+	return func(route Route, next httprouter.Handle) httprouter.Handle {
+		return tracing.Handler(tracer, route.Method, route.Path, next)
+	}
+}
+
+// Instrument counts and times the requests to every route.
+func Instrument(m *metrics.Metrics) Middleware {
+	return func(route Route, next httprouter.Handle) httprouter.Handle {
+		return m.InstrumentHandler(route.Method, route.Path, next)
+	}
+}
+
+// ValidateRequests rejects requests that don't match the spec.
+func ValidateRequests(spec *openapi.Spec) Middleware {
+	return func(route Route, next httprouter.Handle) httprouter.Handle {
+		return spec.Validate(route.Method, route.Path, next)
+	}
+}
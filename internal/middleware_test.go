@@ -0,0 +1,329 @@
+package internal
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gopay/internal/auth"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func captureLogs(t *testing.T) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	logger := log.Logger
+	log.Logger = zerolog.New(buf)
+	t.Cleanup(func() { log.Logger = logger })
+	return buf
+}
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	lines := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := map[string]interface{}{}
+		require.NoError(t, jsoniter.Unmarshal([]byte(line), &entry))
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestChain(t *testing.T) {
+	calls := []string{}
+	mark := func(name string) Middleware {
+		return func(route Route, next httprouter.Handle) httprouter.Handle {
+			return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+				calls = append(calls, name)
+				next(w, r, params)
+			}
+		}
+	}
+
+	router := Router([]Route{{"GET", "/", func(http.ResponseWriter, *http.Request, httprouter.Params) {
+		calls = append(calls, "handler")
+	}}}, mark("outer"), Chain(mark("first"), mark("second")), mark("inner"))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, []string{"outer", "first", "second", "inner", "handler"}, calls)
+}
+
+func TestRequestID(t *testing.T) {
+	scenarios := map[string]struct {
+		given      string
+		wantReused bool
+	}{
+		"reuses-client-id": {
+			given:      "abc-123",
+			wantReused: true,
+		},
+		"generates-missing-id": {
+			given: "",
+		},
+		"replaces-invalid-id": {
+			given: "has spaces",
+		},
+		"replaces-long-id": {
+			given: strings.Repeat("a", maxRequestIdLength+1),
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+
+		t.Run(name, func(t *testing.T) {
+			buf := captureLogs(t)
+
+			var seen string
+			router := Router([]Route{{"GET", "/accounts/:account-id", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+				seen = RequestIDFrom(r.Context())
+				log.Ctx(r.Context()).Error().Msg("Handler::GetAccount")
+			}}}, RequestID())
+
+			req := httptest.NewRequest("GET", "/accounts/0001", nil)
+			if tcase.given != "" {
+				req.Header.Set(RequestIdHeader, tcase.given)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIdHeader)
+			assert.NotEmpty(t, id)
+			assert.Equal(t, id, seen)
+			assert.Equal(t, tcase.wantReused, id == tcase.given)
+
+			lines := logLines(t, buf)
+			require.Len(t, lines, 1)
+			assert.Equal(t, id, lines[0]["request_id"])
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	buf := captureLogs(t)
+
+	router := Router([]Route{
+		{"GET", "/accounts/:account-id", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			utils.ErrorWithMessage(w, http.StatusNotFound, ErrAccountNotFound.Error())
+		}},
+		{"POST", "/transactions", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			SetPrincipal(r.Context(), "ops")
+			utils.WithPayload(w, http.StatusCreated, nil)
+		}},
+	}, RequestID(), AccessLog())
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/accounts/0001", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/transactions", nil))
+
+	lines := logLines(t, buf)
+	require.Len(t, lines, 2)
+
+	assert.Equal(t, "GET", lines[0]["method"])
+	assert.Equal(t, "/accounts/:account-id", lines[0]["route"])
+	assert.Equal(t, "/accounts/0001", lines[0]["path"])
+	assert.Equal(t, 404.0, lines[0]["status"])
+	assert.Equal(t, AnonymousPrincipal, lines[0]["principal"])
+	assert.Contains(t, lines[0], "latency")
+	assert.NotEmpty(t, lines[0]["request_id"])
+
+	assert.Equal(t, 201.0, lines[1]["status"])
+	assert.Equal(t, "ops", lines[1]["principal"])
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys, err := auth.ParseKeys([]string{"ops:secret"})
+	require.NoError(t, err)
+	noKeys, err := auth.ParseKeys(nil)
+	require.NoError(t, err)
+
+	scenarios := map[string]struct {
+		keys          *auth.Keys
+		path          string
+		headers       map[string]string
+		wantStatus    int
+		wantPrincipal string
+	}{
+		"bearer": {
+			keys:          keys,
+			path:          "/accounts",
+			headers:       map[string]string{"Authorization": "Bearer secret"},
+			wantStatus:    http.StatusOK,
+			wantPrincipal: "ops",
+		},
+		"api-key-header": {
+			keys:          keys,
+			path:          "/accounts",
+			headers:       map[string]string{"X-API-Key": "secret"},
+			wantStatus:    http.StatusOK,
+			wantPrincipal: "ops",
+		},
+		"missing-key": {
+			keys:       keys,
+			path:       "/accounts",
+			wantStatus: http.StatusUnauthorized,
+		},
+		"wrong-key": {
+			keys:       keys,
+			path:       "/accounts",
+			headers:    map[string]string{"Authorization": "Bearer nope"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		"public-route": {
+			keys:          keys,
+			path:          "/healthz",
+			wantStatus:    http.StatusOK,
+			wantPrincipal: AnonymousPrincipal,
+		},
+		"auth-disabled": {
+			keys:          noKeys,
+			path:          "/accounts",
+			wantStatus:    http.StatusOK,
+			wantPrincipal: AnonymousPrincipal,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			principal := ""
+			handler := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+				principal = PrincipalFrom(r.Context())
+				utils.WithPayload(w, http.StatusOK, nil)
+			}
+			router := Router([]Route{
+				{"GET", "/accounts", handler},
+				{"GET", "/healthz", handler},
+			}, RequestID(), Authenticate(tcase.keys, "/healthz"))
+
+			req := httptest.NewRequest("GET", tcase.path, nil)
+			for k, v := range tcase.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tcase.wantStatus, rec.Code)
+			assert.Equal(t, tcase.wantPrincipal, principal)
+			if tcase.wantStatus == http.StatusUnauthorized {
+				assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+				assert.JSONEq(t, `{"status": 401, "message": "missing or invalid API key"}`, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	keys, err := auth.ParseKeys([]string{"ci:secret", "ops@admin:topsecret"})
+	require.NoError(t, err)
+	noKeys, err := auth.ParseKeys(nil)
+	require.NoError(t, err)
+
+	scenarios := map[string]struct {
+		keys       *auth.Keys
+		path       string
+		key        string
+		wantStatus int
+		wantScope  auth.Scope
+	}{
+		"admin-key-on-admin-route": {
+			keys:       keys,
+			path:       "/admin/audit",
+			key:        "topsecret",
+			wantStatus: http.StatusOK,
+			wantScope:  auth.ScopeAdmin,
+		},
+		"api-key-on-admin-route": {
+			keys:       keys,
+			path:       "/admin/audit",
+			key:        "secret",
+			wantStatus: http.StatusForbidden,
+		},
+		"api-key-on-freeze": {
+			keys:       keys,
+			path:       "/accounts/0001/freeze",
+			key:        "secret",
+			wantStatus: http.StatusForbidden,
+		},
+		"api-key-on-other-route": {
+			keys:       keys,
+			path:       "/accounts/0001",
+			key:        "secret",
+			wantStatus: http.StatusOK,
+			wantScope:  auth.ScopeAPI,
+		},
+		"auth-disabled": {
+			keys:       noKeys,
+			path:       "/admin/audit",
+			wantStatus: http.StatusOK,
+			wantScope:  auth.ScopeAdmin,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			var scope auth.Scope
+			handler := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+				scope = ScopeFrom(r.Context())
+				utils.WithPayload(w, http.StatusOK, nil)
+			}
+			router := Router([]Route{
+				{"GET", "/admin/audit", handler},
+				{"GET", "/accounts/:account-id", handler},
+				{"GET", "/accounts/:account-id/freeze", handler},
+			}, RequestID(), Authenticate(tcase.keys), Authorize(tcase.keys, auth.ScopeAdmin, AdminPaths...))
+
+			req := httptest.NewRequest("GET", tcase.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tcase.key)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tcase.wantStatus, rec.Code)
+			assert.Equal(t, tcase.wantScope, scope)
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	buf := captureLogs(t)
+
+	router := Router([]Route{
+		{"GET", "/panic", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			panic("boom")
+		}},
+		{"GET", "/panic-after-write", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			utils.WithPayload(w, http.StatusOK, []byte(`{}`))
+			panic("boom")
+		}},
+	}, RequestID(), AccessLog(), Recover())
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	resp := utils.ErrorResponse{}
+	require.NoError(t, jsoniter.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, utils.ErrorResponse{Status: 500, Message: "Internal Server Error"}, resp)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/panic-after-write", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{}`, rec.Body.String())
+
+	lines := logLines(t, buf)
+	require.Len(t, lines, 4)
+	assert.Equal(t, "boom", lines[0]["panic"])
+	assert.Equal(t, lines[0]["request_id"], lines[1]["request_id"])
+	assert.Equal(t, 500.0, lines[1]["status"])
+}
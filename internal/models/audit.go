@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+type AuditOperation string
+
+const (
+	AuditCreate   AuditOperation = "create"
+	AuditConsume  AuditOperation = "consume"
+	AuditLink     AuditOperation = "link"
+	AuditStatus   AuditOperation = "status"
+	AuditCategory AuditOperation = "category"
+)
+
+// AuditRecord is one write to the transaction ledger. Each record holds the
+// hash of the one before it, so changing or dropping a record breaks every
+// hash after it.
+type AuditRecord struct {
+	Seq            uint64         `json:"seq"`
+	At             time.Time      `json:"at"`
+	Operation      AuditOperation `json:"operation"`
+	TransactionIds []string       `json:"transactionIds"`
+	// Transaction is what was written, on create records only.
+	Transaction *Transaction `json:"transaction,omitempty"`
+	// ConsumedBy is what the transactions were consumed by, on link records
+	// only.
+	ConsumedBy string `json:"consumedBy,omitempty"`
+	// Status is the new status, on status records only.
+	Status TransactionStatus `json:"status,omitempty"`
+	// Category is the new category, on category records only. Empty means
+	// it was cleared.
+	Category string `json:"category,omitempty"`
+	PrevHash string `json:"prevHash"`
+	Hash     string `json:"hash"`
+}
+
+// AuditAnchor is a checkpoint of the audit log: the hash of record Seq.
+// Kept outside gopay, anchors catch a log rewritten from scratch.
+type AuditAnchor struct {
+	Seq  uint64    `json:"seq"`
+	Hash string    `json:"hash"`
+	At   time.Time `json:"at"`
+}
+
+// AuditVerification is the result of walking the audit log. BrokenAt is the
+// seq of the first record that doesn't check out.
+type AuditVerification struct {
+	Ok       bool    `json:"ok"`
+	Records  int     `json:"records"`
+	Anchors  int     `json:"anchors"`
+	BrokenAt *uint64 `json:"brokenAt,omitempty"`
+	Reason   string  `json:"reason,omitempty"`
+}
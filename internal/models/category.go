@@ -0,0 +1,105 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Category is a node of the category taxonomy. A subcategory's id is its
+// parent's followed by a dot and a name, "food.groceries" is under "food".
+type Category struct {
+	CategoryId string `json:"categoryId"`
+	Name       string `json:"name"`
+	Parent     string `json:"parent,omitempty"`
+}
+
+// Categories is the taxonomy, every parent before its subcategories.
+var Categories = []Category{
+	{CategoryId: "income", Name: "Income"},
+	{CategoryId: "income.salary", Name: "Salary", Parent: "income"},
+	{CategoryId: "income.refunds", Name: "Refunds", Parent: "income"},
+	{CategoryId: "housing", Name: "Housing"},
+	{CategoryId: "housing.rent", Name: "Rent", Parent: "housing"},
+	{CategoryId: "housing.utilities", Name: "Utilities", Parent: "housing"},
+	{CategoryId: "food", Name: "Food"},
+	{CategoryId: "food.groceries", Name: "Groceries", Parent: "food"},
+	{CategoryId: "food.restaurants", Name: "Restaurants", Parent: "food"},
+	{CategoryId: "transport", Name: "Transport"},
+	{CategoryId: "shopping", Name: "Shopping"},
+	{CategoryId: "entertainment", Name: "Entertainment"},
+	{CategoryId: "health", Name: "Health"},
+	{CategoryId: "savings", Name: "Savings"},
+	{CategoryId: "transfers", Name: "Transfers"},
+	{CategoryId: "other", Name: "Other"},
+}
+
+func ValidCategory(id string) bool {
+	for _, known := range Categories {
+		if id == known.CategoryId {
+			return true
+		}
+	}
+	return false
+}
+
+// InCategory tells whether category is parent or one of its subcategories.
+func InCategory(category string, parent string) bool {
+	return category == parent || strings.HasPrefix(category, parent+".")
+}
+
+// CategoryRule assigns Category to the new transactions of AccountId that
+// match every condition it sets: the counterparty, a regular expression the
+// memo matches, and an amount range. Amounts are signed, debits are
+// negative, so a range can tell spending from income.
+type CategoryRule struct {
+	RuleId       string    `json:"ruleId"`
+	AccountId    string    `json:"accountId"`
+	Category     string    `json:"category"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	MemoPattern  string    `json:"memoPattern,omitempty"`
+	MinAmount    *float64  `json:"minAmount,omitempty"`
+	MaxAmount    *float64  `json:"maxAmount,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	// memo is MemoPattern once Compile ran.
+	memo *regexp.Regexp
+}
+
+// Compile compiles MemoPattern for Matches, which would otherwise compile it
+// for every transaction.
+func (r *CategoryRule) Compile() error {
+	r.memo = nil
+	if r.MemoPattern == "" {
+		return nil
+	}
+
+	memo, err := regexp.Compile(r.MemoPattern)
+	if err != nil {
+		return err
+	}
+	r.memo = memo
+	return nil
+}
+
+// Matches tells whether t meets every condition of r. A memo pattern only
+// matches once r is compiled.
+func (r CategoryRule) Matches(t Transaction) bool {
+	counterparty := t.Sender
+	if t.Sender == t.Owner {
+		counterparty = t.Receiver
+	}
+	amount := float64(t.Amount)
+
+	switch {
+	case r.Counterparty != "" && counterparty != r.Counterparty:
+		return false
+	case r.MinAmount != nil && amount < *r.MinAmount:
+		return false
+	case r.MaxAmount != nil && amount > *r.MaxAmount:
+		return false
+	case r.MemoPattern != "":
+		return r.memo != nil && r.memo.MatchString(t.Memo)
+	}
+
+	return true
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type EventType string
+
+const (
+	EventTransactionCreated  EventType = "transaction.created"
+	EventTransactionConsumed EventType = "transaction.consumed"
+	// EventTransactionStatusChanged carries the transaction in its new
+	// status.
+	EventTransactionStatusChanged EventType = "transaction.status_changed"
+	EventBalanceChanged           EventType = "balance.changed"
+	EventAccountCreated           EventType = "account.created"
+	EventAccountFrozen            EventType = "account.frozen"
+	EventAccountUnfrozen          EventType = "account.unfrozen"
+)
+
+type Event struct {
+	EventId   string      `json:"eventId"`
+	Type      EventType   `json:"type"`
+	AccountId string      `json:"accountId"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// TransactionFilter selects transactions; nil fields match everything.
+type TransactionFilter struct {
+	Consumed     *bool
+	MinAmount    *float64
+	MaxAmount    *float64
+	Since        *time.Time
+	Until        *time.Time
+	Counterparty *string
+	Type         *TransactionType
+	Status       *TransactionStatus
+	// Category matches its subcategories too.
+	Category *string
+	Tag      *string
+}
+
+func (f TransactionFilter) Matches(t Transaction) bool {
+	switch {
+	case f.Consumed != nil && t.IsConsumed != *f.Consumed:
+		return false
+	case f.MinAmount != nil && float64(t.Amount) < *f.MinAmount:
+		return false
+	case f.MaxAmount != nil && float64(t.Amount) > *f.MaxAmount:
+		return false
+	case f.Since != nil && t.CreatedAt.Before(*f.Since):
+		return false
+	case f.Until != nil && t.CreatedAt.After(*f.Until):
+		return false
+	case f.Counterparty != nil && t.Sender != *f.Counterparty && t.Receiver != *f.Counterparty:
+		return false
+	case f.Type != nil && t.Type != *f.Type:
+		return false
+	case f.Status != nil && t.Status != *f.Status:
+		return false
+	case f.Category != nil && !InCategory(t.Category, *f.Category):
+		return false
+	case f.Tag != nil && !hasTag(t, *f.Tag):
+		return false
+	}
+
+	return true
+}
+
+func hasTag(t Transaction, tag string) bool {
+	for _, candidate := range t.Tags {
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
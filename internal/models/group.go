@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+type GroupStatus string
+
+const (
+	GroupBalanced   GroupStatus = "balanced"
+	GroupUnbalanced GroupStatus = "unbalanced"
+)
+
+// Expense is a bill PaidBy paid for the group, shared equally between
+// Participants. Leaving them out shares it between the current members.
+type Expense struct {
+	ExpenseId    string    `json:"expenseId"`
+	PaidBy       string    `json:"paidBy"`
+	Amount       float32   `json:"amount"`
+	Description  string    `json:"description,omitempty"`
+	Participants []string  `json:"participants"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// GroupTransfer moves Amount from a member who owes to one who is owed. The
+// transfers of a settlement plan have no transaction ids until settled.
+type GroupTransfer struct {
+	From           string     `json:"from"`
+	To             string     `json:"to"`
+	Amount         float32    `json:"amount"`
+	SettledAt      *time.Time `json:"settledAt,omitempty"`
+	TransactionIds []string   `json:"transactionIds,omitempty"`
+}
+
+// GroupBalance is what the group owes Account, negative when Account owes
+// the group.
+type GroupBalance struct {
+	Account string  `json:"account"`
+	Amount  float32 `json:"amount"`
+}
+
+// Group tracks shared expenses between its members. Balances are computed
+// from the expenses and the settled transfers.
+type Group struct {
+	GroupId   string          `json:"groupId"`
+	Name      string          `json:"name"`
+	Members   []string        `json:"members"`
+	Status    GroupStatus     `json:"status"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Expenses  []Expense       `json:"expenses"`
+	Transfers []GroupTransfer `json:"transfers"`
+	Balances  []GroupBalance  `json:"balances"`
+}
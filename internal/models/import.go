@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+type ImportOperation string
+
+const (
+	ImportDeposit    ImportOperation = "deposit"
+	ImportWithdrawal ImportOperation = "withdrawal"
+	ImportTransfer   ImportOperation = "transfer"
+)
+
+type ImportRowStatus string
+
+const (
+	ImportRowValid     ImportRowStatus = "valid"
+	ImportRowInvalid   ImportRowStatus = "invalid"
+	ImportRowSucceeded ImportRowStatus = "succeeded"
+	ImportRowFailed    ImportRowStatus = "failed"
+)
+
+// ImportRow is one operation of a bulk import. Rows of a dry run end up
+// valid or invalid, the others invalid, succeeded or failed.
+type ImportRow struct {
+	Line           int             `json:"line"`
+	Operation      ImportOperation `json:"operation"`
+	Account        string          `json:"account"`
+	Receiver       string          `json:"receiver,omitempty"`
+	Amount         float32         `json:"amount"`
+	Reference      string          `json:"reference,omitempty"`
+	Status         ImportRowStatus `json:"status"`
+	Error          string          `json:"error,omitempty"`
+	TransactionIds []string        `json:"transactionIds,omitempty"`
+}
+
+// Import is the report of a bulk import. Dry runs aren't stored and have
+// no id.
+type Import struct {
+	ImportId  string    `json:"importId,omitempty"`
+	DryRun    bool      `json:"dryRun"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Counts has the number of rows per status.
+	Counts map[ImportRowStatus]int `json:"counts"`
+	Rows   []ImportRow             `json:"rows"`
+}
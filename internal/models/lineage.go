@@ -0,0 +1,29 @@
+package models
+
+type LineageKind string
+
+const (
+	// LineageRemainder links a consumed transaction to the remainder split
+	// from it.
+	LineageRemainder LineageKind = "remainder"
+	// LineageConsumed links a consumed transaction to the withdrawal or
+	// outgoing transfer that consumed it.
+	LineageConsumed LineageKind = "consumed"
+	// LineageTransfer links the debit leg of a transfer to its credit leg.
+	LineageTransfer LineageKind = "transfer"
+)
+
+// LineageEdge points the way the money went, From funded To.
+type LineageEdge struct {
+	From string      `json:"from"`
+	To   string      `json:"to"`
+	Kind LineageKind `json:"kind"`
+}
+
+// Lineage is the graph of the transactions linked, directly or not, to
+// TransactionId, oldest first.
+type Lineage struct {
+	TransactionId string        `json:"transactionId"`
+	Transactions  []Transaction `json:"transactions"`
+	Edges         []LineageEdge `json:"edges"`
+}
@@ -8,6 +8,78 @@ type Account struct {
 	AccountId string `json:"accountId"`
 	Name      string `json:"name"`
 	LastName  string `json:"lastName"`
+	// Frozen accounts can't deposit, withdraw, send or receive.
+	Frozen bool `json:"frozen"`
+}
+
+type TransactionType string
+
+const (
+	TransactionDeposit     TransactionType = "deposit"
+	TransactionWithdrawal  TransactionType = "withdrawal"
+	TransactionTransferOut TransactionType = "transfer_out"
+	TransactionTransferIn  TransactionType = "transfer_in"
+	// TransactionRemainder is the change a debit leaves when it consumes
+	// more than it takes.
+	TransactionRemainder TransactionType = "remainder"
+)
+
+// TransactionTypes lists every transaction type.
+var TransactionTypes = []TransactionType{
+	TransactionDeposit, TransactionWithdrawal, TransactionTransferOut, TransactionTransferIn,
+	TransactionRemainder,
+}
+
+func (t TransactionType) Valid() bool {
+	for _, known := range TransactionTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+type TransactionStatus string
+
+const (
+	// TransactionPending transactions are written by an operation before it
+	// moves any money and don't count towards the balance. The operation
+	// posts them once it succeeded or fails them.
+	TransactionPending  TransactionStatus = "pending"
+	TransactionPosted   TransactionStatus = "posted"
+	TransactionFailed   TransactionStatus = "failed"
+	TransactionReversed TransactionStatus = "reversed"
+)
+
+// TransactionStatuses lists every transaction status.
+var TransactionStatuses = []TransactionStatus{
+	TransactionPending, TransactionPosted, TransactionFailed, TransactionReversed,
+}
+
+// transitions lists the statuses each status can move to. Failed and
+// reversed are final.
+var transitions = map[TransactionStatus][]TransactionStatus{
+	TransactionPending: {TransactionPosted, TransactionFailed},
+	TransactionPosted:  {TransactionReversed},
+}
+
+func (s TransactionStatus) Valid() bool {
+	for _, known := range TransactionStatuses {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+// CanBecome tells whether a transaction in status s may move to next.
+func (s TransactionStatus) CanBecome(next TransactionStatus) bool {
+	for _, allowed := range transitions[s] {
+		if next == allowed {
+			return true
+		}
+	}
+	return false
 }
 
 type Transaction struct {
@@ -18,6 +90,29 @@ type Transaction struct {
 	CreatedAt     time.Time `json:"createdAt"`
 	Amount        float32   `json:"amount"`
 	IsConsumed    bool      `json:"isConsumed"`
+	// Type is inferred from the other fields, see InferType, when a
+	// transaction is created without one.
+	Type   TransactionType   `json:"type"`
+	Status TransactionStatus `json:"status"`
+	// Remainder is true for TransactionRemainder transactions, kept for
+	// clients that predate Type. Remainders move no money, the consumed
+	// transactions already count them.
+	Remainder bool `json:"remainder,omitempty"`
+	// ParentId is the transaction a remainder was split from, or the debit
+	// leg of the transfer a credit leg belongs to. ChildIds are the other
+	// way round.
+	ParentId string   `json:"parentId,omitempty"`
+	ChildIds []string `json:"childIds,omitempty"`
+	// ConsumedBy is the withdrawal or outgoing transfer that consumed this
+	// transaction.
+	ConsumedBy string `json:"consumedBy,omitempty"`
+	// Memo is what the transaction was for. Both legs of a transfer carry
+	// it, Tags only the sender's.
+	Memo string   `json:"memo,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	// Category is one of Categories, set by the owner's CategoryRules or by
+	// hand.
+	Category string `json:"category,omitempty"`
 }
 
 type Balance struct {
@@ -25,5 +120,32 @@ type Balance struct {
 	Amount    float64 `json:"balance"`
 }
 
-var Accounts = make(map[string]*Account)
-var Transactions = make(map[string]*Transaction)
+// InferType tells the type of a transaction written before types existed,
+// from the sign conventions: deposits and withdrawals are between an account
+// and itself, positive and negative, and the legs of a transfer are owned by
+// the sender and the receiver.
+func InferType(t Transaction) TransactionType {
+	switch {
+	case t.Remainder:
+		return TransactionRemainder
+	case t.Sender == t.Receiver && t.Amount > 0:
+		return TransactionDeposit
+	case t.Sender == t.Receiver:
+		return TransactionWithdrawal
+	case t.Owner == t.Sender:
+		return TransactionTransferOut
+	default:
+		return TransactionTransferIn
+	}
+}
+
+// Posted tells whether t's money moved.
+func (t Transaction) Posted() bool {
+	return t.Status == TransactionPosted
+}
+
+// Available tells whether t counts towards its owner's balance: posted and
+// not consumed yet.
+func (t Transaction) Available() bool {
+	return t.Posted() && !t.IsConsumed
+}
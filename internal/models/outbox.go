@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// OutboxEntry is an event waiting to be relayed. DeliveredTo lists the sinks
+// that already accepted it, so a retry only targets the ones that failed.
+// Sequence increases with every append and is the order entries are relayed in.
+type OutboxEntry struct {
+	EntryId     string     `json:"entryId"`
+	Sequence    uint64     `json:"sequence"`
+	Event       Event      `json:"event"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	Attempts    int        `json:"attempts"`
+	LastError   string     `json:"lastError,omitempty"`
+	DeliveredTo []string   `json:"deliveredTo,omitempty"`
+	PublishedAt *time.Time `json:"publishedAt,omitempty"`
+}
+
+func (e OutboxEntry) DeliveredToSink(sink string) bool {
+	for _, s := range e.DeliveredTo {
+		if s == sink {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+type PayoutMode string
+
+const (
+	// PayoutAtomic executes every leg or none of them.
+	PayoutAtomic PayoutMode = "atomic"
+	// PayoutBestEffort executes the legs independently, a failing leg
+	// doesn't undo the others.
+	PayoutBestEffort PayoutMode = "best_effort"
+)
+
+type PayoutStatus string
+
+const (
+	PayoutCompleted          PayoutStatus = "completed"
+	PayoutPartiallyCompleted PayoutStatus = "partially_completed"
+	PayoutFailed             PayoutStatus = "failed"
+)
+
+type PayoutLegStatus string
+
+const (
+	PayoutLegSucceeded PayoutLegStatus = "succeeded"
+	PayoutLegFailed    PayoutLegStatus = "failed"
+	// PayoutLegRolledBack legs had succeeded before a later leg of an atomic
+	// payout failed.
+	PayoutLegRolledBack PayoutLegStatus = "rolled_back"
+	// PayoutLegSkipped legs were never attempted because an earlier leg of an
+	// atomic payout failed.
+	PayoutLegSkipped PayoutLegStatus = "skipped"
+)
+
+// PayoutLeg is the transfer of Amount from the payout's sender to Receiver.
+type PayoutLeg struct {
+	Receiver       string          `json:"receiver"`
+	Amount         float32         `json:"amount"`
+	Reference      string          `json:"reference,omitempty"`
+	Status         PayoutLegStatus `json:"status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	TransactionIds []string        `json:"transactionIds,omitempty"`
+}
+
+// Payout is a batch of transfers from one sender. Total is the sum of the
+// legs' amounts.
+type Payout struct {
+	PayoutId  string       `json:"payoutId"`
+	Sender    string       `json:"sender"`
+	Mode      PayoutMode   `json:"mode"`
+	Status    PayoutStatus `json:"status"`
+	Total     float64      `json:"total"`
+	CreatedAt time.Time    `json:"createdAt"`
+	Legs      []PayoutLeg  `json:"legs"`
+}
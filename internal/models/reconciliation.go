@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+type Invariant string
+
+const (
+	// InvariantBalance: an account's balance is the sum of its unconsumed
+	// transactions.
+	InvariantBalance Invariant = "balance_matches_transactions"
+	// InvariantNonNegative: no account has a negative balance.
+	InvariantNonNegative Invariant = "non_negative_balance"
+	// InvariantConsumedExplained: the credits an account consumed add up to
+	// its withdrawals, outgoing transfers and remainders, and those debits
+	// are consumed as they're written.
+	InvariantConsumedExplained Invariant = "consumed_explained"
+	// InvariantTotals: all deposits minus all withdrawals is the sum of all
+	// balances.
+	InvariantTotals Invariant = "deposits_match_balances"
+)
+
+// Invariants lists every invariant reconciliation checks.
+var Invariants = []Invariant{InvariantBalance, InvariantNonNegative, InvariantConsumedExplained, InvariantTotals}
+
+// Violation is a broken invariant. Expected and Actual are the two sides
+// that should have matched.
+type Violation struct {
+	Invariant     Invariant `json:"invariant"`
+	AccountId     string    `json:"accountId,omitempty"`
+	TransactionId string    `json:"transactionId,omitempty"`
+	Expected      float64   `json:"expected"`
+	Actual        float64   `json:"actual"`
+	Message       string    `json:"message"`
+}
+
+// Reconciliation is the report of one reconciliation run.
+type Reconciliation struct {
+	StartedAt    time.Time   `json:"startedAt"`
+	FinishedAt   time.Time   `json:"finishedAt"`
+	Accounts     int         `json:"accounts"`
+	Transactions int         `json:"transactions"`
+	Ok           bool        `json:"ok"`
+	Violations   []Violation `json:"violations"`
+}
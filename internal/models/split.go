@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+type SplitMethod string
+
+const (
+	// SplitEqual divides the total evenly between the participants.
+	SplitEqual SplitMethod = "equal"
+	// SplitAmount takes each participant's amount as given, they must add up
+	// to the total.
+	SplitAmount SplitMethod = "amount"
+	// SplitPercent takes each participant's percentage of the total, they must
+	// add up to 100.
+	SplitPercent SplitMethod = "percent"
+)
+
+type SplitStatus string
+
+const (
+	SplitOpen             SplitStatus = "open"
+	SplitPartiallySettled SplitStatus = "partially_settled"
+	SplitSettled          SplitStatus = "settled"
+)
+
+type ObligationStatus string
+
+const (
+	ObligationPending ObligationStatus = "pending"
+	ObligationSettled ObligationStatus = "settled"
+)
+
+// Obligation is what Account owes the payer of a split. Amount is an input
+// of amount splits and computed for the others.
+type Obligation struct {
+	Account        string           `json:"account"`
+	Amount         float32          `json:"amount"`
+	Percent        float64          `json:"percent,omitempty"`
+	Status         ObligationStatus `json:"status,omitempty"`
+	SettledAt      *time.Time       `json:"settledAt,omitempty"`
+	TransactionIds []string         `json:"transactionIds,omitempty"`
+}
+
+// Split is a bill of Total paid by Payer and shared between the accounts of
+// its obligations.
+type Split struct {
+	SplitId     string       `json:"splitId"`
+	Payer       string       `json:"payer"`
+	Total       float32      `json:"total"`
+	Method      SplitMethod  `json:"method"`
+	Description string       `json:"description,omitempty"`
+	Status      SplitStatus  `json:"status"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	Obligations []Obligation `json:"obligations"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Subscription registers an endpoint for webhook events. An empty AccountId
+// subscribes to every account and an empty Events list to every event type.
+type Subscription struct {
+	SubscriptionId string      `json:"subscriptionId"`
+	AccountId      string      `json:"accountId,omitempty"`
+	Url            string      `json:"url"`
+	Secret         string      `json:"secret,omitempty"`
+	Events         []EventType `json:"events,omitempty"`
+	CreatedAt      time.Time   `json:"createdAt"`
+}
+
+func (s Subscription) Matches(event Event) bool {
+	if s.AccountId != "" && s.AccountId != event.AccountId {
+		return false
+	}
+
+	if len(s.Events) == 0 {
+		return true
+	}
+
+	for _, t := range s.Events {
+		if t == event.Type {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Delivery struct {
+	DeliveryId     string         `json:"deliveryId"`
+	SubscriptionId string         `json:"subscriptionId"`
+	Event          Event          `json:"event"`
+	Status         DeliveryStatus `json:"status"`
+	Attempts       int            `json:"attempts"`
+	StatusCode     int            `json:"statusCode,omitempty"`
+	LastError      string         `json:"lastError,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+}
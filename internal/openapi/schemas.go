@@ -0,0 +1,104 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+	"github.com/gopay/internal/health"
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/statement"
+	"github.com/gopay/internal/utils"
+)
+
+var componentTypes = map[string]interface{}{
+	"Account":           models.Account{},
+	"Transaction":       models.Transaction{},
+	"Balance":           models.Balance{},
+	"Subscription":      models.Subscription{},
+	"Delivery":          models.Delivery{},
+	"Event":             models.Event{},
+	"Error":             utils.ErrorResponse{},
+	"Liveness":          health.Liveness{},
+	"Readiness":         health.Report{},
+	"Statement":         statement.Statement{},
+	"Import":            models.Import{},
+	"Payout":            models.Payout{},
+	"Split":             models.Split{},
+	"Group":             models.Group{},
+	"GroupTransfer":     models.GroupTransfer{},
+	"Reconciliation":    models.Reconciliation{},
+	"AuditRecord":       models.AuditRecord{},
+	"AuditAnchor":       models.AuditAnchor{},
+	"AuditVerification": models.AuditVerification{},
+	"Lineage":           models.Lineage{},
+	"Category":          models.Category{},
+	"CategoryRule":      models.CategoryRule{},
+}
+
+// enums are the values of the string types that only take a few.
+var enums = map[reflect.Type][]interface{}{
+	reflect.TypeOf(models.TransactionType("")):   values(models.TransactionTypes),
+	reflect.TypeOf(models.TransactionStatus("")): values(models.TransactionStatuses),
+}
+
+func values[T ~string](all []T) []interface{} {
+	out := make([]interface{}, 0, len(all))
+	for _, v := range all {
+		out = append(out, string(v))
+	}
+	return out
+}
+
+func modelSchemas() (openapi3.Schemas, error) {
+	schemas := openapi3.Schemas{}
+
+	for name, value := range componentTypes {
+		ref, err := openapi3gen.NewSchemaRefForValue(value, nil, openapi3gen.SchemaCustomizer(strict))
+		if err != nil {
+			return nil, err
+		}
+		schemas[name] = ref
+	}
+
+	return schemas, nil
+}
+
+// strict marks every field without omitempty as required and rejects
+// properties the Go type doesn't have. Interface fields may be null and
+// enums list their values.
+func strict(_ string, t reflect.Type, _ reflect.StructTag, schema *openapi3.Schema) error {
+	if enum, ok := enums[t]; ok {
+		schema.Enum = enum
+		return nil
+	}
+
+	if t.Kind() == reflect.Interface {
+		schema.Nullable = true
+		return nil
+	}
+
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) {
+		return nil
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if !strings.Contains(opts, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	allowed := false
+	schema.AdditionalPropertiesAllowed = &allowed
+	return nil
+}
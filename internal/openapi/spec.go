@@ -0,0 +1,115 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/ghodss/yaml"
+)
+
+//go:embed openapi.yaml
+var document []byte
+
+var ErrOperationNotFound = errors.New("operation not found in spec")
+
+// Spec is the OpenAPI description of the REST API. Paths and request bodies
+// are maintained in openapi.yaml, response models are generated from the Go
+// types so the two can't disagree.
+type Spec struct {
+	doc  *openapi3.T
+	json []byte
+}
+
+func Load() (*Spec, error) {
+	doc := &openapi3.T{}
+	err := yaml.Unmarshal(document, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas, err := modelSchemas()
+	if err != nil {
+		return nil, err
+	}
+	for name, schema := range schemas {
+		doc.Components.Schemas[name] = schema
+	}
+
+	loader := openapi3.NewLoader()
+	err = loader.ResolveRefsIn(doc, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = doc.Validate(loader.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Spec{doc: doc, json: raw}, nil
+}
+
+// JSON returns the document as served at /openapi.json.
+func (s *Spec) JSON() []byte {
+	return s.json
+}
+
+// Route finds the operation for a method and an httprouter path such as
+// /accounts/:account-id.
+func (s *Spec) Route(method string, path string) (*routers.Route, error) {
+	path = Path(path)
+
+	item := s.doc.Paths.Find(path)
+	if item == nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, ErrOperationNotFound)
+	}
+
+	op := item.GetOperation(method)
+	if op == nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, ErrOperationNotFound)
+	}
+
+	return &routers.Route{
+		Spec:      s.doc,
+		Path:      path,
+		PathItem:  item,
+		Method:    method,
+		Operation: op,
+	}, nil
+}
+
+// Operations lists every operation in the spec as "METHOD /path".
+func (s *Spec) Operations() []string {
+	ops := []string{}
+	for path, item := range s.doc.Paths {
+		for method := range item.Operations() {
+			ops = append(ops, method+" "+path)
+		}
+	}
+
+	sort.Strings(ops)
+	return ops
+}
+
+// Path converts an httprouter path to the OpenAPI template syntax.
+func Path(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
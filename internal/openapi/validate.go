@@ -0,0 +1,65 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gopay/internal/utils"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog/log"
+)
+
+var validationOptions = &openapi3filter.Options{
+	AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+}
+
+func init() {
+	// keep the schema and the offending value out of error responses
+	openapi3.SchemaErrorDetailsDisabled = true
+	// statements and exports are plain text as far as the schema goes
+	for _, contentType := range []string{"text/csv", "application/x-ofx", "application/qif"} {
+		openapi3filter.RegisterBodyDecoder(contentType, openapi3filter.FileBodyDecoder)
+	}
+}
+
+// Validate rejects requests to the route that don't match its operation with
+// a 400. Routes the spec doesn't describe are passed through untouched.
+func (s *Spec) Validate(method string, path string, next httprouter.Handle) httprouter.Handle {
+	route, err := s.Route(method, path)
+	if err != nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		// clients that omit the content type are assumed to send JSON
+		if r.ContentLength != 0 && r.Header.Get("Content-Type") == "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+
+		err := openapi3filter.ValidateRequest(r.Context(), RequestInput(r, params, route))
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("OpenAPI::Validate")
+			utils.ErrorWithMessage(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		next(w, r, params)
+	}
+}
+
+// RequestInput describes a request to the validator.
+func RequestInput(r *http.Request, params httprouter.Params, route *routers.Route) *openapi3filter.RequestValidationInput {
+	pathParams := make(map[string]string, len(params))
+	for _, p := range params {
+		pathParams[p.Key] = p.Value
+	}
+
+	return &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: pathParams,
+		Route:      route,
+		Options:    validationOptions,
+	}
+}
@@ -0,0 +1,24 @@
+package internal
+
+import (
+	"net/http"
+
+	"github.com/gopay/internal/openapi"
+	"github.com/gopay/internal/utils"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type OpenAPIHandler struct {
+	spec *openapi.Spec
+}
+
+func NewOpenAPIHandler(spec *openapi.Spec) *OpenAPIHandler {
+	return &OpenAPIHandler{
+		spec: spec,
+	}
+}
+
+func (h *OpenAPIHandler) GetOpenAPI(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	utils.WithPayload(w, http.StatusOK, h.spec.JSON())
+}
@@ -0,0 +1,502 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gopay/internal/audit"
+	"github.com/gopay/internal/categories"
+	"github.com/gopay/internal/events"
+	"github.com/gopay/internal/export"
+	"github.com/gopay/internal/gql"
+	"github.com/gopay/internal/groups"
+	"github.com/gopay/internal/health"
+	"github.com/gopay/internal/imports"
+	"github.com/gopay/internal/metrics"
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/openapi"
+	"github.com/gopay/internal/payouts"
+	"github.com/gopay/internal/reconcile"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/splits"
+	"github.com/gopay/internal/statement"
+	"github.com/gopay/internal/stream"
+	"github.com/gopay/internal/utils"
+	"github.com/gopay/internal/webhook"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type apiFixture struct {
+	spec            *openapi.Spec
+	routes          []Route
+	router          *httprouter.Router
+	accountRepo     repository.AccountRepo
+	transactionRepo repository.TransactionRepo
+	deliveries      webhook.DeliveryRepo
+	dispatcher      *webhook.Dispatcher
+	auditLog        *audit.Log
+}
+
+func newAPIFixture(t *testing.T) *apiFixture {
+	utils.SetSyncGoroutine()
+	t.Cleanup(utils.ResetGoroutine)
+
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	subscriptions := webhook.NewSubscriptionRepo()
+	deliveries := webhook.NewDeliveryRepo()
+	dispatcher := webhook.NewDispatcher(subscriptions, deliveries)
+	dispatcher.AllowPrivateTargets()
+	outboxRepo := repository.NewOutboxRepo()
+	unitOfWork := repository.NewUnitOfWork()
+
+	accountRepo := events.NewAccountRepo(repository.NewAccountRepo(), outboxRepo, unitOfWork)
+	auditLog := audit.NewLog()
+	categoryRules := categories.NewRepo()
+	transactionRepo := audit.NewTransactionRepo(categories.NewTransactionRepo(repository.NewTransactionRepo(), categoryRules), auditLog)
+	transactionService := service.NewTransactionService(transactionRepo, accountRepo, outboxRepo, unitOfWork)
+	hub := stream.NewHub(stream.DefaultBufferSize)
+
+	routes := Routes(NewHandler(transactionService, transactionRepo, accountRepo))
+	routes = append(routes, WebhookRoutes(NewWebhookHandler(dispatcher, subscriptions, deliveries, accountRepo))...)
+	routes = append(routes, StreamRoutes(NewStreamHandler(hub, accountRepo))...)
+	routes = append(routes, GraphQLRoutes(NewGraphQLHandler(gql.NewResolver(transactionService, transactionRepo, accountRepo)))...)
+	routes = append(routes, OpenAPIRoutes(NewOpenAPIHandler(spec))...)
+	routes = append(routes, MetricsRoutes(NewMetricsHandler(metrics.New(prometheus.NewRegistry())))...)
+	routes = append(routes, HealthRoutes(NewHealthHandler(health.NewChecker()))...)
+	routes = append(routes, StatementRoutes(NewStatementHandler(statement.NewGenerator(transactionRepo), accountRepo))...)
+	imported := imports.NewRepo()
+	routes = append(routes, ImportRoutes(NewImportHandler(imports.NewImporter(accountRepo, transactionRepo, transactionService, imported), imported))...)
+	paid := payouts.NewRepo()
+	routes = append(routes, PayoutRoutes(NewPayoutHandler(payouts.NewProcessor(accountRepo, transactionRepo, transactionService, unitOfWork, paid), paid))...)
+	split := splits.NewRepo()
+	routes = append(routes, SplitRoutes(NewSplitHandler(splits.NewSplitter(accountRepo, transactionService, split), split))...)
+	routes = append(routes, GroupRoutes(NewGroupHandler(groups.NewLedger(accountRepo, transactionService, unitOfWork, groups.NewRepo())))...)
+	routes = append(routes, ReconcileRoutes(NewReconcileHandler(reconcile.NewReconciler(accountRepo, transactionRepo, unitOfWork, nil)))...)
+	routes = append(routes, AuditRoutes(NewAuditHandler(auditLog))...)
+	routes = append(routes, CategoryRoutes(NewCategoryHandler(categories.NewCategoriser(accountRepo, transactionRepo, categoryRules)))...)
+	routes = append(routes, ExportRoutes(NewExportHandler(export.NewExporter(statement.NewGenerator(transactionRepo), accountRepo), accountRepo))...)
+
+	return &apiFixture{
+		spec:            spec,
+		routes:          routes,
+		router:          Router(routes, ValidateRequests(spec)),
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		deliveries:      deliveries,
+		dispatcher:      dispatcher,
+		auditLog:        auditLog,
+	}
+}
+
+// do sends the request through the router and checks the response against
+// the operation it was matched to.
+func (f *apiFixture) do(t *testing.T, method string, path string, body string) *httptest.ResponseRecorder {
+	return f.doAs(t, method, path, "application/json", body)
+}
+
+// doAs is do for bodies of another content type.
+func (f *apiFixture) doAs(t *testing.T, method string, path string, contentType string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, req)
+
+	handle, params, _ := f.router.Lookup(method, req.URL.Path)
+	require.NotNil(t, handle, "%s %s is not routed", method, path)
+
+	var template string
+	for _, route := range f.routes {
+		if route.Method == method && routeMatches(route.Path, req.URL.Path) {
+			template = route.Path
+		}
+	}
+
+	route, err := f.spec.Route(method, template)
+	require.NoError(t, err)
+
+	err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: openapi.RequestInput(req, params, route),
+		Status:                 rec.Code,
+		Header:                 rec.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(rec.Body.Bytes())),
+	})
+	assert.NoError(t, err, "%s %s drifted from the spec: %s", method, path, rec.Body.String())
+
+	return rec
+}
+
+func routeMatches(template string, path string) bool {
+	want := strings.Split(template, "/")
+	got := strings.Split(path, "/")
+	if len(want) != len(got) {
+		return false
+	}
+
+	for i := range want {
+		if !strings.HasPrefix(want[i], ":") && want[i] != got[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOpenAPI_RoutesMatchSpec(t *testing.T) {
+	f := newAPIFixture(t)
+
+	routes := []string{}
+	for _, route := range f.routes {
+		routes = append(routes, route.Method+" "+openapi.Path(route.Path))
+	}
+	sort.Strings(routes)
+
+	assert.Equal(t, f.spec.Operations(), routes)
+}
+
+func TestOpenAPI_ResponsesMatchSpec(t *testing.T) {
+	f := newAPIFixture(t)
+	ctx := context.Background()
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	rec := f.do(t, "POST", "/accounts", `{"name": "Shankar", "lastName": "Nakai"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = f.do(t, "POST", "/accounts", `{"name": "Jessica", "lastName": "Lourenco"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	accs, err := f.accountRepo.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, accs, 2)
+	sender, receiverId := accs[0].AccountId, accs[1].AccountId
+
+	rec = f.do(t, "POST", "/webhooks", `{"url": "`+receiver.URL+`", "events": ["transaction.created"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	sub := models.Subscription{}
+	require.NoError(t, jsoniter.Unmarshal(rec.Body.Bytes(), &sub))
+
+	require.NoError(t, f.dispatcher.Publish(ctx, events.New(models.EventTransactionCreated, sender, nil)))
+	deliveries, err := f.deliveries.FindAll(ctx, sub.SubscriptionId)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	rec = f.do(t, "POST", "/transactions", `{"sender": "`+sender+`", "receiver": "`+sender+`", "amount": 100}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = f.do(t, "POST", "/transactions", `{"sender": "`+sender+`", "receiver": "`+receiverId+`", "amount": -40}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	transactions, err := f.transactionRepo.FindAll(ctx, sender)
+	require.NoError(t, err)
+	require.NotEmpty(t, transactions)
+
+	scenarios := []struct {
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"GET", "/", "", http.StatusOK},
+		{"GET", "/openapi.json", "", http.StatusOK},
+		{"GET", "/metrics", "", http.StatusOK},
+		{"GET", "/healthz", "", http.StatusOK},
+		{"GET", "/readyz", "", http.StatusServiceUnavailable},
+		{"GET", "/accounts", "", http.StatusOK},
+		{"GET", "/accounts/" + sender, "", http.StatusOK},
+		{"GET", "/accounts/unknown", "", http.StatusNotFound},
+		{"GET", "/accounts/" + sender + "/transactions", "", http.StatusOK},
+		{"GET", "/accounts/unknown/transactions", "", http.StatusNotFound},
+		{"GET", "/accounts/" + sender + "/balance", "", http.StatusOK},
+		{"GET", "/accounts/unknown/balance", "", http.StatusNotFound},
+		{"GET", "/accounts/unknown/stream", "", http.StatusNotFound},
+		{"GET", "/accounts/" + sender + "/statements?period=" + time.Now().UTC().Format(statement.PeriodLayout), "", http.StatusOK},
+		{"GET", "/accounts/" + sender + "/statements?period=2026-09&format=csv", "", http.StatusOK},
+		{"GET", "/accounts/" + sender + "/statements?period=2026-13", "", http.StatusBadRequest},
+		{"GET", "/accounts/unknown/statements?period=2026-09", "", http.StatusNotFound},
+		{"GET", "/accounts/" + sender + "/export?format=ofx", "", http.StatusOK},
+		{"GET", "/accounts/" + sender + "/export?format=qif&since=2000-01-01T00:00:00Z", "", http.StatusOK},
+		{"GET", "/accounts/" + sender + "/export?format=csv&until=2000-01-01T00:00:00Z", "", http.StatusOK},
+		{"GET", "/accounts/" + sender + "/export?format=csv&since=2000-01-02T00:00:00Z&until=2000-01-01T00:00:00Z", "", http.StatusBadRequest},
+		{"GET", "/accounts/unknown/export?format=csv", "", http.StatusNotFound},
+		{"GET", "/transactions/" + transactions[0].TransactionId, "", http.StatusOK},
+		{"GET", "/transactions/unknown", "", http.StatusNotFound},
+		{"GET", "/transactions/" + transactions[0].TransactionId + "/lineage", "", http.StatusOK},
+		{"GET", "/transactions/" + transactions[0].TransactionId + "/lineage?depth=1", "", http.StatusOK},
+		{"GET", "/transactions/" + transactions[0].TransactionId + "/lineage?depth=-1", "", http.StatusBadRequest},
+		{"GET", "/transactions/unknown/lineage", "", http.StatusNotFound},
+		{"POST", "/transactions/" + transactions[0].TransactionId + "/status", `{"status": "reversed"}`, http.StatusConflict},
+		{"POST", "/transactions/" + transactions[0].TransactionId + "/status", `{"status": "pending"}`, http.StatusConflict},
+		{"POST", "/transactions/unknown/status", `{"status": "posted"}`, http.StatusNotFound},
+		{"GET", "/categories", "", http.StatusOK},
+		{"GET", "/accounts/" + sender + "/category-rules", "", http.StatusOK},
+		{"GET", "/accounts/unknown/category-rules", "", http.StatusNotFound},
+		{"POST", "/accounts/" + sender + "/category-rules", `{"category": "food"}`, http.StatusUnprocessableEntity},
+		{"POST", "/accounts/" + sender + "/category-rules", `{"category": "food", "memoPattern": "(lunch"}`, http.StatusUnprocessableEntity},
+		{"POST", "/accounts/unknown/category-rules", `{"category": "food", "counterparty": "` + receiverId + `"}`, http.StatusNotFound},
+		{"DELETE", "/accounts/" + sender + "/category-rules/unknown", "", http.StatusNotFound},
+		{"POST", "/transactions/" + transactions[0].TransactionId + "/category", `{"category": "income.salary"}`, http.StatusOK},
+		{"POST", "/transactions/" + transactions[0].TransactionId + "/category", `{"category": "gifts"}`, http.StatusUnprocessableEntity},
+		{"POST", "/transactions/unknown/category", `{"category": "food"}`, http.StatusNotFound},
+		{"GET", "/accounts/" + sender + "/transactions?category=income&tag=trip", "", http.StatusOK},
+		{"GET", "/accounts/" + sender + "/transactions?category=gifts", "", http.StatusBadRequest},
+		{"POST", "/transactions", `{"sender": "` + sender + `", "receiver": "` + receiverId + `", "amount": -1000}`, http.StatusForbidden},
+		{"POST", "/transactions", `{"sender": "unknown", "receiver": "` + receiverId + `", "amount": -10}`, http.StatusNotFound},
+		{"POST", "/accounts", `{"name": "", "lastName": "Nakai"}`, http.StatusBadRequest},
+		{"POST", "/webhooks", `{"url": "not a url"}`, http.StatusUnprocessableEntity},
+		{"POST", "/webhooks", `{"url": "http://example.com", "accountId": "unknown"}`, http.StatusNotFound},
+		{"GET", "/webhooks", "", http.StatusOK},
+		{"GET", "/webhooks/" + sub.SubscriptionId + "/deliveries", "", http.StatusOK},
+		{"GET", "/webhooks/unknown/deliveries", "", http.StatusNotFound},
+		{"POST", "/webhooks/" + sub.SubscriptionId + "/deliveries/" + deliveries[0].DeliveryId + "/replay", "", http.StatusOK},
+		{"POST", "/webhooks/" + sub.SubscriptionId + "/deliveries/unknown/replay", "", http.StatusNotFound},
+		{"POST", "/graphql", `{"query": "{ accounts { name balance } }"}`, http.StatusOK},
+		{"POST", "/graphql", `{"query": "{ nope }"}`, http.StatusOK},
+		{"GET", "/accounts/" + sender + "/transactions?consumed=false&minAmount=0&since=2000-01-01T00:00:00Z&counterparty=" + receiverId, "", http.StatusOK},
+		{"GET", "/accounts/" + sender + "/transactions?type=remainder&status=posted", "", http.StatusOK},
+		{"POST", "/accounts/" + sender + "/freeze", "", http.StatusOK},
+		{"POST", "/transactions", `{"sender": "` + sender + `", "receiver": "` + receiverId + `", "amount": -10}`, http.StatusForbidden},
+		{"POST", "/accounts/" + sender + "/unfreeze", "", http.StatusOK},
+		{"POST", "/accounts/unknown/freeze", "", http.StatusNotFound},
+		{"POST", "/accounts/unknown/unfreeze", "", http.StatusNotFound},
+		{"DELETE", "/webhooks/" + sub.SubscriptionId, "", http.StatusNoContent},
+		{"DELETE", "/webhooks/unknown", "", http.StatusNotFound},
+	}
+
+	for _, tcase := range scenarios {
+		t.Run(tcase.method+" "+tcase.path, func(t *testing.T) {
+			rec := f.do(t, tcase.method, tcase.path, tcase.body)
+			assert.Equal(t, tcase.wantStatus, rec.Code, rec.Body.String())
+		})
+	}
+
+	file := "operation,account,receiver,amount\ndeposit," + sender + ",,10\ntransfer," + sender + "," + receiverId + ",5\nwithdrawal,unknown,,1\n"
+	rec = f.doAs(t, "POST", "/imports?dryRun=true", "text/csv", file)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = f.doAs(t, "POST", "/imports?dryRun=maybe", "text/csv", file)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+	rec = f.doAs(t, "POST", "/imports", "text/csv", "account,amount\n")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	rec = f.doAs(t, "POST", "/imports", "text/csv", file)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	imp := models.Import{}
+	require.NoError(t, jsoniter.Unmarshal(rec.Body.Bytes(), &imp))
+	rec = f.do(t, "GET", "/imports/"+imp.ImportId, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = f.do(t, "GET", "/imports/"+imp.ImportId+"/result", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = f.do(t, "GET", "/imports/unknown", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = f.do(t, "GET", "/imports/unknown/result", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = f.do(t, "POST", "/payouts", `{"sender": "`+sender+`", "legs": [{"receiver": "unknown", "amount": 1}]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	rec = f.do(t, "POST", "/payouts", `{"sender": "`+sender+`", "legs": [{"receiver": "`+receiverId+`", "amount": 1000000}]}`)
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+	rec = f.do(t, "POST", "/payouts", `{"sender": "unknown", "legs": [{"receiver": "`+receiverId+`", "amount": 1}]}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+	rec = f.do(t, "POST", "/payouts", `{"sender": "`+sender+`", "mode": "best_effort", "legs": [{"receiver": "`+receiverId+`", "amount": 1, "reference": "june"}]}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	payout := models.Payout{}
+	require.NoError(t, jsoniter.Unmarshal(rec.Body.Bytes(), &payout))
+	assert.Equal(t, models.PayoutCompleted, payout.Status)
+	rec = f.do(t, "GET", "/payouts/"+payout.PayoutId, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = f.do(t, "GET", "/payouts/unknown", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = f.do(t, "POST", "/splits", `{"payer": "`+receiverId+`", "total": 10, "method": "amount", "obligations": [{"account": "`+sender+`", "amount": 3}]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	rec = f.do(t, "POST", "/splits", `{"payer": "unknown", "total": 10, "method": "equal", "obligations": [{"account": "`+sender+`"}]}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+	rec = f.do(t, "POST", "/splits", `{"payer": "`+receiverId+`", "total": 2, "method": "percent", "description": "lunch", "obligations": [{"account": "`+receiverId+`", "percent": 50}, {"account": "`+sender+`", "percent": 50}]}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	split := models.Split{}
+	require.NoError(t, jsoniter.Unmarshal(rec.Body.Bytes(), &split))
+	rec = f.do(t, "POST", "/splits/"+split.SplitId+"/obligations/"+sender+"/settle", "")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = f.do(t, "POST", "/splits/"+split.SplitId+"/obligations/"+sender+"/settle", "")
+	assert.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	rec = f.do(t, "POST", "/splits/"+split.SplitId+"/obligations/unknown/settle", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+	rec = f.do(t, "GET", "/splits/"+split.SplitId, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = f.do(t, "GET", "/splits/unknown", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = f.do(t, "POST", "/groups", `{"name": "Trip", "members": ["unknown"]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	rec = f.do(t, "POST", "/groups", `{"name": "Trip", "members": ["`+sender+`"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	group := models.Group{}
+	require.NoError(t, jsoniter.Unmarshal(rec.Body.Bytes(), &group))
+	rec = f.do(t, "POST", "/groups/"+group.GroupId+"/members", `{"account": "`+receiverId+`"}`)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = f.do(t, "POST", "/groups/"+group.GroupId+"/expenses", `{"paidBy": "`+receiverId+`", "amount": 2, "description": "coffee"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = f.do(t, "POST", "/groups/"+group.GroupId+"/expenses", `{"paidBy": "unknown", "amount": 2}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	rec = f.do(t, "GET", "/groups/"+group.GroupId+"/settlement", "")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = f.do(t, "POST", "/groups/"+group.GroupId+"/settle", "")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = f.do(t, "GET", "/groups/"+group.GroupId, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"balanced"`)
+	rec = f.do(t, "GET", "/groups/unknown", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = f.do(t, "POST", "/transactions", `{"sender": "`+receiverId+`", "receiver": "`+receiverId+`", "amount": 3}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	received, err := f.transactionRepo.FindAll(ctx, receiverId)
+	require.NoError(t, err)
+	deposit := received[len(received)-1].TransactionId
+	rec = f.do(t, "POST", "/transactions/"+deposit+"/status", `{"status": "reversed"}`)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"status":"reversed"`)
+	rec = f.do(t, "POST", "/transactions/"+deposit+"/status", `{"status": "posted"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+
+	rec = f.do(t, "POST", "/accounts/"+receiverId+"/category-rules", `{"category": "food.restaurants", "memoPattern": "(?i)lunch", "maxAmount": 0}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rule := models.CategoryRule{}
+	require.NoError(t, jsoniter.Unmarshal(rec.Body.Bytes(), &rule))
+	rec = f.do(t, "POST", "/transactions", `{"sender": "`+receiverId+`", "receiver": "`+sender+`", "amount": -1, "memo": "Lunch", "tags": ["work"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = f.do(t, "GET", "/accounts/"+receiverId+"/transactions?category=food&tag=work", "")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"memo":"Lunch","tags":["work"],"category":"food.restaurants"`)
+	rec = f.do(t, "DELETE", "/accounts/"+receiverId+"/category-rules/"+rule.RuleId, "")
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+	rec = f.do(t, "GET", "/admin/reconciliation", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = f.do(t, "POST", "/admin/reconciliation", "")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"ok":true`)
+	rec = f.do(t, "GET", "/admin/reconciliation", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	_, ok := f.auditLog.Checkpoint()
+	require.True(t, ok)
+	rec = f.do(t, "GET", "/admin/audit?from=2", "")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"seq":2,`)
+	rec = f.do(t, "GET", "/admin/audit?from=first", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = f.do(t, "GET", "/admin/audit/anchors", "")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = f.do(t, "GET", "/admin/audit/verify", "")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"ok":true`)
+}
+
+func TestOpenAPI_RejectsInvalidRequests(t *testing.T) {
+	f := newAPIFixture(t)
+
+	scenarios := map[string]struct {
+		method string
+		path   string
+		body   string
+	}{
+		"account-missing-last-name": {
+			method: "POST",
+			path:   "/accounts",
+			body:   `{"name": "Shankar"}`,
+		},
+		"account-wrong-type": {
+			method: "POST",
+			path:   "/accounts",
+			body:   `{"name": 1, "lastName": "Nakai"}`,
+		},
+		"account-no-body": {
+			method: "POST",
+			path:   "/accounts",
+		},
+		"transaction-string-amount": {
+			method: "POST",
+			path:   "/transactions",
+			body:   `{"sender": "0001", "receiver": "0002", "amount": "10"}`,
+		},
+		"webhook-unknown-event": {
+			method: "POST",
+			path:   "/webhooks",
+			body:   `{"url": "http://example.com", "events": ["nope"]}`,
+		},
+		"graphql-missing-query": {
+			method: "POST",
+			path:   "/graphql",
+			body:   `{"variables": {}}`,
+		},
+		"statement-missing-period": {
+			method: "GET",
+			path:   "/accounts/0001/statements",
+		},
+		"statement-unknown-format": {
+			method: "GET",
+			path:   "/accounts/0001/statements?period=2026-09&format=ofx",
+		},
+		"export-unknown-format": {
+			method: "GET",
+			path:   "/accounts/0001/export?format=xls",
+		},
+		"export-malformed-since": {
+			method: "GET",
+			path:   "/accounts/0001/export?format=ofx&since=yesterday",
+		},
+		"transactions-malformed-filter": {
+			method: "GET",
+			path:   "/accounts/0001/transactions?since=yesterday",
+		},
+		"transactions-unknown-type": {
+			method: "GET",
+			path:   "/accounts/0001/transactions?type=gift",
+		},
+		"transaction-too-many-tags": {
+			method: "POST",
+			path:   "/transactions",
+			body:   `{"sender": "0001", "receiver": "0002", "amount": -1, "tags": ["a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"]}`,
+		},
+		"category-rule-missing-category": {
+			method: "POST",
+			path:   "/accounts/0001/category-rules",
+			body:   `{"counterparty": "0002"}`,
+		},
+		"transaction-unknown-status": {
+			method: "POST",
+			path:   "/transactions/0001/status",
+			body:   `{"status": "lost"}`,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+
+		t.Run(name, func(t *testing.T) {
+			rec := f.do(t, tcase.method, tcase.path, tcase.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package outbox
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/gopay/internal/events"
+	"github.com/gopay/internal/models"
+	jsoniter "github.com/json-iterator/go"
+)
+
+var _ events.Publisher = (*fileSink)(nil)
+
+// fileSink appends every event as one JSON line to a file.
+type fileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSink(path string) *fileSink {
+	return &fileSink{path: path}
+}
+
+func (s *fileSink) Publish(_ context.Context, event models.Event) error {
+	line, err := jsoniter.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
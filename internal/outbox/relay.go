@@ -0,0 +1,175 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopay/internal/events"
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultBatchSize = 100
+
+var (
+	ErrRelayNotRunning = errors.New("outbox relay is not running")
+	ErrRelayStalled    = errors.New("outbox relay stalled")
+)
+
+type sink struct {
+	name      string
+	publisher events.Publisher
+}
+
+// Relay publishes pending outbox entries to every registered sink. An entry
+// stays pending until all sinks accepted it, so delivery is at least once and
+// sinks may see the same EventId more than once.
+type Relay struct {
+	outboxRepo repository.OutboxRepo
+	sinks      []sink
+	interval   time.Duration
+	batchSize  int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	running bool
+	lastRun time.Time
+	lastErr error
+}
+
+func NewRelay(outboxRepo repository.OutboxRepo, interval time.Duration) *Relay {
+	return &Relay{
+		outboxRepo: outboxRepo,
+		interval:   interval,
+		batchSize:  defaultBatchSize,
+		now:        time.Now,
+	}
+}
+
+func (r *Relay) AddSink(name string, publisher events.Publisher) {
+	r.sinks = append(r.sinks, sink{name: name, publisher: publisher})
+}
+
+// Run flushes the outbox every interval until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.setRunning(true)
+	defer r.setRunning(false)
+
+	for {
+		_, err := r.Flush(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Relay::Run")
+		}
+
+		r.mu.Lock()
+		r.lastRun, r.lastErr = r.now(), err
+		r.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain flushes until the outbox is empty, a pass leaves entries pending or
+// ctx is done. It is meant for after Run has returned, so the events of the
+// last requests before a shutdown aren't left behind; ctx bounds it with the
+// shutdown deadline.
+func (r *Relay) Drain(ctx context.Context) error {
+	for ctx.Err() == nil {
+		published, err := r.Flush(ctx)
+		if err != nil {
+			return err
+		}
+		if published < r.batchSize {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+func (r *Relay) setRunning(running bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running = running
+}
+
+// Check fails when Run isn't running, hasn't made a pass in three intervals
+// or its last pass couldn't read or update the outbox. Sinks rejecting
+// events don't fail it, those entries are retried.
+func (r *Relay) Check(_ context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.running {
+		return ErrRelayNotRunning
+	}
+	if since := r.now().Sub(r.lastRun); since > 3*r.interval {
+		return fmt.Errorf("%w: last pass %s ago", ErrRelayStalled, since.Round(time.Millisecond))
+	}
+	return r.lastErr
+}
+
+// Flush makes one pass over the pending entries and returns how many were
+// fully published.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	entries, err := r.outboxRepo.FindPending(ctx, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, entry := range entries {
+		if r.publish(ctx, &entry) {
+			published++
+		}
+
+		err = r.outboxRepo.Update(ctx, entry)
+		if err != nil {
+			return published, err
+		}
+	}
+
+	return published, nil
+}
+
+func (r *Relay) publish(ctx context.Context, entry *models.OutboxEntry) bool {
+	errs := []string{}
+	entry.Attempts++
+
+	for _, s := range r.sinks {
+		if entry.DeliveredToSink(s.name) {
+			continue
+		}
+
+		err := s.publisher.Publish(ctx, entry.Event)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", s.name, err))
+			continue
+		}
+
+		entry.DeliveredTo = append(entry.DeliveredTo, s.name)
+	}
+
+	if len(errs) > 0 {
+		entry.LastError = strings.Join(errs, "; ")
+		log.Info().Msgf("Outbox entry %s attempt %d failed: %s", entry.EntryId, entry.Attempts, entry.LastError)
+		return false
+	}
+
+	now := r.now()
+	entry.LastError = ""
+	entry.PublishedAt = &now
+
+	return true
+}
@@ -0,0 +1,186 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gopay/internal/events"
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelay_Flush(t *testing.T) {
+	ctx := context.Background()
+	errSink := errors.New("sink unavailable")
+	event := events.New(models.EventBalanceChanged, "0001", models.Balance{AccountId: "0001", Amount: 100})
+
+	scenarios := map[string]struct {
+		doMocks       func(webhooks *events.MockPublisher)
+		wantPublished []int
+		wantBus       int
+	}{
+		"happy-path": {
+			doMocks: func(webhooks *events.MockPublisher) {
+				webhooks.On("Publish", ctx, event).Return(nil).Once()
+			},
+			wantPublished: []int{1, 0},
+			wantBus:       1,
+		},
+		"retries-only-failed-sink": {
+			doMocks: func(webhooks *events.MockPublisher) {
+				webhooks.On("Publish", ctx, event).Return(errSink).Once()
+				webhooks.On("Publish", ctx, event).Return(nil).Once()
+			},
+			wantPublished: []int{0, 1, 0},
+			wantBus:       1,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			outboxRepo := repository.NewOutboxRepo()
+			require.NoError(t, outboxRepo.Append(ctx, event))
+
+			webhooks := events.NewMockPublisher(t)
+			tcase.doMocks(webhooks)
+
+			bus := events.NewBus()
+			received := 0
+			bus.Subscribe(func(e models.Event) {
+				assert.Equal(t, event.EventId, e.EventId)
+				received++
+			})
+
+			relay := NewRelay(outboxRepo, time.Second)
+			relay.AddSink("webhooks", webhooks)
+			relay.AddSink("bus", bus)
+
+			for _, want := range tcase.wantPublished {
+				published, err := relay.Flush(ctx)
+				assert.NoError(t, err)
+				assert.Equal(t, want, published)
+			}
+
+			assert.Equal(t, tcase.wantBus, received)
+			pending, _ := outboxRepo.FindPending(ctx, 0)
+			assert.Empty(t, pending)
+		})
+	}
+}
+
+func TestRelay_Drain(t *testing.T) {
+	errSink := errors.New("sink unavailable")
+
+	scenarios := map[string]struct {
+		cancelled   bool
+		sinkErr     error
+		wantErr     error
+		wantPending int
+	}{
+		"publishes-every-batch": {
+			wantPending: 0,
+		},
+		"stops-on-failing-sink": {
+			sinkErr:     errSink,
+			wantPending: 2*defaultBatchSize + 1,
+		},
+		"bounded-by-ctx": {
+			cancelled:   true,
+			wantErr:     context.Canceled,
+			wantPending: 2*defaultBatchSize + 1,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tcase.cancelled {
+				cancel()
+			}
+
+			outboxRepo := repository.NewOutboxRepo()
+			for i := 0; i < 2*defaultBatchSize+1; i++ {
+				require.NoError(t, outboxRepo.Append(ctx, events.New(models.EventBalanceChanged, "0001", nil)))
+			}
+
+			sink := events.NewMockPublisher(t)
+			sink.On("Publish", mock.Anything, mock.Anything).Return(tcase.sinkErr).Maybe()
+
+			relay := NewRelay(outboxRepo, time.Second)
+			relay.AddSink("webhooks", sink)
+
+			err := relay.Drain(ctx)
+			if tcase.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tcase.wantErr)
+			}
+
+			pending, _ := outboxRepo.FindPending(context.Background(), 0)
+			assert.Len(t, pending, tcase.wantPending)
+		})
+	}
+}
+
+func TestRelay_Check(t *testing.T) {
+	relay := NewRelay(repository.NewOutboxRepo(), 10*time.Millisecond)
+	assert.ErrorIs(t, relay.Check(context.Background()), ErrRelayNotRunning)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return relay.Check(ctx) == nil
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+	assert.ErrorIs(t, relay.Check(context.Background()), ErrRelayNotRunning)
+
+	relay.running, relay.lastRun = true, time.Now().Add(-time.Second)
+	assert.ErrorIs(t, relay.Check(context.Background()), ErrRelayStalled)
+}
+
+func TestFileSink_Publish(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink := NewFileSink(path)
+
+	published := []models.Event{
+		events.New(models.EventAccountCreated, "0001", models.Account{AccountId: "0001"}),
+		events.New(models.EventTransactionCreated, "0001", models.Transaction{TransactionId: "1000000"}),
+	}
+	for _, e := range published {
+		require.NoError(t, sink.Publish(ctx, e))
+	}
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	ids := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e models.Event
+		require.NoError(t, jsoniter.Unmarshal(scanner.Bytes(), &e))
+		ids = append(ids, e.EventId)
+	}
+
+	assert.Equal(t, []string{published[0].EventId, published[1].EventId}, ids)
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/payouts"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const PayoutIdParam = "payout-id"
+
+type PayoutHandler struct {
+	processor *payouts.Processor
+	repo      payouts.Repo
+}
+
+func NewPayoutHandler(processor *payouts.Processor, repo payouts.Repo) *PayoutHandler {
+	return &PayoutHandler{
+		processor: processor,
+		repo:      repo,
+	}
+}
+
+func (h *PayoutHandler) PostPayout(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, OneMegabyte))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostPayout")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	payout := models.Payout{}
+	err = jsoniter.Unmarshal(body, &payout)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostPayout")
+		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	payout, err = h.processor.Run(r.Context(), payout)
+	if errors.Is(err, payouts.ErrInvalidPayout) {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostPayout")
+		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostPayout")
+		utils.ErrorWithMessage(w, transactionErrorStatus(err), err.Error())
+		return
+	}
+
+	res, err := jsoniter.Marshal(&payout)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostPayout")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusCreated, res)
+}
+
+func (h *PayoutHandler) GetPayout(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	payout, err := h.repo.FindOne(r.Context(), params.ByName(PayoutIdParam))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetPayout")
+		utils.ErrorWithMessage(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	res, err := jsoniter.Marshal(&payout)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetPayout")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusOK, res)
+}
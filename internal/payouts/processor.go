@@ -0,0 +1,204 @@
+package payouts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+)
+
+// MaxLegs bounds the number of transfers of one payout.
+const MaxLegs = 1000
+
+var (
+	ErrInvalidPayout   = errors.New("invalid payout")
+	ErrNoLegs          = errors.New("a payout needs at least one leg")
+	ErrTooManyLegs     = fmt.Errorf("a payout has at most %d legs", MaxLegs)
+	ErrUnknownMode     = errors.New("mode must be atomic or best_effort")
+	ErrUnknownReceiver = errors.New("receiver account not found")
+)
+
+// Processor executes payouts leg by leg through the TransactionService, so
+// every leg follows the same rules and emits the same events as a single
+// transfer.
+type Processor struct {
+	accountRepo        repository.AccountRepo
+	transactionRepo    repository.TransactionRepo
+	transactionService service.TransactionService
+	unitOfWork         repository.UnitOfWork
+	repo               Repo
+	now                func() time.Time
+}
+
+func NewProcessor(accountRepo repository.AccountRepo, transactionRepo repository.TransactionRepo, transactionService service.TransactionService, unitOfWork repository.UnitOfWork, repo Repo) *Processor {
+	return &Processor{
+		accountRepo:        accountRepo,
+		transactionRepo:    transactionRepo,
+		transactionService: transactionService,
+		unitOfWork:         unitOfWork,
+		repo:               repo,
+		now:                time.Now,
+	}
+}
+
+// Run checks payout and the sender's balance against its total, executes the
+// legs in order and stores the result. An empty mode is atomic. Payouts
+// rejected before execution, with ErrInvalidPayout for malformed legs, aren't
+// stored.
+func (p *Processor) Run(ctx context.Context, payout models.Payout) (models.Payout, error) {
+	if payout.Mode == "" {
+		payout.Mode = models.PayoutAtomic
+	}
+
+	err := p.validate(ctx, &payout)
+	if err != nil {
+		return models.Payout{}, err
+	}
+
+	payout.CreatedAt = p.now()
+	switch payout.Mode {
+	case models.PayoutAtomic:
+		p.runAtomic(ctx, payout.Legs, payout.Sender)
+	case models.PayoutBestEffort:
+		for n := range payout.Legs {
+			_ = p.execute(ctx, payout.Sender, &payout.Legs[n])
+		}
+	}
+	payout.Status = status(payout.Legs)
+
+	payout.PayoutId, err = p.repo.Create(ctx, payout)
+	return payout, err
+}
+
+// validate checks the legs, the sender and that the sender can afford the
+// whole payout, and sets its total.
+func (p *Processor) validate(ctx context.Context, payout *models.Payout) error {
+	switch {
+	case payout.Mode != models.PayoutAtomic && payout.Mode != models.PayoutBestEffort:
+		return fmt.Errorf("%w: %w", ErrInvalidPayout, ErrUnknownMode)
+	case len(payout.Legs) == 0:
+		return fmt.Errorf("%w: %w", ErrInvalidPayout, ErrNoLegs)
+	case len(payout.Legs) > MaxLegs:
+		return fmt.Errorf("%w: %w", ErrInvalidPayout, ErrTooManyLegs)
+	}
+
+	sender, err := p.accountRepo.FindOne(ctx, payout.Sender)
+	if err != nil {
+		return err
+	}
+	if sender.Frozen {
+		return service.ErrAccountFrozen
+	}
+
+	payout.Total = 0
+	receivers := map[string]models.Account{}
+	for n, leg := range payout.Legs {
+		err := p.checkLeg(ctx, payout.Sender, leg, receivers)
+		if err != nil {
+			return fmt.Errorf("%w: legs[%d]: %w", ErrInvalidPayout, n, err)
+		}
+		payout.Total += float64(leg.Amount)
+	}
+
+	balance, err := p.transactionRepo.GetBalance(ctx, payout.Sender)
+	if err != nil {
+		return err
+	}
+	if balance.Amount < payout.Total {
+		return service.ErrInsufficentBalance
+	}
+
+	return nil
+}
+
+func (p *Processor) checkLeg(ctx context.Context, sender string, leg models.PayoutLeg, receivers map[string]models.Account) error {
+	if !(leg.Amount > 0) {
+		return service.ErrInvalidAmount
+	}
+	if leg.Receiver == sender {
+		return service.ErrSameAccount
+	}
+
+	receiver, found := receivers[leg.Receiver]
+	if !found {
+		var err error
+		receiver, err = p.accountRepo.FindOne(ctx, leg.Receiver)
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			return ErrUnknownReceiver
+		}
+		if err != nil {
+			return err
+		}
+		receivers[leg.Receiver] = receiver
+	}
+
+	if receiver.Frozen {
+		return service.ErrAccountFrozen
+	}
+	return nil
+}
+
+// runAtomic executes the legs in one unit of work. When a leg fails the
+// writes of the legs before it are undone and the legs after it are skipped.
+func (p *Processor) runAtomic(ctx context.Context, legs []models.PayoutLeg, sender string) {
+	failed := -1
+	_ = p.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		for n := range legs {
+			err := p.execute(ctx, sender, &legs[n])
+			if err != nil {
+				failed = n
+				return err
+			}
+		}
+		return nil
+	})
+	if failed < 0 {
+		return
+	}
+
+	for n := range legs {
+		switch {
+		case n < failed:
+			legs[n].Status, legs[n].TransactionIds = models.PayoutLegRolledBack, nil
+		case n > failed:
+			legs[n].Status = models.PayoutLegSkipped
+		}
+	}
+}
+
+// execute transfers the leg's amount and records the transactions it
+// created.
+func (p *Processor) execute(ctx context.Context, sender string, leg *models.PayoutLeg) error {
+	ids, err := repository.CreatedIds(ctx, func(ctx context.Context) error {
+		return p.transactionService.Transfer(ctx, sender, leg.Receiver, (-1)*leg.Amount, service.Note{})
+	})
+	if err != nil {
+		leg.Status, leg.Error = models.PayoutLegFailed, err.Error()
+		return err
+	}
+
+	leg.Status, leg.TransactionIds = models.PayoutLegSucceeded, ids
+	return nil
+}
+
+func status(legs []models.PayoutLeg) models.PayoutStatus {
+	succeeded := 0
+	for _, leg := range legs {
+		if leg.Status == models.PayoutLegSucceeded {
+			succeeded++
+		}
+	}
+
+	switch succeeded {
+	case len(legs):
+		return models.PayoutCompleted
+	case 0:
+		return models.PayoutFailed
+	default:
+		return models.PayoutPartiallyCompleted
+	}
+}
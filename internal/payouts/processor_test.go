@@ -0,0 +1,205 @@
+package payouts
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errBroken = errors.New("broken")
+
+// failingService fails the transfers to receiver after the transactions of
+// the transfer were written, like a failure late in a unit of work.
+type failingService struct {
+	service.TransactionService
+	receiver string
+}
+
+func (s *failingService) Transfer(ctx context.Context, sender string, receiver string, amount float32, note service.Note) error {
+	err := s.TransactionService.Transfer(ctx, sender, receiver, amount, note)
+	if err == nil && receiver == s.receiver {
+		return errBroken
+	}
+	return err
+}
+
+type fixture struct {
+	processor    *Processor
+	repo         Repo
+	transactions repository.TransactionRepo
+	shankar      string
+	jessica      string
+	caio         string
+	frozen       string
+}
+
+func newFixture(t *testing.T) *fixture {
+	bank := testutil.NewBank(t)
+
+	f := &fixture{
+		repo:         NewRepo(),
+		transactions: bank.Transactions,
+		shankar:      bank.Account(t, "Shankar", "Nakai"),
+		jessica:      bank.Account(t, "Jessica", "Lourenco"),
+		caio:         bank.Account(t, "Caio", "Henrique"),
+		frozen:       bank.FrozenAccount(t, "Ana", "Souza"),
+	}
+	bank.Deposit(t, 100, f.shankar)
+
+	f.processor = NewProcessor(bank.Accounts, bank.Transactions, &failingService{TransactionService: bank.Service, receiver: f.caio}, bank.UnitOfWork, f.repo)
+	return f
+}
+
+func (f *fixture) balance(t *testing.T, id string) float64 {
+	balance, err := f.transactions.GetBalance(context.Background(), id)
+	require.NoError(t, err)
+	return balance.Amount
+}
+
+func TestProcessor_Run(t *testing.T) {
+	scenarios := map[string]struct {
+		mode       models.PayoutMode
+		legs       func(f *fixture) []models.PayoutLeg
+		wantStatus models.PayoutStatus
+		wantLegs   []models.PayoutLegStatus
+		wantSender float64
+	}{
+		"atomic-completed": {
+			legs: func(f *fixture) []models.PayoutLeg {
+				return []models.PayoutLeg{{Receiver: f.jessica, Amount: 30}, {Receiver: f.jessica, Amount: 20}}
+			},
+			wantStatus: models.PayoutCompleted,
+			wantLegs:   []models.PayoutLegStatus{models.PayoutLegSucceeded, models.PayoutLegSucceeded},
+			wantSender: 50,
+		},
+		"atomic-failed-leg-rolls-back": {
+			mode: models.PayoutAtomic,
+			legs: func(f *fixture) []models.PayoutLeg {
+				return []models.PayoutLeg{{Receiver: f.jessica, Amount: 30}, {Receiver: f.caio, Amount: 20}, {Receiver: f.jessica, Amount: 10}}
+			},
+			wantStatus: models.PayoutFailed,
+			wantLegs:   []models.PayoutLegStatus{models.PayoutLegRolledBack, models.PayoutLegFailed, models.PayoutLegSkipped},
+			wantSender: 100,
+		},
+		"best-effort-partially-completed": {
+			mode: models.PayoutBestEffort,
+			legs: func(f *fixture) []models.PayoutLeg {
+				return []models.PayoutLeg{{Receiver: f.jessica, Amount: 30}, {Receiver: f.caio, Amount: 20}, {Receiver: f.jessica, Amount: 10}}
+			},
+			wantStatus: models.PayoutPartiallyCompleted,
+			wantLegs:   []models.PayoutLegStatus{models.PayoutLegSucceeded, models.PayoutLegFailed, models.PayoutLegSucceeded},
+			// the failing service only reports the error, caio's transfer went through
+			wantSender: 40,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+
+			payout, err := f.processor.Run(context.Background(), models.Payout{Sender: f.shankar, Mode: tcase.mode, Legs: tcase.legs(f)})
+			require.NoError(t, err)
+
+			assert.NotEmpty(t, payout.PayoutId)
+			assert.Equal(t, tcase.wantStatus, payout.Status)
+			for n, want := range tcase.wantLegs {
+				leg := payout.Legs[n]
+				assert.Equal(t, want, leg.Status, "legs[%d]", n)
+				if want == models.PayoutLegSucceeded {
+					assert.Len(t, leg.TransactionIds, 2, "legs[%d]", n)
+				} else {
+					assert.Empty(t, leg.TransactionIds, "legs[%d]", n)
+				}
+			}
+			assert.InDelta(t, tcase.wantSender, f.balance(t, f.shankar), 0.001)
+
+			stored, err := f.repo.FindOne(context.Background(), payout.PayoutId)
+			require.NoError(t, err)
+			assert.Equal(t, payout, stored)
+		})
+	}
+}
+
+func TestProcessor_RunRejects(t *testing.T) {
+	scenarios := map[string]struct {
+		sender  func(f *fixture) string
+		mode    models.PayoutMode
+		legs    func(f *fixture) []models.PayoutLeg
+		wantErr []error
+	}{
+		"no-legs": {
+			legs:    func(f *fixture) []models.PayoutLeg { return nil },
+			wantErr: []error{ErrInvalidPayout, ErrNoLegs},
+		},
+		"too-many-legs": {
+			legs: func(f *fixture) []models.PayoutLeg {
+				return make([]models.PayoutLeg, MaxLegs+1)
+			},
+			wantErr: []error{ErrInvalidPayout, ErrTooManyLegs},
+		},
+		"unknown-mode": {
+			mode:    "eventually",
+			legs:    func(f *fixture) []models.PayoutLeg { return []models.PayoutLeg{{Receiver: f.jessica, Amount: 1}} },
+			wantErr: []error{ErrInvalidPayout, ErrUnknownMode},
+		},
+		"unknown-sender": {
+			sender:  func(f *fixture) string { return "unknown" },
+			legs:    func(f *fixture) []models.PayoutLeg { return []models.PayoutLeg{{Receiver: f.jessica, Amount: 1}} },
+			wantErr: []error{repository.ErrAccountNotFound},
+		},
+		"frozen-sender": {
+			sender:  func(f *fixture) string { return f.frozen },
+			legs:    func(f *fixture) []models.PayoutLeg { return []models.PayoutLeg{{Receiver: f.jessica, Amount: 1}} },
+			wantErr: []error{service.ErrAccountFrozen},
+		},
+		"unknown-receiver": {
+			legs: func(f *fixture) []models.PayoutLeg {
+				return []models.PayoutLeg{{Receiver: f.jessica, Amount: 1}, {Receiver: "unknown", Amount: 1}}
+			},
+			wantErr: []error{ErrInvalidPayout, ErrUnknownReceiver},
+		},
+		"frozen-receiver": {
+			legs:    func(f *fixture) []models.PayoutLeg { return []models.PayoutLeg{{Receiver: f.frozen, Amount: 1}} },
+			wantErr: []error{ErrInvalidPayout, service.ErrAccountFrozen},
+		},
+		"sender-as-receiver": {
+			legs:    func(f *fixture) []models.PayoutLeg { return []models.PayoutLeg{{Receiver: f.shankar, Amount: 1}} },
+			wantErr: []error{ErrInvalidPayout, service.ErrSameAccount},
+		},
+		"non-positive-amount": {
+			legs:    func(f *fixture) []models.PayoutLeg { return []models.PayoutLeg{{Receiver: f.jessica, Amount: 0}} },
+			wantErr: []error{ErrInvalidPayout, service.ErrInvalidAmount},
+		},
+		"total-exceeds-balance": {
+			legs: func(f *fixture) []models.PayoutLeg {
+				return []models.PayoutLeg{{Receiver: f.jessica, Amount: 60}, {Receiver: f.jessica, Amount: 60}}
+			},
+			wantErr: []error{service.ErrInsufficentBalance},
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			sender := f.shankar
+			if tcase.sender != nil {
+				sender = tcase.sender(f)
+			}
+
+			_, err := f.processor.Run(context.Background(), models.Payout{Sender: sender, Mode: tcase.mode, Legs: tcase.legs(f)})
+			for _, want := range tcase.wantErr {
+				assert.ErrorIs(t, err, want)
+			}
+			assert.InDelta(t, 100, f.balance(t, f.shankar), 0.001)
+		})
+	}
+}
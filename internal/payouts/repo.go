@@ -0,0 +1,56 @@
+package payouts
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/gopay/internal/models"
+)
+
+var ErrPayoutNotFound = errors.New("payout not found")
+
+// Repo keeps executed payouts so their legs can be looked up later.
+type Repo interface {
+	FindOne(ctx context.Context, id string) (models.Payout, error)
+	Create(ctx context.Context, payout models.Payout) (string, error)
+}
+
+var _ Repo = (*repoImpl)(nil)
+
+type repoImpl struct {
+	mu          sync.RWMutex
+	payouts     map[string]models.Payout
+	idGenerator func() string
+}
+
+func NewRepo() *repoImpl {
+	return &repoImpl{
+		payouts:     make(map[string]models.Payout),
+		idGenerator: uuid.NewString,
+	}
+}
+
+func (r *repoImpl) FindOne(_ context.Context, id string) (models.Payout, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	payout, found := r.payouts[id]
+	if !found {
+		return models.Payout{}, ErrPayoutNotFound
+	}
+
+	return payout, nil
+}
+
+func (r *repoImpl) Create(_ context.Context, payout models.Payout) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.idGenerator()
+	payout.PayoutId = id
+	r.payouts[id] = payout
+
+	return id, nil
+}
@@ -0,0 +1,236 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+// tolerance absorbs float32 rounding when amounts are added up in a
+// different order, anything from half a cent on is a mismatch.
+const tolerance = 0.005
+
+// Observer is told about every finished reconciliation, e.g. to export
+// metrics.
+type Observer interface {
+	Reconciled(report models.Reconciliation)
+}
+
+type noopObserver struct{}
+
+func (noopObserver) Reconciled(models.Reconciliation) {}
+
+// Reconciler checks the ledger invariants, see models.Invariants, over every
+// account and keeps the last report.
+type Reconciler struct {
+	accountRepo     repository.AccountRepo
+	transactionRepo repository.TransactionRepo
+	unitOfWork      repository.UnitOfWork
+	observer        Observer
+	now             func() time.Time
+
+	mu   sync.Mutex
+	last *models.Reconciliation
+}
+
+// NewReconciler returns a Reconciler reporting to observer, which may be
+// nil.
+func NewReconciler(accountRepo repository.AccountRepo, transactionRepo repository.TransactionRepo, unitOfWork repository.UnitOfWork, observer Observer) *Reconciler {
+	if observer == nil {
+		observer = noopObserver{}
+	}
+
+	return &Reconciler{
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		unitOfWork:      unitOfWork,
+		observer:        observer,
+		now:             time.Now,
+	}
+}
+
+// Last returns the report of the last run, false before the first one.
+func (r *Reconciler) Last() (models.Reconciliation, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.last == nil {
+		return models.Reconciliation{}, false
+	}
+	return *r.last, true
+}
+
+// Loop reconciles every interval until ctx is cancelled.
+func (r *Reconciler) Loop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		_, err := r.Run(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("Reconciler::Loop")
+		}
+	}
+}
+
+// Run checks every invariant and stores the report. The pass runs in a unit
+// of work, which waits for the operations in flight and holds off new ones,
+// so it never sees half of a transfer.
+func (r *Reconciler) Run(ctx context.Context) (models.Reconciliation, error) {
+	started := r.now()
+
+	var report models.Reconciliation
+	err := r.unitOfWork.Do(ctx, func(ctx context.Context) (err error) {
+		report, err = r.check(ctx)
+		return err
+	})
+	if err != nil {
+		return models.Reconciliation{}, err
+	}
+
+	report.StartedAt, report.FinishedAt = started, r.now()
+	report.Ok = len(report.Violations) == 0
+	for _, v := range report.Violations {
+		log.Warn().Str("invariant", string(v.Invariant)).Str("accountId", v.AccountId).Str("transactionId", v.TransactionId).
+			Float64("expected", v.Expected).Float64("actual", v.Actual).Msg(v.Message)
+	}
+
+	r.mu.Lock()
+	r.last = &report
+	r.mu.Unlock()
+	r.observer.Reconciled(report)
+
+	return report, nil
+}
+
+// check makes one pass over the ledger.
+func (r *Reconciler) check(ctx context.Context) (models.Reconciliation, error) {
+	accounts, err := r.accountRepo.FindAll(ctx)
+	if err != nil {
+		return models.Reconciliation{}, err
+	}
+
+	report := models.Reconciliation{Accounts: len(accounts), Violations: []models.Violation{}}
+	violate := func(v models.Violation) {
+		report.Violations = append(report.Violations, v)
+	}
+
+	var deposits, withdrawals, balances float64
+	for _, acc := range accounts {
+		id := acc.AccountId
+
+		transactions, err := r.transactionRepo.FindAll(ctx, id)
+		if err != nil {
+			return models.Reconciliation{}, err
+		}
+		report.Transactions += len(transactions)
+
+		var unconsumed, consumedCredits, debits, remainders float64
+		for _, t := range transactions {
+			amount := float64(t.Amount)
+
+			// pending and failed transactions never moved money, reversed
+			// ones gave it back
+			if !t.Posted() {
+				continue
+			}
+
+			if !t.IsConsumed {
+				unconsumed += amount
+			}
+
+			switch {
+			case t.Type == models.TransactionRemainder:
+				remainders += amount
+			case amount < 0:
+				debits -= amount
+			}
+
+			switch {
+			case amount > 0 && t.IsConsumed:
+				consumedCredits += amount
+			case amount < 0 && !t.IsConsumed:
+				violate(models.Violation{
+					Invariant:     models.InvariantConsumedExplained,
+					AccountId:     id,
+					TransactionId: t.TransactionId,
+					Actual:        amount,
+					Message:       "debit is not consumed, it still counts towards the balance",
+				})
+			}
+
+			// transfers move money between accounts, only deposits and
+			// withdrawals change the total
+			switch t.Type {
+			case models.TransactionDeposit:
+				deposits += amount
+			case models.TransactionWithdrawal:
+				withdrawals -= amount
+			}
+		}
+
+		balance, err := r.transactionRepo.GetBalance(ctx, id)
+		if err != nil && !errors.Is(err, repository.ErrNegativeBalance) {
+			return models.Reconciliation{}, err
+		}
+		balances += balance.Amount
+
+		if !equal(balance.Amount, unconsumed) {
+			violate(models.Violation{
+				Invariant: models.InvariantBalance,
+				AccountId: id,
+				Expected:  unconsumed,
+				Actual:    balance.Amount,
+				Message:   "balance differs from the sum of unconsumed transactions",
+			})
+		}
+
+		if balance.Amount <= -tolerance {
+			violate(models.Violation{
+				Invariant: models.InvariantNonNegative,
+				AccountId: id,
+				Actual:    balance.Amount,
+				Message:   "balance is negative",
+			})
+		}
+
+		if !equal(consumedCredits, debits+remainders) {
+			violate(models.Violation{
+				Invariant: models.InvariantConsumedExplained,
+				AccountId: id,
+				Expected:  debits + remainders,
+				Actual:    consumedCredits,
+				Message: fmt.Sprintf("consumed credits don't add up to withdrawals and outgoing transfers (%.2f) plus remainders (%.2f)",
+					debits, remainders),
+			})
+		}
+	}
+
+	if !equal(deposits-withdrawals, balances) {
+		violate(models.Violation{
+			Invariant: models.InvariantTotals,
+			Expected:  deposits - withdrawals,
+			Actual:    balances,
+			Message:   fmt.Sprintf("deposits (%.2f) minus withdrawals (%.2f) differ from the sum of balances", deposits, withdrawals),
+		})
+	}
+
+	return report, nil
+}
+
+func equal(a float64, b float64) bool {
+	return math.Abs(a-b) < tolerance
+}
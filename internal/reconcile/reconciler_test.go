@@ -0,0 +1,179 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// skewedRepo adds 1 to the balance of account for the next skewed
+// GetBalance calls.
+type skewedRepo struct {
+	repository.TransactionRepo
+	account string
+	skewed  int
+}
+
+func (r *skewedRepo) GetBalance(ctx context.Context, id string) (models.Balance, error) {
+	balance, err := r.TransactionRepo.GetBalance(ctx, id)
+	if id == r.account && r.skewed > 0 {
+		r.skewed--
+		balance.Amount++
+	}
+	return balance, err
+}
+
+type observer struct {
+	reports []models.Reconciliation
+}
+
+func (o *observer) Reconciled(report models.Reconciliation) {
+	o.reports = append(o.reports, report)
+}
+
+type fixture struct {
+	accounts     repository.AccountRepo
+	transactions *skewedRepo
+	unitOfWork   repository.UnitOfWork
+	observer     *observer
+	reconciler   *Reconciler
+	shankar      string
+	jessica      string
+}
+
+// newFixture has a healthy ledger: deposits, a withdrawal and transfers
+// with remainders.
+func newFixture(t *testing.T) *fixture {
+	ctx := context.Background()
+	transactions := &skewedRepo{TransactionRepo: repository.NewTransactionRepo()}
+	bank := testutil.NewBankWith(t, repository.NewAccountRepo(), transactions)
+	svc := bank.Service
+
+	f := &fixture{
+		accounts:     bank.Accounts,
+		transactions: transactions,
+		unitOfWork:   bank.UnitOfWork,
+		observer:     &observer{},
+		shankar:      bank.Account(t, "Shankar", "Nakai"),
+		jessica:      bank.Account(t, "Jessica", "Lourenco"),
+	}
+
+	bank.Deposit(t, 100, f.shankar)
+	bank.Deposit(t, 50.25, f.shankar)
+	require.NoError(t, svc.Withdraw(ctx, f.shankar, -30, service.Note{}))
+	require.NoError(t, svc.Transfer(ctx, f.shankar, f.jessica, -99.99, service.Note{}))
+	require.NoError(t, svc.Transfer(ctx, f.jessica, f.shankar, -9.99, service.Note{}))
+
+	f.reconciler = NewReconciler(bank.Accounts, transactions, bank.UnitOfWork, f.observer)
+	return f
+}
+
+func TestReconciler_Run(t *testing.T) {
+	scenarios := map[string]struct {
+		corrupt        func(t *testing.T, f *fixture)
+		wantInvariants []models.Invariant
+	}{
+		"healthy": {
+			corrupt: func(t *testing.T, f *fixture) {},
+		},
+		"unexplained-consumption": {
+			corrupt: func(t *testing.T, f *fixture) {
+				id, err := f.transactions.Create(context.Background(), models.Transaction{Owner: f.jessica, Sender: f.jessica, Receiver: f.jessica, Amount: 10})
+				require.NoError(t, err)
+				require.NoError(t, f.transactions.MarkAsConsumed(context.Background(), id))
+			},
+			wantInvariants: []models.Invariant{models.InvariantConsumedExplained, models.InvariantTotals},
+		},
+		"unconsumed-debit": {
+			corrupt: func(t *testing.T, f *fixture) {
+				_, err := f.transactions.Create(context.Background(), models.Transaction{Owner: f.jessica, Sender: f.jessica, Receiver: f.jessica, Amount: -500})
+				require.NoError(t, err)
+			},
+			wantInvariants: []models.Invariant{models.InvariantConsumedExplained, models.InvariantNonNegative, models.InvariantConsumedExplained},
+		},
+		"reversed-deposit": {
+			corrupt: func(t *testing.T, f *fixture) {
+				id, err := f.transactions.Create(context.Background(), models.Transaction{Owner: f.jessica, Sender: f.jessica, Receiver: f.jessica, Amount: 10})
+				require.NoError(t, err)
+				require.NoError(t, f.transactions.UpdateStatus(context.Background(), id, models.TransactionReversed))
+			},
+		},
+		"balance-mismatch": {
+			corrupt: func(t *testing.T, f *fixture) {
+				f.transactions.account, f.transactions.skewed = f.shankar, 1
+			},
+			wantInvariants: []models.Invariant{models.InvariantBalance, models.InvariantTotals},
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			tcase.corrupt(t, f)
+
+			_, found := f.reconciler.Last()
+			assert.False(t, found)
+
+			report, err := f.reconciler.Run(context.Background())
+			require.NoError(t, err)
+
+			invariants := []models.Invariant{}
+			for _, v := range report.Violations {
+				invariants = append(invariants, v.Invariant)
+				assert.NotEmpty(t, v.Message)
+			}
+			assert.ElementsMatch(t, tcase.wantInvariants, invariants, "%+v", report.Violations)
+			assert.Equal(t, len(tcase.wantInvariants) == 0, report.Ok)
+			assert.Equal(t, 2, report.Accounts)
+
+			last, found := f.reconciler.Last()
+			assert.True(t, found)
+			assert.Equal(t, report, last)
+			assert.Equal(t, []models.Reconciliation{report}, f.observer.reports)
+		})
+	}
+}
+
+func TestReconciler_RunWaitsForUnitsOfWork(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	// a unit of work that leaves the ledger inconsistent until it's undone
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		_ = f.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			_, err := f.transactions.Create(ctx, models.Transaction{Owner: f.jessica, Sender: f.jessica, Receiver: f.jessica, Amount: -500})
+			require.NoError(t, err)
+			close(started)
+			<-release
+			return errors.New("abort")
+		})
+	}()
+	<-started
+
+	done := make(chan models.Reconciliation)
+	go func() {
+		report, err := f.reconciler.Run(ctx)
+		assert.NoError(t, err)
+		done <- report
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("reconciled while a unit of work was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	report := <-done
+	assert.True(t, report.Ok, "%+v", report.Violations)
+}
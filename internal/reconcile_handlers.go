@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/reconcile"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+var ErrNotReconciled = errors.New("no reconciliation has run yet")
+
+type ReconcileHandler struct {
+	reconciler *reconcile.Reconciler
+}
+
+func NewReconcileHandler(reconciler *reconcile.Reconciler) *ReconcileHandler {
+	return &ReconcileHandler{
+		reconciler: reconciler,
+	}
+}
+
+func (h *ReconcileHandler) GetReconciliation(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	report, found := h.reconciler.Last()
+	if !found {
+		log.Ctx(r.Context()).Error().Err(ErrNotReconciled).Msg("Handler::GetReconciliation")
+		utils.ErrorWithMessage(w, http.StatusNotFound, ErrNotReconciled.Error())
+		return
+	}
+
+	h.respond(w, r, "Handler::GetReconciliation", report)
+}
+
+func (h *ReconcileHandler) PostReconciliation(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	report, err := h.reconciler.Run(r.Context())
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostReconciliation")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respond(w, r, "Handler::PostReconciliation", report)
+}
+
+func (h *ReconcileHandler) respond(w http.ResponseWriter, r *http.Request, name string, report models.Reconciliation) {
+	res, err := jsoniter.Marshal(&report)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(name)
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusOK, res)
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"sync"
 
 	"github.com/gopay/internal/models"
 	"github.com/gopay/internal/utils"
@@ -16,12 +17,15 @@ var (
 type AccountRepo interface {
 	FindAll(ctx context.Context) ([]models.Account, error)
 	FindOne(ctx context.Context, id string) (models.Account, error)
+	FindMany(ctx context.Context, ids []string) ([]models.Account, error)
 	Create(ctx context.Context, name string, lastname string) (string, error)
+	SetFrozen(ctx context.Context, id string, frozen bool) (models.Account, error)
 }
 
 var _ AccountRepo = (*accountRepoImpl)(nil)
 
 type accountRepoImpl struct {
+	mu          sync.RWMutex
 	accounts    map[string]models.Account
 	idGenerator func() string
 }
@@ -36,6 +40,9 @@ func NewAccountRepo() *accountRepoImpl {
 func (r *accountRepoImpl) FindAll(_ context.Context) ([]models.Account, error) {
 	accs := []models.Account{}
 
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	for _, account := range r.accounts {
 		accs = append(accs, account)
 	}
@@ -44,6 +51,9 @@ func (r *accountRepoImpl) FindAll(_ context.Context) ([]models.Account, error) {
 }
 
 func (r *accountRepoImpl) FindOne(_ context.Context, id string) (models.Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	account, found := r.accounts[id]
 
 	if !found {
@@ -53,11 +63,31 @@ func (r *accountRepoImpl) FindOne(_ context.Context, id string) (models.Account,
 	return account, nil
 }
 
-func (r *accountRepoImpl) Create(_ context.Context, name string, lastname string) (string, error) {
+// FindMany returns the accounts with the given ids in one lookup, skipping
+// the ids that don't exist.
+func (r *accountRepoImpl) FindMany(_ context.Context, ids []string) ([]models.Account, error) {
+	accs := []models.Account{}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, id := range ids {
+		if account, found := r.accounts[id]; found {
+			accs = append(accs, account)
+		}
+	}
+
+	return accs, nil
+}
+
+func (r *accountRepoImpl) Create(ctx context.Context, name string, lastname string) (string, error) {
 	if name == "" || lastname == "" {
 		return "", ErrMissingParams
 	}
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	id := r.idGenerator()
 
 	acc := models.Account{
@@ -66,6 +96,32 @@ func (r *accountRepoImpl) Create(_ context.Context, name string, lastname string
 		LastName:  lastname,
 	}
 	r.accounts[id] = acc
+	recordUndo(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.accounts, id)
+	})
 
 	return id, nil
 }
+
+func (r *accountRepoImpl) SetFrozen(ctx context.Context, id string, frozen bool) (models.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, found := r.accounts[id]
+	if !found {
+		return models.Account{}, ErrAccountNotFound
+	}
+
+	previous := account
+	account.Frozen = frozen
+	r.accounts[id] = account
+	recordUndo(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.accounts[id] = previous
+	})
+
+	return account, nil
+}
@@ -1,4 +1,4 @@
-// Code generated by mockery v2.43.2. DO NOT EDIT.
+// Code generated by mockery v2.53.7. DO NOT EDIT.
 
 package repository
 
@@ -138,6 +138,65 @@ func (_c *MockAccountRepo_FindAll_Call) RunAndReturn(run func(context.Context) (
 	return _c
 }
 
+// FindMany provides a mock function with given fields: ctx, ids
+func (_m *MockAccountRepo) FindMany(ctx context.Context, ids []string) ([]models.Account, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindMany")
+	}
+
+	var r0 []models.Account
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]models.Account, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []models.Account); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Account)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockAccountRepo_FindMany_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindMany'
+type MockAccountRepo_FindMany_Call struct {
+	*mock.Call
+}
+
+// FindMany is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ids []string
+func (_e *MockAccountRepo_Expecter) FindMany(ctx interface{}, ids interface{}) *MockAccountRepo_FindMany_Call {
+	return &MockAccountRepo_FindMany_Call{Call: _e.mock.On("FindMany", ctx, ids)}
+}
+
+func (_c *MockAccountRepo_FindMany_Call) Run(run func(ctx context.Context, ids []string)) *MockAccountRepo_FindMany_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]string))
+	})
+	return _c
+}
+
+func (_c *MockAccountRepo_FindMany_Call) Return(_a0 []models.Account, _a1 error) *MockAccountRepo_FindMany_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockAccountRepo_FindMany_Call) RunAndReturn(run func(context.Context, []string) ([]models.Account, error)) *MockAccountRepo_FindMany_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // FindOne provides a mock function with given fields: ctx, id
 func (_m *MockAccountRepo) FindOne(ctx context.Context, id string) (models.Account, error) {
 	ret := _m.Called(ctx, id)
@@ -195,6 +254,64 @@ func (_c *MockAccountRepo_FindOne_Call) RunAndReturn(run func(context.Context, s
 	return _c
 }
 
+// SetFrozen provides a mock function with given fields: ctx, id, frozen
+func (_m *MockAccountRepo) SetFrozen(ctx context.Context, id string, frozen bool) (models.Account, error) {
+	ret := _m.Called(ctx, id, frozen)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetFrozen")
+	}
+
+	var r0 models.Account
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) (models.Account, error)); ok {
+		return rf(ctx, id, frozen)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) models.Account); ok {
+		r0 = rf(ctx, id, frozen)
+	} else {
+		r0 = ret.Get(0).(models.Account)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool) error); ok {
+		r1 = rf(ctx, id, frozen)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockAccountRepo_SetFrozen_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetFrozen'
+type MockAccountRepo_SetFrozen_Call struct {
+	*mock.Call
+}
+
+// SetFrozen is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - frozen bool
+func (_e *MockAccountRepo_Expecter) SetFrozen(ctx interface{}, id interface{}, frozen interface{}) *MockAccountRepo_SetFrozen_Call {
+	return &MockAccountRepo_SetFrozen_Call{Call: _e.mock.On("SetFrozen", ctx, id, frozen)}
+}
+
+func (_c *MockAccountRepo_SetFrozen_Call) Run(run func(ctx context.Context, id string, frozen bool)) *MockAccountRepo_SetFrozen_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(bool))
+	})
+	return _c
+}
+
+func (_c *MockAccountRepo_SetFrozen_Call) Return(_a0 models.Account, _a1 error) *MockAccountRepo_SetFrozen_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockAccountRepo_SetFrozen_Call) RunAndReturn(run func(context.Context, string, bool) (models.Account, error)) *MockAccountRepo_SetFrozen_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockAccountRepo creates a new instance of MockAccountRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockAccountRepo(t interface {
@@ -285,6 +285,109 @@ func TestAccount_FindOne(t *testing.T) {
 
 }
 
+func TestAccount_FindMany(t *testing.T) {
+	data := map[string]models.Account{
+		"0001": {
+			AccountId: "0001",
+			Name:      "Shankar",
+			LastName:  "Nakai",
+		},
+		"0002": {
+			AccountId: "0002",
+			Name:      "Jessica",
+			LastName:  "Lourenco",
+		},
+	}
+
+	type args struct {
+		ctx context.Context
+		ids []string
+	}
+
+	var scenarios = map[string]struct {
+		given args
+		want  []models.Account
+	}{
+		"happy-path": {
+			given: args{
+				ctx: context.Background(),
+				ids: []string{"0002", "0001"},
+			},
+			want: []models.Account{data["0002"], data["0001"]},
+		},
+		"skips-unknown-ids": {
+			given: args{
+				ctx: context.Background(),
+				ids: []string{"0001", "0009"},
+			},
+			want: []models.Account{data["0001"]},
+		},
+		"no-ids": {
+			given: args{
+				ctx: context.Background(),
+				ids: nil,
+			},
+			want: []models.Account{},
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			repo := setup(t, data, nil)
+
+			result, err := repo.FindMany(tcase.given.ctx, tcase.given.ids)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tcase.want, result)
+		})
+	}
+}
+
+func TestAccount_SetFrozen(t *testing.T) {
+	type args struct {
+		id     string
+		frozen bool
+	}
+
+	var scenarios = map[string]struct {
+		given   args
+		want    models.Account
+		wantErr error
+	}{
+		"freeze": {
+			given: args{id: "0001", frozen: true},
+			want:  models.Account{AccountId: "0001", Name: "Shankar", LastName: "Nakai", Frozen: true},
+		},
+		"unfreeze": {
+			given: args{id: "0001", frozen: false},
+			want:  models.Account{AccountId: "0001", Name: "Shankar", LastName: "Nakai"},
+		},
+		"unknown-account": {
+			given:   args{id: "0009", frozen: true},
+			wantErr: ErrAccountNotFound,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			repo := setup(t, map[string]models.Account{
+				"0001": {AccountId: "0001", Name: "Shankar", LastName: "Nakai", Frozen: !tcase.given.frozen},
+			}, nil)
+
+			result, err := repo.SetFrozen(context.Background(), tcase.given.id, tcase.given.frozen)
+
+			assert.ErrorIs(t, err, tcase.wantErr)
+			assert.Equal(t, tcase.want, result)
+			if err == nil {
+				stored, _ := repo.FindOne(context.Background(), tcase.given.id)
+				assert.Equal(t, tcase.want, stored)
+			}
+		})
+	}
+}
+
 func setup(_ *testing.T, initialData map[string]models.Account, idGenerator func() string) *accountRepoImpl {
 	repo := NewAccountRepo()
 	repo.accounts = initialData
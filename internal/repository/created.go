@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gopay/internal/models"
+)
+
+type createdKey struct{}
+
+// createdLog collects the transactions written under a TrackCreated context.
+type createdLog struct {
+	mu           sync.Mutex
+	transactions []models.Transaction
+}
+
+// TrackCreated returns a context under which TransactionRepo.Create records
+// every transaction it writes, and a function listing them, oldest first.
+// It lets callers of the service learn the ids of what an operation created.
+func TrackCreated(ctx context.Context) (context.Context, func() []models.Transaction) {
+	l := &createdLog{}
+
+	return context.WithValue(ctx, createdKey{}, l), func() []models.Transaction {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return append([]models.Transaction{}, l.transactions...)
+	}
+}
+
+// recordCreated adds t, with its id, to the log of ctx if there is one.
+func recordCreated(ctx context.Context, t models.Transaction) {
+	l, ok := ctx.Value(createdKey{}).(*createdLog)
+	if !ok {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.transactions = append(l.transactions, t)
+}
+
+// CreatedIds runs fn under a TrackCreated context and returns the ids of the
+// transactions it created, oldest first. Remainders are bookkeeping of a
+// debit and aren't listed.
+func CreatedIds(ctx context.Context, fn func(ctx context.Context) error) ([]string, error) {
+	ctx, created := TrackCreated(ctx)
+	if err := fn(ctx); err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, t := range created() {
+		if t.Type != models.TransactionRemainder {
+			ids = append(ids, t.TransactionId)
+		}
+	}
+	return ids, nil
+}
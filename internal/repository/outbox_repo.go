@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/gopay/internal/models"
+)
+
+var (
+	ErrOutboxEntryNotFound = errors.New("outbox entry not found")
+	ErrDuplicateEvent      = errors.New("event already in outbox")
+)
+
+// OutboxRepo stores domain events until the relay has published them. Entries
+// are keyed by event id, which consumers use to de-duplicate redeliveries.
+// Updating an entry to published removes it, nothing reads it afterwards.
+type OutboxRepo interface {
+	Append(ctx context.Context, events ...models.Event) error
+	FindPending(ctx context.Context, limit int) ([]models.OutboxEntry, error)
+	Update(ctx context.Context, entry models.OutboxEntry) error
+}
+
+var _ OutboxRepo = (*outboxRepoImpl)(nil)
+
+type outboxRepoImpl struct {
+	mu      sync.RWMutex
+	entries map[string]models.OutboxEntry
+	// seq orders entries the way they were appended, CreatedAt can tie
+	seq uint64
+}
+
+func NewOutboxRepo() *outboxRepoImpl {
+	return &outboxRepoImpl{
+		entries: make(map[string]models.OutboxEntry),
+	}
+}
+
+func (r *outboxRepoImpl) Append(ctx context.Context, events ...models.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range events {
+		if _, found := r.entries[e.EventId]; found {
+			return ErrDuplicateEvent
+		}
+	}
+
+	for _, e := range events {
+		r.seq++
+		r.entries[e.EventId] = models.OutboxEntry{
+			EntryId:   e.EventId,
+			Sequence:  r.seq,
+			Event:     e,
+			CreatedAt: e.CreatedAt,
+		}
+	}
+
+	recordUndo(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, e := range events {
+			delete(r.entries, e.EventId)
+		}
+	})
+
+	return nil
+}
+
+func (r *outboxRepoImpl) FindPending(_ context.Context, limit int) ([]models.OutboxEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pending := []models.OutboxEntry{}
+	for _, e := range r.entries {
+		if e.PublishedAt == nil {
+			pending = append(pending, e)
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Sequence < pending[j].Sequence
+	})
+
+	if limit > 0 && len(pending) > limit {
+		pending = pending[:limit]
+	}
+
+	return pending, nil
+}
+
+func (r *outboxRepoImpl) Update(_ context.Context, entry models.OutboxEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.entries[entry.EntryId]; !found {
+		return ErrOutboxEntryNotFound
+	}
+
+	if entry.PublishedAt != nil {
+		delete(r.entries, entry.EntryId)
+		return nil
+	}
+
+	r.entries[entry.EntryId] = entry
+
+	return nil
+}
@@ -0,0 +1,204 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package repository
+
+import (
+	context "context"
+
+	models "github.com/gopay/internal/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockOutboxRepo is an autogenerated mock type for the OutboxRepo type
+type MockOutboxRepo struct {
+	mock.Mock
+}
+
+type MockOutboxRepo_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockOutboxRepo) EXPECT() *MockOutboxRepo_Expecter {
+	return &MockOutboxRepo_Expecter{mock: &_m.Mock}
+}
+
+// Append provides a mock function with given fields: ctx, events
+func (_m *MockOutboxRepo) Append(ctx context.Context, events ...models.Event) error {
+	_va := make([]interface{}, len(events))
+	for _i := range events {
+		_va[_i] = events[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Append")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, ...models.Event) error); ok {
+		r0 = rf(ctx, events...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockOutboxRepo_Append_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Append'
+type MockOutboxRepo_Append_Call struct {
+	*mock.Call
+}
+
+// Append is a helper method to define mock.On call
+//   - ctx context.Context
+//   - events ...models.Event
+func (_e *MockOutboxRepo_Expecter) Append(ctx interface{}, events ...interface{}) *MockOutboxRepo_Append_Call {
+	return &MockOutboxRepo_Append_Call{Call: _e.mock.On("Append",
+		append([]interface{}{ctx}, events...)...)}
+}
+
+func (_c *MockOutboxRepo_Append_Call) Run(run func(ctx context.Context, events ...models.Event)) *MockOutboxRepo_Append_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]models.Event, len(args)-1)
+		for i, a := range args[1:] {
+			if a != nil {
+				variadicArgs[i] = a.(models.Event)
+			}
+		}
+		run(args[0].(context.Context), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockOutboxRepo_Append_Call) Return(_a0 error) *MockOutboxRepo_Append_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockOutboxRepo_Append_Call) RunAndReturn(run func(context.Context, ...models.Event) error) *MockOutboxRepo_Append_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FindPending provides a mock function with given fields: ctx, limit
+func (_m *MockOutboxRepo) FindPending(ctx context.Context, limit int) ([]models.OutboxEntry, error) {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindPending")
+	}
+
+	var r0 []models.OutboxEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]models.OutboxEntry, error)); ok {
+		return rf(ctx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.OutboxEntry); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.OutboxEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockOutboxRepo_FindPending_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindPending'
+type MockOutboxRepo_FindPending_Call struct {
+	*mock.Call
+}
+
+// FindPending is a helper method to define mock.On call
+//   - ctx context.Context
+//   - limit int
+func (_e *MockOutboxRepo_Expecter) FindPending(ctx interface{}, limit interface{}) *MockOutboxRepo_FindPending_Call {
+	return &MockOutboxRepo_FindPending_Call{Call: _e.mock.On("FindPending", ctx, limit)}
+}
+
+func (_c *MockOutboxRepo_FindPending_Call) Run(run func(ctx context.Context, limit int)) *MockOutboxRepo_FindPending_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *MockOutboxRepo_FindPending_Call) Return(_a0 []models.OutboxEntry, _a1 error) *MockOutboxRepo_FindPending_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockOutboxRepo_FindPending_Call) RunAndReturn(run func(context.Context, int) ([]models.OutboxEntry, error)) *MockOutboxRepo_FindPending_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Update provides a mock function with given fields: ctx, entry
+func (_m *MockOutboxRepo) Update(ctx context.Context, entry models.OutboxEntry) error {
+	ret := _m.Called(ctx, entry)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.OutboxEntry) error); ok {
+		r0 = rf(ctx, entry)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockOutboxRepo_Update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Update'
+type MockOutboxRepo_Update_Call struct {
+	*mock.Call
+}
+
+// Update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - entry models.OutboxEntry
+func (_e *MockOutboxRepo_Expecter) Update(ctx interface{}, entry interface{}) *MockOutboxRepo_Update_Call {
+	return &MockOutboxRepo_Update_Call{Call: _e.mock.On("Update", ctx, entry)}
+}
+
+func (_c *MockOutboxRepo_Update_Call) Run(run func(ctx context.Context, entry models.OutboxEntry)) *MockOutboxRepo_Update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.OutboxEntry))
+	})
+	return _c
+}
+
+func (_c *MockOutboxRepo_Update_Call) Return(_a0 error) *MockOutboxRepo_Update_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockOutboxRepo_Update_Call) RunAndReturn(run func(context.Context, models.OutboxEntry) error) *MockOutboxRepo_Update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockOutboxRepo creates a new instance of MockOutboxRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockOutboxRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockOutboxRepo {
+	mock := &MockOutboxRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox_Update(t *testing.T) {
+	published := time.Now()
+
+	scenarios := map[string]struct {
+		update      models.OutboxEntry
+		wantErr     error
+		wantPending []string
+		wantStored  int
+	}{
+		"keeps-failed-attempt": {
+			update:      models.OutboxEntry{EntryId: "e1", Attempts: 1, LastError: "bus: down"},
+			wantPending: []string{"e1", "e2"},
+			wantStored:  2,
+		},
+		"prunes-published": {
+			update:      models.OutboxEntry{EntryId: "e1", Attempts: 1, PublishedAt: &published},
+			wantPending: []string{"e2"},
+			wantStored:  1,
+		},
+		"unknown-entry": {
+			update:      models.OutboxEntry{EntryId: "e3"},
+			wantErr:     ErrOutboxEntryNotFound,
+			wantPending: []string{"e1", "e2"},
+			wantStored:  2,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			outbox := NewOutboxRepo()
+			require.NoError(t, outbox.Append(ctx,
+				models.Event{EventId: "e1", CreatedAt: published},
+				models.Event{EventId: "e2", CreatedAt: published.Add(time.Second)},
+			))
+
+			err := outbox.Update(ctx, tcase.update)
+			if tcase.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tcase.wantErr)
+			}
+
+			pending, err := outbox.FindPending(ctx, 0)
+			require.NoError(t, err)
+			ids := []string{}
+			for _, e := range pending {
+				ids = append(ids, e.EntryId)
+			}
+			assert.Equal(t, tcase.wantPending, ids)
+			assert.Len(t, outbox.entries, tcase.wantStored)
+		})
+	}
+}
+
+func TestOutbox_FindPending(t *testing.T) {
+	createdAt := time.Now()
+
+	scenarios := map[string]struct {
+		limit int
+		want  []string
+	}{
+		"all-in-append-order": {
+			limit: 0,
+			want:  []string{"e5", "e3", "e1", "e4", "e2"},
+		},
+		"limited": {
+			limit: 2,
+			want:  []string{"e5", "e3"},
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			outbox := NewOutboxRepo()
+			// same CreatedAt, only the append order tells them apart
+			for _, batch := range [][]string{{"e5", "e3"}, {"e1"}, {"e4", "e2"}} {
+				events := []models.Event{}
+				for _, id := range batch {
+					events = append(events, models.Event{EventId: id, CreatedAt: createdAt})
+				}
+				require.NoError(t, outbox.Append(ctx, events...))
+			}
+
+			for i := 0; i < 10; i++ {
+				pending, err := outbox.FindPending(ctx, tcase.limit)
+				require.NoError(t, err)
+				ids := []string{}
+				for _, e := range pending {
+					ids = append(ids, e.EntryId)
+				}
+				assert.Equal(t, tcase.want, ids)
+			}
+		})
+	}
+}
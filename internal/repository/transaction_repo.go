@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"sync"
 
 	"github.com/gopay/internal/models"
 	"github.com/gopay/internal/utils"
@@ -19,20 +20,33 @@ var (
 	ErrMissingOwnerField    = fmt.Errorf("owner: %w", ErrMissingFields)
 	ErrZeroAmount           = errors.New("transaction amount cannot be zero")
 	ErrNegativeBalance      = errors.New("negative balance")
+	ErrInvalidType          = errors.New("unknown transaction type")
+	ErrInvalidStatus        = errors.New("unknown transaction status")
+	ErrInvalidTransition    = errors.New("transaction status can't change that way")
+	ErrConsumed             = errors.New("transaction is consumed")
+	ErrInvalidCategory      = errors.New("unknown category")
 )
 
 type TransactionRepo interface {
 	FindAll(ctx context.Context, accId string) ([]models.Transaction, error)
 	FindOne(ctx context.Context, id string) (models.Transaction, error)
-	Create(ctx context.Context, transaction models.Transaction) error
+	Create(ctx context.Context, transaction models.Transaction) (string, error)
 	MarkAsConsumed(ctx context.Context, id string) error
+	// SetConsumedBy records the withdrawal or transfer that consumed ids.
+	SetConsumedBy(ctx context.Context, ids []string, consumedBy string) error
+	// UpdateStatus moves a transaction to status, see
+	// models.TransactionStatus.CanBecome.
+	UpdateStatus(ctx context.Context, id string, status models.TransactionStatus) error
+	// SetCategory sets the category of a transaction, or clears it when
+	// category is empty.
+	SetCategory(ctx context.Context, id string, category string) error
 	GetBalance(ctx context.Context, id string) (models.Balance, error)
-	RollBackConsumed(ctx context.Context, tConsumed []string) error
 }
 
 var _ TransactionRepo = (*transactionRepoImpl)(nil)
 
 type transactionRepoImpl struct {
+	mu           sync.RWMutex
 	transactions map[string]models.Transaction
 	idGenerator  func() string
 }
@@ -50,8 +64,11 @@ func (r *transactionRepoImpl) GetBalance(ctx context.Context, id string) (models
 		Amount:    0.0,
 	}
 
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	for _, t := range r.transactions {
-		if t.Owner == id && !t.IsConsumed {
+		if t.Owner == id && t.Available() {
 			balance.Amount += float64(t.Amount)
 		}
 	}
@@ -66,6 +83,9 @@ func (r *transactionRepoImpl) GetBalance(ctx context.Context, id string) (models
 func (r *transactionRepoImpl) FindAll(_ context.Context, accId string) ([]models.Transaction, error) {
 	transactions := []models.Transaction{}
 
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	for _, t := range r.transactions {
 		if t.Owner == accId {
 			transactions = append(transactions, t)
@@ -80,6 +100,9 @@ func (r *transactionRepoImpl) FindAll(_ context.Context, accId string) ([]models
 }
 
 func (r *transactionRepoImpl) FindOne(_ context.Context, id string) (models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	transaction, found := r.transactions[id]
 
 	if !found {
@@ -89,51 +112,195 @@ func (r *transactionRepoImpl) FindOne(_ context.Context, id string) (models.Tran
 	return transaction, nil
 }
 
-func (r *transactionRepoImpl) Create(_ context.Context, transaction models.Transaction) error {
+func (r *transactionRepoImpl) Create(ctx context.Context, transaction models.Transaction) (string, error) {
 	if transaction.Sender == "" {
-		return ErrMissingSenderField
+		return "", ErrMissingSenderField
 	}
 	if transaction.Receiver == "" {
-		return ErrMissingReceiverField
+		return "", ErrMissingReceiverField
 	}
 
 	if transaction.Owner == "" {
-		return ErrMissingOwnerField
+		return "", ErrMissingOwnerField
 	}
 
 	if transaction.Amount == 0 {
-		return ErrZeroAmount
+		return "", ErrZeroAmount
+	}
+
+	if transaction.Type == "" {
+		transaction.Type = models.InferType(transaction)
+	}
+	if !transaction.Type.Valid() {
+		return "", fmt.Errorf("%w %q", ErrInvalidType, transaction.Type)
+	}
+	transaction.Remainder = transaction.Type == models.TransactionRemainder
+
+	// writes outside an operation, e.g. fixtures, move the money right away
+	if transaction.Status == "" {
+		transaction.Status = models.TransactionPosted
+	}
+	if !transaction.Status.Valid() {
+		return "", fmt.Errorf("%w %q", ErrInvalidStatus, transaction.Status)
+	}
+
+	if transaction.Category != "" && !models.ValidCategory(transaction.Category) {
+		return "", fmt.Errorf("%w %q", ErrInvalidCategory, transaction.Category)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parent, found := r.transactions[transaction.ParentId]
+	if transaction.ParentId != "" && !found {
+		return "", fmt.Errorf("parent %s: %w", transaction.ParentId, ErrTransactionNotFound)
 	}
 
 	id := r.idGenerator()
 	transaction.TransactionId = id
+	transaction.ChildIds = nil
 
 	r.transactions[id] = transaction
+	if found {
+		parent.ChildIds = append(append([]string{}, parent.ChildIds...), id)
+		r.transactions[parent.TransactionId] = parent
+	}
+	recordCreated(ctx, transaction)
+	recordUndo(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.transactions, id)
 
-	return nil
+		if parent, found := r.transactions[transaction.ParentId]; found {
+			children := []string{}
+			for _, child := range parent.ChildIds {
+				if child != id {
+					children = append(children, child)
+				}
+			}
+			parent.ChildIds = children
+			r.transactions[parent.TransactionId] = parent
+		}
+	})
+
+	return id, nil
 }
 
 func (r *transactionRepoImpl) MarkAsConsumed(ctx context.Context, id string) error {
-	transaction, err := r.FindOne(ctx, id)
-	if err != nil {
-		return err
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transaction, found := r.transactions[id]
+	if !found {
+		return ErrTransactionNotFound
 	}
 
+	r.recordRestore(ctx, []string{id})
 	transaction.IsConsumed = true
 	r.transactions[id] = transaction
 
 	return nil
 }
 
-func (r *transactionRepoImpl) RollBackConsumed(ctx context.Context, tConsumed []string) error {
-	for _, tid := range tConsumed {
-		t, exists := r.transactions[tid]
-		if !exists {
-			return fmt.Errorf("transaction id %s: %w", tid, ErrTransactionNotFound)
+func (r *transactionRepoImpl) SetConsumedBy(ctx context.Context, ids []string, consumedBy string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range ids {
+		if _, exists := r.transactions[id]; !exists {
+			return fmt.Errorf("transaction id %s: %w", id, ErrTransactionNotFound)
 		}
-		t.IsConsumed = false
-		r.transactions[tid] = t
 	}
 
+	r.recordRestore(ctx, ids)
+	for _, id := range ids {
+		t := r.transactions[id]
+		t.ConsumedBy = consumedBy
+		r.transactions[id] = t
+	}
+
+	return nil
+}
+
+func (r *transactionRepoImpl) UpdateStatus(ctx context.Context, id string, status models.TransactionStatus) error {
+	if !status.Valid() {
+		return fmt.Errorf("%w %q", ErrInvalidStatus, status)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, found := r.transactions[id]
+	if !found {
+		return ErrTransactionNotFound
+	}
+
+	if !t.Status.CanBecome(status) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, t.Status, status)
+	}
+	// the money of a consumed transaction has moved on to what consumed it
+	if status == models.TransactionReversed && t.IsConsumed {
+		return ErrConsumed
+	}
+
+	was := t.Status
+	recordUndo(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		t := r.transactions[id]
+		t.Status = was
+		r.transactions[id] = t
+	})
+	t.Status = status
+	r.transactions[id] = t
+
+	return nil
+}
+
+func (r *transactionRepoImpl) SetCategory(ctx context.Context, id string, category string) error {
+	if category != "" && !models.ValidCategory(category) {
+		return fmt.Errorf("%w %q", ErrInvalidCategory, category)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, found := r.transactions[id]
+	if !found {
+		return ErrTransactionNotFound
+	}
+
+	was := t.Category
+	recordUndo(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		t := r.transactions[id]
+		t.Category = was
+		r.transactions[id] = t
+	})
+	t.Category = category
+	r.transactions[id] = t
+
 	return nil
 }
+
+// recordRestore registers an undo step resetting the consumed flag and
+// consumedBy of ids to their current value. Must be called with the lock
+// held, before the write.
+func (r *transactionRepoImpl) recordRestore(ctx context.Context, ids []string) {
+	was := make(map[string]models.Transaction, len(ids))
+	for _, id := range ids {
+		was[id] = r.transactions[id]
+	}
+
+	recordUndo(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for id, before := range was {
+			t := r.transactions[id]
+			t.IsConsumed = before.IsConsumed
+			t.ConsumedBy = before.ConsumedBy
+			r.transactions[id] = t
+		}
+	})
+}
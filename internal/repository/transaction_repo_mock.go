@@ -1,4 +1,4 @@
-// Code generated by mockery v2.43.2. DO NOT EDIT.
+// Code generated by mockery v2.53.7. DO NOT EDIT.
 
 package repository
 
@@ -23,21 +23,31 @@ func (_m *MockTransactionRepo) EXPECT() *MockTransactionRepo_Expecter {
 }
 
 // Create provides a mock function with given fields: ctx, transaction
-func (_m *MockTransactionRepo) Create(ctx context.Context, transaction models.Transaction) error {
+func (_m *MockTransactionRepo) Create(ctx context.Context, transaction models.Transaction) (string, error) {
 	ret := _m.Called(ctx, transaction)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Create")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, models.Transaction) error); ok {
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Transaction) (string, error)); ok {
+		return rf(ctx, transaction)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Transaction) string); ok {
 		r0 = rf(ctx, transaction)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(string)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, models.Transaction) error); ok {
+		r1 = rf(ctx, transaction)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // MockTransactionRepo_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
@@ -59,12 +69,12 @@ func (_c *MockTransactionRepo_Create_Call) Run(run func(ctx context.Context, tra
 	return _c
 }
 
-func (_c *MockTransactionRepo_Create_Call) Return(_a0 error) *MockTransactionRepo_Create_Call {
-	_c.Call.Return(_a0)
+func (_c *MockTransactionRepo_Create_Call) Return(_a0 string, _a1 error) *MockTransactionRepo_Create_Call {
+	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *MockTransactionRepo_Create_Call) RunAndReturn(run func(context.Context, models.Transaction) error) *MockTransactionRepo_Create_Call {
+func (_c *MockTransactionRepo_Create_Call) RunAndReturn(run func(context.Context, models.Transaction) (string, error)) *MockTransactionRepo_Create_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -289,17 +299,113 @@ func (_c *MockTransactionRepo_MarkAsConsumed_Call) RunAndReturn(run func(context
 	return _c
 }
 
-// RollBackConsumed provides a mock function with given fields: ctx, tConsumed
-func (_m *MockTransactionRepo) RollBackConsumed(ctx context.Context, tConsumed []string) error {
-	ret := _m.Called(ctx, tConsumed)
+// SetCategory provides a mock function with given fields: ctx, id, category
+func (_m *MockTransactionRepo) SetCategory(ctx context.Context, id string, category string) error {
+	ret := _m.Called(ctx, id, category)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetCategory")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, id, category)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockTransactionRepo_SetCategory_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetCategory'
+type MockTransactionRepo_SetCategory_Call struct {
+	*mock.Call
+}
+
+// SetCategory is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - category string
+func (_e *MockTransactionRepo_Expecter) SetCategory(ctx interface{}, id interface{}, category interface{}) *MockTransactionRepo_SetCategory_Call {
+	return &MockTransactionRepo_SetCategory_Call{Call: _e.mock.On("SetCategory", ctx, id, category)}
+}
+
+func (_c *MockTransactionRepo_SetCategory_Call) Run(run func(ctx context.Context, id string, category string)) *MockTransactionRepo_SetCategory_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockTransactionRepo_SetCategory_Call) Return(_a0 error) *MockTransactionRepo_SetCategory_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockTransactionRepo_SetCategory_Call) RunAndReturn(run func(context.Context, string, string) error) *MockTransactionRepo_SetCategory_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetConsumedBy provides a mock function with given fields: ctx, ids, consumedBy
+func (_m *MockTransactionRepo) SetConsumedBy(ctx context.Context, ids []string, consumedBy string) error {
+	ret := _m.Called(ctx, ids, consumedBy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetConsumedBy")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string, string) error); ok {
+		r0 = rf(ctx, ids, consumedBy)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockTransactionRepo_SetConsumedBy_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetConsumedBy'
+type MockTransactionRepo_SetConsumedBy_Call struct {
+	*mock.Call
+}
+
+// SetConsumedBy is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ids []string
+//   - consumedBy string
+func (_e *MockTransactionRepo_Expecter) SetConsumedBy(ctx interface{}, ids interface{}, consumedBy interface{}) *MockTransactionRepo_SetConsumedBy_Call {
+	return &MockTransactionRepo_SetConsumedBy_Call{Call: _e.mock.On("SetConsumedBy", ctx, ids, consumedBy)}
+}
+
+func (_c *MockTransactionRepo_SetConsumedBy_Call) Run(run func(ctx context.Context, ids []string, consumedBy string)) *MockTransactionRepo_SetConsumedBy_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockTransactionRepo_SetConsumedBy_Call) Return(_a0 error) *MockTransactionRepo_SetConsumedBy_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockTransactionRepo_SetConsumedBy_Call) RunAndReturn(run func(context.Context, []string, string) error) *MockTransactionRepo_SetConsumedBy_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateStatus provides a mock function with given fields: ctx, id, status
+func (_m *MockTransactionRepo) UpdateStatus(ctx context.Context, id string, status models.TransactionStatus) error {
+	ret := _m.Called(ctx, id, status)
 
 	if len(ret) == 0 {
-		panic("no return value specified for RollBackConsumed")
+		panic("no return value specified for UpdateStatus")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, []string) error); ok {
-		r0 = rf(ctx, tConsumed)
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.TransactionStatus) error); ok {
+		r0 = rf(ctx, id, status)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -307,31 +413,32 @@ func (_m *MockTransactionRepo) RollBackConsumed(ctx context.Context, tConsumed [
 	return r0
 }
 
-// MockTransactionRepo_RollBackConsumed_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RollBackConsumed'
-type MockTransactionRepo_RollBackConsumed_Call struct {
+// MockTransactionRepo_UpdateStatus_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateStatus'
+type MockTransactionRepo_UpdateStatus_Call struct {
 	*mock.Call
 }
 
-// RollBackConsumed is a helper method to define mock.On call
+// UpdateStatus is a helper method to define mock.On call
 //   - ctx context.Context
-//   - tConsumed []string
-func (_e *MockTransactionRepo_Expecter) RollBackConsumed(ctx interface{}, tConsumed interface{}) *MockTransactionRepo_RollBackConsumed_Call {
-	return &MockTransactionRepo_RollBackConsumed_Call{Call: _e.mock.On("RollBackConsumed", ctx, tConsumed)}
+//   - id string
+//   - status models.TransactionStatus
+func (_e *MockTransactionRepo_Expecter) UpdateStatus(ctx interface{}, id interface{}, status interface{}) *MockTransactionRepo_UpdateStatus_Call {
+	return &MockTransactionRepo_UpdateStatus_Call{Call: _e.mock.On("UpdateStatus", ctx, id, status)}
 }
 
-func (_c *MockTransactionRepo_RollBackConsumed_Call) Run(run func(ctx context.Context, tConsumed []string)) *MockTransactionRepo_RollBackConsumed_Call {
+func (_c *MockTransactionRepo_UpdateStatus_Call) Run(run func(ctx context.Context, id string, status models.TransactionStatus)) *MockTransactionRepo_UpdateStatus_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].([]string))
+		run(args[0].(context.Context), args[1].(string), args[2].(models.TransactionStatus))
 	})
 	return _c
 }
 
-func (_c *MockTransactionRepo_RollBackConsumed_Call) Return(_a0 error) *MockTransactionRepo_RollBackConsumed_Call {
+func (_c *MockTransactionRepo_UpdateStatus_Call) Return(_a0 error) *MockTransactionRepo_UpdateStatus_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *MockTransactionRepo_RollBackConsumed_Call) RunAndReturn(run func(context.Context, []string) error) *MockTransactionRepo_RollBackConsumed_Call {
+func (_c *MockTransactionRepo_UpdateStatus_Call) RunAndReturn(run func(context.Context, string, models.TransactionStatus) error) *MockTransactionRepo_UpdateStatus_Call {
 	_c.Call.Return(run)
 	return _c
 }
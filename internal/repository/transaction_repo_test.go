@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -9,21 +10,21 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestTransaction_RollBackConsumed(t *testing.T) {
+func TestTransaction_GetBalance(t *testing.T) {
 	time := time.Now()
-	id := "001"
+	id := "1000"
 
 	type args struct {
-		ctx           context.Context
-		data          map[string]models.Transaction
-		transConsumed []string
+		ctx  context.Context
+		data map[string]models.Transaction
 	}
 
 	scenarios := map[string]struct {
 		given   args
+		want    models.Balance
 		wantErr error
 	}{
-		"happy-path": {
+		"happy-path-credits": {
 			given: args{
 				ctx: context.Background(),
 				data: map[string]models.Transaction{
@@ -35,6 +36,7 @@ func TestTransaction_RollBackConsumed(t *testing.T) {
 						CreatedAt:     time,
 						Amount:        7000.00,
 						IsConsumed:    false,
+						Status:        models.TransactionPosted,
 					},
 					"2000000": {
 						TransactionId: "2000000",
@@ -44,13 +46,19 @@ func TestTransaction_RollBackConsumed(t *testing.T) {
 						CreatedAt:     time,
 						Amount:        3000.00,
 						IsConsumed:    false,
+						Status:        models.TransactionPosted,
 					},
 				},
-				transConsumed: []string{"1000000", "2000000"},
+			},
+
+			want: models.Balance{
+				AccountId: id,
+				Amount:    10000.00,
 			},
 			wantErr: nil,
 		},
-		"invalid-transaction": {
+
+		"happy-path-mix": {
 			given: args{
 				ctx: context.Background(),
 				data: map[string]models.Transaction{
@@ -62,6 +70,7 @@ func TestTransaction_RollBackConsumed(t *testing.T) {
 						CreatedAt:     time,
 						Amount:        7000.00,
 						IsConsumed:    false,
+						Status:        models.TransactionPosted,
 					},
 					"2000000": {
 						TransactionId: "2000000",
@@ -70,82 +79,29 @@ func TestTransaction_RollBackConsumed(t *testing.T) {
 						Receiver:      id,
 						CreatedAt:     time,
 						Amount:        3000.00,
-						IsConsumed:    false,
-					},
-				},
-				transConsumed: []string{"1000000", "3000000"},
-			},
-			wantErr: ErrTransactionNotFound,
-		},
-	}
-
-	for name, tcase := range scenarios {
-		tcase := tcase
-		t.Run(name, func(t *testing.T) {
-			repo := setupTransactions(t, tcase.given.data, nil)
-
-			err := repo.RollBackConsumed(tcase.given.ctx, tcase.given.transConsumed)
-
-			if tcase.wantErr == nil {
-				assert.NoError(t, err)
-				for _, tr := range tcase.given.data {
-					result, _ := repo.FindOne(tcase.given.ctx, tr.TransactionId)
-					assert.Equal(t, false, result.IsConsumed)
-				}
-			} else {
-				assert.ErrorIs(t, err, tcase.wantErr)
-			}
-		})
-	}
-}
-
-func TestTransaction_GetBalance(t *testing.T) {
-	time := time.Now()
-	id := "1000"
-
-	type args struct {
-		ctx  context.Context
-		data map[string]models.Transaction
-	}
-
-	scenarios := map[string]struct {
-		given   args
-		want    models.Balance
-		wantErr error
-	}{
-		"happy-path-credits": {
-			given: args{
-				ctx: context.Background(),
-				data: map[string]models.Transaction{
-					"1000000": {
-						TransactionId: "1000000",
-						Owner:         id,
-						Sender:        id,
-						Receiver:      id,
-						CreatedAt:     time,
-						Amount:        7000.00,
-						IsConsumed:    false,
+						IsConsumed:    true,
+						Status:        models.TransactionPosted,
 					},
-					"2000000": {
-						TransactionId: "2000000",
+					"3000000": {
+						TransactionId: "3000000",
 						Owner:         id,
 						Sender:        id,
 						Receiver:      id,
 						CreatedAt:     time,
-						Amount:        3000.00,
-						IsConsumed:    false,
+						Amount:        -3000.00,
+						IsConsumed:    true,
+						Status:        models.TransactionPosted,
 					},
 				},
 			},
 
 			want: models.Balance{
 				AccountId: id,
-				Amount:    10000.00,
+				Amount:    7000.00,
 			},
 			wantErr: nil,
 		},
-
-		"happy-path-mix": {
+		"skips-pending-and-failed": {
 			given: args{
 				ctx: context.Background(),
 				data: map[string]models.Transaction{
@@ -156,7 +112,7 @@ func TestTransaction_GetBalance(t *testing.T) {
 						Receiver:      id,
 						CreatedAt:     time,
 						Amount:        7000.00,
-						IsConsumed:    false,
+						Status:        models.TransactionPosted,
 					},
 					"2000000": {
 						TransactionId: "2000000",
@@ -165,7 +121,7 @@ func TestTransaction_GetBalance(t *testing.T) {
 						Receiver:      id,
 						CreatedAt:     time,
 						Amount:        3000.00,
-						IsConsumed:    true,
+						Status:        models.TransactionPending,
 					},
 					"3000000": {
 						TransactionId: "3000000",
@@ -173,12 +129,11 @@ func TestTransaction_GetBalance(t *testing.T) {
 						Sender:        id,
 						Receiver:      id,
 						CreatedAt:     time,
-						Amount:        -3000.00,
-						IsConsumed:    true,
+						Amount:        1000.00,
+						Status:        models.TransactionFailed,
 					},
 				},
 			},
-
 			want: models.Balance{
 				AccountId: id,
 				Amount:    7000.00,
@@ -208,6 +163,7 @@ func TestTransaction_GetBalance(t *testing.T) {
 						CreatedAt:     time,
 						Amount:        -7000.00,
 						IsConsumed:    false,
+						Status:        models.TransactionPosted,
 					},
 					"2000000": {
 						TransactionId: "2000000",
@@ -217,6 +173,7 @@ func TestTransaction_GetBalance(t *testing.T) {
 						CreatedAt:     time,
 						Amount:        3000.00,
 						IsConsumed:    false,
+						Status:        models.TransactionPosted,
 					},
 				},
 			},
@@ -439,10 +396,74 @@ func TestTransaction_Create(t *testing.T) {
 				CreatedAt:     time,
 				Amount:        1000,
 				IsConsumed:    false,
+				Type:          models.TransactionDeposit,
+				Status:        models.TransactionPosted,
+			},
+			wantErr: nil,
+		},
+
+		"remainder type": {
+			given: args{
+				ctx: context.Background(),
+				transaction: models.Transaction{
+					Owner:     "0001",
+					Sender:    "0001",
+					Receiver:  "0001",
+					CreatedAt: time,
+					Amount:    400,
+					Type:      models.TransactionRemainder,
+					Status:    models.TransactionPending,
+				},
+				data: map[string]models.Transaction{},
+			},
+			want: models.Transaction{
+				TransactionId: id,
+				Owner:         "0001",
+				Sender:        "0001",
+				Receiver:      "0001",
+				CreatedAt:     time,
+				Amount:        400,
+				Type:          models.TransactionRemainder,
+				Status:        models.TransactionPending,
+				Remainder:     true,
 			},
 			wantErr: nil,
 		},
 
+		"invalid type": {
+			given: args{
+				ctx: context.Background(),
+				transaction: models.Transaction{
+					Owner:     "0001",
+					Sender:    "0001",
+					Receiver:  "0001",
+					CreatedAt: time,
+					Amount:    1000,
+					Type:      "gift",
+				},
+				data: map[string]models.Transaction{},
+			},
+			want:    models.Transaction{},
+			wantErr: ErrInvalidType,
+		},
+
+		"invalid status": {
+			given: args{
+				ctx: context.Background(),
+				transaction: models.Transaction{
+					Owner:     "0001",
+					Sender:    "0001",
+					Receiver:  "0001",
+					CreatedAt: time,
+					Amount:    1000,
+					Status:    "lost",
+				},
+				data: map[string]models.Transaction{},
+			},
+			want:    models.Transaction{},
+			wantErr: ErrInvalidStatus,
+		},
+
 		"missing owner": {
 			given: args{
 				ctx: context.Background(),
@@ -527,6 +548,23 @@ func TestTransaction_Create(t *testing.T) {
 			want:    models.Transaction{},
 			wantErr: ErrZeroAmount,
 		},
+
+		"unknown parent": {
+			given: args{
+				ctx: context.Background(),
+				transaction: models.Transaction{
+					Owner:     "0001",
+					Sender:    "0001",
+					Receiver:  "0001",
+					CreatedAt: time,
+					Amount:    1000,
+					ParentId:  "0999",
+				},
+				data: map[string]models.Transaction{},
+			},
+			want:    models.Transaction{},
+			wantErr: ErrTransactionNotFound,
+		},
 	}
 
 	for name, tcase := range scenarios {
@@ -534,10 +572,11 @@ func TestTransaction_Create(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			repo := setupTransactions(t, tcase.given.data, idGenerator)
 
-			err := repo.Create(tcase.given.ctx, tcase.given.transaction)
+			result, err := repo.Create(tcase.given.ctx, tcase.given.transaction)
 
 			if tcase.wantErr == nil {
 				assert.NoError(t, err)
+				assert.Equal(t, id, result)
 				transaction, err := repo.FindOne(tcase.given.ctx, id)
 				assert.NoError(t, err)
 				assert.Equal(t, tcase.want, transaction)
@@ -554,3 +593,201 @@ func setupTransactions(_ *testing.T, initialData map[string]models.Transaction,
 	repo.idGenerator = idGenerator
 	return repo
 }
+
+func TestTransaction_TrackCreated(t *testing.T) {
+	repo := NewTransactionRepo()
+	transaction := models.Transaction{Owner: "001", Sender: "001", Receiver: "001", Amount: 10}
+
+	_, err := repo.Create(context.Background(), transaction)
+	assert.NoError(t, err)
+
+	ctx, created := TrackCreated(context.Background())
+	first, err := repo.Create(ctx, transaction)
+	assert.NoError(t, err)
+	second, err := repo.Create(ctx, transaction)
+	assert.NoError(t, err)
+
+	ids := []string{}
+	for _, t := range created() {
+		ids = append(ids, t.TransactionId)
+	}
+	assert.Equal(t, []string{first, second}, ids)
+}
+
+func TestTransaction_CreatedIds(t *testing.T) {
+	repo := NewTransactionRepo()
+	errAbort := errors.New("abort")
+
+	var want []string
+	ids, err := CreatedIds(context.Background(), func(ctx context.Context) error {
+		for _, transaction := range []models.Transaction{
+			{Owner: "001", Sender: "001", Receiver: "002", Amount: -10},
+			{Owner: "001", Sender: "001", Receiver: "001", Amount: 5, Remainder: true},
+			{Owner: "002", Sender: "001", Receiver: "002", Amount: 10},
+		} {
+			id, err := repo.Create(ctx, transaction)
+			if err != nil {
+				return err
+			}
+			if !transaction.Remainder {
+				want = append(want, id)
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, want, ids)
+
+	ids, err = CreatedIds(context.Background(), func(ctx context.Context) error {
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+	assert.Nil(t, ids)
+}
+
+func TestTransaction_Lineage(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTransactionRepo()
+	errAbort := errors.New("abort")
+
+	parent, err := repo.Create(ctx, models.Transaction{Owner: "001", Sender: "001", Receiver: "001", Amount: 10})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.MarkAsConsumed(ctx, parent))
+
+	child, err := repo.Create(ctx, models.Transaction{Owner: "001", Sender: "001", Receiver: "001", Amount: 4, Remainder: true, ParentId: parent})
+	assert.NoError(t, err)
+	debit, err := repo.Create(ctx, models.Transaction{Owner: "001", Sender: "001", Receiver: "001", Amount: -6, IsConsumed: true})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.SetConsumedBy(ctx, []string{parent}, debit))
+
+	found, _ := repo.FindOne(ctx, parent)
+	assert.Equal(t, []string{child}, found.ChildIds)
+	assert.Equal(t, debit, found.ConsumedBy)
+
+	err = NewUnitOfWork().Do(ctx, func(ctx context.Context) error {
+		if _, err := repo.Create(ctx, models.Transaction{Owner: "001", Sender: "001", Receiver: "001", Amount: 1, ParentId: parent}); err != nil {
+			return err
+		}
+		if err := repo.SetConsumedBy(ctx, []string{parent}, "other"); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+
+	found, _ = repo.FindOne(ctx, parent)
+	assert.Equal(t, []string{child}, found.ChildIds)
+	assert.Equal(t, debit, found.ConsumedBy)
+
+	assert.ErrorIs(t, repo.SetConsumedBy(ctx, []string{"unknown"}, debit), ErrTransactionNotFound)
+
+}
+
+func TestTransaction_UpdateStatus(t *testing.T) {
+	errAbort := errors.New("abort")
+
+	scenarios := map[string]struct {
+		given   models.Transaction
+		status  models.TransactionStatus
+		wantErr error
+	}{
+		"post-pending": {
+			given:  models.Transaction{Status: models.TransactionPending},
+			status: models.TransactionPosted,
+		},
+		"fail-pending": {
+			given:  models.Transaction{Status: models.TransactionPending},
+			status: models.TransactionFailed,
+		},
+		"reverse-posted": {
+			given:  models.Transaction{Status: models.TransactionPosted},
+			status: models.TransactionReversed,
+		},
+		"reverse-pending": {
+			given:   models.Transaction{Status: models.TransactionPending},
+			status:  models.TransactionReversed,
+			wantErr: ErrInvalidTransition,
+		},
+		"reverse-consumed": {
+			given:   models.Transaction{Status: models.TransactionPosted, IsConsumed: true},
+			status:  models.TransactionReversed,
+			wantErr: ErrConsumed,
+		},
+		"post-failed": {
+			given:   models.Transaction{Status: models.TransactionFailed},
+			status:  models.TransactionPosted,
+			wantErr: ErrInvalidTransition,
+		},
+		"pending-posted": {
+			given:   models.Transaction{Status: models.TransactionPosted},
+			status:  models.TransactionPending,
+			wantErr: ErrInvalidTransition,
+		},
+		"invalid-status": {
+			given:   models.Transaction{Status: models.TransactionPending},
+			status:  "lost",
+			wantErr: ErrInvalidStatus,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			given := tcase.given
+			given.TransactionId = "0001"
+			repo := setupTransactions(t, map[string]models.Transaction{"0001": given}, nil)
+
+			err := repo.UpdateStatus(ctx, "0001", tcase.status)
+
+			found, _ := repo.FindOne(ctx, "0001")
+			if tcase.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tcase.status, found.Status)
+
+				err = NewUnitOfWork().Do(ctx, func(ctx context.Context) error {
+					if err := repo.UpdateStatus(ctx, "0001", models.TransactionReversed); err != nil {
+						return err
+					}
+					return errAbort
+				})
+				found, _ = repo.FindOne(ctx, "0001")
+				assert.Equal(t, tcase.status, found.Status)
+			} else {
+				assert.ErrorIs(t, err, tcase.wantErr)
+				assert.Equal(t, tcase.given.Status, found.Status)
+			}
+		})
+	}
+
+	repo := NewTransactionRepo()
+	assert.ErrorIs(t, repo.UpdateStatus(context.Background(), "0001", models.TransactionPosted), ErrTransactionNotFound)
+}
+
+func TestTransaction_SetCategory(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTransactionRepo()
+	errAbort := errors.New("abort")
+
+	id, err := repo.Create(ctx, models.Transaction{Owner: "001", Sender: "001", Receiver: "001", Amount: 10, Category: "income"})
+	assert.NoError(t, err)
+	_, err = repo.Create(ctx, models.Transaction{Owner: "001", Sender: "001", Receiver: "001", Amount: 10, Category: "gifts"})
+	assert.ErrorIs(t, err, ErrInvalidCategory)
+
+	assert.NoError(t, repo.SetCategory(ctx, id, "income.salary"))
+	found, _ := repo.FindOne(ctx, id)
+	assert.Equal(t, "income.salary", found.Category)
+
+	err = NewUnitOfWork().Do(ctx, func(ctx context.Context) error {
+		if err := repo.SetCategory(ctx, id, ""); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+	found, _ = repo.FindOne(ctx, id)
+	assert.Equal(t, "income.salary", found.Category)
+
+	assert.ErrorIs(t, repo.SetCategory(ctx, id, "gifts"), ErrInvalidCategory)
+	assert.ErrorIs(t, repo.SetCategory(ctx, "unknown", "income"), ErrTransactionNotFound)
+}
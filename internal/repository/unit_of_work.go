@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"sync"
+)
+
+// UnitOfWork groups repository writes so they either all take effect or none
+// do. Writes made with the context passed to fn are undone when fn fails.
+// Units of work run one at a time, so what fn reads, e.g. whether an account
+// is frozen, stays true until it returns.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+var _ UnitOfWork = (*unitOfWorkImpl)(nil)
+
+type unitOfWorkKey struct{}
+
+// journal collects the undo steps of the writes made inside a unit of work
+// and what to run once it succeeded.
+type journal struct {
+	mu     sync.Mutex
+	undo   []func()
+	commit []func()
+}
+
+type unitOfWorkImpl struct {
+	mu sync.Mutex
+}
+
+func NewUnitOfWork() *unitOfWorkImpl {
+	return &unitOfWorkImpl{}
+}
+
+func (u *unitOfWorkImpl) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	// nested units of work join the outer one
+	if _, ok := ctx.Value(unitOfWorkKey{}).(*journal); ok {
+		return fn(ctx)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	j := &journal{}
+	err := fn(context.WithValue(ctx, unitOfWorkKey{}, j))
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err != nil {
+		for i := len(j.undo) - 1; i >= 0; i-- {
+			j.undo[i]()
+		}
+		return err
+	}
+
+	for _, fn := range j.commit {
+		fn()
+	}
+	return nil
+}
+
+// AfterCommit runs fn once the unit of work ctx belongs to succeeded, in the
+// order registered, or right away when ctx isn't in one. fn never runs for
+// writes a failed unit of work undid.
+func AfterCommit(ctx context.Context, fn func()) {
+	j, ok := ctx.Value(unitOfWorkKey{}).(*journal)
+	if !ok {
+		fn()
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.commit = append(j.commit, fn)
+}
+
+// recordUndo registers the inverse of a write when ctx belongs to a unit of
+// work. It must be called after the write succeeded.
+func recordUndo(ctx context.Context, fn func()) {
+	j, ok := ctx.Value(unitOfWorkKey{}).(*journal)
+	if !ok {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.undo = append(j.undo, fn)
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitOfWork_Do(t *testing.T) {
+	now := time.Now()
+	id := "0001"
+	errAbort := errors.New("abort")
+
+	existing := models.Transaction{
+		TransactionId: "1000000",
+		Owner:         id,
+		Sender:        id,
+		Receiver:      id,
+		CreatedAt:     now,
+		Amount:        7000.00,
+		IsConsumed:    false,
+	}
+	created := models.Transaction{
+		Owner:     id,
+		Sender:    id,
+		Receiver:  id,
+		CreatedAt: now,
+		Amount:    -1000.00,
+	}
+	event := models.Event{EventId: "e-1", Type: models.EventTransactionCreated, AccountId: id, CreatedAt: now}
+
+	scenarios := map[string]struct {
+		fn             func(ctx context.Context, transactions *transactionRepoImpl, outbox *outboxRepoImpl) error
+		wantErr        error
+		wantConsumed   bool
+		wantCreated    bool
+		wantOutboxSize int
+	}{
+		"commit": {
+			fn: func(ctx context.Context, transactions *transactionRepoImpl, outbox *outboxRepoImpl) error {
+				if err := transactions.MarkAsConsumed(ctx, existing.TransactionId); err != nil {
+					return err
+				}
+				if _, err := transactions.Create(ctx, created); err != nil {
+					return err
+				}
+				return outbox.Append(ctx, event)
+			},
+			wantErr:        nil,
+			wantConsumed:   true,
+			wantCreated:    true,
+			wantOutboxSize: 1,
+		},
+		"rollback-all-writes": {
+			fn: func(ctx context.Context, transactions *transactionRepoImpl, outbox *outboxRepoImpl) error {
+				if err := transactions.MarkAsConsumed(ctx, existing.TransactionId); err != nil {
+					return err
+				}
+				if _, err := transactions.Create(ctx, created); err != nil {
+					return err
+				}
+				if err := outbox.Append(ctx, event); err != nil {
+					return err
+				}
+				return errAbort
+			},
+			wantErr:        errAbort,
+			wantConsumed:   false,
+			wantCreated:    false,
+			wantOutboxSize: 0,
+		},
+		"failed-write-leaves-no-event": {
+			fn: func(ctx context.Context, transactions *transactionRepoImpl, outbox *outboxRepoImpl) error {
+				if _, err := transactions.Create(ctx, models.Transaction{Owner: id, Sender: id, Receiver: id}); err != nil {
+					return err
+				}
+				return outbox.Append(ctx, event)
+			},
+			wantErr:        ErrZeroAmount,
+			wantConsumed:   false,
+			wantCreated:    false,
+			wantOutboxSize: 0,
+		},
+		"nested-joins-outer": {
+			fn: func(ctx context.Context, transactions *transactionRepoImpl, outbox *outboxRepoImpl) error {
+				err := NewUnitOfWork().Do(ctx, func(ctx context.Context) error {
+					_, err := transactions.Create(ctx, created)
+					return err
+				})
+				if err != nil {
+					return err
+				}
+				return errAbort
+			},
+			wantErr:        errAbort,
+			wantConsumed:   false,
+			wantCreated:    false,
+			wantOutboxSize: 0,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			transactions := setupTransactions(t, map[string]models.Transaction{
+				existing.TransactionId: existing,
+			}, func() string { return "2000000" })
+			outbox := NewOutboxRepo()
+
+			err := NewUnitOfWork().Do(ctx, func(ctx context.Context) error {
+				return tcase.fn(ctx, transactions, outbox)
+			})
+
+			if tcase.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tcase.wantErr)
+			}
+
+			result, _ := transactions.FindOne(ctx, existing.TransactionId)
+			assert.Equal(t, tcase.wantConsumed, result.IsConsumed)
+
+			_, err = transactions.FindOne(ctx, "2000000")
+			assert.Equal(t, tcase.wantCreated, err == nil)
+
+			pending, _ := outbox.FindPending(ctx, 0)
+			assert.Len(t, pending, tcase.wantOutboxSize)
+		})
+	}
+}
+
+func TestAfterCommit(t *testing.T) {
+	errAbort := errors.New("abort")
+
+	scenarios := map[string]struct {
+		err  error
+		want []string
+	}{
+		"commit": {
+			err:  nil,
+			want: []string{"outside", "first", "nested", "second"},
+		},
+		"rollback": {
+			err:  errAbort,
+			want: []string{"outside"},
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			got := []string{}
+			record := func(s string) func() {
+				return func() { got = append(got, s) }
+			}
+
+			uow := NewUnitOfWork()
+			err := uow.Do(ctx, func(ctx context.Context) error {
+				AfterCommit(ctx, record("first"))
+				err := uow.Do(ctx, func(ctx context.Context) error {
+					AfterCommit(ctx, record("nested"))
+					return nil
+				})
+				assert.NoError(t, err)
+				assert.Empty(t, got)
+
+				AfterCommit(ctx, record("second"))
+				AfterCommit(context.Background(), record("outside"))
+				return tcase.err
+			})
+
+			assert.ErrorIs(t, err, tcase.err)
+			assert.Equal(t, tcase.want, got)
+		})
+	}
+}
+
+func TestUnitOfWork_RunsOneAtATime(t *testing.T) {
+	uow := NewUnitOfWork()
+	inside := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		_ = uow.Do(context.Background(), func(ctx context.Context) error {
+			close(inside)
+			<-release
+			return nil
+		})
+	}()
+	<-inside
+
+	go func() {
+		_ = uow.Do(context.Background(), func(ctx context.Context) error {
+			close(done)
+			return nil
+		})
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("second unit of work ran while the first was open")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-done
+}
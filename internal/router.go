@@ -2,11 +2,18 @@ package internal
 
 import "github.com/julienschmidt/httprouter"
 
-func Router(routes []Route) *httprouter.Router {
+// Middleware wraps the handler of a single route. It's given the route so it
+// can be configured per route at registration time.
+type Middleware func(route Route, next httprouter.Handle) httprouter.Handle
+
+// Router registers the routes, wrapping each handler in the middleware chain.
+// The first middleware is the outermost one.
+func Router(routes []Route, middlewares ...Middleware) *httprouter.Router {
 	router := httprouter.New()
+	chain := Chain(middlewares...)
 
 	for _, route := range routes {
-		var handle httprouter.Handle = route.HandlerFunc
+		var handle httprouter.Handle = chain(route, route.HandlerFunc)
 
 		router.Handle(route.Method, route.Path, handle)
 	}
@@ -8,15 +8,138 @@ type Route struct {
 	HandlerFunc httprouter.Handle
 }
 
-func Routes() []Route {
+// AdminPaths are the routes only admin API keys may use, see Authorize.
+var AdminPaths = []string{
+	"/accounts/:account-id/freeze",
+	"/accounts/:account-id/unfreeze",
+	"/admin/",
+}
+
+func Routes(h *Handler) []Route {
 	return []Route{
 		{"GET", "/", Index},
-		{"GET", "/accounts", GetAllAccounts},
-		{"GET", "/accounts/:account-id", GetAccount},
-		{"POST", "/accounts", PostAccount},
-		{"GET", "/accounts/:account-id/transactions", GetAllTransactions},
-		{"GET", "/transactions/:transaction-id", GetTransaction},
-		{"POST", "/transactions", PostTransaction},
-		{"GET", "/accounts/:account-id/balance", GetBalance},
+		{"GET", "/accounts", h.GetAllAccounts},
+		{"GET", "/accounts/:account-id", h.GetAccount},
+		{"POST", "/accounts", h.PostAccount},
+		{"POST", "/accounts/:account-id/freeze", h.PostFreezeAccount},
+		{"POST", "/accounts/:account-id/unfreeze", h.PostUnfreezeAccount},
+		{"GET", "/accounts/:account-id/transactions", h.GetAllTransactions},
+		{"GET", "/transactions/:transaction-id", h.GetTransaction},
+		{"GET", "/transactions/:transaction-id/lineage", h.GetTransactionLineage},
+		{"POST", "/transactions/:transaction-id/status", h.PostTransactionStatus},
+		{"POST", "/transactions", h.PostTransaction},
+		{"GET", "/accounts/:account-id/balance", h.GetBalance},
+	}
+}
+
+func WebhookRoutes(h *WebhookHandler) []Route {
+	return []Route{
+		{"GET", "/webhooks", h.GetAllWebhooks},
+		{"POST", "/webhooks", h.PostWebhook},
+		{"DELETE", "/webhooks/:webhook-id", h.DeleteWebhook},
+		{"GET", "/webhooks/:webhook-id/deliveries", h.GetAllDeliveries},
+		{"POST", "/webhooks/:webhook-id/deliveries/:delivery-id/replay", h.PostReplayDelivery},
+	}
+}
+
+func StreamRoutes(h *StreamHandler) []Route {
+	return []Route{
+		{"GET", "/accounts/:account-id/stream", h.GetAccountStream},
+	}
+}
+
+func GraphQLRoutes(h *GraphQLHandler) []Route {
+	return []Route{
+		{"POST", "/graphql", h.PostGraphQL},
+	}
+}
+
+func OpenAPIRoutes(h *OpenAPIHandler) []Route {
+	return []Route{
+		{"GET", "/openapi.json", h.GetOpenAPI},
+	}
+}
+
+func MetricsRoutes(h *MetricsHandler) []Route {
+	return []Route{
+		{"GET", "/metrics", h.GetMetrics},
+	}
+}
+
+func HealthRoutes(h *HealthHandler) []Route {
+	return []Route{
+		{"GET", "/healthz", h.GetHealthz},
+		{"GET", "/readyz", h.GetReadyz},
+	}
+}
+
+func StatementRoutes(h *StatementHandler) []Route {
+	return []Route{
+		{"GET", "/accounts/:account-id/statements", h.GetStatement},
+	}
+}
+
+func ExportRoutes(h *ExportHandler) []Route {
+	return []Route{
+		{"GET", "/accounts/:account-id/export", h.GetExport},
+	}
+}
+
+func ImportRoutes(h *ImportHandler) []Route {
+	return []Route{
+		{"POST", "/imports", h.PostImport},
+		{"GET", "/imports/:import-id", h.GetImport},
+		{"GET", "/imports/:import-id/result", h.GetImportResult},
+	}
+}
+
+func PayoutRoutes(h *PayoutHandler) []Route {
+	return []Route{
+		{"POST", "/payouts", h.PostPayout},
+		{"GET", "/payouts/:payout-id", h.GetPayout},
+	}
+}
+
+func SplitRoutes(h *SplitHandler) []Route {
+	return []Route{
+		{"POST", "/splits", h.PostSplit},
+		{"GET", "/splits/:split-id", h.GetSplit},
+		{"POST", "/splits/:split-id/obligations/:account-id/settle", h.PostSettleObligation},
+	}
+}
+
+func GroupRoutes(h *GroupHandler) []Route {
+	return []Route{
+		{"POST", "/groups", h.PostGroup},
+		{"GET", "/groups/:group-id", h.GetGroup},
+		{"POST", "/groups/:group-id/members", h.PostGroupMember},
+		{"POST", "/groups/:group-id/expenses", h.PostGroupExpense},
+		{"GET", "/groups/:group-id/settlement", h.GetGroupSettlement},
+		{"POST", "/groups/:group-id/settle", h.PostGroupSettle},
+	}
+}
+
+func ReconcileRoutes(h *ReconcileHandler) []Route {
+	return []Route{
+		{"GET", "/admin/reconciliation", h.GetReconciliation},
+		{"POST", "/admin/reconciliation", h.PostReconciliation},
+	}
+}
+
+func AuditRoutes(h *AuditHandler) []Route {
+	return []Route{
+		{"GET", "/admin/audit", h.GetAuditRecords},
+		{"GET", "/admin/audit/anchors", h.GetAuditAnchors},
+		{"GET", "/admin/audit/verify", h.GetAuditVerification},
+	}
+}
+
+func CategoryRoutes(h *CategoryHandler) []Route {
+	return []Route{
+		{"GET", "/categories", h.GetCategories},
+		{"GET", "/accounts/:account-id/category-rules", h.GetCategoryRules},
+		{"POST", "/accounts/:account-id/category-rules", h.PostCategoryRule},
+		{"DELETE", "/accounts/:account-id/category-rules/:rule-id", h.DeleteCategoryRule},
+		{"POST", "/transactions/:transaction-id/category", h.PostTransactionCategory},
 	}
 }
@@ -0,0 +1,61 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gopay/internal/auth"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Authenticate returns the server options that require the same API keys as
+// the REST API, sent as "authorization: Bearer <key>" or x-api-key metadata.
+// Without configured keys every call is allowed.
+func Authenticate(keys *auth.Keys) []grpc.ServerOption {
+	if !keys.Enabled() {
+		return nil
+	}
+
+	check := func(ctx context.Context, method string) error {
+		md, _ := metadata.FromIncomingContext(ctx)
+		key := auth.FromHeaders(first(md, auth.AuthorizationHeader), first(md, auth.APIKeyHeader))
+
+		principal, _, err := keys.Principal(key)
+		if err != nil {
+			log.Error().Err(err).Str("method", method).Msg("GRPC::Authenticate")
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		log.Debug().Str("method", method).Str("principal", principal).Msg("GRPC::Authenticate")
+		return nil
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			err := check(ctx, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			err := check(ss.Context(), info.FullMethod)
+			if err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
+
+func first(md metadata.MD, key string) string {
+	values := md.Get(strings.ToLower(key))
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
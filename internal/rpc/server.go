@@ -0,0 +1,285 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/rpc/gopaypb"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/stream"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var _ gopaypb.GoPayServer = (*Server)(nil)
+
+// Server implements the GoPay gRPC service on top of the same service and
+// repositories as the REST handlers.
+type Server struct {
+	gopaypb.UnimplementedGoPayServer
+	transactionService service.TransactionService
+	transactionRepo    repository.TransactionRepo
+	accountRepo        repository.AccountRepo
+	hub                *stream.Hub
+}
+
+func NewServer(transactionService service.TransactionService, transactionRepo repository.TransactionRepo, accountRepo repository.AccountRepo, hub *stream.Hub) *Server {
+	return &Server{
+		transactionService: transactionService,
+		transactionRepo:    transactionRepo,
+		accountRepo:        accountRepo,
+		hub:                hub,
+	}
+}
+
+// Register creates a grpc.Server serving s.
+func (s *Server) Register(opts ...grpc.ServerOption) *grpc.Server {
+	srv := grpc.NewServer(opts...)
+	gopaypb.RegisterGoPayServer(srv, s)
+	return srv
+}
+
+func (s *Server) ListAccounts(ctx context.Context, _ *gopaypb.ListAccountsRequest) (*gopaypb.ListAccountsResponse, error) {
+	accs, err := s.accountRepo.FindAll(ctx)
+	if err != nil {
+		return nil, toStatus(err, "ListAccounts")
+	}
+
+	res := &gopaypb.ListAccountsResponse{}
+	for _, acc := range accs {
+		res.Accounts = append(res.Accounts, toAccount(acc))
+	}
+
+	return res, nil
+}
+
+func (s *Server) GetAccount(ctx context.Context, req *gopaypb.GetAccountRequest) (*gopaypb.Account, error) {
+	acc, err := s.accountRepo.FindOne(ctx, req.GetAccountId())
+	if err != nil {
+		return nil, toStatus(err, "GetAccount")
+	}
+
+	return toAccount(acc), nil
+}
+
+func (s *Server) CreateAccount(ctx context.Context, req *gopaypb.CreateAccountRequest) (*gopaypb.Account, error) {
+	id, err := s.accountRepo.Create(ctx, req.GetName(), req.GetLastName())
+	if err != nil {
+		return nil, toStatus(err, "CreateAccount")
+	}
+
+	acc, err := s.accountRepo.FindOne(ctx, id)
+	if err != nil {
+		return nil, toStatus(err, "CreateAccount")
+	}
+
+	return toAccount(acc), nil
+}
+
+func (s *Server) ListTransactions(ctx context.Context, req *gopaypb.ListTransactionsRequest) (*gopaypb.ListTransactionsResponse, error) {
+	_, err := s.accountRepo.FindOne(ctx, req.GetAccountId())
+	if err != nil {
+		return nil, toStatus(err, "ListTransactions")
+	}
+
+	transactions, err := s.transactionRepo.FindAll(ctx, req.GetAccountId())
+	if err != nil {
+		return nil, toStatus(err, "ListTransactions")
+	}
+
+	res := &gopaypb.ListTransactionsResponse{}
+	for _, t := range transactions {
+		res.Transactions = append(res.Transactions, toTransaction(t))
+	}
+
+	return res, nil
+}
+
+func (s *Server) GetTransaction(ctx context.Context, req *gopaypb.GetTransactionRequest) (*gopaypb.Transaction, error) {
+	t, err := s.transactionRepo.FindOne(ctx, req.GetTransactionId())
+	if err != nil {
+		return nil, toStatus(err, "GetTransaction")
+	}
+
+	return toTransaction(t), nil
+}
+
+func (s *Server) GetBalance(ctx context.Context, req *gopaypb.GetBalanceRequest) (*gopaypb.Balance, error) {
+	_, err := s.accountRepo.FindOne(ctx, req.GetAccountId())
+	if err != nil {
+		return nil, toStatus(err, "GetBalance")
+	}
+
+	return s.balance(ctx, req.GetAccountId(), "GetBalance")
+}
+
+func (s *Server) Deposit(ctx context.Context, req *gopaypb.DepositRequest) (*gopaypb.Balance, error) {
+	err := s.transactionService.Deposit(ctx, req.GetAccountId(), req.GetAmount(), service.Note{})
+	if err != nil {
+		return nil, toStatus(err, "Deposit")
+	}
+
+	return s.balance(ctx, req.GetAccountId(), "Deposit")
+}
+
+func (s *Server) Withdraw(ctx context.Context, req *gopaypb.WithdrawRequest) (*gopaypb.Balance, error) {
+	if req.GetAmount() <= 0 {
+		return nil, toStatus(service.ErrInvalidAmount, "Withdraw")
+	}
+
+	err := s.transactionService.Withdraw(ctx, req.GetAccountId(), (-1)*req.GetAmount(), service.Note{})
+	if err != nil {
+		return nil, toStatus(err, "Withdraw")
+	}
+
+	return s.balance(ctx, req.GetAccountId(), "Withdraw")
+}
+
+func (s *Server) Transfer(ctx context.Context, req *gopaypb.TransferRequest) (*gopaypb.Balance, error) {
+	if req.GetAmount() <= 0 {
+		return nil, toStatus(service.ErrInvalidAmount, "Transfer")
+	}
+
+	err := s.transactionService.Transfer(ctx, req.GetSender(), req.GetReceiver(), (-1)*req.GetAmount(), service.Note{})
+	if err != nil {
+		return nil, toStatus(err, "Transfer")
+	}
+
+	return s.balance(ctx, req.GetSender(), "Transfer")
+}
+
+func (s *Server) StreamAccountActivity(req *gopaypb.StreamAccountActivityRequest, srv gopaypb.GoPay_StreamAccountActivityServer) error {
+	ctx := srv.Context()
+
+	_, err := s.accountRepo.FindOne(ctx, req.GetAccountId())
+	if err != nil {
+		return toStatus(err, "StreamAccountActivity")
+	}
+
+	replay, truncated, messages, cancel := s.hub.Subscribe(req.GetAccountId(), req.GetLastEventId())
+	defer cancel()
+
+	if truncated {
+		err = srv.Send(&gopaypb.AccountActivity{Type: "stream.truncated"})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, msg := range replay {
+		err = srv.Send(toActivity(msg))
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, open := <-messages:
+			if !open {
+				return status.Error(codes.Unavailable, "stream fell behind, resume with last_event_id")
+			}
+
+			err = srv.Send(toActivity(msg))
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) balance(ctx context.Context, accountId string, op string) (*gopaypb.Balance, error) {
+	balance, err := s.transactionRepo.GetBalance(ctx, accountId)
+	if err != nil {
+		return nil, toStatus(err, op)
+	}
+
+	return toBalance(balance), nil
+}
+
+// toStatus maps domain errors to gRPC status codes.
+func toStatus(err error, op string) error {
+	log.Error().Err(err).Msgf("GRPC::%s", op)
+
+	var code codes.Code
+	switch {
+	case errors.Is(err, repository.ErrAccountNotFound),
+		errors.Is(err, repository.ErrTransactionNotFound):
+		code = codes.NotFound
+	case errors.Is(err, service.ErrInsufficentBalance),
+		errors.Is(err, service.ErrAccountFrozen):
+		code = codes.FailedPrecondition
+	case errors.Is(err, service.ErrInvalidAmount),
+		errors.Is(err, service.ErrSameAccount),
+		errors.Is(err, repository.ErrMissingParams),
+		errors.Is(err, repository.ErrMissingFields),
+		errors.Is(err, repository.ErrZeroAmount):
+		code = codes.InvalidArgument
+	case errors.Is(err, service.ErrFailedDebitOperation):
+		code = codes.Aborted
+	default:
+		code = codes.Internal
+	}
+
+	return status.Error(code, err.Error())
+}
+
+func toAccount(acc models.Account) *gopaypb.Account {
+	return &gopaypb.Account{
+		AccountId: acc.AccountId,
+		Name:      acc.Name,
+		LastName:  acc.LastName,
+		Frozen:    acc.Frozen,
+	}
+}
+
+func toTransaction(t models.Transaction) *gopaypb.Transaction {
+	return &gopaypb.Transaction{
+		TransactionId: t.TransactionId,
+		Owner:         t.Owner,
+		Sender:        t.Sender,
+		Receiver:      t.Receiver,
+		CreatedAt:     timestamppb.New(t.CreatedAt),
+		Amount:        t.Amount,
+		IsConsumed:    t.IsConsumed,
+		ParentId:      t.ParentId,
+		ChildIds:      t.ChildIds,
+		ConsumedBy:    t.ConsumedBy,
+		Type:          string(t.Type),
+		Status:        string(t.Status),
+		Memo:          t.Memo,
+		Tags:          t.Tags,
+		Category:      t.Category,
+	}
+}
+
+func toBalance(b models.Balance) *gopaypb.Balance {
+	return &gopaypb.Balance{
+		AccountId: b.AccountId,
+		Balance:   b.Amount,
+	}
+}
+
+// toActivity maps a stream message; account events only carry their type,
+// like stream.truncated.
+func toActivity(msg stream.Message) *gopaypb.AccountActivity {
+	activity := &gopaypb.AccountActivity{
+		EventId: msg.Id,
+		Type:    msg.Event,
+	}
+	if msg.Payload.Transaction != nil {
+		activity.Transaction = toTransaction(*msg.Payload.Transaction)
+	}
+	if msg.Payload.Balance != nil {
+		activity.Balance = toBalance(*msg.Payload.Balance)
+	}
+
+	return activity
+}
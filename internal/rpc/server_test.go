@@ -0,0 +1,243 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gopay/internal/auth"
+	"github.com/gopay/internal/events"
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/rpc/gopaypb"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/stream"
+	"github.com/gopay/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestServer_Transfer(t *testing.T) {
+	utils.SetSyncGoroutine()
+	defer utils.ResetGoroutine()
+
+	ctx := context.Background()
+
+	scenarios := map[string]struct {
+		sender      string
+		receiver    string
+		amount      float32
+		wantCode    codes.Code
+		wantBalance float64
+	}{
+		"happy-path": {
+			sender:      "sender",
+			receiver:    "receiver",
+			amount:      400,
+			wantCode:    codes.OK,
+			wantBalance: 600,
+		},
+		"insufficient-balance": {
+			sender:   "sender",
+			receiver: "receiver",
+			amount:   5000,
+			wantCode: codes.FailedPrecondition,
+		},
+		"unknown-receiver": {
+			sender:   "sender",
+			receiver: "missing",
+			amount:   400,
+			wantCode: codes.NotFound,
+		},
+		"negative-amount": {
+			sender:   "sender",
+			receiver: "receiver",
+			amount:   -400,
+			wantCode: codes.InvalidArgument,
+		},
+		"same-account": {
+			sender:   "sender",
+			receiver: "sender",
+			amount:   400,
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			client, ids, _ := setupServer(t)
+
+			res, err := client.Transfer(ctx, &gopaypb.TransferRequest{
+				Sender:   ids[tcase.sender],
+				Receiver: ids[tcase.receiver],
+				Amount:   tcase.amount,
+			})
+
+			assert.Equal(t, tcase.wantCode, status.Code(err))
+			if tcase.wantCode == codes.OK {
+				assert.Equal(t, tcase.wantBalance, res.GetBalance())
+
+				receiver, err := client.GetBalance(ctx, &gopaypb.GetBalanceRequest{AccountId: ids[tcase.receiver]})
+				assert.NoError(t, err)
+				assert.Equal(t, float64(tcase.amount), receiver.GetBalance())
+			}
+		})
+	}
+}
+
+func TestServer_GetAccount(t *testing.T) {
+	ctx := context.Background()
+	client, ids, _ := setupServer(t)
+
+	acc, err := client.GetAccount(ctx, &gopaypb.GetAccountRequest{AccountId: ids["sender"]})
+	assert.NoError(t, err)
+	assert.Equal(t, "Shankar", acc.GetName())
+
+	_, err = client.GetAccount(ctx, &gopaypb.GetAccountRequest{AccountId: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.CreateAccount(ctx, &gopaypb.CreateAccountRequest{Name: "Caio"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_ListTransactions(t *testing.T) {
+	utils.SetSyncGoroutine()
+	defer utils.ResetGoroutine()
+
+	ctx := context.Background()
+	client, ids, _ := setupServer(t)
+
+	_, err := client.Transfer(ctx, &gopaypb.TransferRequest{Sender: ids["sender"], Receiver: ids["receiver"], Amount: 400})
+	require.NoError(t, err)
+
+	sent, err := client.ListTransactions(ctx, &gopaypb.ListTransactionsRequest{AccountId: ids["sender"]})
+	require.NoError(t, err)
+	require.Len(t, sent.GetTransactions(), 3)
+	deposit, debit, remainder := sent.GetTransactions()[0], sent.GetTransactions()[1], sent.GetTransactions()[2]
+	assert.Equal(t, debit.GetTransactionId(), deposit.GetConsumedBy())
+	assert.Equal(t, []string{remainder.GetTransactionId()}, deposit.GetChildIds())
+	assert.Equal(t, deposit.GetTransactionId(), remainder.GetParentId())
+	assert.Equal(t, string(models.TransactionTransferOut), debit.GetType())
+	assert.Equal(t, string(models.TransactionPosted), debit.GetStatus())
+	assert.Equal(t, string(models.TransactionRemainder), remainder.GetType())
+
+	received, err := client.ListTransactions(ctx, &gopaypb.ListTransactionsRequest{AccountId: ids["receiver"]})
+	require.NoError(t, err)
+	require.Len(t, received.GetTransactions(), 1)
+	assert.Equal(t, debit.GetTransactionId(), received.GetTransactions()[0].GetParentId())
+}
+
+func TestServer_StreamAccountActivity(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, ids, hub := setupServer(t)
+
+	for _, id := range []string{"1000000", "2000000"} {
+		hub.Handle(events.New(models.EventTransactionCreated, ids["receiver"], models.Transaction{
+			TransactionId: id,
+			Owner:         ids["receiver"],
+			Amount:        10,
+			Memo:          "Rent",
+			Tags:          []string{"flat"},
+			Category:      "housing.rent",
+		}))
+	}
+	hub.Handle(events.New(models.EventBalanceChanged, ids["receiver"], models.Balance{AccountId: ids["receiver"], Amount: 20}))
+
+	activity, err := client.StreamAccountActivity(ctx, &gopaypb.StreamAccountActivityRequest{
+		AccountId:   ids["receiver"],
+		LastEventId: 1,
+	})
+	require.NoError(t, err)
+
+	msg, err := activity.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), msg.GetEventId())
+	assert.Equal(t, string(models.EventTransactionCreated), msg.GetType())
+	assert.Equal(t, "2000000", msg.GetTransaction().GetTransactionId())
+	assert.Equal(t, "Rent", msg.GetTransaction().GetMemo())
+	assert.Equal(t, []string{"flat"}, msg.GetTransaction().GetTags())
+	assert.Equal(t, "housing.rent", msg.GetTransaction().GetCategory())
+	assert.Equal(t, ids["receiver"], msg.GetBalance().GetAccountId())
+
+	missing, err := client.StreamAccountActivity(ctx, &gopaypb.StreamAccountActivityRequest{AccountId: "missing"})
+	require.NoError(t, err)
+	_, err = missing.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys, err := auth.ParseKeys([]string{"ops:secret"})
+	require.NoError(t, err)
+	client, ids, _ := setupServer(t, Authenticate(keys)...)
+
+	scenarios := map[string]struct {
+		md       metadata.MD
+		wantCode codes.Code
+	}{
+		"bearer":    {md: metadata.Pairs("authorization", "Bearer secret"), wantCode: codes.OK},
+		"api-key":   {md: metadata.Pairs("x-api-key", "secret"), wantCode: codes.OK},
+		"wrong-key": {md: metadata.Pairs("authorization", "Bearer nope"), wantCode: codes.Unauthenticated},
+		"no-key":    {md: metadata.MD{}, wantCode: codes.Unauthenticated},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			ctx := metadata.NewOutgoingContext(context.Background(), tcase.md)
+
+			_, err := client.GetAccount(ctx, &gopaypb.GetAccountRequest{AccountId: ids["sender"]})
+			assert.Equal(t, tcase.wantCode, status.Code(err))
+
+			activity, err := client.StreamAccountActivity(ctx, &gopaypb.StreamAccountActivityRequest{AccountId: "missing"})
+			require.NoError(t, err)
+			_, err = activity.Recv()
+			if tcase.wantCode == codes.OK {
+				assert.Equal(t, codes.NotFound, status.Code(err))
+			} else {
+				assert.Equal(t, tcase.wantCode, status.Code(err))
+			}
+		})
+	}
+}
+
+func setupServer(t *testing.T, opts ...grpc.ServerOption) (gopaypb.GoPayClient, map[string]string, *stream.Hub) {
+	ctx := context.Background()
+
+	accountRepo := repository.NewAccountRepo()
+	transactionRepo := repository.NewTransactionRepo()
+	transactionService := service.NewTransactionService(transactionRepo, accountRepo, repository.NewOutboxRepo(), repository.NewUnitOfWork())
+	hub := stream.NewHub(stream.DefaultBufferSize)
+
+	sender, err := accountRepo.Create(ctx, "Shankar", "Nakai")
+	require.NoError(t, err)
+	receiver, err := accountRepo.Create(ctx, "Jessica", "Lourenco")
+	require.NoError(t, err)
+	require.NoError(t, transactionService.Deposit(ctx, sender, 1000, service.Note{}))
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := NewServer(transactionService, transactionRepo, accountRepo, hub).Register(opts...)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return gopaypb.NewGoPayClient(conn), map[string]string{"sender": sender, "receiver": receiver}, hub
+}
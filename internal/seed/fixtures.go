@@ -0,0 +1,173 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidFixtures = errors.New("invalid fixtures")
+	ErrUnknownAccount  = errors.New("unknown account")
+	ErrDuplicateRef    = errors.New("duplicate account ref")
+	ErrDuplicateName   = errors.New("duplicate account")
+	ErrMissingName     = errors.New("name and lastName are required")
+	ErrInvalidAmount   = errors.New("amount must be greater than zero")
+	ErrUnknownField    = errors.New("unknown field")
+)
+
+// Fixtures are accounts and their opening transactions, e.g.
+//
+//	accounts:
+//	  - ref: shankar
+//	    name: Shankar
+//	    lastName: Nakai
+//	transactions:
+//	  - account: shankar
+//	    amount: 7000
+//	    at: 2024-01-01T09:00:00Z
+type Fixtures struct {
+	// Source names the file in error messages.
+	Source       string               `yaml:"-"`
+	Accounts     []AccountFixture     `yaml:"accounts"`
+	Transactions []TransactionFixture `yaml:"transactions"`
+}
+
+type AccountFixture struct {
+	// Ref is how transactions in the same file refer to the account.
+	Ref      string `yaml:"ref"`
+	Name     string `yaml:"name"`
+	LastName string `yaml:"lastName"`
+	Frozen   bool   `yaml:"frozen"`
+	Line     int    `yaml:"-"`
+}
+
+// TransactionFixture is a deposit into an account, e.g. its opening
+// balance. At defaults to the time of loading.
+type TransactionFixture struct {
+	Account string     `yaml:"account"`
+	Amount  float32    `yaml:"amount"`
+	At      *time.Time `yaml:"at"`
+	Line    int        `yaml:"-"`
+}
+
+func (a *AccountFixture) UnmarshalYAML(node *yaml.Node) error {
+	err := checkFields(node, "ref", "name", "lastName", "frozen")
+	if err != nil {
+		return err
+	}
+
+	type plain AccountFixture
+	a.Line = node.Line
+	return node.Decode((*plain)(a))
+}
+
+func (t *TransactionFixture) UnmarshalYAML(node *yaml.Node) error {
+	err := checkFields(node, "account", "amount", "at")
+	if err != nil {
+		return err
+	}
+
+	type plain TransactionFixture
+	t.Line = node.Line
+	return node.Decode((*plain)(t))
+}
+
+// checkFields rejects keys of a mapping that aren't in allowed; nested
+// decoders don't inherit KnownFields.
+func checkFields(node *yaml.Node, allowed ...string) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		known := false
+		for _, name := range allowed {
+			known = known || key.Value == name
+		}
+		if !known {
+			return fmt.Errorf("line %d: %w %q", key.Line, ErrUnknownField, key.Value)
+		}
+	}
+	return nil
+}
+
+// ParseFile reads and validates a fixtures file.
+func ParseFile(path string) (*Fixtures, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(path, file)
+}
+
+// Parse reads fixtures and checks that they're complete and every reference
+// resolves. Errors carry the source and line, e.g. "fixtures.yaml:12: ...".
+func Parse(source string, r io.Reader) (*Fixtures, error) {
+	f := &Fixtures{Source: source}
+
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	err := dec.Decode(f)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidFixtures, source, err)
+	}
+
+	err = f.validate()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *Fixtures) validate() error {
+	errs := []error{}
+	invalid := func(line int, err error) {
+		errs = append(errs, fmt.Errorf("%s:%d: %w", f.Source, line, err))
+	}
+
+	refs := map[string]int{}
+	names := map[string]int{}
+	for _, acc := range f.Accounts {
+		if acc.Name == "" || acc.LastName == "" {
+			invalid(acc.Line, ErrMissingName)
+		}
+
+		name := acc.Name + " " + acc.LastName
+		if line, found := names[name]; found {
+			invalid(acc.Line, fmt.Errorf("%w %q, first declared on line %d", ErrDuplicateName, name, line))
+		} else {
+			names[name] = acc.Line
+		}
+
+		if acc.Ref == "" {
+			continue
+		}
+		if line, found := refs[acc.Ref]; found {
+			invalid(acc.Line, fmt.Errorf("%w %q, first declared on line %d", ErrDuplicateRef, acc.Ref, line))
+		} else {
+			refs[acc.Ref] = acc.Line
+		}
+	}
+
+	for _, t := range f.Transactions {
+		if _, found := refs[t.Account]; !found {
+			invalid(t.Line, fmt.Errorf("%w %q", ErrUnknownAccount, t.Account))
+		}
+		if t.Amount <= 0 {
+			invalid(t.Line, ErrInvalidAmount)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidFixtures, errors.Join(errs...))
+	}
+	return nil
+}
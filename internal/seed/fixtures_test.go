@@ -0,0 +1,133 @@
+package seed
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	scenarios := map[string]struct {
+		given    string
+		wantErrs []string
+	}{
+		"valid": {
+			given: `
+accounts:
+  - ref: ann
+    name: Ann
+    lastName: Lee
+  - name: Bob
+    lastName: Ray
+transactions:
+  - account: ann
+    amount: 10
+    at: 2024-01-01T09:00:00Z
+`,
+		},
+		"empty": {
+			given: ``,
+		},
+		"unknown-account": {
+			given: `
+accounts:
+  - ref: ann
+    name: Ann
+    lastName: Lee
+transactions:
+  - account: bob
+    amount: 10
+`,
+			wantErrs: []string{`fixtures.yaml:7: unknown account "bob"`},
+		},
+		"duplicates": {
+			given: `
+accounts:
+  - ref: ann
+    name: Ann
+    lastName: Lee
+  - ref: ann
+    name: Ann
+    lastName: Lee
+`,
+			wantErrs: []string{
+				`fixtures.yaml:6: duplicate account "Ann Lee", first declared on line 3`,
+				`fixtures.yaml:6: duplicate account ref "ann", first declared on line 3`,
+			},
+		},
+		"missing-name-and-invalid-amount": {
+			given: `
+accounts:
+  - ref: ann
+    name: Ann
+transactions:
+  - account: ann
+    amount: 0
+`,
+			wantErrs: []string{
+				"fixtures.yaml:3: name and lastName are required",
+				"fixtures.yaml:6: amount must be greater than zero",
+			},
+		},
+		"unknown-field": {
+			given: `
+accounts:
+  - ref: ann
+    name: Ann
+    lastName: Lee
+    balance: 10
+`,
+			wantErrs: []string{`line 6: unknown field "balance"`},
+		},
+		"unknown-top-level-field": {
+			given: `
+users: []
+`,
+			wantErrs: []string{"field users not found"},
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+
+		t.Run(name, func(t *testing.T) {
+			f, err := Parse("fixtures.yaml", strings.NewReader(tcase.given))
+
+			if len(tcase.wantErrs) == 0 {
+				require.NoError(t, err)
+				assert.Equal(t, "fixtures.yaml", f.Source)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrInvalidFixtures)
+			for _, want := range tcase.wantErrs {
+				assert.ErrorContains(t, err, want)
+			}
+		})
+	}
+}
+
+func TestParse_Lines(t *testing.T) {
+	f, err := Parse("fixtures.yaml", strings.NewReader(`
+accounts:
+  - ref: ann
+    name: Ann
+    lastName: Lee
+transactions:
+  - account: ann
+    amount: 10
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, f.Accounts[0].Line)
+	assert.Equal(t, 7, f.Transactions[0].Line)
+}
+
+func TestDemo(t *testing.T) {
+	f := Demo()
+
+	assert.Len(t, f.Accounts, 4)
+	assert.Len(t, f.Transactions, 2)
+}
@@ -0,0 +1,158 @@
+package seed
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+)
+
+//go:embed demo.yaml
+var demo embed.FS
+
+// errDryRun fails the unit of work of a DryRun so its writes are undone.
+var errDryRun = errors.New("dry run")
+
+// Demo returns the demo data the server loads when features.seedDemoData
+// is on.
+func Demo() *Fixtures {
+	file, err := demo.Open("demo.yaml")
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	f, err := Parse("demo.yaml", file)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Result counts what a Load did.
+type Result struct {
+	AccountsCreated     int
+	AccountsExisting    int
+	TransactionsCreated int
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%d accounts created, %d already present, %d transactions created",
+		r.AccountsCreated, r.AccountsExisting, r.TransactionsCreated)
+}
+
+// Loader writes fixtures through the repositories, so it works with every
+// storage backend.
+type Loader struct {
+	accountRepo     repository.AccountRepo
+	transactionRepo repository.TransactionRepo
+	unitOfWork      repository.UnitOfWork
+	now             func() time.Time
+}
+
+func NewLoader(accountRepo repository.AccountRepo, transactionRepo repository.TransactionRepo, unitOfWork repository.UnitOfWork) *Loader {
+	return &Loader{
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		unitOfWork:      unitOfWork,
+		now:             time.Now,
+	}
+}
+
+// Load creates the accounts of f that don't exist yet, matched by name and
+// last name, together with their transactions. Accounts that already exist
+// are left as they are, transactions included, so loading the same
+// fixtures again changes nothing. Either everything is written or nothing.
+func (l *Loader) Load(ctx context.Context, f *Fixtures) (Result, error) {
+	res := Result{}
+
+	err := l.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		res = Result{}
+
+		existing, err := l.accountRepo.FindAll(ctx)
+		if err != nil {
+			return err
+		}
+		byName := map[string]string{}
+		for _, acc := range existing {
+			byName[acc.Name+" "+acc.LastName] = acc.AccountId
+		}
+
+		// only refs of accounts created by this load get transactions
+		created := map[string]string{}
+		for _, acc := range f.Accounts {
+			if _, found := byName[acc.Name+" "+acc.LastName]; found {
+				res.AccountsExisting++
+				continue
+			}
+
+			id, err := l.accountRepo.Create(ctx, acc.Name, acc.LastName)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", f.Source, acc.Line, err)
+			}
+			if acc.Frozen {
+				_, err = l.accountRepo.SetFrozen(ctx, id, true)
+				if err != nil {
+					return fmt.Errorf("%s:%d: %w", f.Source, acc.Line, err)
+				}
+			}
+
+			res.AccountsCreated++
+			if acc.Ref != "" {
+				created[acc.Ref] = id
+			}
+		}
+
+		for _, t := range f.Transactions {
+			id, found := created[t.Account]
+			if !found {
+				continue
+			}
+
+			at := l.now()
+			if t.At != nil {
+				at = *t.At
+			}
+
+			_, err := l.transactionRepo.Create(ctx, models.Transaction{
+				CreatedAt: at,
+				Owner:     id,
+				Sender:    id,
+				Receiver:  id,
+				Amount:    t.Amount,
+			})
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", f.Source, t.Line, err)
+			}
+			res.TransactionsCreated++
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+// DryRun does what Load would do against the current data and undoes it,
+// so the Result says what loading f would create.
+func (l *Loader) DryRun(ctx context.Context, f *Fixtures) (Result, error) {
+	res := Result{}
+
+	err := l.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = l.Load(ctx, f)
+		if err != nil {
+			return err
+		}
+		return errDryRun
+	})
+	if !errors.Is(err, errDryRun) {
+		return Result{}, err
+	}
+
+	return res, nil
+}
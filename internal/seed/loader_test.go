@@ -0,0 +1,148 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const fixtures = `
+accounts:
+  - ref: ann
+    name: Ann
+    lastName: Lee
+  - ref: bob
+    name: Bob
+    lastName: Ray
+    frozen: true
+transactions:
+  - account: ann
+    amount: 7000
+    at: 2024-01-01T09:00:00Z
+  - account: ann
+    amount: 500
+  - account: bob
+    amount: 3000
+`
+
+func parse(t *testing.T, s string) *Fixtures {
+	f, err := Parse("fixtures.yaml", strings.NewReader(s))
+	require.NoError(t, err)
+	return f
+}
+
+func TestLoader_Load(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	accounts := repository.NewAccountRepo()
+	transactions := repository.NewTransactionRepo()
+	loader := NewLoader(accounts, transactions, repository.NewUnitOfWork())
+	loader.now = func() time.Time { return now }
+
+	res, err := loader.Load(ctx, parse(t, fixtures))
+	require.NoError(t, err)
+	assert.Equal(t, Result{AccountsCreated: 2, TransactionsCreated: 3}, res)
+
+	accs, err := accounts.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, accs, 2)
+
+	byName := map[string]models.Account{}
+	for _, acc := range accs {
+		byName[acc.Name] = acc
+	}
+	assert.False(t, byName["Ann"].Frozen)
+	assert.True(t, byName["Bob"].Frozen)
+
+	balance, err := transactions.GetBalance(ctx, byName["Ann"].AccountId)
+	require.NoError(t, err)
+	assert.Equal(t, 7500.0, balance.Amount)
+
+	history, err := transactions.FindAll(ctx, byName["Ann"].AccountId)
+	require.NoError(t, err)
+	dates := []time.Time{}
+	for _, tr := range history {
+		dates = append(dates, tr.CreatedAt.UTC())
+	}
+	assert.ElementsMatch(t, []time.Time{time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), now}, dates)
+
+	// loading again changes nothing, a new account is all that's added
+	res, err = loader.Load(ctx, parse(t, fixtures))
+	require.NoError(t, err)
+	assert.Equal(t, Result{AccountsExisting: 2}, res)
+
+	res, err = loader.Load(ctx, parse(t, `
+accounts:
+  - ref: ann
+    name: Ann
+    lastName: Lee
+  - ref: cid
+    name: Cid
+    lastName: Moe
+transactions:
+  - account: ann
+    amount: 100
+  - account: cid
+    amount: 100
+`))
+	require.NoError(t, err)
+	assert.Equal(t, Result{AccountsCreated: 1, AccountsExisting: 1, TransactionsCreated: 1}, res)
+
+	accs, err = accounts.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, accs, 3)
+	balance, err = transactions.GetBalance(ctx, byName["Ann"].AccountId)
+	require.NoError(t, err)
+	assert.Equal(t, 7500.0, balance.Amount)
+}
+
+func TestLoader_Load_RollsBack(t *testing.T) {
+	ctx := context.Background()
+	errStorage := errors.New("storage failed")
+
+	accounts := repository.NewAccountRepo()
+	transactions := repository.NewMockTransactionRepo(t)
+	transactions.EXPECT().Create(mock.Anything, mock.Anything).Return("", errStorage)
+
+	loader := NewLoader(accounts, transactions, repository.NewUnitOfWork())
+
+	_, err := loader.Load(ctx, parse(t, fixtures))
+	assert.ErrorIs(t, err, errStorage)
+	assert.ErrorContains(t, err, "fixtures.yaml:11:")
+
+	accs, err := accounts.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, accs)
+}
+
+func TestLoader_DryRun(t *testing.T) {
+	ctx := context.Background()
+
+	accounts := repository.NewAccountRepo()
+	transactions := repository.NewTransactionRepo()
+	_, err := accounts.Create(ctx, "Ann", "Lee")
+	require.NoError(t, err)
+
+	loader := NewLoader(accounts, transactions, repository.NewUnitOfWork())
+
+	res, err := loader.DryRun(ctx, parse(t, fixtures))
+	require.NoError(t, err)
+	assert.Equal(t, Result{AccountsCreated: 1, AccountsExisting: 1, TransactionsCreated: 1}, res)
+
+	accs, err := accounts.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, accs, 1)
+
+	res, err = loader.Load(ctx, parse(t, fixtures))
+	require.NoError(t, err)
+	assert.Equal(t, Result{AccountsCreated: 1, AccountsExisting: 1, TransactionsCreated: 1}, res)
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxMemoLength, MaxTags and MaxTagLength bound what users write on their
+// transactions.
+const (
+	MaxMemoLength = 140
+	MaxTags       = 10
+	MaxTagLength  = 32
+)
+
+var (
+	ErrInvalidMemo = fmt.Errorf("memo is longer than %d characters", MaxMemoLength)
+	ErrInvalidTags = fmt.Errorf("at most %d tags of 1 to %d characters", MaxTags, MaxTagLength)
+)
+
+// Note is what users write on a transaction. Deposit, Withdraw and Transfer
+// write Memo on the transactions they create, and Tags on the one owned by
+// the account making the operation. The zero Note writes nothing.
+type Note struct {
+	Memo string
+	Tags []string
+}
+
+// clean returns n trimmed and without duplicate tags.
+func (n Note) clean() (Note, error) {
+	memo := strings.TrimSpace(n.Memo)
+	if utf8.RuneCountInString(memo) > MaxMemoLength {
+		return Note{}, ErrInvalidMemo
+	}
+
+	var tags []string
+	seen := map[string]bool{}
+	for _, tag := range n.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+			return Note{}, ErrInvalidTags
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > MaxTags {
+		return Note{}, ErrInvalidTags
+	}
+
+	return Note{Memo: memo, Tags: tags}, nil
+}
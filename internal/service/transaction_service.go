@@ -3,11 +3,12 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/gopay/internal/events"
 	"github.com/gopay/internal/models"
 	"github.com/gopay/internal/repository"
-	"github.com/gopay/internal/utils"
 	"github.com/rs/zerolog/log"
 )
 
@@ -15,6 +16,9 @@ var (
 	ErrInvalidAmount        = errors.New("amount cannot be less or equal to zero")
 	ErrInsufficentBalance   = errors.New("insufficient balance")
 	ErrFailedDebitOperation = errors.New("debit operation  unsuccessful ")
+	ErrSameAccount          = errors.New("sender and receiver must be different accounts")
+	ErrAccountFrozen        = errors.New("account is frozen")
+	ErrNotReversible        = errors.New("only deposits can be reversed")
 )
 
 var nowOriginal = func() time.Time {
@@ -33,8 +37,10 @@ func resetClock() {
 }
 
 type TransactionService interface {
-	Deposit(ctx context.Context, owner string, amount float32) error
-	Withdraw(ctx context.Context, owner string, amount float32) error
+	Deposit(ctx context.Context, owner string, amount float32, note Note) error
+	Withdraw(ctx context.Context, owner string, amount float32, note Note) error
+	Transfer(ctx context.Context, sender string, receiver string, amount float32, note Note) error
+	UpdateStatus(ctx context.Context, id string, status models.TransactionStatus) (models.Transaction, error)
 }
 
 var _ TransactionService = (*transactionServiceImpl)(nil)
@@ -42,21 +48,25 @@ var _ TransactionService = (*transactionServiceImpl)(nil)
 type transactionServiceImpl struct {
 	transactionRepo repository.TransactionRepo
 	accountRepo     repository.AccountRepo
+	outboxRepo      repository.OutboxRepo
+	unitOfWork      repository.UnitOfWork
 }
 
-func NewTransactionService(transactionRepo repository.TransactionRepo, accountRepo repository.AccountRepo) *transactionServiceImpl {
+func NewTransactionService(transactionRepo repository.TransactionRepo, accountRepo repository.AccountRepo, outboxRepo repository.OutboxRepo, unitOfWork repository.UnitOfWork) *transactionServiceImpl {
 	return &transactionServiceImpl{
 		transactionRepo: transactionRepo,
 		accountRepo:     accountRepo,
+		outboxRepo:      outboxRepo,
+		unitOfWork:      unitOfWork,
 	}
 }
 
-func (r *transactionServiceImpl) Deposit(ctx context.Context, owner string, amount float32) error {
+func (r *transactionServiceImpl) Deposit(ctx context.Context, owner string, amount float32, note Note) error {
 	if amount <= 0 {
 		return ErrInvalidAmount
 	}
 
-	_, err := r.accountRepo.FindOne(ctx, owner)
+	note, err := note.clean()
 	if err != nil {
 		return err
 	}
@@ -68,18 +78,43 @@ func (r *transactionServiceImpl) Deposit(ctx context.Context, owner string, amou
 		Sender:     owner,
 		Receiver:   owner,
 		Amount:     amount,
+		Type:       models.TransactionDeposit,
+		Status:     models.TransactionPending,
+		Memo:       note.Memo,
+		Tags:       note.Tags,
 	}
 
-	return r.transactionRepo.Create(ctx, transaction)
-}
-
-func (r *transactionServiceImpl) Withdraw(ctx context.Context, owner string, amount float32) error {
-	_, err := r.accountRepo.FindOne(ctx, owner)
+	pending, err := r.open(ctx, []models.Transaction{transaction}, owner)
 	if err != nil {
 		return err
 	}
 
-	consumed, err := r.debit(ctx, owner, owner, amount)
+	err = r.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		err := r.checkActive(ctx, owner)
+		if err != nil {
+			return err
+		}
+
+		posted, err := r.post(ctx, pending)
+		if err != nil {
+			return err
+		}
+
+		return r.recordEvents(ctx, posted, nil, owner)
+	})
+	if err != nil {
+		r.fail(ctx, pending)
+	}
+
+	return err
+}
+
+func (r *transactionServiceImpl) Withdraw(ctx context.Context, owner string, amount float32, note Note) error {
+	if amount >= 0 {
+		return ErrInvalidAmount
+	}
+
+	note, err := note.clean()
 	if err != nil {
 		return err
 	}
@@ -91,55 +126,345 @@ func (r *transactionServiceImpl) Withdraw(ctx context.Context, owner string, amo
 		Sender:     owner,
 		Receiver:   owner,
 		Amount:     amount,
+		Type:       models.TransactionWithdrawal,
+		Status:     models.TransactionPending,
+		Memo:       note.Memo,
+		Tags:       note.Tags,
+	}
+
+	pending, err := r.open(ctx, []models.Transaction{transaction}, owner)
+	if err != nil {
+		return err
 	}
 
-	err = r.transactionRepo.Create(ctx, transaction)
+	debited := false
+	err = r.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		err := r.checkActive(ctx, owner)
+		if err != nil {
+			return err
+		}
+
+		consumed, created, err := r.debit(ctx, owner, owner, amount)
+		if err != nil {
+			return err
+		}
+		debited = true
+
+		err = r.transactionRepo.SetConsumedBy(ctx, consumed, pending[0].TransactionId)
+		if err != nil {
+			return err
+		}
+
+		posted, err := r.post(ctx, pending)
+		if err != nil {
+			return err
+		}
+
+		return r.recordEvents(ctx, append(created, posted...), consumed, owner)
+	})
 	if err != nil {
-		go func() {
-			err := utils.Retry(func() error {
-				return r.transactionRepo.RollBackConsumed(ctx, consumed)
-			}, "rollback of MarkAsConsumed")
-			log.Error().Err(err)
-		}()
-		log.Error().Err(err)
+		r.fail(ctx, pending)
+	}
+	if err != nil && debited {
+		log.Ctx(ctx).Error().Err(err).Msg("TransactionService::Withdraw")
 		return ErrFailedDebitOperation
 	}
 
+	return err
+}
+
+func (r *transactionServiceImpl) Transfer(ctx context.Context, sender string, receiver string, amount float32, note Note) error {
+	if sender == receiver {
+		return ErrSameAccount
+	}
+	if amount >= 0 {
+		return ErrInvalidAmount
+	}
+
+	note, err := note.clean()
+	if err != nil {
+		return err
+	}
+
+	debitTransaction := models.Transaction{
+		CreatedAt:  clockNow(),
+		IsConsumed: true,
+		Owner:      sender,
+		Sender:     sender,
+		Receiver:   receiver,
+		Amount:     amount,
+		Type:       models.TransactionTransferOut,
+		Status:     models.TransactionPending,
+		Memo:       note.Memo,
+		Tags:       note.Tags,
+	}
+
+	creditTransaction := models.Transaction{
+		CreatedAt:  clockNow(),
+		IsConsumed: false,
+		Owner:      receiver,
+		Sender:     sender,
+		Receiver:   receiver,
+		Amount:     (-1) * amount,
+		Type:       models.TransactionTransferIn,
+		Status:     models.TransactionPending,
+		Memo:       note.Memo,
+	}
+
+	pending, err := r.open(ctx, []models.Transaction{debitTransaction, creditTransaction}, sender, receiver)
+	if err != nil {
+		return err
+	}
+
+	debited := false
+	err = r.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		err := r.checkActive(ctx, sender)
+		if err != nil {
+			return err
+		}
+
+		err = r.checkActive(ctx, receiver)
+		if err != nil {
+			return err
+		}
+
+		consumed, created, err := r.debit(ctx, sender, sender, amount)
+		if err != nil {
+			return err
+		}
+		debited = true
+
+		err = r.transactionRepo.SetConsumedBy(ctx, consumed, pending[0].TransactionId)
+		if err != nil {
+			return err
+		}
+
+		posted, err := r.post(ctx, pending)
+		if err != nil {
+			return err
+		}
+
+		return r.recordEvents(ctx, append(created, posted...), consumed, sender, receiver)
+	})
+	if err != nil {
+		r.fail(ctx, pending)
+	}
+	if err != nil && debited {
+		log.Ctx(ctx).Error().Err(err).Msg("TransactionService::Transfer")
+		return ErrFailedDebitOperation
+	}
+
+	return err
+}
+
+// UpdateStatus moves transaction id to status and returns it. Pending
+// transactions are posted or failed by the operation that wrote them, so
+// the only move left to ask for is a reversal, and only deposits can be
+// reversed, before they're consumed.
+func (r *transactionServiceImpl) UpdateStatus(ctx context.Context, id string, status models.TransactionStatus) (models.Transaction, error) {
+	transaction, err := r.transactionRepo.FindOne(ctx, id)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	if status != models.TransactionReversed {
+		return models.Transaction{}, fmt.Errorf("%w: %s to %s, only reversals can be requested",
+			repository.ErrInvalidTransition, transaction.Status, status)
+	}
+	if transaction.Type != models.TransactionDeposit {
+		return models.Transaction{}, ErrNotReversible
+	}
+
+	err = r.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		err := r.transactionRepo.UpdateStatus(ctx, id, status)
+		if err != nil {
+			return err
+		}
+
+		transaction, err = r.transactionRepo.FindOne(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		balance, err := r.transactionRepo.GetBalance(ctx, transaction.Owner)
+		if err != nil {
+			return err
+		}
+
+		return r.outboxRepo.Append(ctx,
+			events.New(models.EventTransactionStatusChanged, transaction.Owner, transaction),
+			events.New(models.EventBalanceChanged, transaction.Owner, balance),
+		)
+	})
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	return transaction, nil
+}
+
+// open writes the transactions of an operation as pending, once its
+// accounts are found active, and returns them with their ids. A transfer's
+// credit leg, the second transaction, links to its debit leg. Nothing moves
+// until they're posted.
+func (r *transactionServiceImpl) open(ctx context.Context, transactions []models.Transaction, accounts ...string) ([]models.Transaction, error) {
+	pending := append([]models.Transaction{}, transactions...)
+
+	err := r.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		for _, acc := range accounts {
+			err := r.checkActive(ctx, acc)
+			if err != nil {
+				return err
+			}
+		}
+
+		for n := range pending {
+			if n > 0 {
+				pending[n].ParentId = pending[0].TransactionId
+			}
+
+			id, err := r.transactionRepo.Create(ctx, pending[n])
+			if err != nil {
+				return err
+			}
+			pending[n].TransactionId = id
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// post moves the pending transactions of an operation to posted and
+// returns them as they are now. It runs in the unit of work that moves the
+// money.
+func (r *transactionServiceImpl) post(ctx context.Context, pending []models.Transaction) ([]models.Transaction, error) {
+	posted := []models.Transaction{}
+	for _, t := range pending {
+		err := r.transactionRepo.UpdateStatus(ctx, t.TransactionId, models.TransactionPosted)
+		if err != nil {
+			return nil, err
+		}
+
+		t, err = r.transactionRepo.FindOne(ctx, t.TransactionId)
+		if err != nil {
+			return nil, err
+		}
+		posted = append(posted, t)
+	}
+	return posted, nil
+}
+
+// fail moves the pending transactions of an operation that failed to
+// failed, after its unit of work undid whatever it had moved, and sends a
+// transaction.status_changed event for each. Failing is best effort, the
+// operation's error is what the caller gets.
+func (r *transactionServiceImpl) fail(ctx context.Context, pending []models.Transaction) {
+	err := r.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		evts := []models.Event{}
+		for _, t := range pending {
+			err := r.transactionRepo.UpdateStatus(ctx, t.TransactionId, models.TransactionFailed)
+			if err != nil {
+				return err
+			}
+
+			t, err = r.transactionRepo.FindOne(ctx, t.TransactionId)
+			if err != nil {
+				return err
+			}
+			evts = append(evts, events.New(models.EventTransactionStatusChanged, t.Owner, t))
+		}
+
+		return r.outboxRepo.Append(ctx, evts...)
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("TransactionService::fail")
+	}
+}
+
+// checkActive returns the lookup error for unknown accounts and
+// ErrAccountFrozen for frozen ones. It runs in the unit of work of the
+// operation, so a freeze can't land between the check and the writes.
+func (r *transactionServiceImpl) checkActive(ctx context.Context, id string) error {
+	account, err := r.accountRepo.FindOne(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if account.Frozen {
+		return ErrAccountFrozen
+	}
 	return nil
 }
 
-func (r *transactionServiceImpl) debit(ctx context.Context, owner string, receiver string, amount float32) ([]string, error) {
+// recordEvents writes transaction.created for every created transaction,
+// transaction.consumed for every consumed one and balance.changed for every
+// affected account to the outbox. It must run in the same unit of work as the
+// writes it describes.
+func (r *transactionServiceImpl) recordEvents(ctx context.Context, created []models.Transaction, consumed []string, accounts ...string) error {
+	evts := []models.Event{}
+
+	for _, t := range created {
+		evts = append(evts, events.New(models.EventTransactionCreated, t.Owner, t))
+	}
+
+	for _, id := range consumed {
+		t, err := r.transactionRepo.FindOne(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		evts = append(evts, events.New(models.EventTransactionConsumed, t.Owner, t))
+	}
+
+	for _, acc := range accounts {
+		balance, err := r.transactionRepo.GetBalance(ctx, acc)
+		if err != nil {
+			return err
+		}
+
+		evts = append(evts, events.New(models.EventBalanceChanged, acc, balance))
+	}
+
+	return r.outboxRepo.Append(ctx, evts...)
+}
+
+// debit consumes transactions of owner, oldest first, until they cover amount
+// and returns their ids along with the remainder it created for the change, if
+// any. It must run in a unit of work, which undoes both when it fails.
+func (r *transactionServiceImpl) debit(ctx context.Context, owner string, receiver string, amount float32) ([]string, []models.Transaction, error) {
 	if amount >= 0 {
-		return []string{}, ErrInvalidAmount
+		return nil, nil, ErrInvalidAmount
 	}
 
 	balance, err := r.transactionRepo.GetBalance(ctx, owner)
 	if err != nil {
-		return []string{}, err
+		return nil, nil, err
 	}
 
 	if (balance.Amount + float64(amount)) < 0 {
-		return []string{}, ErrInsufficentBalance
+		return nil, nil, ErrInsufficentBalance
 	}
 
 	transactions, err := r.transactionRepo.FindAll(ctx, owner)
 	if err != nil {
-		return []string{}, err
+		return nil, nil, err
 	}
 
 	debit := (-1) * amount
 	transConsumed := []string{}
 	for _, t := range transactions {
+		if !t.Available() {
+			continue
+		}
+
 		err = r.transactionRepo.MarkAsConsumed(ctx, t.TransactionId)
 		if err != nil {
-			utils.Go(func() {
-				err := utils.Retry(func() error {
-					return r.transactionRepo.RollBackConsumed(ctx, transConsumed)
-				}, "rollback of MarkAsConsumed")
-				log.Error().Err(err)
-			})
-			log.Error().Err(err)
-			return []string{}, ErrFailedDebitOperation
+			log.Ctx(ctx).Error().Err(err).Msg("TransactionService::debit")
+			return nil, nil, ErrFailedDebitOperation
 		}
 
 		transConsumed = append(transConsumed, t.TransactionId)
@@ -162,22 +487,19 @@ func (r *transactionServiceImpl) debit(ctx context.Context, owner string, receiv
 				Sender:     owner,
 				Receiver:   receiver,
 				Amount:     t.Amount - debit,
+				Type:       models.TransactionRemainder,
+				Status:     models.TransactionPosted,
+				ParentId:   t.TransactionId,
 			}
-			err = r.transactionRepo.Create(ctx, transaction)
+			transaction.TransactionId, err = r.transactionRepo.Create(ctx, transaction)
 			if err != nil {
-				go func() {
-					err := utils.Retry(func() error {
-						return r.transactionRepo.RollBackConsumed(ctx, transConsumed)
-					}, "rollback of MarkAsConsumed")
-					log.Error().Err(err)
-				}()
-				log.Error().Err(err)
-				return []string{}, ErrFailedDebitOperation
+				log.Ctx(ctx).Error().Err(err).Msg("TransactionService::debit")
+				return nil, nil, ErrFailedDebitOperation
 			}
 
-			break
+			return transConsumed, []models.Transaction{transaction}, nil
 		}
 	}
 
-	return transConsumed, nil
+	return transConsumed, nil, nil
 }
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -9,6 +10,8 @@ import (
 	"github.com/gopay/internal/repository"
 	"github.com/gopay/internal/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTransactionService_Deposit(t *testing.T) {
@@ -46,17 +49,59 @@ func TestTransactionService_Deposit(t *testing.T) {
 					Sender:     owner,
 					Receiver:   owner,
 					Amount:     amount,
+					Type:       models.TransactionDeposit,
+					Status:     models.TransactionPending,
 				}
+				posted := transaction
+				posted.TransactionId = "1000000"
+				posted.Status = models.TransactionPosted
 
 				deps.accRepoMock.On("FindOne", ctx, owner).Return(models.Account{
 					AccountId: owner,
 					Name:      "Shankar",
 					LastName:  "Nakai",
 				}, nil)
-				deps.transRepoMock.On("Create", ctx, transaction).Return(nil)
+				deps.transRepoMock.On("Create", ctx, transaction).Return("1000000", nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "1000000", models.TransactionPosted).Return(nil)
+				deps.transRepoMock.On("FindOne", ctx, "1000000").Return(posted, nil)
+				deps.transRepoMock.On("GetBalance", ctx, owner).Return(models.Balance{
+					AccountId: owner,
+					Amount:    float64(amount),
+				}, nil)
+				deps.outboxMock.On("Append", ctx,
+					mock.MatchedBy(func(e models.Event) bool {
+						return e.Type == models.EventTransactionCreated && e.AccountId == owner
+					}),
+					mock.MatchedBy(func(e models.Event) bool {
+						return e.Type == models.EventBalanceChanged && e.AccountId == owner
+					}),
+				).Return(nil).Once()
 			},
 			wantErr: nil,
 		},
+		"post-failure": {
+			given: args{
+				owner:  owner,
+				amount: amount,
+			},
+			doMocks: func(deps transactionServiceDependencies) {
+				deps.accRepoMock.On("FindOne", ctx, owner).Return(models.Account{AccountId: owner}, nil)
+				deps.transRepoMock.On("Create", ctx, mock.Anything).Return("1000000", nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "1000000", models.TransactionPosted).Return(repository.ErrTransactionNotFound)
+				deps.transRepoMock.On("UpdateStatus", ctx, "1000000", models.TransactionFailed).Return(nil)
+				deps.transRepoMock.On("FindOne", ctx, "1000000").Return(models.Transaction{
+					TransactionId: "1000000",
+					Owner:         owner,
+					Status:        models.TransactionFailed,
+				}, nil)
+				deps.outboxMock.On("Append", ctx,
+					mock.MatchedBy(func(e models.Event) bool {
+						return e.Type == models.EventTransactionStatusChanged && e.AccountId == owner
+					}),
+				).Return(nil).Once()
+			},
+			wantErr: repository.ErrTransactionNotFound,
+		},
 		"zero-amount": {
 			given: args{
 				owner:  owner,
@@ -71,6 +116,16 @@ func TestTransactionService_Deposit(t *testing.T) {
 			},
 			wantErr: ErrInvalidAmount,
 		},
+		"frozen-owner": {
+			given: args{
+				owner:  owner,
+				amount: amount,
+			},
+			doMocks: func(deps transactionServiceDependencies) {
+				deps.accRepoMock.On("FindOne", ctx, owner).Return(models.Account{AccountId: owner, Frozen: true}, nil)
+			},
+			wantErr: ErrAccountFrozen,
+		},
 		"invalid-owner": {
 			given: args{
 				owner:  owner,
@@ -91,7 +146,7 @@ func TestTransactionService_Deposit(t *testing.T) {
 				tcase.doMocks(deps)
 			}
 
-			err := service.Deposit(ctx, tcase.given.owner, tcase.given.amount)
+			err := service.Deposit(ctx, tcase.given.owner, tcase.given.amount, Note{})
 
 			if tcase.wantErr == nil {
 				assert.NoError(t, err)
@@ -140,6 +195,7 @@ func TestTransactionService_Withdraw(t *testing.T) {
 						Sender:        owner,
 						Receiver:      owner,
 						Amount:        7000.0,
+						Status:        models.TransactionPosted,
 					},
 				}
 
@@ -150,6 +206,8 @@ func TestTransactionService_Withdraw(t *testing.T) {
 					Sender:     owner,
 					Receiver:   owner,
 					Amount:     amount,
+					Type:       models.TransactionWithdrawal,
+					Status:     models.TransactionPending,
 				}
 
 				transaction := models.Transaction{
@@ -159,6 +217,9 @@ func TestTransactionService_Withdraw(t *testing.T) {
 					Sender:     owner,
 					Receiver:   owner,
 					Amount:     7000 + amount,
+					Type:       models.TransactionRemainder,
+					Status:     models.TransactionPosted,
+					ParentId:   "1000000",
 				}
 
 				deps.accRepoMock.On("FindOne", ctx, owner).Return(models.Account{
@@ -173,11 +234,100 @@ func TestTransactionService_Withdraw(t *testing.T) {
 				}, nil)
 				deps.transRepoMock.On("FindAll", ctx, owner).Return(transactions, nil)
 				deps.transRepoMock.On("MarkAsConsumed", ctx, transactions[0].TransactionId).Return(nil)
-				deps.transRepoMock.On("Create", ctx, transaction).Return(nil)
-				deps.transRepoMock.On("Create", ctx, debitTransaction).Return(nil)
+				deps.transRepoMock.On("Create", ctx, transaction).Return("4000000", nil)
+				deps.transRepoMock.On("Create", ctx, debitTransaction).Return("5000000", nil)
+				deps.transRepoMock.On("SetConsumedBy", ctx, []string{"1000000"}, "5000000").Return(nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "5000000", models.TransactionPosted).Return(nil)
+				deps.transRepoMock.On("FindOne", ctx, "5000000").Return(debitTransaction, nil)
+				deps.transRepoMock.On("FindOne", ctx, transactions[0].TransactionId).Return(transactions[0], nil)
+				deps.outboxMock.On("Append", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			wantErr: nil,
 		},
+		"skips-consumed-transactions": {
+			given: args{
+				owner:  owner,
+				amount: amount,
+			},
+			doMocks: func(deps transactionServiceDependencies) {
+				transactions := []models.Transaction{
+					{
+						TransactionId: "0900000",
+						CreatedAt:     now.Add(-10),
+						IsConsumed:    true,
+						Owner:         owner,
+						Sender:        owner,
+						Receiver:      owner,
+						Amount:        9000.0,
+						Status:        models.TransactionPosted,
+					},
+					{
+						TransactionId: "1000000",
+						CreatedAt:     now,
+						IsConsumed:    false,
+						Owner:         owner,
+						Sender:        owner,
+						Receiver:      owner,
+						Amount:        7000.0,
+						Status:        models.TransactionPosted,
+					},
+				}
+
+				debitTransaction := models.Transaction{
+					CreatedAt:  now,
+					IsConsumed: true,
+					Owner:      owner,
+					Sender:     owner,
+					Receiver:   owner,
+					Amount:     amount,
+					Type:       models.TransactionWithdrawal,
+					Status:     models.TransactionPending,
+				}
+
+				transaction := models.Transaction{
+					CreatedAt:  now,
+					IsConsumed: false,
+					Owner:      owner,
+					Sender:     owner,
+					Receiver:   owner,
+					Amount:     7000 + amount,
+					Type:       models.TransactionRemainder,
+					Status:     models.TransactionPosted,
+					ParentId:   "1000000",
+				}
+
+				deps.accRepoMock.On("FindOne", ctx, owner).Return(models.Account{
+					AccountId: owner,
+					Name:      "Shankar",
+					LastName:  "Nakai",
+				}, nil)
+
+				deps.transRepoMock.On("GetBalance", ctx, owner).Return(models.Balance{
+					AccountId: owner,
+					Amount:    7000,
+				}, nil)
+				deps.transRepoMock.On("FindAll", ctx, owner).Return(transactions, nil)
+				deps.transRepoMock.On("MarkAsConsumed", ctx, transactions[1].TransactionId).Return(nil)
+				deps.transRepoMock.On("Create", ctx, transaction).Return("4000000", nil)
+				deps.transRepoMock.On("Create", ctx, debitTransaction).Return("5000000", nil)
+				deps.transRepoMock.On("SetConsumedBy", ctx, []string{"1000000"}, "5000000").Return(nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "5000000", models.TransactionPosted).Return(nil)
+				deps.transRepoMock.On("FindOne", ctx, "5000000").Return(debitTransaction, nil)
+				deps.transRepoMock.On("FindOne", ctx, transactions[1].TransactionId).Return(transactions[1], nil)
+				deps.outboxMock.On("Append", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			},
+			wantErr: nil,
+		},
+		"frozen-owner": {
+			given: args{
+				owner:  owner,
+				amount: amount,
+			},
+			doMocks: func(deps transactionServiceDependencies) {
+				deps.accRepoMock.On("FindOne", ctx, owner).Return(models.Account{AccountId: owner, Frozen: true}, nil)
+			},
+			wantErr: ErrAccountFrozen,
+		},
 		"invalid-owner": {
 			given: args{
 				owner:  owner,
@@ -193,13 +343,6 @@ func TestTransactionService_Withdraw(t *testing.T) {
 				owner:  owner,
 				amount: amount * -1,
 			},
-			doMocks: func(deps transactionServiceDependencies) {
-				deps.accRepoMock.On("FindOne", ctx, owner).Return(models.Account{
-					AccountId: owner,
-					Name:      "Shankar",
-					LastName:  "Nakai",
-				}, nil)
-			},
 			wantErr: ErrInvalidAmount,
 		},
 		"insufficient-balance": {
@@ -218,6 +361,10 @@ func TestTransactionService_Withdraw(t *testing.T) {
 					AccountId: owner,
 					Amount:    500,
 				}, nil)
+				deps.transRepoMock.On("Create", ctx, mock.Anything).Return("5000000", nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "5000000", models.TransactionFailed).Return(nil)
+				deps.transRepoMock.On("FindOne", ctx, "5000000").Return(models.Transaction{TransactionId: "5000000", Owner: owner}, nil)
+				deps.outboxMock.On("Append", ctx, mock.Anything).Return(nil).Once()
 			},
 			wantErr: ErrInsufficentBalance,
 		},
@@ -236,6 +383,7 @@ func TestTransactionService_Withdraw(t *testing.T) {
 						Sender:        owner,
 						Receiver:      owner,
 						Amount:        200.0,
+						Status:        models.TransactionPosted,
 					},
 					{
 						TransactionId: "2000000",
@@ -245,6 +393,7 @@ func TestTransactionService_Withdraw(t *testing.T) {
 						Sender:        owner,
 						Receiver:      owner,
 						Amount:        100.0,
+						Status:        models.TransactionPosted,
 					},
 					{
 						TransactionId: "3000000",
@@ -254,6 +403,7 @@ func TestTransactionService_Withdraw(t *testing.T) {
 						Sender:        owner,
 						Receiver:      owner,
 						Amount:        300.0,
+						Status:        models.TransactionPosted,
 					},
 				}
 
@@ -264,6 +414,8 @@ func TestTransactionService_Withdraw(t *testing.T) {
 					Sender:     owner,
 					Receiver:   owner,
 					Amount:     -400,
+					Type:       models.TransactionWithdrawal,
+					Status:     models.TransactionPending,
 				}
 
 				transaction := models.Transaction{
@@ -273,6 +425,9 @@ func TestTransactionService_Withdraw(t *testing.T) {
 					Sender:     owner,
 					Receiver:   owner,
 					Amount:     200,
+					Type:       models.TransactionRemainder,
+					Status:     models.TransactionPosted,
+					ParentId:   "3000000",
 				}
 
 				deps.accRepoMock.On("FindOne", ctx, owner).Return(models.Account{
@@ -289,8 +444,15 @@ func TestTransactionService_Withdraw(t *testing.T) {
 				deps.transRepoMock.On("MarkAsConsumed", ctx, transactions[0].TransactionId).Return(nil)
 				deps.transRepoMock.On("MarkAsConsumed", ctx, transactions[1].TransactionId).Return(nil)
 				deps.transRepoMock.On("MarkAsConsumed", ctx, transactions[2].TransactionId).Return(nil)
-				deps.transRepoMock.On("Create", ctx, transaction).Return(nil)
-				deps.transRepoMock.On("Create", ctx, debitTransaction).Return(nil)
+				deps.transRepoMock.On("Create", ctx, transaction).Return("4000000", nil)
+				deps.transRepoMock.On("Create", ctx, debitTransaction).Return("5000000", nil)
+				deps.transRepoMock.On("SetConsumedBy", ctx, []string{"1000000", "2000000", "3000000"}, "5000000").Return(nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "5000000", models.TransactionPosted).Return(nil)
+				deps.transRepoMock.On("FindOne", ctx, "5000000").Return(debitTransaction, nil)
+				for _, tr := range transactions {
+					deps.transRepoMock.On("FindOne", ctx, tr.TransactionId).Return(tr, nil)
+				}
+				deps.outboxMock.On("Append", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			wantErr: nil,
 		},
@@ -309,6 +471,7 @@ func TestTransactionService_Withdraw(t *testing.T) {
 						Sender:        owner,
 						Receiver:      owner,
 						Amount:        200.0,
+						Status:        models.TransactionPosted,
 					},
 					{
 						TransactionId: "2000000",
@@ -318,6 +481,7 @@ func TestTransactionService_Withdraw(t *testing.T) {
 						Sender:        owner,
 						Receiver:      owner,
 						Amount:        200.0,
+						Status:        models.TransactionPosted,
 					},
 					{
 						TransactionId: "3000000",
@@ -327,6 +491,7 @@ func TestTransactionService_Withdraw(t *testing.T) {
 						Sender:        owner,
 						Receiver:      owner,
 						Amount:        200.0,
+						Status:        models.TransactionPosted,
 					},
 				}
 
@@ -337,6 +502,8 @@ func TestTransactionService_Withdraw(t *testing.T) {
 					Sender:     owner,
 					Receiver:   owner,
 					Amount:     -400,
+					Type:       models.TransactionWithdrawal,
+					Status:     models.TransactionPending,
 				}
 
 				deps.accRepoMock.On("FindOne", ctx, owner).Return(models.Account{
@@ -352,7 +519,13 @@ func TestTransactionService_Withdraw(t *testing.T) {
 				deps.transRepoMock.On("FindAll", ctx, owner).Return(transactions, nil)
 				deps.transRepoMock.On("MarkAsConsumed", ctx, transactions[0].TransactionId).Return(nil)
 				deps.transRepoMock.On("MarkAsConsumed", ctx, transactions[1].TransactionId).Return(nil)
-				deps.transRepoMock.On("Create", ctx, debitTransaction).Return(nil)
+				deps.transRepoMock.On("Create", ctx, debitTransaction).Return("5000000", nil)
+				deps.transRepoMock.On("SetConsumedBy", ctx, []string{"1000000", "2000000"}, "5000000").Return(nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "5000000", models.TransactionPosted).Return(nil)
+				deps.transRepoMock.On("FindOne", ctx, "5000000").Return(debitTransaction, nil)
+				deps.transRepoMock.On("FindOne", ctx, transactions[0].TransactionId).Return(transactions[0], nil)
+				deps.transRepoMock.On("FindOne", ctx, transactions[1].TransactionId).Return(transactions[1], nil)
+				deps.outboxMock.On("Append", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			wantErr: nil,
 		},
@@ -371,6 +544,7 @@ func TestTransactionService_Withdraw(t *testing.T) {
 						Sender:        owner,
 						Receiver:      owner,
 						Amount:        200.0,
+						Status:        models.TransactionPosted,
 					},
 					{
 						TransactionId: "2000000",
@@ -380,6 +554,7 @@ func TestTransactionService_Withdraw(t *testing.T) {
 						Sender:        owner,
 						Receiver:      owner,
 						Amount:        100.0,
+						Status:        models.TransactionPosted,
 					},
 					{
 						TransactionId: "3000000",
@@ -389,6 +564,7 @@ func TestTransactionService_Withdraw(t *testing.T) {
 						Sender:        owner,
 						Receiver:      owner,
 						Amount:        300.0,
+						Status:        models.TransactionPosted,
 					},
 				}
 
@@ -406,7 +582,227 @@ func TestTransactionService_Withdraw(t *testing.T) {
 				deps.transRepoMock.On("MarkAsConsumed", ctx, transactions[0].TransactionId).Return(nil)
 				deps.transRepoMock.On("MarkAsConsumed", ctx, transactions[1].TransactionId).Return(nil)
 				deps.transRepoMock.On("MarkAsConsumed", ctx, transactions[2].TransactionId).Return(repository.ErrTransactionNotFound)
-				deps.transRepoMock.On("RollBackConsumed", ctx, []string{"1000000", "2000000"}).Return(nil)
+				deps.transRepoMock.On("Create", ctx, mock.Anything).Return("5000000", nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "5000000", models.TransactionFailed).Return(nil)
+				deps.transRepoMock.On("FindOne", ctx, "5000000").Return(models.Transaction{TransactionId: "5000000", Owner: owner}, nil)
+				deps.outboxMock.On("Append", ctx, mock.Anything).Return(nil).Once()
+			},
+			wantErr: ErrFailedDebitOperation,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			service, deps := setupTransactionService(t)
+			if tcase.doMocks != nil {
+				tcase.doMocks(deps)
+			}
+
+			err := service.Withdraw(ctx, tcase.given.owner, tcase.given.amount, Note{})
+
+			if tcase.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tcase.wantErr)
+			}
+		})
+	}
+}
+
+func TestTransactionService_Transfer(t *testing.T) {
+	now := time.Now()
+	setupClock(now)
+	utils.SetSyncGoroutine()
+	defer utils.ResetGoroutine()
+	defer resetClock()
+
+	var (
+		ctx               = context.Background()
+		sender            = "0001"
+		receiver          = "0002"
+		amount    float32 = -1000.0
+		available         = models.Transaction{
+			TransactionId: "1000000",
+			CreatedAt:     now,
+			IsConsumed:    false,
+			Owner:         sender,
+			Sender:        sender,
+			Receiver:      sender,
+			Amount:        7000.0,
+			Status:        models.TransactionPosted,
+		}
+		remaining = models.Transaction{
+			CreatedAt:  now,
+			IsConsumed: false,
+			Owner:      sender,
+			Sender:     sender,
+			Receiver:   sender,
+			Amount:     6000.0,
+			Type:       models.TransactionRemainder,
+			Status:     models.TransactionPosted,
+			ParentId:   available.TransactionId,
+		}
+		debitTransaction = models.Transaction{
+			CreatedAt:  now,
+			IsConsumed: true,
+			Owner:      sender,
+			Sender:     sender,
+			Receiver:   receiver,
+			Amount:     amount,
+			Type:       models.TransactionTransferOut,
+			Status:     models.TransactionPending,
+		}
+		creditTransaction = models.Transaction{
+			CreatedAt:  now,
+			IsConsumed: false,
+			Owner:      receiver,
+			Sender:     sender,
+			Receiver:   receiver,
+			Amount:     -amount,
+			Type:       models.TransactionTransferIn,
+			Status:     models.TransactionPending,
+			ParentId:   "3000000",
+		}
+	)
+
+	type args struct {
+		sender   string
+		receiver string
+		amount   float32
+	}
+
+	scenarios := map[string]struct {
+		given   args
+		doMocks func(deps transactionServiceDependencies)
+		wantErr error
+	}{
+		"happy-path": {
+			given: args{
+				sender:   sender,
+				receiver: receiver,
+				amount:   amount,
+			},
+			doMocks: func(deps transactionServiceDependencies) {
+				deps.accRepoMock.On("FindOne", ctx, sender).Return(models.Account{AccountId: sender}, nil)
+				deps.accRepoMock.On("FindOne", ctx, receiver).Return(models.Account{AccountId: receiver}, nil)
+
+				deps.transRepoMock.On("GetBalance", ctx, sender).Return(models.Balance{
+					AccountId: sender,
+					Amount:    7000,
+				}, nil)
+				deps.transRepoMock.On("GetBalance", ctx, receiver).Return(models.Balance{
+					AccountId: receiver,
+					Amount:    1000,
+				}, nil)
+				deps.transRepoMock.On("FindAll", ctx, sender).Return([]models.Transaction{available}, nil)
+				deps.transRepoMock.On("MarkAsConsumed", ctx, available.TransactionId).Return(nil)
+				deps.transRepoMock.On("Create", ctx, remaining).Return("2000000", nil)
+				deps.transRepoMock.On("Create", ctx, debitTransaction).Return("3000000", nil)
+				deps.transRepoMock.On("SetConsumedBy", ctx, []string{available.TransactionId}, "3000000").Return(nil)
+				deps.transRepoMock.On("Create", ctx, creditTransaction).Return("4000000", nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "3000000", models.TransactionPosted).Return(nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "4000000", models.TransactionPosted).Return(nil)
+				deps.transRepoMock.On("FindOne", ctx, "3000000").Return(debitTransaction, nil)
+				deps.transRepoMock.On("FindOne", ctx, "4000000").Return(creditTransaction, nil)
+				isTransactionCreated := mock.MatchedBy(func(e models.Event) bool {
+					return e.Type == models.EventTransactionCreated
+				})
+				isTransactionConsumed := mock.MatchedBy(func(e models.Event) bool {
+					return e.Type == models.EventTransactionConsumed && e.AccountId == sender
+				})
+				isBalanceChanged := mock.MatchedBy(func(e models.Event) bool {
+					return e.Type == models.EventBalanceChanged
+				})
+				deps.transRepoMock.On("FindOne", ctx, available.TransactionId).Return(available, nil)
+				deps.outboxMock.On("Append", ctx,
+					isTransactionCreated, isTransactionCreated, isTransactionCreated, isTransactionConsumed, isBalanceChanged, isBalanceChanged,
+				).Return(nil).Once()
+			},
+			wantErr: nil,
+		},
+		"same-account": {
+			given: args{
+				sender:   sender,
+				receiver: sender,
+				amount:   amount,
+			},
+			wantErr: ErrSameAccount,
+		},
+		"frozen-receiver": {
+			given: args{
+				sender:   sender,
+				receiver: receiver,
+				amount:   amount,
+			},
+			doMocks: func(deps transactionServiceDependencies) {
+				deps.accRepoMock.On("FindOne", ctx, sender).Return(models.Account{AccountId: sender}, nil)
+				deps.accRepoMock.On("FindOne", ctx, receiver).Return(models.Account{AccountId: receiver, Frozen: true}, nil)
+			},
+			wantErr: ErrAccountFrozen,
+		},
+		"invalid-receiver": {
+			given: args{
+				sender:   sender,
+				receiver: receiver,
+				amount:   amount,
+			},
+			doMocks: func(deps transactionServiceDependencies) {
+				deps.accRepoMock.On("FindOne", ctx, sender).Return(models.Account{AccountId: sender}, nil)
+				deps.accRepoMock.On("FindOne", ctx, receiver).Return(models.Account{}, repository.ErrAccountNotFound)
+			},
+			wantErr: repository.ErrAccountNotFound,
+		},
+		"insufficient-balance": {
+			given: args{
+				sender:   sender,
+				receiver: receiver,
+				amount:   amount,
+			},
+			doMocks: func(deps transactionServiceDependencies) {
+				deps.accRepoMock.On("FindOne", ctx, sender).Return(models.Account{AccountId: sender}, nil)
+				deps.accRepoMock.On("FindOne", ctx, receiver).Return(models.Account{AccountId: receiver}, nil)
+				deps.transRepoMock.On("GetBalance", ctx, sender).Return(models.Balance{
+					AccountId: sender,
+					Amount:    500,
+				}, nil)
+				deps.transRepoMock.On("Create", ctx, debitTransaction).Return("3000000", nil)
+				deps.transRepoMock.On("Create", ctx, creditTransaction).Return("4000000", nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "3000000", models.TransactionFailed).Return(nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "4000000", models.TransactionFailed).Return(nil)
+				deps.transRepoMock.On("FindOne", ctx, "3000000").Return(debitTransaction, nil)
+				deps.transRepoMock.On("FindOne", ctx, "4000000").Return(creditTransaction, nil)
+				deps.outboxMock.On("Append", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+			},
+			wantErr: ErrInsufficentBalance,
+		},
+		"credit-post-failure-rollback": {
+			given: args{
+				sender:   sender,
+				receiver: receiver,
+				amount:   amount,
+			},
+			doMocks: func(deps transactionServiceDependencies) {
+				deps.accRepoMock.On("FindOne", ctx, sender).Return(models.Account{AccountId: sender}, nil)
+				deps.accRepoMock.On("FindOne", ctx, receiver).Return(models.Account{AccountId: receiver}, nil)
+
+				deps.transRepoMock.On("GetBalance", ctx, sender).Return(models.Balance{
+					AccountId: sender,
+					Amount:    7000,
+				}, nil)
+				deps.transRepoMock.On("FindAll", ctx, sender).Return([]models.Transaction{available}, nil)
+				deps.transRepoMock.On("MarkAsConsumed", ctx, available.TransactionId).Return(nil)
+				deps.transRepoMock.On("Create", ctx, remaining).Return("2000000", nil)
+				deps.transRepoMock.On("Create", ctx, debitTransaction).Return("3000000", nil)
+				deps.transRepoMock.On("SetConsumedBy", ctx, []string{available.TransactionId}, "3000000").Return(nil)
+				deps.transRepoMock.On("Create", ctx, creditTransaction).Return("4000000", nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "3000000", models.TransactionPosted).Return(nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "4000000", models.TransactionPosted).Return(repository.ErrTransactionNotFound)
+				deps.transRepoMock.On("UpdateStatus", ctx, "3000000", models.TransactionFailed).Return(nil)
+				deps.transRepoMock.On("UpdateStatus", ctx, "4000000", models.TransactionFailed).Return(nil)
+				deps.transRepoMock.On("FindOne", ctx, "3000000").Return(debitTransaction, nil)
+				deps.transRepoMock.On("FindOne", ctx, "4000000").Return(creditTransaction, nil)
+				deps.outboxMock.On("Append", ctx, mock.Anything, mock.Anything).Return(nil).Once()
 			},
 			wantErr: ErrFailedDebitOperation,
 		},
@@ -420,7 +816,7 @@ func TestTransactionService_Withdraw(t *testing.T) {
 				tcase.doMocks(deps)
 			}
 
-			err := service.Withdraw(ctx, tcase.given.owner, tcase.given.amount)
+			err := service.Transfer(ctx, tcase.given.sender, tcase.given.receiver, tcase.given.amount, Note{})
 
 			if tcase.wantErr == nil {
 				assert.NoError(t, err)
@@ -431,16 +827,267 @@ func TestTransactionService_Withdraw(t *testing.T) {
 	}
 }
 
+func TestTransactionService_UpdateStatus(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		deposit = models.Transaction{
+			TransactionId: "1000000",
+			Owner:         "0001",
+			Sender:        "0001",
+			Receiver:      "0001",
+			Amount:        100,
+			Type:          models.TransactionDeposit,
+			Status:        models.TransactionPosted,
+		}
+		reversed = models.Transaction{
+			TransactionId: "1000000",
+			Owner:         "0001",
+			Sender:        "0001",
+			Receiver:      "0001",
+			Amount:        100,
+			Type:          models.TransactionDeposit,
+			Status:        models.TransactionReversed,
+		}
+	)
+
+	scenarios := map[string]struct {
+		status  models.TransactionStatus
+		doMocks func(deps transactionServiceDependencies)
+		want    models.Transaction
+		wantErr error
+	}{
+		"reverse-deposit": {
+			status: models.TransactionReversed,
+			doMocks: func(deps transactionServiceDependencies) {
+				deps.transRepoMock.On("FindOne", ctx, "1000000").Return(deposit, nil).Once()
+				deps.transRepoMock.On("UpdateStatus", ctx, "1000000", models.TransactionReversed).Return(nil)
+				deps.transRepoMock.On("FindOne", ctx, "1000000").Return(reversed, nil).Once()
+				deps.transRepoMock.On("GetBalance", ctx, "0001").Return(models.Balance{AccountId: "0001"}, nil)
+				deps.outboxMock.On("Append", ctx,
+					mock.MatchedBy(func(e models.Event) bool {
+						return e.Type == models.EventTransactionStatusChanged && e.AccountId == "0001"
+					}),
+					mock.MatchedBy(func(e models.Event) bool {
+						return e.Type == models.EventBalanceChanged && e.AccountId == "0001"
+					}),
+				).Return(nil).Once()
+			},
+			want: reversed,
+		},
+		"reverse-withdrawal": {
+			status: models.TransactionReversed,
+			doMocks: func(deps transactionServiceDependencies) {
+				withdrawal := deposit
+				withdrawal.Type = models.TransactionWithdrawal
+				deps.transRepoMock.On("FindOne", ctx, "1000000").Return(withdrawal, nil)
+			},
+			wantErr: ErrNotReversible,
+		},
+		"invalid-transition": {
+			status: models.TransactionPending,
+			doMocks: func(deps transactionServiceDependencies) {
+				deps.transRepoMock.On("FindOne", ctx, "1000000").Return(deposit, nil)
+			},
+			wantErr: repository.ErrInvalidTransition,
+		},
+		"not-found": {
+			status: models.TransactionPosted,
+			doMocks: func(deps transactionServiceDependencies) {
+				deps.transRepoMock.On("FindOne", ctx, "1000000").Return(models.Transaction{}, repository.ErrTransactionNotFound)
+			},
+			wantErr: repository.ErrTransactionNotFound,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			service, deps := setupTransactionService(t)
+			tcase.doMocks(deps)
+
+			result, err := service.UpdateStatus(ctx, "1000000", tcase.status)
+
+			if tcase.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tcase.want, result)
+			} else {
+				assert.ErrorIs(t, err, tcase.wantErr)
+			}
+		})
+	}
+}
+
 type transactionServiceDependencies struct {
 	transRepoMock *repository.MockTransactionRepo
 	accRepoMock   *repository.MockAccountRepo
+	outboxMock    *repository.MockOutboxRepo
+	uowMock       *repository.MockUnitOfWork
 }
 
 func setupTransactionService(t *testing.T) (*transactionServiceImpl, transactionServiceDependencies) {
 	deps := transactionServiceDependencies{
 		transRepoMock: repository.NewMockTransactionRepo(t),
 		accRepoMock:   repository.NewMockAccountRepo(t),
+		outboxMock:    repository.NewMockOutboxRepo(t),
+		uowMock:       repository.NewMockUnitOfWork(t),
+	}
+
+	deps.uowMock.EXPECT().Do(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+			return fn(ctx)
+		}).
+		Maybe()
+
+	return NewTransactionService(deps.transRepoMock, deps.accRepoMock, deps.outboxMock, deps.uowMock), deps
+}
+
+func TestTransactionService_Note(t *testing.T) {
+	utils.SetSyncGoroutine()
+	defer utils.ResetGoroutine()
+
+	accounts := repository.NewAccountRepo()
+	transactions := repository.NewTransactionRepo()
+	service := NewTransactionService(transactions, accounts, repository.NewOutboxRepo(), repository.NewUnitOfWork())
+
+	ctx := context.Background()
+	sender, err := accounts.Create(ctx, "Shankar", "Nakai")
+	require.NoError(t, err)
+	receiver, err := accounts.Create(ctx, "Jessica", "Lourenco")
+	require.NoError(t, err)
+
+	require.NoError(t, service.Deposit(ctx, sender, 100, Note{}))
+	err = service.Transfer(ctx, sender, receiver, -30, Note{Memo: " dinner ", Tags: []string{"trip", " trip", "food"}})
+	require.NoError(t, err)
+
+	sent, err := transactions.FindAll(ctx, sender)
+	require.NoError(t, err)
+	// the debit leg is written before the remainder it leaves
+	require.Len(t, sent, 3)
+	debit := sent[1]
+	assert.Equal(t, models.TransactionTransferOut, debit.Type)
+	assert.Equal(t, "dinner", debit.Memo)
+	assert.Equal(t, []string{"trip", "food"}, debit.Tags)
+
+	received, err := transactions.FindAll(ctx, receiver)
+	require.NoError(t, err)
+	require.Len(t, received, 1)
+	assert.Equal(t, "dinner", received[0].Memo)
+	assert.Empty(t, received[0].Tags)
+
+	scenarios := map[string]struct {
+		memo    string
+		tags    []string
+		wantErr error
+	}{
+		"memo-too-long": {
+			memo:    strings.Repeat("a", MaxMemoLength+1),
+			wantErr: ErrInvalidMemo,
+		},
+		"empty-tag": {
+			tags:    []string{" "},
+			wantErr: ErrInvalidTags,
+		},
+		"tag-too-long": {
+			tags:    []string{strings.Repeat("a", MaxTagLength+1)},
+			wantErr: ErrInvalidTags,
+		},
+		"too-many-tags": {
+			tags:    strings.Split("a b c d e f g h i j k", " "),
+			wantErr: ErrInvalidTags,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			note := Note{Memo: tcase.memo, Tags: tcase.tags}
+
+			assert.ErrorIs(t, service.Deposit(ctx, sender, 1, note), tcase.wantErr)
+			assert.ErrorIs(t, service.Withdraw(ctx, sender, -1, note), tcase.wantErr)
+			assert.ErrorIs(t, service.Transfer(ctx, sender, receiver, -1, note), tcase.wantErr)
+		})
 	}
+}
+
+// failingCreditRepo fails to post the credit leg of transfers.
+type failingCreditRepo struct {
+	repository.TransactionRepo
+}
+
+func (r failingCreditRepo) UpdateStatus(ctx context.Context, id string, status models.TransactionStatus) error {
+	transaction, err := r.TransactionRepo.FindOne(ctx, id)
+	if err != nil {
+		return err
+	}
+	if transaction.Type == models.TransactionTransferIn && status == models.TransactionPosted {
+		return repository.ErrTransactionNotFound
+	}
+	return r.TransactionRepo.UpdateStatus(ctx, id, status)
+}
+
+func TestTransactionService_TransferUndoesDebit(t *testing.T) {
+	accounts := repository.NewAccountRepo()
+	transactions := repository.NewTransactionRepo()
+	outbox := repository.NewOutboxRepo()
+	service := NewTransactionService(failingCreditRepo{transactions}, accounts, outbox, repository.NewUnitOfWork())
+
+	ctx := context.Background()
+	sender, err := accounts.Create(ctx, "Shankar", "Nakai")
+	require.NoError(t, err)
+	receiver, err := accounts.Create(ctx, "Jessica", "Lourenco")
+	require.NoError(t, err)
+	require.NoError(t, service.Deposit(ctx, sender, 100, Note{}))
+
+	err = service.Transfer(ctx, sender, receiver, -30, Note{})
+	assert.ErrorIs(t, err, ErrFailedDebitOperation)
+
+	// neither the consumed deposit nor the remainder outlive the transfer,
+	// its legs are left behind failed
+	sent, err := transactions.FindAll(ctx, sender)
+	require.NoError(t, err)
+	require.Len(t, sent, 2)
+	assert.Equal(t, models.TransactionDeposit, sent[0].Type)
+	assert.True(t, sent[0].Available())
+	assert.Equal(t, models.TransactionTransferOut, sent[1].Type)
+	assert.Equal(t, models.TransactionFailed, sent[1].Status)
+
+	received, err := transactions.FindAll(ctx, receiver)
+	require.NoError(t, err)
+	require.Len(t, received, 1)
+	assert.Equal(t, models.TransactionFailed, received[0].Status)
+
+	balance, err := transactions.GetBalance(ctx, sender)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, balance.Amount)
+
+	// the deposit's transaction.created and balance.changed, then a
+	// transaction.status_changed for each failed leg
+	pending, err := outbox.FindPending(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 4)
+	assert.Equal(t, models.EventTransactionStatusChanged, pending[2].Event.Type)
+	assert.Equal(t, models.EventTransactionStatusChanged, pending[3].Event.Type)
+}
+
+func TestTransactionService_DebitsOnlyAvailable(t *testing.T) {
+	accounts := repository.NewAccountRepo()
+	transactions := repository.NewTransactionRepo()
+	service := NewTransactionService(transactions, accounts, repository.NewOutboxRepo(), repository.NewUnitOfWork())
+
+	ctx := context.Background()
+	sender, err := accounts.Create(ctx, "Shankar", "Nakai")
+	require.NoError(t, err)
+	receiver, err := accounts.Create(ctx, "Jessica", "Lourenco")
+	require.NoError(t, err)
+	require.NoError(t, service.Deposit(ctx, sender, 100, Note{}))
+
+	// each debit consumes the remainder of the one before, never the deposit
+	// again
+	require.NoError(t, service.Withdraw(ctx, sender, -30, Note{}))
+	require.NoError(t, service.Transfer(ctx, sender, receiver, -30, Note{}))
+	assert.ErrorIs(t, service.Withdraw(ctx, sender, -50, Note{}), ErrInsufficentBalance)
 
-	return NewTransactionService(deps.transRepoMock, deps.accRepoMock), deps
+	balance, err := transactions.GetBalance(ctx, sender)
+	require.NoError(t, err)
+	assert.Equal(t, 40.0, balance.Amount)
 }
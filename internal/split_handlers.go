@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/splits"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const SplitIdParam = "split-id"
+
+type SplitHandler struct {
+	splitter *splits.Splitter
+	repo     splits.Repo
+}
+
+func NewSplitHandler(splitter *splits.Splitter, repo splits.Repo) *SplitHandler {
+	return &SplitHandler{
+		splitter: splitter,
+		repo:     repo,
+	}
+}
+
+func (h *SplitHandler) PostSplit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, OneMegabyte))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostSplit")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	split := models.Split{}
+	err = jsoniter.Unmarshal(body, &split)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostSplit")
+		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	split, err = h.splitter.Create(r.Context(), split)
+	if errors.Is(err, splits.ErrInvalidSplit) {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostSplit")
+		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostSplit")
+		utils.ErrorWithMessage(w, transactionErrorStatus(err), err.Error())
+		return
+	}
+
+	h.respond(w, r, "Handler::PostSplit", http.StatusCreated, split)
+}
+
+func (h *SplitHandler) GetSplit(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	split, err := h.repo.FindOne(r.Context(), params.ByName(SplitIdParam))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetSplit")
+		utils.ErrorWithMessage(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	h.respond(w, r, "Handler::GetSplit", http.StatusOK, split)
+}
+
+func (h *SplitHandler) PostSettleObligation(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	split, err := h.splitter.Settle(r.Context(), params.ByName(SplitIdParam), params.ByName(AccountIdParam))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostSettleObligation")
+		utils.ErrorWithMessage(w, settleErrorStatus(err), err.Error())
+		return
+	}
+
+	h.respond(w, r, "Handler::PostSettleObligation", http.StatusOK, split)
+}
+
+func (h *SplitHandler) respond(w http.ResponseWriter, r *http.Request, name string, status int, split models.Split) {
+	res, err := jsoniter.Marshal(&split)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(name)
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, status, res)
+}
+
+func settleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, splits.ErrSplitNotFound), errors.Is(err, splits.ErrObligationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, splits.ErrObligationSettled):
+		return http.StatusConflict
+	default:
+		return transactionErrorStatus(err)
+	}
+}
@@ -0,0 +1,69 @@
+package splits
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/gopay/internal/models"
+)
+
+var ErrSplitNotFound = errors.New("split not found")
+
+// Repo keeps splits and the settlement state of their obligations.
+type Repo interface {
+	FindOne(ctx context.Context, id string) (models.Split, error)
+	Create(ctx context.Context, split models.Split) (string, error)
+	Update(ctx context.Context, split models.Split) error
+}
+
+var _ Repo = (*repoImpl)(nil)
+
+type repoImpl struct {
+	mu          sync.RWMutex
+	splits      map[string]models.Split
+	idGenerator func() string
+}
+
+func NewRepo() *repoImpl {
+	return &repoImpl{
+		splits:      make(map[string]models.Split),
+		idGenerator: uuid.NewString,
+	}
+}
+
+func (r *repoImpl) FindOne(_ context.Context, id string) (models.Split, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	split, found := r.splits[id]
+	if !found {
+		return models.Split{}, ErrSplitNotFound
+	}
+
+	return split, nil
+}
+
+func (r *repoImpl) Create(_ context.Context, split models.Split) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.idGenerator()
+	split.SplitId = id
+	r.splits[id] = split
+
+	return id, nil
+}
+
+func (r *repoImpl) Update(_ context.Context, split models.Split) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.splits[split.SplitId]; !found {
+		return ErrSplitNotFound
+	}
+	r.splits[split.SplitId] = split
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package splits
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ToCents converts an amount to whole cents, false when it has more than two
+// decimals. It reads the decimals off the shortest text that parses back to
+// amount, which is what the client sent; float64(amount) is already off by
+// more than any fixed tolerance above a few thousand, 1000.10 is
+// 1000.0999755859375.
+func ToCents(amount float32) (int64, bool) {
+	whole, frac, _ := strings.Cut(strconv.FormatFloat(float64(amount), 'f', -1, 32), ".")
+	if len(frac) > 2 {
+		return int64(math.Round(float64(amount) * 100)), false
+	}
+
+	cents, err := strconv.ParseInt(whole+frac+strings.Repeat("0", 2-len(frac)), 10, 64)
+	return cents, err == nil
+}
+
+// toBasisPoints converts a percentage to hundredths of a percent, false when
+// it has more than two decimals.
+func toBasisPoints(percent float64) (int64, bool) {
+	bp := math.Round(percent * 100)
+	return int64(bp), math.Abs(percent*100-bp) < 1e-6
+}
+
+// Allocate divides total cents in proportion to weights. Every share is
+// rounded down and the leftover cents go one each to the shares with the
+// largest rounding remainder, ties to the earlier one, so the shares always
+// add up to total and the same inputs always give the same shares.
+func Allocate(total int64, weights []int64) []int64 {
+	var sum int64
+	for _, w := range weights {
+		sum += w
+	}
+
+	shares := make([]int64, len(weights))
+	remainders := make([]int64, len(weights))
+	left := total
+	for i, w := range weights {
+		shares[i] = total * w / sum
+		remainders[i] = total * w % sum
+		left -= shares[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+
+	for _, i := range order[:left] {
+		shares[i]++
+	}
+
+	return shares
+}
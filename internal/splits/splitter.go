@@ -0,0 +1,217 @@
+package splits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+)
+
+// MaxParticipants bounds the obligations of one split.
+const MaxParticipants = 100
+
+var (
+	ErrInvalidSplit          = errors.New("invalid split")
+	ErrInvalidTotal          = errors.New("total must be a positive amount with at most two decimals")
+	ErrUnknownMethod         = errors.New("method must be equal, amount or percent")
+	ErrNoParticipants        = errors.New("a split needs at least one participant")
+	ErrTooManyParticipants   = fmt.Errorf("a split has at most %d participants", MaxParticipants)
+	ErrDuplicateParticipant  = errors.New("participant is listed twice")
+	ErrUnknownParticipant    = errors.New("participant account not found")
+	ErrInvalidShare          = errors.New("amounts and percentages must be non-negative with at most two decimals")
+	ErrSharesMismatch        = errors.New("amounts must add up to the total")
+	ErrPercentagesMismatch   = errors.New("percentages must add up to 100")
+	ErrObligationNotFound    = errors.New("account has no obligation in this split")
+	ErrObligationSettled     = errors.New("obligation is already settled")
+	ErrUnexpectedShareFields = errors.New("equal splits take no amounts or percentages")
+)
+
+// Splitter shares bills between accounts and settles what each participant
+// owes the payer with transfers through the TransactionService.
+type Splitter struct {
+	accountRepo        repository.AccountRepo
+	transactionService service.TransactionService
+	repo               Repo
+	now                func() time.Time
+
+	// settling serializes settlements so an obligation is never paid twice
+	settling sync.Mutex
+}
+
+func NewSplitter(accountRepo repository.AccountRepo, transactionService service.TransactionService, repo Repo) *Splitter {
+	return &Splitter{
+		accountRepo:        accountRepo,
+		transactionService: transactionService,
+		repo:               repo,
+		now:                time.Now,
+	}
+}
+
+// Create computes every participant's share of split and stores it with an
+// obligation per participant. The payer's own share and zero shares are
+// settled from the start. Invalid splits fail with ErrInvalidSplit.
+func (s *Splitter) Create(ctx context.Context, split models.Split) (models.Split, error) {
+	_, err := s.accountRepo.FindOne(ctx, split.Payer)
+	if err != nil {
+		return models.Split{}, err
+	}
+
+	err = s.share(ctx, &split)
+	if err != nil {
+		return models.Split{}, fmt.Errorf("%w: %w", ErrInvalidSplit, err)
+	}
+
+	split.CreatedAt = s.now()
+	for n := range split.Obligations {
+		o := &split.Obligations[n]
+		o.Status = models.ObligationPending
+		if o.Account == split.Payer || o.Amount == 0 {
+			o.Status, o.SettledAt = models.ObligationSettled, &split.CreatedAt
+		}
+	}
+	split.Status = status(split.Obligations)
+
+	split.SplitId, err = s.repo.Create(ctx, split)
+	return split, err
+}
+
+// share validates the participants and sets the amount of every obligation.
+func (s *Splitter) share(ctx context.Context, split *models.Split) error {
+	total, ok := ToCents(split.Total)
+	if !ok || total <= 0 {
+		return ErrInvalidTotal
+	}
+
+	switch {
+	case len(split.Obligations) == 0:
+		return ErrNoParticipants
+	case len(split.Obligations) > MaxParticipants:
+		return ErrTooManyParticipants
+	}
+
+	seen := map[string]bool{}
+	for n, o := range split.Obligations {
+		if seen[o.Account] {
+			return fmt.Errorf("obligations[%d]: %w", n, ErrDuplicateParticipant)
+		}
+		seen[o.Account] = true
+
+		_, err := s.accountRepo.FindOne(ctx, o.Account)
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			return fmt.Errorf("obligations[%d]: %w", n, ErrUnknownParticipant)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	weights := make([]int64, len(split.Obligations))
+	switch split.Method {
+	case models.SplitEqual:
+		for n, o := range split.Obligations {
+			if o.Amount != 0 || o.Percent != 0 {
+				return fmt.Errorf("obligations[%d]: %w", n, ErrUnexpectedShareFields)
+			}
+			weights[n] = 1
+		}
+	case models.SplitPercent:
+		var sum int64
+		for n, o := range split.Obligations {
+			bp, ok := toBasisPoints(o.Percent)
+			if !ok || bp < 0 {
+				return fmt.Errorf("obligations[%d]: %w", n, ErrInvalidShare)
+			}
+			weights[n], sum = bp, sum+bp
+		}
+		if sum != 100*100 {
+			return ErrPercentagesMismatch
+		}
+	case models.SplitAmount:
+		var sum int64
+		for n, o := range split.Obligations {
+			cents, ok := ToCents(o.Amount)
+			if !ok || cents < 0 {
+				return fmt.Errorf("obligations[%d]: %w", n, ErrInvalidShare)
+			}
+			weights[n], sum = cents, sum+cents
+		}
+		if sum != total {
+			return ErrSharesMismatch
+		}
+	default:
+		return ErrUnknownMethod
+	}
+
+	for n, cents := range Allocate(total, weights) {
+		split.Obligations[n].Amount = float32(cents) / 100
+	}
+	return nil
+}
+
+// Settle transfers what account owes from account to the payer of the split
+// and marks the obligation settled. A failed transfer leaves it pending.
+func (s *Splitter) Settle(ctx context.Context, id string, account string) (models.Split, error) {
+	s.settling.Lock()
+	defer s.settling.Unlock()
+
+	split, err := s.repo.FindOne(ctx, id)
+	if err != nil {
+		return models.Split{}, err
+	}
+
+	n := -1
+	for i, o := range split.Obligations {
+		if o.Account == account {
+			n = i
+		}
+	}
+	if n < 0 {
+		return models.Split{}, ErrObligationNotFound
+	}
+
+	o := &split.Obligations[n]
+	if o.Status == models.ObligationSettled {
+		return models.Split{}, ErrObligationSettled
+	}
+
+	ids, err := repository.CreatedIds(ctx, func(ctx context.Context) error {
+		return s.transactionService.Transfer(ctx, account, split.Payer, (-1)*o.Amount, service.Note{})
+	})
+	if err != nil {
+		return models.Split{}, err
+	}
+
+	now := s.now()
+	o.Status, o.SettledAt, o.TransactionIds = models.ObligationSettled, &now, ids
+	split.Status = status(split.Obligations)
+
+	return split, s.repo.Update(ctx, split)
+}
+
+// status is settled once nothing is pending and open until a participant
+// paid, shares settled from the start don't count as payments.
+func status(obligations []models.Obligation) models.SplitStatus {
+	pending, paid := 0, 0
+	for _, o := range obligations {
+		switch {
+		case o.Status == models.ObligationPending:
+			pending++
+		case len(o.TransactionIds) > 0:
+			paid++
+		}
+	}
+
+	switch {
+	case pending == 0:
+		return models.SplitSettled
+	case paid > 0:
+		return models.SplitPartiallySettled
+	default:
+		return models.SplitOpen
+	}
+}
@@ -0,0 +1,262 @@
+package splits
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	splitter     *Splitter
+	transactions repository.TransactionRepo
+	shankar      string
+	jessica      string
+	caio         string
+}
+
+func newFixture(t *testing.T) *fixture {
+	bank := testutil.NewBank(t)
+
+	f := &fixture{
+		transactions: bank.Transactions,
+		shankar:      bank.Account(t, "Shankar", "Nakai"),
+		jessica:      bank.Account(t, "Jessica", "Lourenco"),
+		caio:         bank.Account(t, "Caio", "Henrique"),
+	}
+	bank.Deposit(t, 100, f.jessica)
+
+	f.splitter = NewSplitter(bank.Accounts, bank.Service, NewRepo())
+	return f
+}
+
+func (f *fixture) balance(t *testing.T, id string) float64 {
+	balance, err := f.transactions.GetBalance(context.Background(), id)
+	require.NoError(t, err)
+	return balance.Amount
+}
+
+func TestToCents(t *testing.T) {
+	scenarios := map[string]struct {
+		given  float32
+		want   int64
+		wantOk bool
+	}{
+		"whole":           {given: 10, want: 1000, wantOk: true},
+		"cents":           {given: 0.07, want: 7, wantOk: true},
+		"negative":        {given: -12.5, want: -1250, wantOk: true},
+		"thousand-ten":    {given: 1000.10, want: 100010, wantOk: true},
+		"five-thousand":   {given: 5000.05, want: 500005, wantOk: true},
+		"twelve-thousand": {given: 12345.67, want: 1234567, wantOk: true},
+		"hundred-k":       {given: 100000.01, want: 10000001, wantOk: true},
+		"million":         {given: 1000000, want: 100000000, wantOk: true},
+		"third-decimal":   {given: 0.005, wantOk: false},
+		"large-third":     {given: 1000.125, wantOk: false},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			cents, ok := ToCents(tcase.given)
+			assert.Equal(t, tcase.wantOk, ok)
+			if tcase.wantOk {
+				assert.Equal(t, tcase.want, cents)
+			}
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	scenarios := map[string]struct {
+		total   int64
+		weights []int64
+		want    []int64
+	}{
+		"even": {
+			total:   900,
+			weights: []int64{1, 1, 1},
+			want:    []int64{300, 300, 300},
+		},
+		"leftover-to-earlier-on-ties": {
+			total:   1000,
+			weights: []int64{1, 1, 1},
+			want:    []int64{334, 333, 333},
+		},
+		"leftover-to-largest-remainder": {
+			// 10.00 at 33.33% / 33.33% / 33.34% is 333.3, 333.3, 333.4 cents
+			total:   1000,
+			weights: []int64{3333, 3333, 3334},
+			want:    []int64{333, 333, 334},
+		},
+		"zero-weight": {
+			total:   1,
+			weights: []int64{0, 1, 1},
+			want:    []int64{0, 1, 0},
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tcase.want, Allocate(tcase.total, tcase.weights))
+		})
+	}
+}
+
+func TestSplitter_Create(t *testing.T) {
+	scenarios := map[string]struct {
+		method      models.SplitMethod
+		total       float32
+		obligations func(f *fixture) []models.Obligation
+		wantErr     error
+		wantAmounts []float32
+	}{
+		"equal-with-payer": {
+			method: models.SplitEqual,
+			total:  100,
+			obligations: func(f *fixture) []models.Obligation {
+				return []models.Obligation{{Account: f.shankar}, {Account: f.jessica}, {Account: f.caio}}
+			},
+			wantAmounts: []float32{33.34, 33.33, 33.33},
+		},
+		"percent": {
+			method: models.SplitPercent,
+			total:  80,
+			obligations: func(f *fixture) []models.Obligation {
+				return []models.Obligation{{Account: f.jessica, Percent: 62.5}, {Account: f.caio, Percent: 37.5}}
+			},
+			wantAmounts: []float32{50, 30},
+		},
+		"amount": {
+			method: models.SplitAmount,
+			total:  12.5,
+			obligations: func(f *fixture) []models.Obligation {
+				return []models.Obligation{{Account: f.jessica, Amount: 10.25}, {Account: f.caio, Amount: 2.25}}
+			},
+			wantAmounts: []float32{10.25, 2.25},
+		},
+		"amounts-mismatch": {
+			method: models.SplitAmount,
+			total:  12.5,
+			obligations: func(f *fixture) []models.Obligation {
+				return []models.Obligation{{Account: f.jessica, Amount: 10}, {Account: f.caio, Amount: 2}}
+			},
+			wantErr: ErrSharesMismatch,
+		},
+		"percentages-mismatch": {
+			method: models.SplitPercent,
+			total:  10,
+			obligations: func(f *fixture) []models.Obligation {
+				return []models.Obligation{{Account: f.jessica, Percent: 50}, {Account: f.caio, Percent: 49.99}}
+			},
+			wantErr: ErrPercentagesMismatch,
+		},
+		"too-many-decimals": {
+			method: models.SplitEqual,
+			total:  10.001,
+			obligations: func(f *fixture) []models.Obligation {
+				return []models.Obligation{{Account: f.jessica}}
+			},
+			wantErr: ErrInvalidTotal,
+		},
+		"unknown-method": {
+			method: "random",
+			total:  10,
+			obligations: func(f *fixture) []models.Obligation {
+				return []models.Obligation{{Account: f.jessica}}
+			},
+			wantErr: ErrUnknownMethod,
+		},
+		"duplicate-participant": {
+			method: models.SplitEqual,
+			total:  10,
+			obligations: func(f *fixture) []models.Obligation {
+				return []models.Obligation{{Account: f.jessica}, {Account: f.jessica}}
+			},
+			wantErr: ErrDuplicateParticipant,
+		},
+		"unknown-participant": {
+			method: models.SplitEqual,
+			total:  10,
+			obligations: func(f *fixture) []models.Obligation {
+				return []models.Obligation{{Account: "unknown"}}
+			},
+			wantErr: ErrUnknownParticipant,
+		},
+		"no-participants": {
+			method:      models.SplitEqual,
+			total:       10,
+			obligations: func(f *fixture) []models.Obligation { return nil },
+			wantErr:     ErrNoParticipants,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+
+			split, err := f.splitter.Create(context.Background(), models.Split{
+				Payer:       f.shankar,
+				Total:       tcase.total,
+				Method:      tcase.method,
+				Obligations: tcase.obligations(f),
+			})
+			if tcase.wantErr != nil {
+				assert.ErrorIs(t, err, ErrInvalidSplit)
+				assert.ErrorIs(t, err, tcase.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.NotEmpty(t, split.SplitId)
+			assert.Equal(t, models.SplitOpen, split.Status)
+			for n, want := range tcase.wantAmounts {
+				assert.Equal(t, want, split.Obligations[n].Amount, "obligations[%d]", n)
+			}
+		})
+	}
+}
+
+func TestSplitter_Settle(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	split, err := f.splitter.Create(ctx, models.Split{
+		Payer:       f.shankar,
+		Total:       60,
+		Method:      models.SplitEqual,
+		Obligations: []models.Obligation{{Account: f.shankar}, {Account: f.jessica}, {Account: f.caio}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.ObligationSettled, split.Obligations[0].Status)
+	assert.Equal(t, models.SplitOpen, split.Status)
+
+	split, err = f.splitter.Settle(ctx, split.SplitId, f.jessica)
+	require.NoError(t, err)
+	assert.Equal(t, models.ObligationSettled, split.Obligations[1].Status)
+	assert.Len(t, split.Obligations[1].TransactionIds, 2)
+	assert.Equal(t, models.SplitPartiallySettled, split.Status)
+	assert.InDelta(t, 20, f.balance(t, f.shankar), 0.001)
+	assert.InDelta(t, 80, f.balance(t, f.jessica), 0.001)
+
+	_, err = f.splitter.Settle(ctx, split.SplitId, f.jessica)
+	assert.ErrorIs(t, err, ErrObligationSettled)
+
+	// caio has no money, the obligation stays pending
+	_, err = f.splitter.Settle(ctx, split.SplitId, f.caio)
+	assert.ErrorIs(t, err, service.ErrInsufficentBalance)
+	split, err = f.splitter.repo.FindOne(ctx, split.SplitId)
+	require.NoError(t, err)
+	assert.Equal(t, models.ObligationPending, split.Obligations[2].Status)
+
+	_, err = f.splitter.Settle(ctx, split.SplitId, "unknown")
+	assert.ErrorIs(t, err, ErrObligationNotFound)
+	_, err = f.splitter.Settle(ctx, "unknown", f.caio)
+	assert.ErrorIs(t, err, ErrSplitNotFound)
+}
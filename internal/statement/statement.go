@@ -0,0 +1,187 @@
+package statement
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/utils"
+)
+
+const PeriodLayout = "2006-01"
+
+var ErrInvalidPeriod = errors.New("period must be a month formatted as YYYY-MM")
+
+type Kind string
+
+const (
+	KindDeposit     Kind = "deposit"
+	KindWithdrawal  Kind = "withdrawal"
+	KindTransferIn  Kind = "transfer_in"
+	KindTransferOut Kind = "transfer_out"
+)
+
+// Period is a calendar month in UTC, from the first instant of the month
+// up to but excluding the first instant of the next one.
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+func ParsePeriod(s string) (Period, error) {
+	start, err := time.Parse(PeriodLayout, s)
+	if err != nil {
+		return Period{}, ErrInvalidPeriod
+	}
+
+	return Period{Start: start, End: start.AddDate(0, 1, 0)}, nil
+}
+
+func (p Period) String() string {
+	return p.Start.Format(PeriodLayout)
+}
+
+// Line is one credit or debit of the account. Amount is negative for
+// debits and Balance is the balance right after it.
+type Line struct {
+	TransactionId string    `json:"transactionId"`
+	CreatedAt     time.Time `json:"createdAt"`
+	Kind          Kind      `json:"kind"`
+	Counterparty  string    `json:"counterparty,omitempty"`
+	Amount        float64   `json:"amount"`
+	Balance       float64   `json:"balance"`
+}
+
+// Statement covers one account over one period. ClosingBalance is
+// OpeningBalance plus TotalCredits minus TotalDebits and TotalFees.
+type Statement struct {
+	AccountId      string    `json:"accountId"`
+	Period         string    `json:"period"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	OpeningBalance float64   `json:"openingBalance"`
+	TotalCredits   float64   `json:"totalCredits"`
+	TotalDebits    float64   `json:"totalDebits"`
+	// TotalFees is always 0 as GoPay doesn't charge fees yet.
+	TotalFees      float64 `json:"totalFees"`
+	ClosingBalance float64 `json:"closingBalance"`
+	Lines          []Line  `json:"lines"`
+}
+
+// Generator computes statements from the transaction history alone, so the
+// balance at either end of a period is what GetBalance returned at that
+// instant.
+type Generator struct {
+	transactionRepo repository.TransactionRepo
+}
+
+func NewGenerator(transactionRepo repository.TransactionRepo) *Generator {
+	return &Generator{
+		transactionRepo: transactionRepo,
+	}
+}
+
+func (g *Generator) Generate(ctx context.Context, accountId string, period Period) (Statement, error) {
+	transactions, err := g.transactionRepo.FindAll(ctx, accountId)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	s := Statement{
+		AccountId: accountId,
+		Period:    period.String(),
+		From:      period.Start,
+		To:        period.End,
+		Lines:     []Line{},
+	}
+
+	// FindAll is ordered by creation time
+	for _, t := range transactions {
+		line, moved := toLine(accountId, t)
+		if !moved || !t.CreatedAt.Before(period.End) {
+			continue
+		}
+
+		if t.CreatedAt.Before(period.Start) {
+			s.OpeningBalance += line.Amount
+			continue
+		}
+
+		if line.Amount > 0 {
+			s.TotalCredits += line.Amount
+		} else {
+			s.TotalDebits -= line.Amount
+		}
+		line.Balance = s.OpeningBalance + s.TotalCredits - s.TotalDebits
+		s.Lines = append(s.Lines, line)
+	}
+
+	s.ClosingBalance = s.OpeningBalance + s.TotalCredits - s.TotalDebits - s.TotalFees
+	return s, nil
+}
+
+// toLine classifies t from the point of view of accountId. Remainders only
+// move value between transactions of the same account and aren't lines, nor
+// are transactions whose money didn't move.
+func toLine(accountId string, t models.Transaction) (Line, bool) {
+	if t.Type == models.TransactionRemainder || !t.Posted() || t.Owner != accountId {
+		return Line{}, false
+	}
+
+	line := Line{
+		TransactionId: t.TransactionId,
+		CreatedAt:     t.CreatedAt,
+		Amount:        float64(t.Amount),
+	}
+
+	switch {
+	case t.Type == models.TransactionTransferOut:
+		line.Kind, line.Counterparty = KindTransferOut, t.Receiver
+	case t.Type == models.TransactionTransferIn:
+		line.Kind, line.Counterparty = KindTransferIn, t.Sender
+	case t.Amount > 0:
+		line.Kind = KindDeposit
+	default:
+		line.Kind = KindWithdrawal
+	}
+
+	return line, true
+}
+
+// WriteCSV writes the lines of s between an opening and a closing balance
+// row, followed by the totals, which are positive like in the JSON. Text
+// cells are escaped so spreadsheets don't run them as formulas.
+func WriteCSV(w io.Writer, s Statement) error {
+	out := csv.NewWriter(w)
+	amount := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', 2, 64)
+	}
+	at := func(t time.Time) string {
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	rows := [][]string{
+		{"date", "transactionId", "kind", "counterparty", "amount", "balance"},
+		{at(s.From), "", "opening_balance", "", "", amount(s.OpeningBalance)},
+	}
+	for _, line := range s.Lines {
+		rows = append(rows, []string{at(line.CreatedAt), utils.CSVText(line.TransactionId), string(line.Kind), utils.CSVText(line.Counterparty), amount(line.Amount), amount(line.Balance)})
+	}
+	rows = append(rows,
+		[]string{at(s.To), "", "closing_balance", "", "", amount(s.ClosingBalance)},
+		[]string{at(s.To), "", "total_credits", "", amount(s.TotalCredits), ""},
+		[]string{at(s.To), "", "total_debits", "", amount(s.TotalDebits), ""},
+		[]string{at(s.To), "", "total_fees", "", amount(s.TotalFees), ""},
+	)
+
+	err := out.WriteAll(rows)
+	if err != nil {
+		return err
+	}
+	return out.Error()
+}
@@ -0,0 +1,198 @@
+package statement
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	alice = "0001"
+	bob   = "0002"
+)
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2026, month, d, 12, 0, 0, 0, time.UTC)
+}
+
+// history writes what the service leaves behind for alice: a deposit, a
+// withdrawal and a transfer out, each consuming what came before and
+// leaving a remainder, then a transfer in and another deposit.
+func history(t *testing.T) repository.TransactionRepo {
+	ctx := context.Background()
+	repo := repository.NewTransactionRepo()
+
+	for _, tr := range []models.Transaction{
+		{CreatedAt: day(time.August, 10), Owner: alice, Sender: alice, Receiver: alice, Amount: 1000, IsConsumed: true},
+		{CreatedAt: day(time.September, 5), Owner: alice, Sender: alice, Receiver: alice, Amount: 700, IsConsumed: true, Remainder: true},
+		{CreatedAt: day(time.September, 5), Owner: alice, Sender: alice, Receiver: alice, Amount: -300, IsConsumed: true},
+		{CreatedAt: day(time.September, 20), Owner: alice, Sender: alice, Receiver: alice, Amount: 500, Remainder: true},
+		{CreatedAt: day(time.September, 20), Owner: alice, Sender: alice, Receiver: bob, Amount: -200, IsConsumed: true},
+		{CreatedAt: day(time.September, 20), Owner: bob, Sender: alice, Receiver: bob, Amount: 200},
+		{CreatedAt: day(time.September, 25), Owner: alice, Sender: bob, Receiver: alice, Amount: 50},
+		{CreatedAt: day(time.October, 2), Owner: alice, Sender: alice, Receiver: alice, Amount: 100},
+	} {
+		_, err := repo.Create(ctx, tr)
+		require.NoError(t, err)
+	}
+
+	return repo
+}
+
+func TestParsePeriod(t *testing.T) {
+	scenarios := map[string]struct {
+		given     string
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   error
+	}{
+		"month": {
+			given:     "2026-09",
+			wantStart: time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"december": {
+			given:     "2026-12",
+			wantStart: time.Date(2026, time.December, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"invalid-month": {
+			given:   "2026-13",
+			wantErr: ErrInvalidPeriod,
+		},
+		"day": {
+			given:   "2026-09-01",
+			wantErr: ErrInvalidPeriod,
+		},
+		"empty": {
+			given:   "",
+			wantErr: ErrInvalidPeriod,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+
+		t.Run(name, func(t *testing.T) {
+			period, err := ParsePeriod(tcase.given)
+
+			assert.ErrorIs(t, err, tcase.wantErr)
+			assert.Equal(t, tcase.wantStart, period.Start)
+			assert.Equal(t, tcase.wantEnd, period.End)
+		})
+	}
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	ctx := context.Background()
+	generator := NewGenerator(history(t))
+
+	period, err := ParsePeriod("2026-09")
+	require.NoError(t, err)
+
+	s, err := generator.Generate(ctx, alice, period)
+	require.NoError(t, err)
+
+	assert.Equal(t, "2026-09", s.Period)
+	assert.Equal(t, 1000.0, s.OpeningBalance)
+	assert.Equal(t, 50.0, s.TotalCredits)
+	assert.Equal(t, 500.0, s.TotalDebits)
+	assert.Equal(t, 0.0, s.TotalFees)
+	assert.Equal(t, 550.0, s.ClosingBalance)
+
+	kinds := []Kind{}
+	for _, line := range s.Lines {
+		kinds = append(kinds, line.Kind)
+	}
+	assert.Equal(t, []Kind{KindWithdrawal, KindTransferOut, KindTransferIn}, kinds)
+	assert.Equal(t, bob, s.Lines[1].Counterparty)
+	assert.Equal(t, -200.0, s.Lines[1].Amount)
+	assert.Equal(t, 500.0, s.Lines[1].Balance)
+	assert.Equal(t, bob, s.Lines[2].Counterparty)
+	assert.Equal(t, 550.0, s.Lines[2].Balance)
+}
+
+func TestGenerator_Generate_ReconcilesWithBalance(t *testing.T) {
+	ctx := context.Background()
+	repo := history(t)
+	generator := NewGenerator(repo)
+
+	// every period opens where the previous one closed
+	closing := 0.0
+	for _, month := range []string{"2026-07", "2026-08", "2026-09", "2026-10"} {
+		period, err := ParsePeriod(month)
+		require.NoError(t, err)
+
+		s, err := generator.Generate(ctx, alice, period)
+		require.NoError(t, err)
+		assert.Equal(t, closing, s.OpeningBalance, month)
+		closing = s.ClosingBalance
+	}
+
+	balance, err := repo.GetBalance(ctx, alice)
+	require.NoError(t, err)
+	assert.Equal(t, balance.Amount, closing)
+}
+
+func TestGenerator_Generate_ThroughService(t *testing.T) {
+	ctx := context.Background()
+
+	accounts := repository.NewAccountRepo()
+	transactions := repository.NewTransactionRepo()
+	svc := service.NewTransactionService(transactions, accounts, repository.NewOutboxRepo(), repository.NewUnitOfWork())
+
+	sender, err := accounts.Create(ctx, "Shankar", "Nakai")
+	require.NoError(t, err)
+	receiver, err := accounts.Create(ctx, "Jessica", "Lourenco")
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Deposit(ctx, sender, 700, service.Note{}))
+	require.NoError(t, svc.Deposit(ctx, sender, 300, service.Note{}))
+	require.NoError(t, svc.Withdraw(ctx, sender, -450, service.Note{}))
+	require.NoError(t, svc.Transfer(ctx, sender, receiver, -125, service.Note{}))
+	require.NoError(t, svc.Transfer(ctx, receiver, sender, -25, service.Note{}))
+
+	period, err := ParsePeriod(time.Now().UTC().Format(PeriodLayout))
+	require.NoError(t, err)
+
+	for _, id := range []string{sender, receiver} {
+		s, err := NewGenerator(transactions).Generate(ctx, id, period)
+		require.NoError(t, err)
+
+		balance, err := transactions.GetBalance(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, balance.Amount, s.ClosingBalance, id)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	period, err := ParsePeriod("2026-09")
+	require.NoError(t, err)
+	s, err := NewGenerator(history(t)).Generate(context.Background(), alice, period)
+	require.NoError(t, err)
+	s.Lines[0].TransactionId = "t-1"
+	s.Lines[1].TransactionId = "t-2"
+	s.Lines[2].TransactionId = "t-3"
+	s.Lines[2].Counterparty = "=HYPERLINK(\"http://example.com\")"
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteCSV(buf, s))
+
+	assert.Equal(t, `date,transactionId,kind,counterparty,amount,balance
+2026-09-01T00:00:00Z,,opening_balance,,,1000.00
+2026-09-05T12:00:00Z,t-1,withdrawal,,-300.00,700.00
+2026-09-20T12:00:00Z,t-2,transfer_out,0002,-200.00,500.00
+2026-09-25T12:00:00Z,t-3,transfer_in,"'=HYPERLINK(""http://example.com"")",50.00,550.00
+2026-10-01T00:00:00Z,,closing_balance,,,550.00
+2026-10-01T00:00:00Z,,total_credits,,50.00,
+2026-10-01T00:00:00Z,,total_debits,,500.00,
+2026-10-01T00:00:00Z,,total_fees,,0.00,
+`, buf.String())
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/statement"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+var ErrUnknownFormat = errors.New("format must be json or csv")
+
+type StatementHandler struct {
+	generator   *statement.Generator
+	accountRepo repository.AccountRepo
+}
+
+func NewStatementHandler(generator *statement.Generator, accountRepo repository.AccountRepo) *StatementHandler {
+	return &StatementHandler{
+		generator:   generator,
+		accountRepo: accountRepo,
+	}
+}
+
+func (h *StatementHandler) GetStatement(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	id := params.ByName(AccountIdParam)
+	query := r.URL.Query()
+
+	period, err := statement.ParsePeriod(query.Get("period"))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetStatement")
+		utils.ErrorWithMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		log.Ctx(r.Context()).Error().Err(ErrUnknownFormat).Msg("Handler::GetStatement")
+		utils.ErrorWithMessage(w, http.StatusBadRequest, ErrUnknownFormat.Error())
+		return
+	}
+
+	_, err = h.accountRepo.FindOne(r.Context(), id)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(ErrAccountNotFound).Msg("Handler::GetStatement")
+		utils.ErrorWithMessage(w, http.StatusNotFound, ErrAccountNotFound.Error())
+		return
+	}
+
+	s, err := h.generator.Generate(r.Context(), id, period)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetStatement")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%s-%s.csv"`, id, s.Period))
+		w.WriteHeader(http.StatusOK)
+
+		err = statement.WriteCSV(w, s)
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetStatement")
+		}
+		return
+	}
+
+	res, err := jsoniter.Marshal(&s)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetStatement")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusOK, res)
+}
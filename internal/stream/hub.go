@@ -0,0 +1,186 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/gopay/internal/models"
+)
+
+const (
+	DefaultBufferSize = 100
+	subscriberBuffer  = 16
+)
+
+// Message is one Server-Sent Event. Ids increase per account so clients can
+// resume with Last-Event-ID.
+type Message struct {
+	Id      uint64
+	Event   string
+	Payload Payload
+}
+
+// Payload holds the transaction and the balance for transaction events and
+// the account for account events.
+type Payload struct {
+	Transaction *models.Transaction `json:"transaction,omitempty"`
+	Balance     *models.Balance     `json:"balance,omitempty"`
+	Account     *models.Account     `json:"account,omitempty"`
+}
+
+type account struct {
+	nextId uint64
+	// pending are the transaction events waiting for the balance.changed
+	// event of the operation they belong to.
+	pending     []models.Event
+	buffer      []Message
+	subscribers map[chan Message]struct{}
+}
+
+// Hub turns transaction and freeze events from the bus into per-account
+// streams and
+// keeps the last bufferSize messages of every account for replay. Every
+// operation writes the balance.changed event of an account after its
+// transaction events, so their messages carry the balance the operation left,
+// whenever the events are delivered.
+type Hub struct {
+	mu         sync.Mutex
+	bufferSize int
+	accounts   map[string]*account
+	closed     bool
+}
+
+func NewHub(bufferSize int) *Hub {
+	return &Hub{
+		bufferSize: bufferSize,
+		accounts:   make(map[string]*account),
+	}
+}
+
+// Handle is meant to be subscribed to the event bus.
+func (h *Hub) Handle(event models.Event) {
+	switch event.Type {
+	case models.EventTransactionCreated, models.EventTransactionConsumed, models.EventTransactionStatusChanged:
+		if _, ok := event.Data.(models.Transaction); !ok {
+			return
+		}
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		acc := h.account(event.AccountId)
+		acc.pending = append(acc.pending, event)
+	case models.EventBalanceChanged:
+		balance, ok := event.Data.(models.Balance)
+		if !ok {
+			return
+		}
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		acc := h.account(event.AccountId)
+		for _, pending := range acc.pending {
+			transaction := pending.Data.(models.Transaction)
+			h.send(acc, Message{
+				Event:   string(pending.Type),
+				Payload: Payload{Transaction: &transaction, Balance: &balance},
+			})
+		}
+		acc.pending = nil
+	case models.EventAccountFrozen, models.EventAccountUnfrozen:
+		account, ok := event.Data.(models.Account)
+		if !ok {
+			return
+		}
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		h.send(h.account(event.AccountId), Message{Event: string(event.Type), Payload: Payload{Account: &account}})
+	}
+}
+
+// send numbers msg, buffers it and hands it to the subscribers of acc. Must
+// be called with the lock held.
+func (h *Hub) send(acc *account, msg Message) {
+	acc.nextId++
+	msg.Id = acc.nextId
+
+	acc.buffer = append(acc.buffer, msg)
+	if len(acc.buffer) > h.bufferSize {
+		acc.buffer = acc.buffer[len(acc.buffer)-h.bufferSize:]
+	}
+
+	for ch := range acc.subscribers {
+		select {
+		case ch <- msg:
+		default:
+			// too slow to keep up: drop it, the client resumes from its
+			// last received id when it reconnects
+			delete(acc.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns the buffered messages after lastEventId and a channel
+// for new ones. truncated reports that older messages were already evicted
+// from the buffer. The channel is closed by cancel or when the subscriber
+// falls behind.
+func (h *Hub) Subscribe(accountId string, lastEventId uint64) (replay []Message, truncated bool, ch <-chan Message, cancel func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	acc := h.account(accountId)
+
+	if lastEventId > 0 {
+		for _, msg := range acc.buffer {
+			if msg.Id > lastEventId {
+				replay = append(replay, msg)
+			}
+		}
+		truncated = len(acc.buffer) > 0 && acc.buffer[0].Id > lastEventId+1
+	}
+
+	c := make(chan Message, subscriberBuffer)
+	if h.closed {
+		close(c)
+		return replay, truncated, c, func() {}
+	}
+	acc.subscribers[c] = struct{}{}
+
+	cancel = func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, found := acc.subscribers[c]; found {
+			delete(acc.subscribers, c)
+			close(c)
+		}
+	}
+
+	return replay, truncated, c, cancel
+}
+
+// Close ends every subscription so streaming clients disconnect on shutdown.
+// Later subscriptions get an already closed channel.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, acc := range h.accounts {
+		for ch := range acc.subscribers {
+			delete(acc.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (h *Hub) account(id string) *account {
+	acc, found := h.accounts[id]
+	if !found {
+		acc = &account{subscribers: make(map[chan Message]struct{})}
+		h.accounts[id] = acc
+	}
+	return acc
+}
@@ -0,0 +1,134 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/gopay/internal/events"
+	"github.com/gopay/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_Subscribe(t *testing.T) {
+	owner := "0001"
+
+	scenarios := map[string]struct {
+		published     int
+		lastEventId   uint64
+		wantReplay    []uint64
+		wantTruncated bool
+	}{
+		"no-last-event-id": {
+			published:   3,
+			lastEventId: 0,
+			wantReplay:  nil,
+		},
+		"resume": {
+			published:   3,
+			lastEventId: 1,
+			wantReplay:  []uint64{2, 3},
+		},
+		"up-to-date": {
+			published:   3,
+			lastEventId: 3,
+			wantReplay:  nil,
+		},
+		"evicted-from-buffer": {
+			published:     5,
+			lastEventId:   1,
+			wantReplay:    []uint64{3, 4, 5},
+			wantTruncated: true,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			hub := setupHub(t)
+
+			for i := 0; i < tcase.published; i++ {
+				hub.Handle(events.New(models.EventTransactionCreated, owner, models.Transaction{Owner: owner, Amount: 10}))
+				hub.Handle(events.New(models.EventBalanceChanged, owner, models.Balance{AccountId: owner, Amount: float64(10 * (i + 1))}))
+			}
+
+			replay, truncated, _, cancel := hub.Subscribe(owner, tcase.lastEventId)
+			defer cancel()
+
+			ids := []uint64(nil)
+			for _, msg := range replay {
+				ids = append(ids, msg.Id)
+			}
+			assert.Equal(t, tcase.wantReplay, ids)
+			assert.Equal(t, tcase.wantTruncated, truncated)
+		})
+	}
+}
+
+func TestHub_Handle(t *testing.T) {
+	owner := "0001"
+	hub := setupHub(t)
+
+	_, _, messages, cancel := hub.Subscribe(owner, 0)
+	defer cancel()
+	_, _, others, cancelOthers := hub.Subscribe("0002", 0)
+	defer cancelOthers()
+
+	transaction := models.Transaction{TransactionId: "1000000", Owner: owner, Sender: owner, Receiver: owner, Amount: 50}
+
+	// a balance without transaction events of its own sends nothing
+	hub.Handle(events.New(models.EventBalanceChanged, owner, models.Balance{AccountId: owner}))
+	hub.Handle(events.New(models.EventTransactionConsumed, owner, transaction))
+	hub.Handle(events.New(models.EventTransactionStatusChanged, owner, transaction))
+	assert.Len(t, messages, 0)
+
+	hub.Handle(events.New(models.EventBalanceChanged, owner, models.Balance{AccountId: owner, Amount: 50}))
+	// a later operation doesn't change what the earlier messages say
+	hub.Handle(events.New(models.EventTransactionCreated, owner, transaction))
+	hub.Handle(events.New(models.EventBalanceChanged, owner, models.Balance{AccountId: owner, Amount: 100}))
+
+	require.Len(t, messages, 3)
+	msg := <-messages
+	assert.Equal(t, uint64(1), msg.Id)
+	assert.Equal(t, string(models.EventTransactionConsumed), msg.Event)
+	assert.Equal(t, transaction.TransactionId, msg.Payload.Transaction.TransactionId)
+	assert.Equal(t, &models.Balance{AccountId: owner, Amount: 50}, msg.Payload.Balance)
+
+	msg = <-messages
+	assert.Equal(t, string(models.EventTransactionStatusChanged), msg.Event)
+	assert.Equal(t, 50.0, msg.Payload.Balance.Amount)
+
+	msg = <-messages
+	assert.Equal(t, string(models.EventTransactionCreated), msg.Event)
+	assert.Equal(t, 100.0, msg.Payload.Balance.Amount)
+
+	hub.Handle(events.New(models.EventAccountFrozen, owner, models.Account{AccountId: owner, Frozen: true}))
+	require.Len(t, messages, 1)
+	msg = <-messages
+	assert.Equal(t, string(models.EventAccountFrozen), msg.Event)
+	assert.Equal(t, &models.Account{AccountId: owner, Frozen: true}, msg.Payload.Account)
+	assert.Nil(t, msg.Payload.Transaction)
+
+	assert.Len(t, others, 0)
+}
+
+func setupHub(_ *testing.T) *Hub {
+	return NewHub(3)
+}
+
+func TestHub_Close(t *testing.T) {
+	owner := "0001"
+	hub := setupHub(t)
+
+	_, _, messages, cancel := hub.Subscribe(owner, 0)
+	hub.Close()
+	cancel()
+
+	_, open := <-messages
+	assert.False(t, open)
+
+	_, _, later, cancelLater := hub.Subscribe(owner, 0)
+	defer cancelLater()
+
+	_, open = <-later
+	assert.False(t, open)
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/stream"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const heartbeatInterval = 15 * time.Second
+
+var ErrStreamingUnsupported = errors.New("streaming unsupported")
+
+type StreamHandler struct {
+	hub         *stream.Hub
+	accountRepo repository.AccountRepo
+}
+
+func NewStreamHandler(hub *stream.Hub, accountRepo repository.AccountRepo) *StreamHandler {
+	return &StreamHandler{
+		hub:         hub,
+		accountRepo: accountRepo,
+	}
+}
+
+func (h *StreamHandler) GetAccountStream(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	id := params.ByName(AccountIdParam)
+
+	_, err := h.accountRepo.FindOne(r.Context(), id)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(ErrAccountNotFound).Msg("Handler::GetAccountStream")
+		utils.ErrorWithMessage(w, http.StatusNotFound, ErrAccountNotFound.Error())
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Ctx(r.Context()).Error().Err(ErrStreamingUnsupported).Msg("Handler::GetAccountStream")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, ErrStreamingUnsupported.Error())
+		return
+	}
+
+	// the server's write timeout is meant for regular responses, a stream
+	// stays open until the client leaves or the server shuts down
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	lastEventId, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	replay, truncated, messages, cancel := h.hub.Subscribe(id, lastEventId)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if truncated {
+		fmt.Fprint(w, "event: stream.truncated\ndata: {}\n\n")
+	}
+	for _, msg := range replay {
+		writeMessage(r.Context(), w, msg)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg, open := <-messages:
+			if !open {
+				return
+			}
+			writeMessage(r.Context(), w, msg)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+func writeMessage(ctx context.Context, w http.ResponseWriter, msg stream.Message) {
+	data, err := jsoniter.Marshal(&msg.Payload)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("Handler::GetAccountStream")
+		return
+	}
+
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.Id, msg.Event, data)
+}
@@ -0,0 +1,63 @@
+// Package testutil holds the setup the feature package tests share.
+package testutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// Bank is the in-memory wiring of a transaction service the tests build
+// their feature on.
+type Bank struct {
+	Accounts     repository.AccountRepo
+	Transactions repository.TransactionRepo
+	UnitOfWork   repository.UnitOfWork
+	Service      service.TransactionService
+}
+
+// NewBank returns a Bank with fresh repositories.
+func NewBank(t *testing.T) *Bank {
+	return NewBankWith(t, repository.NewAccountRepo(), repository.NewTransactionRepo())
+}
+
+// NewBankWith returns a Bank on top of the given repositories, e.g. ones a
+// test decorates. Goroutines run synchronously until the test ends.
+func NewBankWith(t *testing.T, accounts repository.AccountRepo, transactions repository.TransactionRepo) *Bank {
+	utils.SetSyncGoroutine()
+	t.Cleanup(utils.ResetGoroutine)
+
+	unitOfWork := repository.NewUnitOfWork()
+	return &Bank{
+		Accounts:     accounts,
+		Transactions: transactions,
+		UnitOfWork:   unitOfWork,
+		Service:      service.NewTransactionService(transactions, accounts, repository.NewOutboxRepo(), unitOfWork),
+	}
+}
+
+// Account creates an account and returns its id.
+func (b *Bank) Account(t *testing.T, name, lastname string) string {
+	id, err := b.Accounts.Create(context.Background(), name, lastname)
+	require.NoError(t, err)
+	return id
+}
+
+// FrozenAccount creates an account and freezes it.
+func (b *Bank) FrozenAccount(t *testing.T, name, lastname string) string {
+	id := b.Account(t, name, lastname)
+	_, err := b.Accounts.SetFrozen(context.Background(), id, true)
+	require.NoError(t, err)
+	return id
+}
+
+// Deposit deposits amount into each of the accounts.
+func (b *Bank) Deposit(t *testing.T, amount float32, ids ...string) {
+	for _, id := range ids {
+		require.NoError(t, b.Service.Deposit(context.Background(), id, amount, service.Note{}))
+	}
+}
@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gopay/internal/utils"
+	"github.com/julienschmidt/httprouter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// paramKeys maps route parameters to the span attributes they're recorded as.
+var paramKeys = map[string]attribute.Key{
+	"account-id":     AccountIdKey,
+	"transaction-id": TransactionIdKey,
+}
+
+// Handler starts a server span for every request to one route, continuing
+// the trace of an incoming traceparent header.
+func Handler(tracer trace.Tracer, method string, route string, next httprouter.Handle) httprouter.Handle {
+	name := method + " " + route
+
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		span.SetAttributes(
+			semconv.HTTPRequestMethodKey.String(method),
+			semconv.HTTPRoute(route),
+			semconv.URLPath(r.URL.Path),
+		)
+		for _, p := range params {
+			if key, ok := paramKeys[p.Key]; ok {
+				span.SetAttributes(key.String(p.Value))
+			}
+		}
+
+		rec := utils.NewStatusRecorder(w)
+		next(rec, r.WithContext(ctx), params)
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.Status))
+		if rec.Status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("status %d", rec.Status))
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	_ repository.TransactionRepo = (*transactionRepo)(nil)
+	_ repository.AccountRepo     = (*accountRepo)(nil)
+)
+
+// transactionRepo records a span for every call to the decorated
+// TransactionRepo.
+type transactionRepo struct {
+	next   repository.TransactionRepo
+	tracer trace.Tracer
+}
+
+func NewTransactionRepo(next repository.TransactionRepo, tracer trace.Tracer) *transactionRepo {
+	return &transactionRepo{
+		next:   next,
+		tracer: tracer,
+	}
+}
+
+func (r *transactionRepo) FindAll(ctx context.Context, accId string) (ts []models.Transaction, err error) {
+	ctx, span := r.tracer.Start(ctx, "TransactionRepo.FindAll", trace.WithAttributes(AccountIdKey.String(accId)))
+	defer func() { end(span, err) }()
+
+	return r.next.FindAll(ctx, accId)
+}
+
+func (r *transactionRepo) FindOne(ctx context.Context, id string) (t models.Transaction, err error) {
+	ctx, span := r.tracer.Start(ctx, "TransactionRepo.FindOne", trace.WithAttributes(TransactionIdKey.String(id)))
+	defer func() { end(span, err) }()
+
+	return r.next.FindOne(ctx, id)
+}
+
+func (r *transactionRepo) Create(ctx context.Context, t models.Transaction) (id string, err error) {
+	ctx, span := r.tracer.Start(ctx, "TransactionRepo.Create", trace.WithAttributes(AccountIdKey.String(t.Owner)))
+	defer func() { end(span, err) }()
+
+	id, err = r.next.Create(ctx, t)
+	span.SetAttributes(TransactionIdKey.String(id))
+	return id, err
+}
+
+func (r *transactionRepo) MarkAsConsumed(ctx context.Context, id string) (err error) {
+	ctx, span := r.tracer.Start(ctx, "TransactionRepo.MarkAsConsumed", trace.WithAttributes(TransactionIdKey.String(id)))
+	defer func() { end(span, err) }()
+
+	return r.next.MarkAsConsumed(ctx, id)
+}
+
+func (r *transactionRepo) SetConsumedBy(ctx context.Context, ids []string, consumedBy string) (err error) {
+	ctx, span := r.tracer.Start(ctx, "TransactionRepo.SetConsumedBy", trace.WithAttributes(TransactionIdsKey.StringSlice(ids)))
+	defer func() { end(span, err) }()
+
+	return r.next.SetConsumedBy(ctx, ids, consumedBy)
+}
+
+func (r *transactionRepo) UpdateStatus(ctx context.Context, id string, status models.TransactionStatus) (err error) {
+	ctx, span := r.tracer.Start(ctx, "TransactionRepo.UpdateStatus", trace.WithAttributes(TransactionIdKey.String(id)))
+	defer func() { end(span, err) }()
+
+	return r.next.UpdateStatus(ctx, id, status)
+}
+
+func (r *transactionRepo) SetCategory(ctx context.Context, id string, category string) (err error) {
+	ctx, span := r.tracer.Start(ctx, "TransactionRepo.SetCategory", trace.WithAttributes(TransactionIdKey.String(id)))
+	defer func() { end(span, err) }()
+
+	return r.next.SetCategory(ctx, id, category)
+}
+
+func (r *transactionRepo) GetBalance(ctx context.Context, id string) (b models.Balance, err error) {
+	ctx, span := r.tracer.Start(ctx, "TransactionRepo.GetBalance", trace.WithAttributes(AccountIdKey.String(id)))
+	defer func() { end(span, err) }()
+
+	return r.next.GetBalance(ctx, id)
+}
+
+// accountRepo records a span for every call to the decorated AccountRepo.
+type accountRepo struct {
+	next   repository.AccountRepo
+	tracer trace.Tracer
+}
+
+func NewAccountRepo(next repository.AccountRepo, tracer trace.Tracer) *accountRepo {
+	return &accountRepo{
+		next:   next,
+		tracer: tracer,
+	}
+}
+
+func (r *accountRepo) FindAll(ctx context.Context) (accs []models.Account, err error) {
+	ctx, span := r.tracer.Start(ctx, "AccountRepo.FindAll")
+	defer func() { end(span, err) }()
+
+	return r.next.FindAll(ctx)
+}
+
+func (r *accountRepo) FindOne(ctx context.Context, id string) (acc models.Account, err error) {
+	ctx, span := r.tracer.Start(ctx, "AccountRepo.FindOne", trace.WithAttributes(AccountIdKey.String(id)))
+	defer func() { end(span, err) }()
+
+	return r.next.FindOne(ctx, id)
+}
+
+func (r *accountRepo) FindMany(ctx context.Context, ids []string) (accs []models.Account, err error) {
+	ctx, span := r.tracer.Start(ctx, "AccountRepo.FindMany", trace.WithAttributes(AccountIdKey.StringSlice(ids)))
+	defer func() { end(span, err) }()
+
+	return r.next.FindMany(ctx, ids)
+}
+
+func (r *accountRepo) Create(ctx context.Context, name string, lastname string) (id string, err error) {
+	ctx, span := r.tracer.Start(ctx, "AccountRepo.Create")
+	defer func() { end(span, err) }()
+
+	id, err = r.next.Create(ctx, name, lastname)
+	span.SetAttributes(AccountIdKey.String(id))
+	return id, err
+}
+
+func (r *accountRepo) SetFrozen(ctx context.Context, id string, frozen bool) (acc models.Account, err error) {
+	ctx, span := r.tracer.Start(ctx, "AccountRepo.SetFrozen", trace.WithAttributes(AccountIdKey.String(id)))
+	defer func() { end(span, err) }()
+
+	return r.next.SetFrozen(ctx, id, frozen)
+}
@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/service"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ service.TransactionService = (*transactionService)(nil)
+
+// transactionService records a span for every operation of the decorated
+// TransactionService.
+type transactionService struct {
+	next   service.TransactionService
+	tracer trace.Tracer
+}
+
+func NewTransactionService(next service.TransactionService, tracer trace.Tracer) *transactionService {
+	return &transactionService{
+		next:   next,
+		tracer: tracer,
+	}
+}
+
+func (s *transactionService) Deposit(ctx context.Context, owner string, amount float32, note service.Note) (err error) {
+	ctx, span := s.tracer.Start(ctx, "TransactionService.Deposit", trace.WithAttributes(
+		AccountIdKey.String(owner),
+		AmountKey.Float64(float64(amount)),
+	))
+	defer func() { end(span, err) }()
+
+	return s.next.Deposit(ctx, owner, amount, note)
+}
+
+func (s *transactionService) Withdraw(ctx context.Context, owner string, amount float32, note service.Note) (err error) {
+	ctx, span := s.tracer.Start(ctx, "TransactionService.Withdraw", trace.WithAttributes(
+		AccountIdKey.String(owner),
+		AmountKey.Float64(float64(amount)),
+	))
+	defer func() { end(span, err) }()
+
+	return s.next.Withdraw(ctx, owner, amount, note)
+}
+
+func (s *transactionService) Transfer(ctx context.Context, sender string, receiver string, amount float32, note service.Note) (err error) {
+	ctx, span := s.tracer.Start(ctx, "TransactionService.Transfer", trace.WithAttributes(
+		AccountIdKey.String(sender),
+		ReceiverIdKey.String(receiver),
+		AmountKey.Float64(float64(amount)),
+	))
+	defer func() { end(span, err) }()
+
+	return s.next.Transfer(ctx, sender, receiver, amount, note)
+}
+
+func (s *transactionService) UpdateStatus(ctx context.Context, id string, status models.TransactionStatus) (t models.Transaction, err error) {
+	ctx, span := s.tracer.Start(ctx, "TransactionService.UpdateStatus", trace.WithAttributes(TransactionIdKey.String(id)))
+	defer func() { end(span, err) }()
+
+	return s.next.UpdateStatus(ctx, id, status)
+}
@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/service"
+	"github.com/gopay/internal/utils"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func newRecorder() (*tracetest.SpanRecorder, trace.Tracer) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return recorder, provider.Tracer("test")
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestHandler(t *testing.T) {
+	scenarios := map[string]struct {
+		givenHeader   string
+		givenStatus   int
+		wantRemote    bool
+		wantErrStatus bool
+	}{
+		"continues-traceparent": {
+			givenHeader: traceparent,
+			givenStatus: http.StatusOK,
+			wantRemote:  true,
+		},
+		"new-trace": {
+			givenStatus: http.StatusNotFound,
+		},
+		"server-error": {
+			givenStatus:   http.StatusInternalServerError,
+			wantErrStatus: true,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+
+		t.Run(name, func(t *testing.T) {
+			recorder, tracer := newRecorder()
+
+			var inner trace.SpanContext
+			handle := Handler(tracer, "GET", "/accounts/:account-id", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+				inner = trace.SpanContextFromContext(r.Context())
+				w.WriteHeader(tcase.givenStatus)
+			})
+
+			req := httptest.NewRequest("GET", "/accounts/0001", nil)
+			if tcase.givenHeader != "" {
+				req.Header.Set("traceparent", tcase.givenHeader)
+			}
+			handle(httptest.NewRecorder(), req, httprouter.Params{{Key: "account-id", Value: "0001"}})
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			span := spans[0]
+
+			assert.Equal(t, "GET /accounts/:account-id", span.Name())
+			assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+			assert.Equal(t, span.SpanContext(), inner)
+			assert.Equal(t, tcase.wantRemote, span.Parent().IsRemote())
+			if tcase.wantRemote {
+				assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+			}
+
+			attrs := attributes(span)
+			assert.Equal(t, "0001", attrs[AccountIdKey].AsString())
+			assert.Equal(t, "/accounts/:account-id", attrs["http.route"].AsString())
+			assert.Equal(t, int64(tcase.givenStatus), attrs["http.response.status_code"].AsInt64())
+			assert.Equal(t, tcase.wantErrStatus, span.Status().Code == codes.Error)
+		})
+	}
+}
+
+func TestDecorators(t *testing.T) {
+	utils.SetSyncGoroutine()
+	defer utils.ResetGoroutine()
+
+	recorder, tracer := newRecorder()
+	ctx, root := tracer.Start(context.Background(), "root")
+
+	accountRepo := NewAccountRepo(repository.NewAccountRepo(), tracer)
+	transactionRepo := NewTransactionRepo(repository.NewTransactionRepo(), tracer)
+	transactionService := NewTransactionService(
+		service.NewTransactionService(transactionRepo, accountRepo, repository.NewOutboxRepo(), repository.NewUnitOfWork()),
+		tracer,
+	)
+
+	owner, err := accountRepo.Create(ctx, "Shankar", "Nakai")
+	require.NoError(t, err)
+	require.NoError(t, transactionService.Deposit(ctx, owner, 100, service.Note{}))
+	assert.ErrorIs(t, transactionService.Withdraw(ctx, owner, -1000, service.Note{}), service.ErrInsufficentBalance)
+	root.End()
+
+	byName := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, root.SpanContext().TraceID(), span.SpanContext().TraceID())
+		byName[span.Name()] = append(byName[span.Name()], span)
+	}
+
+	require.Len(t, byName["AccountRepo.Create"], 1)
+	assert.Equal(t, owner, attributes(byName["AccountRepo.Create"][0])[AccountIdKey].AsString())
+
+	require.Len(t, byName["TransactionService.Deposit"], 1)
+	deposit := byName["TransactionService.Deposit"][0]
+	assert.Equal(t, owner, attributes(deposit)[AccountIdKey].AsString())
+	assert.Equal(t, codes.Unset, deposit.Status().Code)
+
+	// the withdrawal writes its transaction pending before it fails
+	require.Len(t, byName["TransactionRepo.Create"], 2)
+	create := byName["TransactionRepo.Create"][0]
+	assert.Equal(t, deposit.SpanContext().SpanID(), create.Parent().SpanID())
+	assert.NotEmpty(t, attributes(create)[TransactionIdKey].AsString())
+
+	require.Len(t, byName["TransactionService.Withdraw"], 1)
+	withdraw := byName["TransactionService.Withdraw"][0]
+	assert.Equal(t, codes.Error, withdraw.Status().Code)
+	assert.Equal(t, service.ErrInsufficentBalance.Error(), withdraw.Status().Description)
+}
+
+func TestLogHook(t *testing.T) {
+	_, tracer := newRecorder()
+	ctx, span := tracer.Start(context.Background(), "root")
+	defer span.End()
+
+	buf := &bytes.Buffer{}
+	logger := zerolog.New(buf).Hook(LogHook{})
+
+	logger.Error().Ctx(ctx).Msg("with span")
+	assert.Contains(t, buf.String(), `"trace_id":"`+span.SpanContext().TraceID().String()+`"`)
+	assert.Contains(t, buf.String(), `"span_id":"`+span.SpanContext().SpanID().String()+`"`)
+
+	buf.Reset()
+	logger.Error().Ctx(context.Background()).Msg("without span")
+	assert.NotContains(t, buf.String(), "trace_id")
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := Setup(context.Background(), ExporterNone)
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), "jaeger")
+	assert.ErrorIs(t, err, ErrUnknownExporter)
+}
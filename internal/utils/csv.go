@@ -0,0 +1,15 @@
+package utils
+
+import "strings"
+
+// CSVText escapes a text cell of a CSV file meant for spreadsheets, which
+// run a cell starting with =, +, - or @ as a formula, as they do with a tab
+// or carriage return in front of one. Such cells get a leading quote, which
+// spreadsheets don't show. Numbers are written as they are, a negative
+// amount isn't a formula.
+func CSVText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
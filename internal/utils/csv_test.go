@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVText(t *testing.T) {
+	scenarios := map[string]struct {
+		given string
+		want  string
+	}{
+		"plain":       {given: "Alice Smith", want: "Alice Smith"},
+		"empty":       {given: "", want: ""},
+		"equals":      {given: "=HYPERLINK(\"http://x\")", want: "'=HYPERLINK(\"http://x\")"},
+		"plus":        {given: "+1+1", want: "'+1+1"},
+		"minus":       {given: "-2+3", want: "'-2+3"},
+		"at":          {given: "@SUM(A1)", want: "'@SUM(A1)"},
+		"tab":         {given: "\t=1", want: "'\t=1"},
+		"carriage":    {given: "\r=1", want: "'\r=1"},
+		"inner-equal": {given: "a=b", want: "a=b"},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tcase.want, CSVText(tcase.given))
+		})
+	}
+}
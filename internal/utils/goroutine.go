@@ -1,7 +1,16 @@
 package utils
 
+import (
+	"context"
+	"sync"
+)
+
 var goFuncSyncronous = false
 
+// background tracks the goroutines started by Go so shutdown can wait for
+// them, e.g. webhook deliveries and the reconciler and audit loops.
+var background sync.WaitGroup
+
 func SetSyncGoroutine() {
 	goFuncSyncronous = true
 }
@@ -15,5 +24,27 @@ func Go(fn func()) {
 		fn()
 		return
 	}
-	go fn()
+
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		fn()
+	}()
+}
+
+// Wait blocks until every goroutine started by Go has returned, or returns
+// ctx.Err() when ctx is done first.
+func Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
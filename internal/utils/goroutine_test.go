@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWait(t *testing.T) {
+	scenarios := map[string]struct {
+		work    time.Duration
+		timeout time.Duration
+		wantErr error
+	}{
+		"finished": {
+			work:    time.Millisecond,
+			timeout: time.Second,
+		},
+		"deadline-exceeded": {
+			work:    time.Second,
+			timeout: 10 * time.Millisecond,
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			release := make(chan struct{})
+			defer close(release)
+
+			Go(func() {
+				select {
+				case <-time.After(tcase.work):
+				case <-release:
+				}
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), tcase.timeout)
+			defer cancel()
+
+			assert.ErrorIs(t, Wait(ctx), tcase.wantErr)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/gopay/internal/models"
+)
+
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+// DeliveryRepo is the delivery log: one entry per event sent to a
+// subscription, updated after every attempt.
+type DeliveryRepo interface {
+	FindAll(ctx context.Context, subscriptionId string) ([]models.Delivery, error)
+	FindOne(ctx context.Context, id string) (models.Delivery, error)
+	Create(ctx context.Context, delivery models.Delivery) (string, error)
+	Update(ctx context.Context, delivery models.Delivery) error
+}
+
+var _ DeliveryRepo = (*deliveryRepoImpl)(nil)
+
+type deliveryRepoImpl struct {
+	mu          sync.RWMutex
+	deliveries  map[string]models.Delivery
+	idGenerator func() string
+}
+
+func NewDeliveryRepo() *deliveryRepoImpl {
+	return &deliveryRepoImpl{
+		deliveries:  make(map[string]models.Delivery),
+		idGenerator: uuid.NewString,
+	}
+}
+
+func (r *deliveryRepoImpl) FindAll(_ context.Context, subscriptionId string) ([]models.Delivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := []models.Delivery{}
+	for _, d := range r.deliveries {
+		if d.SubscriptionId == subscriptionId {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+	})
+
+	return deliveries, nil
+}
+
+func (r *deliveryRepoImpl) FindOne(_ context.Context, id string) (models.Delivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	delivery, found := r.deliveries[id]
+	if !found {
+		return models.Delivery{}, ErrDeliveryNotFound
+	}
+
+	return delivery, nil
+}
+
+func (r *deliveryRepoImpl) Create(_ context.Context, delivery models.Delivery) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.idGenerator()
+	delivery.DeliveryId = id
+	r.deliveries[id] = delivery
+
+	return id, nil
+}
+
+func (r *deliveryRepoImpl) Update(_ context.Context, delivery models.Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.deliveries[delivery.DeliveryId]; !found {
+		return ErrDeliveryNotFound
+	}
+
+	r.deliveries[delivery.DeliveryId] = delivery
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gopay/internal/events"
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/utils"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = 2 * time.Second
+	defaultTimeout     = 10 * time.Second
+)
+
+var _ events.Publisher = (*Dispatcher)(nil)
+
+// Dispatcher delivers events to every matching subscription. Deliveries run
+// in the background and are retried with exponential backoff; each attempt
+// is recorded in the delivery log. Only public addresses are delivered to,
+// see CheckTarget.
+type Dispatcher struct {
+	subscriptions SubscriptionRepo
+	deliveries    DeliveryRepo
+	client        *http.Client
+	resolver      *net.Resolver
+	allowPrivate  bool
+	maxAttempts   int
+	backoff       time.Duration
+	sleep         func(ctx context.Context, d time.Duration) error
+	now           func() time.Time
+	closing       context.Context
+	stop          context.CancelFunc
+}
+
+func NewDispatcher(subscriptions SubscriptionRepo, deliveries DeliveryRepo) *Dispatcher {
+	closing, stop := context.WithCancel(context.Background())
+	return &Dispatcher{
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+		client:        publicClient(),
+		resolver:      net.DefaultResolver,
+		maxAttempts:   defaultMaxAttempts,
+		backoff:       defaultBackoff,
+		sleep:         sleep,
+		now:           time.Now,
+		closing:       closing,
+		stop:          stop,
+	}
+}
+
+// AllowPrivateTargets lets subscriptions point at loopback and private
+// addresses, for development against a local receiver.
+func (d *Dispatcher) AllowPrivateTargets() {
+	d.allowPrivate = true
+	d.client = &http.Client{Timeout: defaultTimeout}
+}
+
+// Close abandons the deliveries in flight, for shutdown. They stay failed in
+// the delivery log, from where they can be replayed.
+func (d *Dispatcher) Close() {
+	d.stop()
+}
+
+func (d *Dispatcher) Publish(ctx context.Context, event models.Event) error {
+	subs, err := d.subscriptions.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subs {
+		if !sub.Matches(event) {
+			continue
+		}
+
+		// the outbox relays at least once; skip events already logged
+		delivered, err := d.alreadyLogged(ctx, sub.SubscriptionId, event.EventId)
+		if err != nil {
+			return err
+		}
+		if delivered {
+			continue
+		}
+
+		delivery := models.Delivery{
+			SubscriptionId: sub.SubscriptionId,
+			Event:          event,
+			Status:         models.DeliveryPending,
+			CreatedAt:      d.now(),
+			UpdatedAt:      d.now(),
+		}
+
+		delivery.DeliveryId, err = d.deliveries.Create(ctx, delivery)
+		if err != nil {
+			return err
+		}
+
+		sub, delivery := sub, delivery
+		utils.Go(func() {
+			ctx, cancel := d.detach(ctx)
+			defer cancel()
+			d.deliver(ctx, sub, delivery, d.maxAttempts)
+		})
+	}
+
+	return nil
+}
+
+// Replay sends a logged delivery again, once and synchronously, regardless
+// of its current status.
+func (d *Dispatcher) Replay(ctx context.Context, deliveryId string) (models.Delivery, error) {
+	delivery, err := d.deliveries.FindOne(ctx, deliveryId)
+	if err != nil {
+		return models.Delivery{}, err
+	}
+
+	sub, err := d.subscriptions.FindOne(ctx, delivery.SubscriptionId)
+	if err != nil {
+		return models.Delivery{}, err
+	}
+
+	return d.deliver(ctx, sub, delivery, 1), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, sub models.Subscription, delivery models.Delivery, attempts int) models.Delivery {
+	payload, err := jsoniter.Marshal(delivery.Event)
+	if err != nil {
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = err.Error()
+		d.record(ctx, &delivery)
+		return delivery
+	}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			err = d.sleep(ctx, d.backoff*time.Duration(1<<(attempt-2)))
+			if err != nil {
+				return delivery
+			}
+		}
+
+		delivery.Attempts++
+		delivery.StatusCode, err = d.send(ctx, sub, delivery, payload)
+		if err == nil {
+			delivery.Status = models.DeliverySucceeded
+			delivery.LastError = ""
+			d.record(ctx, &delivery)
+			return delivery
+		}
+
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = err.Error()
+		d.record(ctx, &delivery)
+		log.Info().Err(err).Msgf("Webhook delivery %s attempt %d failed", delivery.DeliveryId, attempt)
+	}
+
+	return delivery
+}
+
+func (d *Dispatcher) send(ctx context.Context, sub models.Subscription, delivery models.Delivery, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(delivery.Event.Type))
+	req.Header.Set(DeliveryHeader, delivery.DeliveryId)
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, d.now(), payload))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("receiver responded with status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+// detach returns a context with the values of ctx that is only cancelled by
+// Close: deliveries outlive the request that produced the event.
+func (d *Dispatcher) detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(d.closing, cancel)
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func (d *Dispatcher) alreadyLogged(ctx context.Context, subscriptionId string, eventId string) (bool, error) {
+	deliveries, err := d.deliveries.FindAll(ctx, subscriptionId)
+	if err != nil {
+		return false, err
+	}
+
+	for _, delivery := range deliveries {
+		if delivery.Event.EventId == eventId {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (d *Dispatcher) record(ctx context.Context, delivery *models.Delivery) {
+	delivery.UpdatedAt = d.now()
+
+	err := d.deliveries.Update(ctx, *delivery)
+	if err != nil {
+		log.Error().Err(err).Msg("Dispatcher::record")
+	}
+}
+
+// sleep waits for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
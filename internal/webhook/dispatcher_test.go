@@ -0,0 +1,317 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gopay/internal/events"
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status = rc.statuses[0]
+		rc.statuses = rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func TestDispatcher_Publish(t *testing.T) {
+	utils.SetSyncGoroutine()
+	defer utils.ResetGoroutine()
+
+	var (
+		ctx    = context.Background()
+		secret = "whsec_test"
+		event  = events.New(models.EventTransactionCreated, "0001", models.Transaction{TransactionId: "1000000"})
+	)
+
+	scenarios := map[string]struct {
+		subscription  models.Subscription
+		statuses      []int
+		wantRequests  int
+		wantStatus    models.DeliveryStatus
+		wantSleeps    []time.Duration
+		wantDelivered bool
+	}{
+		"happy-path": {
+			subscription:  models.Subscription{AccountId: "0001", Secret: secret},
+			wantRequests:  1,
+			wantStatus:    models.DeliverySucceeded,
+			wantDelivered: true,
+		},
+		"retries-with-backoff": {
+			subscription:  models.Subscription{Secret: secret, Events: []models.EventType{models.EventTransactionCreated}},
+			statuses:      []int{http.StatusInternalServerError, http.StatusBadGateway},
+			wantRequests:  3,
+			wantStatus:    models.DeliverySucceeded,
+			wantSleeps:    []time.Duration{time.Second, 2 * time.Second},
+			wantDelivered: true,
+		},
+		"exhausts-attempts": {
+			subscription: models.Subscription{Secret: secret},
+			statuses: []int{
+				http.StatusInternalServerError,
+				http.StatusInternalServerError,
+				http.StatusInternalServerError,
+			},
+			wantRequests:  3,
+			wantStatus:    models.DeliveryFailed,
+			wantSleeps:    []time.Duration{time.Second, 2 * time.Second},
+			wantDelivered: true,
+		},
+		"other-account": {
+			subscription:  models.Subscription{AccountId: "0002", Secret: secret},
+			wantRequests:  0,
+			wantDelivered: false,
+		},
+		"other-event": {
+			subscription:  models.Subscription{Events: []models.EventType{models.EventAccountCreated}},
+			wantRequests:  0,
+			wantDelivered: false,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			rc := &receiver{statuses: tcase.statuses}
+			dispatcher, subs, deliveries, sleeps := setupDispatcher(t, rc)
+
+			tcase.subscription.Url = subs.url
+			subId, err := subs.Create(ctx, tcase.subscription)
+			require.NoError(t, err)
+
+			err = dispatcher.Publish(ctx, event)
+			assert.NoError(t, err)
+
+			assert.Len(t, rc.requests, tcase.wantRequests)
+			assert.Equal(t, tcase.wantSleeps, *sleeps)
+
+			logged, err := deliveries.FindAll(ctx, subId)
+			require.NoError(t, err)
+			if !tcase.wantDelivered {
+				assert.Empty(t, logged)
+				return
+			}
+
+			require.Len(t, logged, 1)
+			assert.Equal(t, tcase.wantStatus, logged[0].Status)
+			assert.Equal(t, tcase.wantRequests, logged[0].Attempts)
+			assert.Equal(t, event.EventId, logged[0].Event.EventId)
+
+			req := rc.requests[len(rc.requests)-1]
+			assert.Equal(t, string(models.EventTransactionCreated), req.Header.Get(EventHeader))
+			assert.Equal(t, logged[0].DeliveryId, req.Header.Get(DeliveryHeader))
+			assert.NoError(t, Verify(secret, req.Header.Get(SignatureHeader), rc.bodies[len(rc.bodies)-1], time.Minute, time.Now()))
+		})
+	}
+}
+
+func TestDispatcher_Replay(t *testing.T) {
+	utils.SetSyncGoroutine()
+	defer utils.ResetGoroutine()
+
+	ctx := context.Background()
+	rc := &receiver{statuses: []int{
+		http.StatusInternalServerError,
+		http.StatusInternalServerError,
+		http.StatusInternalServerError,
+	}}
+	dispatcher, subs, deliveries, _ := setupDispatcher(t, rc)
+
+	subId, err := subs.Create(ctx, models.Subscription{Url: subs.url, Secret: "whsec_test"})
+	require.NoError(t, err)
+
+	err = dispatcher.Publish(ctx, events.New(models.EventAccountCreated, "0001", models.Account{AccountId: "0001"}))
+	require.NoError(t, err)
+
+	logged, err := deliveries.FindAll(ctx, subId)
+	require.NoError(t, err)
+	require.Len(t, logged, 1)
+	assert.Equal(t, models.DeliveryFailed, logged[0].Status)
+
+	replayed, err := dispatcher.Replay(ctx, logged[0].DeliveryId)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DeliverySucceeded, replayed.Status)
+	assert.Equal(t, 4, replayed.Attempts)
+	assert.Len(t, rc.requests, 4)
+
+	stored, err := deliveries.FindOne(ctx, logged[0].DeliveryId)
+	assert.NoError(t, err)
+	assert.Equal(t, replayed, stored)
+
+	_, err = dispatcher.Replay(ctx, "missing")
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+}
+
+func TestDispatcher_PublishDeduplicates(t *testing.T) {
+	utils.SetSyncGoroutine()
+	defer utils.ResetGoroutine()
+
+	ctx := context.Background()
+	rc := &receiver{}
+	dispatcher, subs, deliveries, _ := setupDispatcher(t, rc)
+
+	subId, err := subs.Create(ctx, models.Subscription{Url: subs.url, Secret: "whsec_test"})
+	require.NoError(t, err)
+
+	event := events.New(models.EventAccountCreated, "0001", models.Account{AccountId: "0001"})
+	assert.NoError(t, dispatcher.Publish(ctx, event))
+	assert.NoError(t, dispatcher.Publish(ctx, event))
+
+	logged, err := deliveries.FindAll(ctx, subId)
+	assert.NoError(t, err)
+	assert.Len(t, logged, 1)
+	assert.Len(t, rc.requests, 1)
+}
+
+func TestDispatcher_Close(t *testing.T) {
+	utils.SetSyncGoroutine()
+	defer utils.ResetGoroutine()
+
+	ctx := context.Background()
+	rc := &receiver{statuses: []int{http.StatusInternalServerError}}
+	dispatcher, subs, deliveries, _ := setupDispatcher(t, rc)
+	// shutdown starts while the first retry waits for its backoff
+	dispatcher.sleep = func(ctx context.Context, _ time.Duration) error {
+		dispatcher.Close()
+		return sleep(ctx, time.Hour)
+	}
+
+	subId, err := subs.Create(ctx, models.Subscription{Url: subs.url, Secret: "whsec_test"})
+	require.NoError(t, err)
+
+	assert.NoError(t, dispatcher.Publish(ctx, events.New(models.EventAccountCreated, "0001", models.Account{AccountId: "0001"})))
+
+	logged, err := deliveries.FindAll(ctx, subId)
+	require.NoError(t, err)
+	require.Len(t, logged, 1)
+	assert.Equal(t, models.DeliveryFailed, logged[0].Status)
+	assert.Equal(t, 1, logged[0].Attempts)
+	assert.Len(t, rc.requests, 1)
+}
+
+func TestDispatcher_CheckTarget(t *testing.T) {
+	scenarios := map[string]struct {
+		url          string
+		allowPrivate bool
+		wantErr      error
+	}{
+		"public-address": {
+			url: "https://93.184.216.34/hooks",
+		},
+		"loopback": {
+			url:     "http://127.0.0.1:8080/hooks",
+			wantErr: ErrPrivateTarget,
+		},
+		"loopback-v6": {
+			url:     "http://[::1]/hooks",
+			wantErr: ErrPrivateTarget,
+		},
+		"resolves-to-loopback": {
+			url:     "http://localhost/hooks",
+			wantErr: ErrPrivateTarget,
+		},
+		"metadata-service": {
+			url:     "http://169.254.169.254/latest/meta-data",
+			wantErr: ErrPrivateTarget,
+		},
+		"private-network": {
+			url:     "https://10.0.0.7/hooks",
+			wantErr: ErrPrivateTarget,
+		},
+		"carrier-grade-nat": {
+			url:     "https://100.64.0.1/hooks",
+			wantErr: ErrPrivateTarget,
+		},
+		"private-allowed": {
+			url:          "http://127.0.0.1:8080/hooks",
+			allowPrivate: true,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			dispatcher := NewDispatcher(NewSubscriptionRepo(), NewDeliveryRepo())
+			if tcase.allowPrivate {
+				dispatcher.AllowPrivateTargets()
+			}
+
+			err := dispatcher.CheckTarget(context.Background(), tcase.url)
+			assert.ErrorIs(t, err, tcase.wantErr)
+		})
+	}
+}
+
+func TestDispatcher_PublishRefusesPrivateAddresses(t *testing.T) {
+	utils.SetSyncGoroutine()
+	defer utils.ResetGoroutine()
+
+	ctx := context.Background()
+	rc := &receiver{}
+	dispatcher, subs, deliveries, _ := setupDispatcher(t, rc)
+	// the subscription was made while the host resolved elsewhere
+	dispatcher.client = publicClient()
+
+	subId, err := subs.Create(ctx, models.Subscription{Url: subs.url, Secret: "whsec_test"})
+	require.NoError(t, err)
+
+	assert.NoError(t, dispatcher.Publish(ctx, events.New(models.EventAccountCreated, "0001", models.Account{AccountId: "0001"})))
+
+	logged, err := deliveries.FindAll(ctx, subId)
+	require.NoError(t, err)
+	require.Len(t, logged, 1)
+	assert.Equal(t, models.DeliveryFailed, logged[0].Status)
+	assert.Contains(t, logged[0].LastError, ErrPrivateTarget.Error())
+	assert.Empty(t, rc.requests)
+}
+
+type testSubscriptionRepo struct {
+	*subscriptionRepoImpl
+	url string
+}
+
+func setupDispatcher(t *testing.T, rc *receiver) (*Dispatcher, testSubscriptionRepo, *deliveryRepoImpl, *[]time.Duration) {
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	subs := testSubscriptionRepo{subscriptionRepoImpl: NewSubscriptionRepo(), url: srv.URL}
+	deliveries := NewDeliveryRepo()
+	var sleeps []time.Duration
+
+	dispatcher := NewDispatcher(subs, deliveries)
+	dispatcher.client = srv.Client()
+	dispatcher.maxAttempts = 3
+	dispatcher.backoff = time.Second
+	dispatcher.sleep = func(_ context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+
+	return dispatcher, subs, deliveries, &sleeps
+}
@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignature_Verify(t *testing.T) {
+	now := time.Unix(1790000000, 0)
+	payload := []byte(`{"type":"transaction.created"}`)
+	secret := "whsec_test"
+
+	scenarios := map[string]struct {
+		header  string
+		payload []byte
+		now     time.Time
+		wantErr error
+	}{
+		"happy-path": {
+			header:  Sign(secret, now, payload),
+			payload: payload,
+			now:     now.Add(30 * time.Second),
+			wantErr: nil,
+		},
+		"wrong-secret": {
+			header:  Sign("whsec_other", now, payload),
+			payload: payload,
+			now:     now,
+			wantErr: ErrInvalidSignature,
+		},
+		"tampered-payload": {
+			header:  Sign(secret, now, payload),
+			payload: []byte(`{"type":"balance.changed"}`),
+			now:     now,
+			wantErr: ErrInvalidSignature,
+		},
+		"stale-timestamp": {
+			header:  Sign(secret, now, payload),
+			payload: payload,
+			now:     now.Add(10 * time.Minute),
+			wantErr: ErrStaleSignature,
+		},
+		"malformed-header": {
+			header:  "v1=abc",
+			payload: payload,
+			now:     now,
+			wantErr: ErrInvalidSignature,
+		},
+	}
+
+	for name, tcase := range scenarios {
+		tcase := tcase
+		t.Run(name, func(t *testing.T) {
+			err := Verify(secret, tcase.header, tcase.payload, 5*time.Minute, tcase.now)
+
+			if tcase.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tcase.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/gopay/internal/models"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrMissingUrl           = errors.New("must provide a webhook url")
+)
+
+type SubscriptionRepo interface {
+	FindAll(ctx context.Context) ([]models.Subscription, error)
+	FindOne(ctx context.Context, id string) (models.Subscription, error)
+	Create(ctx context.Context, subscription models.Subscription) (string, error)
+	Delete(ctx context.Context, id string) error
+}
+
+var _ SubscriptionRepo = (*subscriptionRepoImpl)(nil)
+
+type subscriptionRepoImpl struct {
+	mu            sync.RWMutex
+	subscriptions map[string]models.Subscription
+	idGenerator   func() string
+}
+
+func NewSubscriptionRepo() *subscriptionRepoImpl {
+	return &subscriptionRepoImpl{
+		subscriptions: make(map[string]models.Subscription),
+		idGenerator:   uuid.NewString,
+	}
+}
+
+func (r *subscriptionRepoImpl) FindAll(_ context.Context) ([]models.Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subs := []models.Subscription{}
+	for _, s := range r.subscriptions {
+		subs = append(subs, s)
+	}
+
+	sort.Slice(subs, func(i, j int) bool {
+		return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+	})
+
+	return subs, nil
+}
+
+func (r *subscriptionRepoImpl) FindOne(_ context.Context, id string) (models.Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub, found := r.subscriptions[id]
+	if !found {
+		return models.Subscription{}, ErrSubscriptionNotFound
+	}
+
+	return sub, nil
+}
+
+func (r *subscriptionRepoImpl) Create(_ context.Context, subscription models.Subscription) (string, error) {
+	if subscription.Url == "" {
+		return "", ErrMissingUrl
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.idGenerator()
+	subscription.SubscriptionId = id
+	r.subscriptions[id] = subscription
+
+	return id, nil
+}
+
+func (r *subscriptionRepoImpl) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.subscriptions[id]; !found {
+		return ErrSubscriptionNotFound
+	}
+
+	delete(r.subscriptions, id)
+
+	return nil
+}
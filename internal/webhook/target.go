@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+)
+
+var (
+	ErrPrivateTarget    = errors.New("webhook url must point to a public address")
+	ErrUnresolvedTarget = errors.New("webhook url host doesn't resolve")
+)
+
+// reserved are the ranges net.IP has no predicate for that still aren't
+// reachable on the internet: "this network", carrier-grade NAT and
+// benchmarking.
+var reserved = []*net.IPNet{
+	mustCIDR("0.0.0.0/8"),
+	mustCIDR("100.64.0.0/10"),
+	mustCIDR("198.18.0.0/15"),
+}
+
+func mustCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// public tells whether ip is an internet address rather than the loopback,
+// a private network or a link-local one such as the cloud metadata service
+// at 169.254.169.254.
+func public(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+
+	for _, n := range reserved {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckTarget fails with ErrPrivateTarget when the host of rawUrl is, or
+// resolves to, an address that isn't public, unless the dispatcher allows
+// private targets.
+func (d *Dispatcher) CheckTarget(ctx context.Context, rawUrl string) error {
+	if d.allowPrivate {
+		return nil
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		if !public(ip) {
+			return ErrPrivateTarget
+		}
+		return nil
+	}
+
+	addrs, err := d.resolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUnresolvedTarget, err)
+	}
+	for _, addr := range addrs {
+		if !public(addr.IP) {
+			return ErrPrivateTarget
+		}
+	}
+	return nil
+}
+
+// publicClient only connects to public addresses. The check runs on the
+// address being dialled, after resolution, so a host that resolved to a
+// public address when it was subscribed can't be pointed elsewhere later.
+func publicClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: defaultTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !public(ip) {
+				return ErrPrivateTarget
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// a proxy would connect on our behalf and skip the check
+	transport.Proxy = nil
+
+	return &http.Client{Timeout: defaultTimeout, Transport: transport}
+}
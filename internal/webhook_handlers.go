@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gopay/internal/auth"
+	"github.com/gopay/internal/models"
+	"github.com/gopay/internal/repository"
+	"github.com/gopay/internal/utils"
+	"github.com/gopay/internal/webhook"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	SubscriptionIdParam = "webhook-id"
+	DeliveryIdParam     = "delivery-id"
+)
+
+var ErrInvalidWebhookUrl = errors.New("webhook url must be an absolute http(s) url")
+
+type WebhookHandler struct {
+	dispatcher    *webhook.Dispatcher
+	subscriptions webhook.SubscriptionRepo
+	deliveries    webhook.DeliveryRepo
+	accountRepo   repository.AccountRepo
+}
+
+func NewWebhookHandler(dispatcher *webhook.Dispatcher, subscriptions webhook.SubscriptionRepo, deliveries webhook.DeliveryRepo, accountRepo repository.AccountRepo) *WebhookHandler {
+	return &WebhookHandler{
+		dispatcher:    dispatcher,
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+		accountRepo:   accountRepo,
+	}
+}
+
+func (h *WebhookHandler) GetAllWebhooks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	subs, err := h.subscriptions.FindAll(r.Context())
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetAllWebhooks")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// secrets are only returned once, when the subscription is created
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+
+	res, err := jsoniter.Marshal(&subs)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusOK, res)
+}
+
+func (h *WebhookHandler) PostWebhook(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	sub := models.Subscription{}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, OneMegabyte))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer r.Body.Close()
+	err = jsoniter.Unmarshal(body, &sub)
+
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
+		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	u, err := url.ParseRequestURI(sub.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		log.Ctx(r.Context()).Error().Err(ErrInvalidWebhookUrl).Msg("Handler::PostWebhook")
+		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, ErrInvalidWebhookUrl.Error())
+		return
+	}
+
+	if sub.AccountId != "" {
+		_, err = h.accountRepo.FindOne(r.Context(), sub.AccountId)
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(ErrAccountNotFound).Msg("Handler::PostWebhook")
+			utils.ErrorWithMessage(w, http.StatusNotFound, ErrAccountNotFound.Error())
+			return
+		}
+	} else if !ScopeFrom(r.Context()).Allows(auth.ScopeAdmin) {
+		// events of every account are for admins only
+		log.Ctx(r.Context()).Error().Err(auth.ErrForbidden).Msg("Handler::PostWebhook")
+		utils.ErrorWithMessage(w, http.StatusForbidden, auth.ErrForbidden.Error())
+		return
+	}
+
+	err = h.dispatcher.CheckTarget(r.Context(), sub.Url)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostWebhook")
+		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	if sub.Secret == "" {
+		sub.Secret = webhook.NewSecret()
+	}
+	sub.CreatedAt = time.Now()
+
+	sub.SubscriptionId, err = h.subscriptions.Create(r.Context(), sub)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostWebhook")
+		utils.ErrorWithMessage(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	res, err := jsoniter.Marshal(&sub)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusCreated, res)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	id := params.ByName(SubscriptionIdParam)
+
+	err := h.subscriptions.Delete(r.Context(), id)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::DeleteWebhook")
+		utils.ErrorWithMessage(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusNoContent, nil)
+}
+
+func (h *WebhookHandler) GetAllDeliveries(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	id := params.ByName(SubscriptionIdParam)
+
+	_, err := h.subscriptions.FindOne(r.Context(), id)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetAllDeliveries")
+		utils.ErrorWithMessage(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	deliveries, err := h.deliveries.FindAll(r.Context(), id)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::GetAllDeliveries")
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	res, err := jsoniter.Marshal(&deliveries)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusOK, res)
+}
+
+func (h *WebhookHandler) PostReplayDelivery(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	subId := params.ByName(SubscriptionIdParam)
+	id := params.ByName(DeliveryIdParam)
+
+	delivery, err := h.deliveries.FindOne(r.Context(), id)
+	if err != nil || delivery.SubscriptionId != subId {
+		log.Ctx(r.Context()).Error().Err(webhook.ErrDeliveryNotFound).Msg("Handler::PostReplayDelivery")
+		utils.ErrorWithMessage(w, http.StatusNotFound, webhook.ErrDeliveryNotFound.Error())
+		return
+	}
+
+	delivery, err = h.dispatcher.Replay(r.Context(), id)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("Handler::PostReplayDelivery")
+		utils.ErrorWithMessage(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	res, err := jsoniter.Marshal(&delivery)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg(err.Error())
+		utils.ErrorWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WithPayload(w, http.StatusOK, res)
+}